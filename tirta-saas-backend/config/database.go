@@ -39,6 +39,29 @@ func ConnectDB() {
 func Migrate() {
 	log.Println("🚀 Memulai proses migrasi database...")
 
+	// Template codes used to be unique across all tenants, replaced by idx_tenant_template_code
+	if DB.Migrator().HasIndex(&models.NotificationTemplate{}, "idx_tenant_code") {
+		if err := DB.Migrator().DropIndex(&models.NotificationTemplate{}, "idx_tenant_code"); err != nil {
+			log.Printf("⚠️ Failed to drop index idx_tenant_code: %v", err)
+		}
+	}
+
+	// Client reading IDs were only indexed, replaced by the unique idx_usage_client_reading
+	if DB.Migrator().HasIndex(&models.WaterUsage{}, "idx_water_usages_client_reading_id") {
+		if err := DB.Migrator().DropIndex(&models.WaterUsage{}, "idx_water_usages_client_reading_id"); err != nil {
+			log.Printf("⚠️ Failed to drop index idx_water_usages_client_reading_id: %v", err)
+		}
+	}
+
+	// Invoice numbers used to be unique across all tenants, replaced by idx_tenant_invoice_number
+	for _, index := range []string{"uni_invoices_invoice_number", "invoice_number"} {
+		if DB.Migrator().HasIndex(&models.Invoice{}, index) {
+			if err := DB.Migrator().DropIndex(&models.Invoice{}, index); err != nil {
+				log.Printf("⚠️ Failed to drop index %s: %v", index, err)
+			}
+		}
+	}
+
 	// Migration order is important due to foreign key constraints
 	// 1. Base entities first (no dependencies)
 	// 2. Entities with foreign keys last
@@ -80,6 +103,23 @@ func Migrate() {
 		&models.ReadingAnomaly{},             // References Tenant + WaterUsage + User
 		&models.SubscriptionPayment{},        // References Tenant (subscription upgrade payments)
 		&models.InvoiceGenerationHistory{},   // References Tenant
+		&models.CustomerLedgerEntry{},        // References Tenant + Customer + Invoice + Payment
+		&models.CustomerPayment{},            // References Tenant + Customer
+		&models.PaymentAllocation{},          // References CustomerPayment + Payment + Invoice
+		&models.PaymentTransaction{},         // References Tenant + Customer + Invoice + Payment
+		&models.BankStatementImport{},        // References Tenant + BankAccount
+		&models.BankStatementLine{},          // References BankStatementImport + Payment + Invoice
+		&models.InvoiceAdjustment{},          // References Tenant + Invoice + Customer
+		&models.InvoicePenalty{},             // References Invoice (overdue and charged)
+		&models.InstallmentPlan{},            // References Tenant + Customer
+		&models.InstallmentPlanInvoice{},     // References InstallmentPlan + Invoice
+		&models.Installment{},                // References InstallmentPlan
+		&models.InvoiceGenerationJob{},       // References Tenant
+		&models.InvoiceGenerationJobItem{},   // References InvoiceGenerationJob + WaterUsage + Invoice
+		&models.SchedulerLease{},             // Leader election between instances
+		&models.SchedulerJobRun{},            // Scheduled job executions
+		&models.InvoiceSequence{},            // References Tenant
+		&models.InvoiceLine{},                // References Invoice + InvoiceAdjustment
 	)
 
 	if err != nil {
@@ -7,10 +7,12 @@ import (
 	"github.com/adipras/tirta-saas-backend/config"
 	"github.com/adipras/tirta-saas-backend/constants"
 	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/services"
 	"github.com/adipras/tirta-saas-backend/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type RegisterInput struct {
@@ -207,19 +209,21 @@ func CreateCustomerAccount(c *gin.Context) {
 
 	// Create registration invoice
 	invoice := models.Invoice{
-		CustomerID:  customer.ID,
-		UsageMonth:  "",
-		UsageM3:     0,
-		Abonemen:    subscription.RegistrationFee,
-		PricePerM3:  0,
-		TotalAmount: subscription.RegistrationFee,
-		TotalPaid:   0,
-		IsPaid:      false,
-		TenantID:    tenantID,
-		Type:        "registration",
-	}
-
-	if err := config.DB.Create(&invoice).Error; err != nil {
+		CustomerID: customer.ID,
+		UsageMonth: "",
+		UsageM3:    0,
+		TotalPaid:  0,
+		IsPaid:     false,
+		TenantID:   tenantID,
+		Type:       "registration",
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := services.CreateInvoice(tx, &invoice, services.RegistrationInvoiceLines(tenantID, subscription.RegistrationFee)); err != nil {
+			return err
+		}
+		return services.RecordInvoiceCharges(tx, invoice)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat invoice pendaftaran"})
 		return
 	}
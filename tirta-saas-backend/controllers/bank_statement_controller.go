@@ -0,0 +1,290 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Largest bank statement file accepted (10MB)
+const maxStatementFileSize = 10 * 1024 * 1024
+
+// ImportBankStatement godoc
+// @Summary Import bank statement
+// @Description Upload a bank mutation file (CSV, or the CSV export of BCA, Mandiri, BRI or BNI) for one of the tenant's bank accounts. Incoming transfers are matched to pending transfer proofs and open invoices by transfer reference and invoice number and posted; lines that only fit on amount and date are left on the exceptions list with a suggestion for finance.
+// @Tags Bank Reconciliation
+// @Accept multipart/form-data
+// @Produce json
+// @Param bank_account_id formData string true "Bank account the statement belongs to"
+// @Param format formData string false "AUTO (default), CSV, BCA, MANDIRI, BRI or BNI"
+// @Param file formData file true "Bank statement file"
+// @Security BearerAuth
+// @Success 201 {object} models.BankStatementImport
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/bank-statements/import [post]
+func ImportBankStatement(c *gin.Context) {
+	tenantID, userID, ok := reconciliationContext(c)
+	if !ok {
+		return
+	}
+
+	bankAccountID, err := uuid.Parse(c.PostForm("bank_account_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bank account ID"})
+		return
+	}
+
+	format := c.DefaultPostForm("format", models.BankStatementFormatAuto)
+	switch format {
+	case models.BankStatementFormatAuto, models.BankStatementFormatCSV, models.BankStatementFormatBCA,
+		models.BankStatementFormatMandiri, models.BankStatementFormatBRI, models.BankStatementFormatBNI:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format harus AUTO, CSV, BCA, MANDIRI, BRI atau BNI"})
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File mutasi wajib diunggah"})
+		return
+	}
+	if file.Size > maxStatementFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File size must not exceed 10MB"})
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File mutasi tidak dapat dibuka"})
+		return
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(src)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File mutasi tidak dapat dibaca"})
+		return
+	}
+
+	statement, err := services.NewBankReconciliationService().Import(services.StatementImportInput{
+		TenantID:      tenantID,
+		BankAccountID: bankAccountID,
+		Format:        format,
+		FileName:      file.Filename,
+		Data:          data,
+		ImportedBy:    userID,
+	})
+	if err != nil {
+		respondReconciliationError(c, err, "Gagal mengimpor mutasi bank")
+		return
+	}
+
+	c.JSON(http.StatusCreated, statement)
+}
+
+// GetBankStatementImports godoc
+// @Summary List bank statement imports
+// @Tags Bank Reconciliation
+// @Produce json
+// @Param bank_account_id query string false "Bank account ID"
+// @Security BearerAuth
+// @Success 200 {array} models.BankStatementImport
+// @Router /api/bank-statements [get]
+func GetBankStatementImports(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := config.DB.Preload("BankAccount").Where("tenant_id = ?", tenantID)
+	if bankAccountID := c.Query("bank_account_id"); bankAccountID != "" {
+		query = query.Where("bank_account_id = ?", bankAccountID)
+	}
+
+	var imports []models.BankStatementImport
+	if err := query.Order("created_at desc").Find(&imports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data mutasi"})
+		return
+	}
+
+	c.JSON(http.StatusOK, imports)
+}
+
+// GetBankStatementImport godoc
+// @Summary Get bank statement import
+// @Description Get an import with all its lines and how each was reconciled
+// @Tags Bank Reconciliation
+// @Produce json
+// @Param id path string true "Import ID"
+// @Security BearerAuth
+// @Success 200 {object} models.BankStatementImport
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/bank-statements/{id} [get]
+func GetBankStatementImport(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var statement models.BankStatementImport
+	if err := config.DB.Preload("BankAccount").
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("line_number ASC") }).
+		Where("id = ? AND tenant_id = ?", c.Param("id"), tenantID).First(&statement).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mutasi tidak ditemukan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+// GetStatementExceptions godoc
+// @Summary Reconciliation exceptions
+// @Description Incoming transfers that could not be matched automatically, oldest first, with a suggested invoice where only the amount fits
+// @Tags Bank Reconciliation
+// @Produce json
+// @Param bank_account_id query string false "Bank account ID"
+// @Security BearerAuth
+// @Success 200 {array} models.BankStatementLine
+// @Router /api/bank-statements/exceptions [get]
+func GetStatementExceptions(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := config.DB.Where("tenant_id = ? AND status = ?", tenantID, models.StatementLineException)
+	if bankAccountID := c.Query("bank_account_id"); bankAccountID != "" {
+		query = query.Where("bank_account_id = ?", bankAccountID)
+	}
+
+	var lines []models.BankStatementLine
+	if err := query.Order("transaction_date asc, line_number asc").Find(&lines).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data mutasi"})
+		return
+	}
+
+	c.JSON(http.StatusOK, lines)
+}
+
+// MatchStatementLine godoc
+// @Summary Match statement line
+// @Description Post an exception line to an open invoice, or approve the pending transfer proof it confirms
+// @Tags Bank Reconciliation
+// @Accept json
+// @Produce json
+// @Param id path string true "Statement line ID"
+// @Param request body requests.MatchStatementLineRequest true "Invoice or payment"
+// @Security BearerAuth
+// @Success 200 {object} models.BankStatementLine
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/bank-statements/lines/{id}/match [post]
+func MatchStatementLine(c *gin.Context) {
+	var req requests.MatchStatementLineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID, userID, ok := reconciliationContext(c)
+	if !ok {
+		return
+	}
+
+	lineID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid line ID"})
+		return
+	}
+
+	line, err := services.NewBankReconciliationService().MatchLine(tenantID, lineID, userID, services.StatementMatchInput{
+		InvoiceID: req.InvoiceID,
+		PaymentID: req.PaymentID,
+	})
+	if err != nil {
+		respondReconciliationError(c, err, "Gagal mencocokkan mutasi")
+		return
+	}
+
+	c.JSON(http.StatusOK, line)
+}
+
+// IgnoreStatementLine godoc
+// @Summary Ignore statement line
+// @Description Take a transfer that is not a customer payment, e.g. bank interest, off the exceptions list
+// @Tags Bank Reconciliation
+// @Accept json
+// @Produce json
+// @Param id path string true "Statement line ID"
+// @Param request body requests.IgnoreStatementLineRequest true "Reason"
+// @Security BearerAuth
+// @Success 200 {object} models.BankStatementLine
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/bank-statements/lines/{id}/ignore [post]
+func IgnoreStatementLine(c *gin.Context) {
+	var req requests.IgnoreStatementLineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID, userID, ok := reconciliationContext(c)
+	if !ok {
+		return
+	}
+
+	lineID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid line ID"})
+		return
+	}
+
+	line, err := services.NewBankReconciliationService().IgnoreLine(tenantID, lineID, userID, req.Notes)
+	if err != nil {
+		respondReconciliationError(c, err, "Gagal mengabaikan mutasi")
+		return
+	}
+
+	c.JSON(http.StatusOK, line)
+}
+
+// reconciliationContext returns the tenant and the user doing the reconciliation
+func reconciliationContext(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, ok := c.Get("user_id")
+	id, isUUID := userID.(uuid.UUID)
+	if !ok || !isUUID {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return tenantID, id, true
+}
+
+func respondReconciliationError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrBankAccountNotFound), errors.Is(err, services.ErrStatementLineNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrStatementFormatUnknown), errors.Is(err, services.ErrStatementLineReconciled),
+		errors.Is(err, services.ErrStatementAmountMismatch), errors.Is(err, services.ErrStatementMatchTarget):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		respondPaymentPostingError(c, err, message)
+	}
+}
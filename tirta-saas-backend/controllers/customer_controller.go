@@ -8,6 +8,7 @@ import (
 	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/adipras/tirta-saas-backend/requests"
 	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/services"
 	"github.com/adipras/tirta-saas-backend/utils"
 
 	"github.com/gin-gonic/gin"
@@ -76,18 +77,20 @@ func CreateCustomer(c *gin.Context) {
 
 	// Buat Invoice untuk biaya pendaftaran
 	invoice := models.Invoice{
-		CustomerID:  customer.ID,
-		UsageMonth:  "", // Kosong karena ini bukan invoice pemakaian
-		UsageM3:     0,
-		Abonemen:    0,
-		PricePerM3:  0,
-		TotalAmount: subType.RegistrationFee,
-		IsPaid:      false,
-		TotalPaid:   0,
-		Type:        "registration",
-		TenantID:    tenantID,
-	}
-	if err := tx.Create(&invoice).Error; err != nil {
+		CustomerID: customer.ID,
+		UsageMonth: "", // Kosong karena ini bukan invoice pemakaian
+		UsageM3:    0,
+		IsPaid:     false,
+		TotalPaid:  0,
+		Type:       "registration",
+		TenantID:   tenantID,
+	}
+	if err := services.CreateInvoice(tx, &invoice, services.RegistrationInvoiceLines(tenantID, subType.RegistrationFee)); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create registration invoice"})
+		return
+	}
+	if err := services.RecordInvoiceCharges(tx, invoice); err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create registration invoice"})
 		return
@@ -225,6 +228,7 @@ func GetCustomer(c *gin.Context) {
 		Phone:          customer.Phone,
 		SubscriptionID: customer.SubscriptionID,
 		IsActive:       customer.IsActive,
+		CreditBalance:  customer.CreditBalance,
 		CreatedAt:      customer.CreatedAt,
 	}
 	
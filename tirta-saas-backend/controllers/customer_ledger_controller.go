@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetCustomerStatement godoc
+// @Summary Customer statement of account
+// @Description Ledger entries of a customer with running balance. A negative balance is credit in the customer's favour.
+// @Tags Customers
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Param from query string false "First day of the period (YYYY-MM-DD)"
+// @Param to query string false "Last day of the period (YYYY-MM-DD)"
+// @Security BearerAuth
+// @Success 200 {object} responses.CustomerStatementResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/customers/{id}/statement [get]
+func GetCustomerStatement(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "customer_id tidak valid"})
+		return
+	}
+
+	respondCustomerStatement(c, tenantID, customerID)
+}
+
+// CreateCustomerCredit godoc
+// @Summary Credit a customer account
+// @Description Post a refund, write-off or adjustment credit. The credit pays the customer's open invoices, oldest first, and the rest stays as credit balance. A write-off cannot exceed the outstanding amount and never becomes credit balance.
+// @Tags Customers
+// @Accept json
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Param request body requests.CreateCustomerCreditRequest true "Credit"
+// @Security BearerAuth
+// @Success 201 {object} responses.CustomerLedgerEntryResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/customers/{id}/ledger/credits [post]
+func CreateCustomerCredit(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "customer_id tidak valid"})
+		return
+	}
+
+	var req requests.CreateCustomerCreditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input := services.ManualCreditInput{
+		TenantID:    tenantID,
+		CustomerID:  customerID,
+		Type:        models.LedgerEntryType(req.Type),
+		Amount:      req.Amount,
+		Description: req.Description,
+	}
+	if userID, ok := c.Get("user_id"); ok {
+		if id, ok := userID.(uuid.UUID); ok {
+			input.CreatedBy = &id
+		}
+	}
+
+	entry, err := services.NewCustomerLedgerService().PostCredit(input)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrLedgerCustomerNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrLedgerCreditType), errors.Is(err, services.ErrLedgerAmountInvalid),
+			errors.Is(err, services.ErrLedgerWriteOffExceeds):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mencatat kredit pelanggan"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, toLedgerEntryResponse(services.StatementLine{CustomerLedgerEntry: *entry}))
+}
+
+// CustomerGetStatement returns the statement of account of the logged in customer
+func CustomerGetStatement(c *gin.Context) {
+	customerID := c.MustGet("customer_id").(uuid.UUID)
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+
+	respondCustomerStatement(c, tenantID, customerID)
+}
+
+func respondCustomerStatement(c *gin.Context, tenantID, customerID uuid.UUID) {
+	var from, to *time.Time
+	if value := c.Query("from"); value != "" {
+		date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from harus berformat YYYY-MM-DD"})
+			return
+		}
+		from = &date
+	}
+	if value := c.Query("to"); value != "" {
+		date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to harus berformat YYYY-MM-DD"})
+			return
+		}
+		// The last day is included in the period
+		end := date.AddDate(0, 0, 1)
+		to = &end
+	}
+
+	statement, err := services.NewCustomerLedgerService().Statement(tenantID, customerID, from, to)
+	if err != nil {
+		if errors.Is(err, services.ErrLedgerCustomerNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil laporan rekening pelanggan"})
+		return
+	}
+
+	response := responses.CustomerStatementResponse{
+		CustomerID:     statement.Customer.ID,
+		CustomerName:   statement.Customer.Name,
+		MeterNumber:    statement.Customer.MeterNumber,
+		From:           from,
+		To:             to,
+		OpeningBalance: statement.OpeningBalance,
+		TotalDebit:     statement.TotalDebit,
+		TotalCredit:    statement.TotalCredit,
+		ClosingBalance: statement.ClosingBalance,
+		CreditBalance:  statement.Customer.CreditBalance,
+		Entries:        make([]responses.CustomerLedgerEntryResponse, len(statement.Lines)),
+	}
+	if to != nil {
+		last := to.AddDate(0, 0, -1)
+		response.To = &last
+	}
+	for i, line := range statement.Lines {
+		response.Entries[i] = toLedgerEntryResponse(line)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func toLedgerEntryResponse(line services.StatementLine) responses.CustomerLedgerEntryResponse {
+	return responses.CustomerLedgerEntryResponse{
+		ID:          line.ID,
+		EntryDate:   line.EntryDate,
+		Type:        string(line.Type),
+		Description: line.Description,
+		Debit:       line.Debit,
+		Credit:      line.Credit,
+		Balance:     line.Balance,
+		InvoiceID:   line.InvoiceID,
+		PaymentID:   line.PaymentID,
+	}
+}
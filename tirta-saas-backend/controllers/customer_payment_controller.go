@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateCustomerPayment godoc
+// @Summary Create customer payment
+// @Description Record one payment of a customer and allocate it across the outstanding invoices, oldest first. Penalties are paid before principal unless the tenant allocates principal first. Money left over becomes credit.
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param request body requests.CreateCustomerPaymentRequest true "Customer payment"
+// @Security BearerAuth
+// @Success 201 {object} responses.CustomerPaymentResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/payments/customer-payments [post]
+func CreateCustomerPayment(c *gin.Context) {
+	var req requests.CreateCustomerPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.PaymentMethodID != nil {
+		var method models.PaymentMethod
+		if err := config.DB.Where("id = ? AND tenant_id = ?", *req.PaymentMethodID, tenantID).First(&method).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Metode pembayaran tidak ditemukan"})
+			return
+		}
+	}
+
+	input := services.CustomerPaymentInput{
+		TenantID:        tenantID,
+		CustomerID:      req.CustomerID,
+		Amount:          req.Amount,
+		PaymentMethodID: req.PaymentMethodID,
+		ReferenceNumber: req.ReferenceNumber,
+		Notes:           req.Notes,
+	}
+	if userID, ok := c.Get("user_id"); ok {
+		if id, ok := userID.(uuid.UUID); ok {
+			input.ReceivedBy = &id
+		}
+	}
+
+	payment, err := services.NewPaymentPostingService().PostCustomerPayment(input)
+	if err != nil {
+		respondPaymentPostingError(c, err, "Gagal mencatat pembayaran")
+		return
+	}
+
+	loaded, err := loadCustomerPayment(tenantID, payment.ID.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data pembayaran"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toCustomerPaymentResponse(*loaded))
+}
+
+// ListCustomerPayments godoc
+// @Summary List customer payments
+// @Description List payments that were allocated across several invoices
+// @Tags Payments
+// @Produce json
+// @Param customer_id query string false "Filter by customer ID"
+// @Security BearerAuth
+// @Success 200 {array} responses.CustomerPaymentResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/payments/customer-payments [get]
+func ListCustomerPayments(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := config.DB.Preload("Customer").Preload("Allocations", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sequence ASC")
+	}).Preload("Allocations.Invoice").Where("tenant_id = ?", tenantID)
+
+	if value := c.Query("customer_id"); value != "" {
+		customerID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "customer_id tidak valid"})
+			return
+		}
+		query = query.Where("customer_id = ?", customerID)
+	}
+
+	var payments []models.CustomerPayment
+	if err := query.Order("paid_at DESC").Find(&payments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data pembayaran"})
+		return
+	}
+
+	result := make([]responses.CustomerPaymentResponse, len(payments))
+	for i, payment := range payments {
+		result[i] = toCustomerPaymentResponse(payment)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetCustomerPayment godoc
+// @Summary Get customer payment
+// @Description Get a customer payment with its allocation lines
+// @Tags Payments
+// @Produce json
+// @Param id path string true "Customer payment ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.CustomerPaymentResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/payments/customer-payments/{id} [get]
+func GetCustomerPayment(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment, err := loadCustomerPayment(tenantID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pembayaran tidak ditemukan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toCustomerPaymentResponse(*payment))
+}
+
+// DownloadCustomerPaymentReceipt godoc
+// @Summary Download customer payment receipt
+// @Description Download a printable receipt listing the invoices a customer payment was allocated to
+// @Tags Payments
+// @Produce application/pdf
+// @Param id path string true "Customer payment ID"
+// @Security BearerAuth
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/payments/customer-payments/{id}/receipt [get]
+func DownloadCustomerPaymentReceipt(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment, err := loadCustomerPayment(tenantID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pembayaran tidak ditemukan"})
+		return
+	}
+
+	data, err := services.NewInvoicePDFService().RenderCustomerPaymentReceipt(tenantID, *payment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat PDF kuitansi"})
+		return
+	}
+
+	sendPDF(c, "kuitansi-"+payment.ID.String()+".pdf", data)
+}
+
+func loadCustomerPayment(tenantID uuid.UUID, id string) (*models.CustomerPayment, error) {
+	var payment models.CustomerPayment
+	err := config.DB.Preload("Customer").Preload("PaymentMethod").Preload("Receiver").
+		Preload("Allocations", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sequence ASC")
+		}).Preload("Allocations.Invoice").
+		Where("id = ? AND tenant_id = ?", id, tenantID).First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func toCustomerPaymentResponse(payment models.CustomerPayment) responses.CustomerPaymentResponse {
+	response := responses.CustomerPaymentResponse{
+		ID:              payment.ID,
+		CustomerID:      payment.CustomerID,
+		CustomerName:    payment.Customer.Name,
+		Amount:          payment.Amount,
+		AllocatedAmount: payment.AllocatedAmount,
+		CreditAmount:    payment.CreditAmount,
+		AllocationOrder: payment.AllocationOrder,
+		ReferenceNumber: payment.ReferenceNumber,
+		Notes:           payment.Notes,
+		PaidAt:          payment.PaidAt,
+		Allocations:     make([]responses.PaymentAllocationResponse, len(payment.Allocations)),
+	}
+
+	for i, allocation := range payment.Allocations {
+		line := responses.PaymentAllocationResponse{
+			Sequence:        allocation.Sequence,
+			PaymentID:       allocation.PaymentID,
+			InvoiceID:       allocation.InvoiceID,
+			PenaltyAmount:   allocation.PenaltyAmount,
+			PrincipalAmount: allocation.PrincipalAmount,
+			Amount:          allocation.Amount,
+		}
+		if allocation.Invoice != nil {
+			line.InvoiceNumber = allocation.Invoice.InvoiceNumber
+			line.UsageMonth = allocation.Invoice.UsageMonth
+			line.InvoicePaid = allocation.Invoice.IsPaid
+		}
+		response.Allocations[i] = line
+	}
+
+	return response
+}
@@ -5,10 +5,13 @@ import (
 
 	"github.com/adipras/tirta-saas-backend/config"
 	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/services"
 	"github.com/adipras/tirta-saas-backend/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 func GetCustomerProfile(c *gin.Context) {
@@ -33,6 +36,7 @@ func GetCustomerProfile(c *gin.Context) {
 		"phone":         customer.Phone,
 		"subscription":  customer.Subscription,
 		"is_active":     customer.IsActive,
+		"credit_balance": customer.CreditBalance,
 		"created_at":    customer.CreatedAt,
 	}
 
@@ -86,7 +90,9 @@ func GetCustomerInvoices(c *gin.Context) {
 	tenantID := c.MustGet("tenant_id").(uuid.UUID)
 
 	var invoices []models.Invoice
-	if err := config.DB.Where("customer_id = ? AND tenant_id = ?", customerID, tenantID).
+	if err := config.DB.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Where("customer_id = ? AND tenant_id = ?", customerID, tenantID).
 		Order("created_at desc").
 		Find(&invoices).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data tagihan"})
@@ -127,77 +133,62 @@ func GetCustomerWaterUsage(c *gin.Context) {
 	c.JSON(http.StatusOK, usage)
 }
 
+// CustomerMakePayment opens an online payment of one of the customer's invoices. The payment is
+// only recorded once the gateway confirms settlement through its webhook.
 func CustomerMakePayment(c *gin.Context) {
 	customerID := c.MustGet("customer_id").(uuid.UUID)
 	tenantID := c.MustGet("tenant_id").(uuid.UUID)
 
-	type PaymentInput struct {
-		InvoiceID uuid.UUID `json:"invoice_id" binding:"required"`
-		Amount    float64   `json:"amount" binding:"required,min=0"`
-	}
-
-	var input PaymentInput
+	var input requests.OnlinePaymentRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	// Verify invoice belongs to this customer
-	var invoice models.Invoice
-	if err := config.DB.Where("id = ? AND customer_id = ? AND tenant_id = ?", 
-		input.InvoiceID, customerID, tenantID).First(&invoice).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Tagihan tidak ditemukan"})
-		return
+	if input.Channel == "" {
+		input.Channel = models.GatewayChannelQRIS
 	}
 
-	if invoice.IsPaid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Tagihan sudah lunas"})
+	// Invoice must belong to this customer
+	transaction, err := services.NewOnlinePaymentService().CreateInvoicePayment(services.OnlinePaymentInput{
+		TenantID:   tenantID,
+		CustomerID: customerID,
+		InvoiceID:  input.InvoiceID,
+		Channel:    input.Channel,
+		Amount:     input.Amount,
+	})
+	if err != nil {
+		if services.IsPaymentNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tagihan tidak ditemukan"})
+			return
+		}
+		respondOnlinePaymentError(c, err, "Gagal membuat pembayaran online")
 		return
 	}
 
-	if invoice.TotalPaid+input.Amount > invoice.TotalAmount {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Pembayaran melebihi total tagihan",
-			"remaining_amount": invoice.TotalAmount - invoice.TotalPaid,
-		})
-		return
-	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":     "Silakan selesaikan pembayaran sesuai instruksi",
+		"transaction": transaction,
+	})
+}
 
-	// Create payment record
-	payment := models.Payment{
-		InvoiceID: input.InvoiceID,
-		Amount:    input.Amount,
-		TenantID:  tenantID,
-	}
+// CustomerGetPaymentTransaction returns an online payment of the customer, checking the gateway
+// while it is still pending
+func CustomerGetPaymentTransaction(c *gin.Context) {
+	customerID := c.MustGet("customer_id").(uuid.UUID)
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
 
-	if err := config.DB.Create(&payment).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mencatat pembayaran"})
+	var transaction models.PaymentTransaction
+	if err := config.DB.Where("id = ? AND tenant_id = ? AND customer_id = ?", c.Param("id"), tenantID, customerID).
+		First(&transaction).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaksi pembayaran tidak ditemukan"})
 		return
 	}
 
-	// Update invoice
-	var totalPaid float64
-	config.DB.Model(&models.Payment{}).
-		Where("invoice_id = ?", input.InvoiceID).
-		Select("SUM(amount)").Scan(&totalPaid)
-
-	invoice.TotalPaid = totalPaid
-	invoice.IsPaid = totalPaid >= invoice.TotalAmount
-	config.DB.Save(&invoice)
-
-	// If registration invoice is now paid, activate customer
-	if invoice.Type == "registration" && invoice.IsPaid {
-		config.DB.Model(&models.Customer{}).
-			Where("id = ?", customerID).
-			Update("is_active", true)
+	if synced, err := services.NewOnlinePaymentService().SyncTransaction(&transaction); err == nil {
+		transaction = *synced
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message":     "Pembayaran berhasil dicatat",
-		"payment_id":  payment.ID,
-		"total_paid":  invoice.TotalPaid,
-		"is_paid":     invoice.IsPaid,
-	})
+	c.JSON(http.StatusOK, transaction)
 }
 
 func ChangeCustomerPassword(c *gin.Context) {
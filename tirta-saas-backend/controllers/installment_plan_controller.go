@@ -0,0 +1,313 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateInstallmentPlan godoc
+// @Summary Create installment plan
+// @Description Consolidate overdue invoices of a customer into monthly installments. Late penalties of the invoices are suspended while the plan is active.
+// @Tags Installment Plans
+// @Accept json
+// @Produce json
+// @Param request body requests.CreateInstallmentPlanRequest true "Installment plan"
+// @Security BearerAuth
+// @Success 201 {object} models.InstallmentPlan
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/installment-plans [post]
+func CreateInstallmentPlan(c *gin.Context) {
+	var req requests.CreateInstallmentPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input := services.InstallmentPlanInput{
+		TenantID:         tenantID,
+		CustomerID:       req.CustomerID,
+		InvoiceIDs:       req.InvoiceIDs,
+		InstallmentCount: req.InstallmentCount,
+		MaxMissed:        req.MaxMissed,
+		Notes:            req.Notes,
+	}
+	if req.FirstDueDate != "" {
+		firstDue, err := time.ParseInLocation("2006-01-02", req.FirstDueDate, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Format first_due_date harus YYYY-MM-DD"})
+			return
+		}
+		input.FirstDueDate = &firstDue
+	}
+	if userID, ok := c.Get("user_id"); ok {
+		if id, ok := userID.(uuid.UUID); ok {
+			input.CreatedBy = &id
+		}
+	}
+
+	plan, err := services.NewInstallmentPlanService().CreatePlan(input)
+	if err != nil {
+		respondInstallmentPlanError(c, err, "Gagal membuat rencana cicilan")
+		return
+	}
+
+	c.JSON(http.StatusCreated, plan)
+}
+
+// GetInstallmentPlans godoc
+// @Summary List installment plans
+// @Tags Installment Plans
+// @Produce json
+// @Param customer_id query string false "Customer ID"
+// @Param status query string false "ACTIVE, COMPLETED, DEFAULTED or CANCELLED"
+// @Security BearerAuth
+// @Success 200 {array} models.InstallmentPlan
+// @Router /api/installment-plans [get]
+func GetInstallmentPlans(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := config.DB.Preload("Customer").Where("tenant_id = ?", tenantID)
+	if customerID := c.Query("customer_id"); customerID != "" {
+		query = query.Where("customer_id = ?", customerID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var plans []models.InstallmentPlan
+	if err := query.Order("created_at desc").Find(&plans).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data rencana cicilan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, plans)
+}
+
+// GetInstallmentPlan godoc
+// @Summary Get installment plan
+// @Description Get a plan with its invoices and installments, updated with the payments made so far
+// @Tags Installment Plans
+// @Produce json
+// @Param id path string true "Plan ID"
+// @Security BearerAuth
+// @Success 200 {object} models.InstallmentPlan
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/installment-plans/{id} [get]
+func GetInstallmentPlan(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	planID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid plan ID"})
+		return
+	}
+
+	plan, err := services.NewInstallmentPlanService().GetPlan(tenantID, planID, nil)
+	if err != nil {
+		respondInstallmentPlanError(c, err, "Gagal mengambil rencana cicilan")
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// PayInstallmentPlan godoc
+// @Summary Pay installment
+// @Description Record money received for a plan. It is posted to the plan's invoices, oldest due first, and covers the installments in order.
+// @Tags Installment Plans
+// @Accept json
+// @Produce json
+// @Param id path string true "Plan ID"
+// @Param request body requests.InstallmentPaymentRequest true "Payment"
+// @Security BearerAuth
+// @Success 200 {object} models.InstallmentPlan
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/installment-plans/{id}/payments [post]
+func PayInstallmentPlan(c *gin.Context) {
+	var req requests.InstallmentPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	planID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid plan ID"})
+		return
+	}
+
+	if req.PaymentMethodID != nil {
+		var method models.PaymentMethod
+		if err := config.DB.Where("id = ? AND tenant_id = ?", *req.PaymentMethodID, tenantID).First(&method).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Metode pembayaran tidak ditemukan"})
+			return
+		}
+	}
+
+	input := services.InstallmentPaymentInput{
+		TenantID:        tenantID,
+		PlanID:          planID,
+		Amount:          req.Amount,
+		PaymentMethodID: req.PaymentMethodID,
+		ReferenceNumber: req.ReferenceNumber,
+		Notes:           req.Notes,
+	}
+	if userID, ok := c.Get("user_id"); ok {
+		if id, ok := userID.(uuid.UUID); ok {
+			input.ReceivedBy = &id
+		}
+	}
+
+	plan, err := services.NewInstallmentPlanService().PayInstallment(input)
+	if err != nil {
+		respondInstallmentPlanError(c, err, "Gagal mencatat pembayaran cicilan")
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// CancelInstallmentPlan godoc
+// @Summary Cancel installment plan
+// @Description End an active plan. Late penalties of its unpaid invoices resume from the next day.
+// @Tags Installment Plans
+// @Accept json
+// @Produce json
+// @Param id path string true "Plan ID"
+// @Param request body requests.CancelInstallmentPlanRequest true "Reason"
+// @Security BearerAuth
+// @Success 200 {object} models.InstallmentPlan
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/installment-plans/{id}/cancel [post]
+func CancelInstallmentPlan(c *gin.Context) {
+	var req requests.CancelInstallmentPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	planID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid plan ID"})
+		return
+	}
+
+	plan, err := services.NewInstallmentPlanService().CancelPlan(tenantID, planID, req.Reason)
+	if err != nil {
+		respondInstallmentPlanError(c, err, "Gagal membatalkan rencana cicilan")
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// CustomerGetInstallmentPlans godoc
+// @Summary My installment plans
+// @Description Installment plans of the logged in customer with their schedule
+// @Tags Customer Self-Service
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.InstallmentPlan
+// @Router /api/customer/installment-plans [get]
+func CustomerGetInstallmentPlans(c *gin.Context) {
+	customerID := c.MustGet("customer_id").(uuid.UUID)
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+
+	var ids []uuid.UUID
+	if err := config.DB.Model(&models.InstallmentPlan{}).
+		Where("tenant_id = ? AND customer_id = ?", tenantID, customerID).
+		Order("created_at desc").Pluck("id", &ids).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data rencana cicilan"})
+		return
+	}
+
+	service := services.NewInstallmentPlanService()
+	plans := make([]models.InstallmentPlan, 0, len(ids))
+	for _, id := range ids {
+		plan, err := service.GetPlan(tenantID, id, &customerID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data rencana cicilan"})
+			return
+		}
+		plans = append(plans, *plan)
+	}
+
+	c.JSON(http.StatusOK, plans)
+}
+
+// CustomerGetInstallmentPlan godoc
+// @Summary My installment plan
+// @Tags Customer Self-Service
+// @Produce json
+// @Param id path string true "Plan ID"
+// @Security BearerAuth
+// @Success 200 {object} models.InstallmentPlan
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/customer/installment-plans/{id} [get]
+func CustomerGetInstallmentPlan(c *gin.Context) {
+	customerID := c.MustGet("customer_id").(uuid.UUID)
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+
+	planID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid plan ID"})
+		return
+	}
+
+	plan, err := services.NewInstallmentPlanService().GetPlan(tenantID, planID, &customerID)
+	if err != nil {
+		respondInstallmentPlanError(c, err, "Gagal mengambil rencana cicilan")
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+func respondInstallmentPlanError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInstallmentPlanNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInstallmentPlanNotActive), errors.Is(err, services.ErrInstallmentInvoices),
+		errors.Is(err, services.ErrInstallmentInvoiceNotDue), errors.Is(err, services.ErrInstallmentInvoiceInPlan),
+		errors.Is(err, services.ErrInstallmentCount), errors.Is(err, services.ErrInstallmentAmountExceeds),
+		errors.Is(err, services.ErrInstallmentFirstDueInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		respondPaymentPostingError(c, err, message)
+	}
+}
@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// VoidInvoice godoc
+// @Summary Void invoice
+// @Description Cancel an unpaid invoice without transfer proofs waiting for verification. It is kept with status VOID so the number stays used, and its amount is credited on the customer ledger.
+// @Tags Invoices
+// @Accept json
+// @Produce json
+// @Param id path string true "Invoice ID"
+// @Param request body requests.VoidInvoiceRequest true "Reason"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/invoices/{id}/void [post]
+func VoidInvoice(c *gin.Context) {
+	var req requests.VoidInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input, ok := adjustmentInput(c)
+	if !ok {
+		return
+	}
+	input.Reason = req.Reason
+
+	adjustment, invoice, err := services.NewInvoiceAdjustmentService().Void(input)
+	if err != nil {
+		respondInvoiceAdjustmentError(c, err, "Gagal membatalkan tagihan")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Tagihan berhasil dibatalkan",
+		"adjustment": adjustment,
+		"invoice":    invoice,
+	})
+}
+
+// IssueCreditNote godoc
+// @Summary Issue credit note
+// @Description Lower the amount due of an invoice. The note cannot exceed the unpaid amount.
+// @Tags Invoices
+// @Accept json
+// @Produce json
+// @Param id path string true "Invoice ID"
+// @Param request body requests.InvoiceAdjustmentRequest true "Amount and reason"
+// @Security BearerAuth
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/invoices/{id}/credit-notes [post]
+func IssueCreditNote(c *gin.Context) {
+	issueInvoiceNote(c, services.NewInvoiceAdjustmentService().CreditNote, "Gagal menerbitkan nota kredit")
+}
+
+// IssueDebitNote godoc
+// @Summary Issue debit note
+// @Description Raise the amount due of an invoice, e.g. for a charge that was left out
+// @Tags Invoices
+// @Accept json
+// @Produce json
+// @Param id path string true "Invoice ID"
+// @Param request body requests.InvoiceAdjustmentRequest true "Amount and reason"
+// @Security BearerAuth
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/invoices/{id}/debit-notes [post]
+func IssueDebitNote(c *gin.Context) {
+	issueInvoiceNote(c, services.NewInvoiceAdjustmentService().DebitNote, "Gagal menerbitkan nota debit")
+}
+
+// RebillInvoice godoc
+// @Summary Rebill invoice
+// @Description Void a monthly invoice and issue a new one from the corrected water usage of the month, keeping its penalty and debit note lines. Payments move to the new invoice; any excess becomes customer credit.
+// @Tags Invoices
+// @Accept json
+// @Produce json
+// @Param id path string true "Invoice ID"
+// @Param request body requests.VoidInvoiceRequest true "Reason"
+// @Security BearerAuth
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/invoices/{id}/rebill [post]
+func RebillInvoice(c *gin.Context) {
+	var req requests.VoidInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input, ok := adjustmentInput(c)
+	if !ok {
+		return
+	}
+	input.Reason = req.Reason
+
+	adjustment, invoice, err := services.NewInvoiceAdjustmentService().Rebill(input)
+	if err != nil {
+		respondInvoiceAdjustmentError(c, err, "Gagal menagih ulang")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Tagihan berhasil ditagih ulang",
+		"adjustment": adjustment,
+		"invoice":    invoice,
+	})
+}
+
+// GetInvoiceAdjustments godoc
+// @Summary List invoice adjustments
+// @Description Credit notes, debit notes and voids issued against an invoice, oldest first
+// @Tags Invoices
+// @Produce json
+// @Param id path string true "Invoice ID"
+// @Security BearerAuth
+// @Success 200 {array} models.InvoiceAdjustment
+// @Router /api/invoices/{id}/adjustments [get]
+func GetInvoiceAdjustments(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var adjustments []models.InvoiceAdjustment
+	if err := config.DB.Where("tenant_id = ? AND invoice_id = ?", tenantID, c.Param("id")).
+		Order("issued_at ASC").Find(&adjustments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data"})
+		return
+	}
+
+	c.JSON(http.StatusOK, adjustments)
+}
+
+func issueInvoiceNote(c *gin.Context, issue func(services.InvoiceAdjustmentInput) (*models.InvoiceAdjustment, *models.Invoice, error), message string) {
+	var req requests.InvoiceAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input, ok := adjustmentInput(c)
+	if !ok {
+		return
+	}
+	input.Amount = req.Amount
+	input.LineType = models.InvoiceLineType(req.LineType)
+	input.Reason = req.Reason
+
+	adjustment, invoice, err := issue(input)
+	if err != nil {
+		respondInvoiceAdjustmentError(c, err, message)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"adjustment": adjustment,
+		"invoice":    invoice,
+	})
+}
+
+// adjustmentInput reads the tenant, invoice and user of an adjustment request
+func adjustmentInput(c *gin.Context) (services.InvoiceAdjustmentInput, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return services.InvoiceAdjustmentInput{}, false
+	}
+
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return services.InvoiceAdjustmentInput{}, false
+	}
+
+	input := services.InvoiceAdjustmentInput{TenantID: tenantID, InvoiceID: invoiceID}
+	if userID, ok := c.Get("user_id"); ok {
+		if id, ok := userID.(uuid.UUID); ok {
+			input.CreatedBy = &id
+		}
+	}
+	return input, true
+}
+
+func respondInvoiceAdjustmentError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvoiceVoided), errors.Is(err, services.ErrInvoiceHasPayments),
+		errors.Is(err, services.ErrInvoiceHasPendingProofs), errors.Is(err, services.ErrInstallmentInvoiceInPlan):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAdjustmentAmountInvalid), errors.Is(err, services.ErrAdjustmentReason),
+		errors.Is(err, services.ErrCreditNoteExceedsDue), errors.Is(err, services.ErrRebillNotMonthly),
+		errors.Is(err, services.ErrRebillUsageNotFound), errors.Is(err, services.ErrRebillUsageInReview),
+		errors.Is(err, services.ErrRebillAmountInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		respondPaymentPostingError(c, err, message)
+	}
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -22,7 +23,7 @@ type GenerateMonthlyInvoiceRequest struct {
 
 // GenerateMonthlyInvoice godoc
 // @Summary Generate monthly invoice
-// @Description Generate monthly invoice for a customer
+// @Description Generate the monthly invoices of a usage month right away, with due dates and late penalties like the scheduled generation
 // @Tags Invoices
 // @Accept json
 // @Produce json
@@ -56,88 +57,26 @@ func GenerateMonthlyInvoice(c *gin.Context) {
 
 	}
 
-	// Ambil semua WaterUsage bulan tsb yang belum dibuatkan Invoice
-	var usages []models.WaterUsage
-	if err := config.DB.
-		Where("usage_month = ? AND tenant_id = ?", req.UsageMonth, tenantID).
-		Find(&usages).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal ambil data water usage"})
-		return
-	}
-
-	if len(usages) == 0 {
+	// Sama seperti generate bulanan terjadwal: tanggal jatuh tempo, denda dan saldo kredit
+	// diproses oleh InvoiceGenerationService
+	result, err := services.NewInvoiceGenerationService().GenerateInvoices(services.InvoiceGenerationRequest{
+		TenantID:   tenantID,
+		UsageMonth: req.UsageMonth,
+	})
+	if errors.Is(err, services.ErrNoUsageForPeriod) {
 		c.JSON(http.StatusOK, gin.H{"message": "Tidak ada water usage untuk bulan tersebut"})
 		return
 	}
-
-	created := 0
-	skipped := 0
-
-	for _, usage := range usages {
-		// Cek apakah invoice sudah pernah dibuat
-		var existing models.Invoice
-		err := config.DB.Where("customer_id = ? AND usage_month = ? AND type = ?",
-			usage.CustomerID, usage.UsageMonth, "monthly").First(&existing).Error
-		if err == nil {
-			skipped++
-			continue
-		}
-
-		// Ambil data pelanggan & SubscriptionType
-		var customer models.Customer
-		if err := config.DB.Where("id = ? AND tenant_id = ?", usage.CustomerID, tenantID).First(&customer).Error; err != nil {
-			continue
-		}
-
-		var subType models.SubscriptionType
-		if err := config.DB.Where("id = ? AND tenant_id = ?", customer.SubscriptionID, tenantID).First(&subType).Error; err != nil {
-			continue
-		}
-
-		// Business rule validations
-		if usage.UsageM3 < 0 {
-			continue // Skip invalid usage records
-		}
-
-		if usage.AmountCalculated < 0 {
-			continue // Skip invalid calculated amounts
-		}
-
-		total := usage.AmountCalculated + subType.MonthlyFee + subType.MaintenanceFee
-
-		// Validate calculated total is reasonable
-		if total <= 0 || total > 999999 {
-			continue // Skip invoices with invalid totals
-		}
-
-		// Calculate price per m3 safely
-		pricePerM3 := 0.0
-		if usage.UsageM3 > 0 {
-			pricePerM3 = usage.AmountCalculated / usage.UsageM3
-		}
-
-		invoice := models.Invoice{
-			CustomerID:  usage.CustomerID,
-			UsageMonth:  usage.UsageMonth,
-			UsageM3:     usage.UsageM3,
-			Abonemen:    subType.MonthlyFee,
-			PricePerM3:  pricePerM3,
-			TotalAmount: total,
-			TotalPaid:   0,
-			IsPaid:      false,
-			TenantID:    tenantID,
-			Type:        "monthly",
-		}
-
-		if err := config.DB.Create(&invoice).Error; err == nil {
-			created++
-		}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal generate invoice"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":       "Generate invoice selesai",
-		"created_count": created,
-		"skipped":       skipped,
+		"created_count": result.Success,
+		"skipped":       result.Skipped,
+		"failed":        result.Failed,
 	})
 }
 
@@ -187,6 +126,13 @@ func GetInvoices(c *gin.Context) {
 			IsPaid:      invoice.IsPaid,
 			Type:        invoice.Type,
 			CreatedAt:   invoice.CreatedAt,
+
+			Status:          invoice.Status,
+			CreditNoteTotal: invoice.CreditNoteTotal,
+			DebitNoteTotal:  invoice.DebitNoteTotal,
+			Notes:           invoice.Notes,
+
+			TaxAmount: invoice.TaxAmount,
 		}
 	}
 
@@ -247,10 +193,31 @@ func GetInvoice(c *gin.Context) {
 		IsPaid:      invoice.IsPaid,
 		Type:        invoice.Type,
 		CreatedAt:   invoice.CreatedAt,
+
+		Status:          invoice.Status,
+		CreditNoteTotal: invoice.CreditNoteTotal,
+		DebitNoteTotal:  invoice.DebitNoteTotal,
+		Notes:           invoice.Notes,
+
+		TaxAmount: invoice.TaxAmount,
+	}
+	if lines, err := services.InvoiceLines(config.DB, invoice); err == nil {
+		response.Lines = lines
 	}
 	c.JSON(http.StatusOK, response)
 }
 
+// UpdateInvoice godoc
+// @Summary Update invoice notes
+// @Description Issued invoices are immutable; only the notes can be changed. Amounts are corrected with credit or debit notes, a void or a rebill.
+// @Tags Invoices
+// @Accept json
+// @Produce json
+// @Param id path string true "Invoice ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.InvoiceResponse
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/invoices/{id} [put]
 func UpdateInvoice(c *gin.Context) {
 	tenantID, err := helpers.RequireTenantID(c)
 
@@ -277,12 +244,15 @@ func UpdateInvoice(c *gin.Context) {
 	}
 
 	type UpdateInvoiceInput struct {
-		UsageM3     float64 `json:"usage_m3"`
-		Abonemen    float64 `json:"abonemen"`
-		PricePerM3  float64 `json:"price_per_m3"`
-		TotalAmount float64 `json:"total_amount"`
-		IsPaid      bool    `json:"is_paid"`
-		TotalPaid   float64 `json:"total_paid"`
+		Notes *string `json:"notes"`
+
+		// No longer editable, kept to reject clients that still send them
+		UsageM3     *float64 `json:"usage_m3"`
+		Abonemen    *float64 `json:"abonemen"`
+		PricePerM3  *float64 `json:"price_per_m3"`
+		TotalAmount *float64 `json:"total_amount"`
+		IsPaid      *bool    `json:"is_paid"`
+		TotalPaid   *float64 `json:"total_paid"`
 	}
 
 	var input UpdateInvoiceInput
@@ -291,18 +261,22 @@ func UpdateInvoice(c *gin.Context) {
 		return
 	}
 
-	invoice.UsageM3 = input.UsageM3
-	invoice.Abonemen = input.Abonemen
-	invoice.PricePerM3 = input.PricePerM3
-	invoice.TotalAmount = input.TotalAmount
-	invoice.IsPaid = input.IsPaid
-	invoice.TotalPaid = input.TotalPaid
-
-	if err := config.DB.Save(&invoice).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui invoice"})
+	if input.UsageM3 != nil || input.Abonemen != nil || input.PricePerM3 != nil ||
+		input.TotalAmount != nil || input.IsPaid != nil || input.TotalPaid != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Tagihan yang sudah terbit tidak dapat diubah, gunakan nota kredit, nota debit, void atau tagih ulang",
+		})
 		return
 	}
 
+	if input.Notes != nil {
+		invoice.Notes = *input.Notes
+		if err := config.DB.Model(&invoice).Update("notes", invoice.Notes).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui invoice"})
+			return
+		}
+	}
+
 	response := responses.InvoiceResponse{
 		ID:          invoice.ID,
 		CustomerID:  invoice.CustomerID,
@@ -315,46 +289,42 @@ func UpdateInvoice(c *gin.Context) {
 		IsPaid:      invoice.IsPaid,
 		Type:        invoice.Type,
 		CreatedAt:   invoice.CreatedAt,
+
+		Status:          invoice.Status,
+		CreditNoteTotal: invoice.CreditNoteTotal,
+		DebitNoteTotal:  invoice.DebitNoteTotal,
+		Notes:           invoice.Notes,
+
+		TaxAmount: invoice.TaxAmount,
 	}
 	c.JSON(http.StatusOK, response)
 }
 
+// DeleteInvoice godoc
+// @Summary Delete invoice
+// @Description Invoices are never deleted so their numbers stay continuous; void the invoice instead
+// @Tags Invoices
+// @Produce json
+// @Param id path string true "Invoice ID"
+// @Security BearerAuth
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/invoices/{id} [delete]
 func DeleteInvoice(c *gin.Context) {
-	tenantID, err := helpers.RequireTenantID(c)
-
-	if err != nil {
-
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-
-		return
-
-	}
-	id := c.Param("id")
-
-	invoiceID, err := uuid.Parse(id)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
-		return
-	}
-
-	if err := config.DB.Where("id = ? AND tenant_id = ?", invoiceID, tenantID).
-		Delete(&models.Invoice{}).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus invoice"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Invoice berhasil dihapus"})
+	c.JSON(http.StatusConflict, gin.H{
+		"error": "Tagihan tidak dapat dihapus, gunakan void untuk membatalkan tagihan",
+	})
 }
 
 // BulkGenerateInvoices godoc
 // @Summary Bulk generate invoices
-// @Description Generate invoices in bulk for specified month and customers
+// @Description Generate invoices in bulk for specified month and customers. With preview the invoices are returned without being created; otherwise a generation job is queued and its progress is followed with GET /api/invoices/generation-jobs/{id}.
 // @Tags Invoices
 // @Accept json
 // @Produce json
 // @Param request body requests.BulkInvoiceGenerationRequest true "Bulk generation request"
 // @Security BearerAuth
 // @Success 200 {object} responses.BulkInvoiceGenerationResponse
+// @Success 202 {object} responses.InvoiceGenerationJobResponse
 // @Failure 400 {object} map[string]interface{}
 // @Router /api/invoices/bulk-generate [post]
 func BulkGenerateInvoices(c *gin.Context) {
@@ -378,15 +348,20 @@ func BulkGenerateInvoices(c *gin.Context) {
 		return
 	}
 
+	if !req.Preview {
+		queueInvoiceGeneration(c, tenantID, req)
+		return
+	}
+
 	// Create service
 	service := services.NewInvoiceGenerationService()
 
-	// Generate invoices
+	// Preview invoices
 	result, err := service.GenerateInvoices(services.InvoiceGenerationRequest{
 		TenantID:    tenantID,
 		UsageMonth:  req.UsageMonth,
 		CustomerIDs: req.CustomerIDs,
-		DryRun:      req.Preview,
+		DryRun:      true,
 	})
 
 	if err != nil {
@@ -416,10 +391,14 @@ func BulkGenerateInvoices(c *gin.Context) {
 			WaterCharge:   inv.WaterCharge,
 			Abonemen:      inv.Abonemen,
 			PenaltyAmount: inv.PenaltyAmount,
+			Adjustment:    inv.AdjustmentAmount,
 			SubTotal:      inv.SubTotal,
+			TaxAmount:     inv.TaxAmount,
 			TotalAmount:   inv.TotalAmount,
 			DueDate:       inv.DueDate,
 			Notes:         inv.Notes,
+
+			Lines: inv.Lines,
 		}
 	}
 
@@ -428,6 +407,7 @@ func BulkGenerateInvoices(c *gin.Context) {
 		Message:     getMessage(req.Preview, result),
 		Success:     result.Success,
 		Skipped:     result.Skipped,
+		Estimated:   result.Estimated,
 		Failed:      result.Failed,
 		TotalAmount: result.TotalAmount,
 		Invoices:    invoiceItems,
@@ -507,10 +487,14 @@ func PreviewInvoiceGeneration(c *gin.Context) {
 			WaterCharge:   inv.WaterCharge,
 			Abonemen:      inv.Abonemen,
 			PenaltyAmount: inv.PenaltyAmount,
+			Adjustment:    inv.AdjustmentAmount,
 			SubTotal:      inv.SubTotal,
+			TaxAmount:     inv.TaxAmount,
 			TotalAmount:   inv.TotalAmount,
 			DueDate:       inv.DueDate,
 			Notes:         inv.Notes,
+
+			Lines: inv.Lines,
 		}
 	}
 
@@ -529,6 +513,32 @@ func PreviewInvoiceGeneration(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// GetInvoicePenalties godoc
+// @Summary List invoice penalties
+// @Description Late penalty lines billed on an invoice, with the overdue invoice and period each one was charged for
+// @Tags Invoices
+// @Produce json
+// @Param id path string true "Invoice ID"
+// @Security BearerAuth
+// @Success 200 {array} models.InvoicePenalty
+// @Router /api/invoices/{id}/penalties [get]
+func GetInvoicePenalties(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var penalties []models.InvoicePenalty
+	if err := config.DB.Where("tenant_id = ? AND charged_invoice_id = ?", tenantID, c.Param("id")).
+		Order("period_start ASC").Find(&penalties).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data"})
+		return
+	}
+
+	c.JSON(http.StatusOK, penalties)
+}
+
 // getMessage generates appropriate message based on result
 func getMessage(preview bool, result *services.InvoiceGenerationResult) string {
 	if preview {
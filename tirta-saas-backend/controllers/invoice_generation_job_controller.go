@@ -0,0 +1,295 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// queueInvoiceGeneration queues a bulk generation request as a background job
+func queueInvoiceGeneration(c *gin.Context, tenantID uuid.UUID, req requests.BulkInvoiceGenerationRequest) {
+	input := services.InvoiceGenerationJobInput{
+		TenantID:        tenantID,
+		UsageMonth:      req.UsageMonth,
+		CustomerIDs:     req.CustomerIDs,
+		EstimateMissing: req.EstimateMissing,
+		TriggerType:     "manual",
+	}
+	if userID, ok := c.Get("user_id"); ok {
+		if id, ok := userID.(uuid.UUID); ok {
+			input.TriggeredBy = &id
+		}
+	}
+
+	job, err := services.NewInvoiceGenerationJobService().Enqueue(input)
+	if err != nil {
+		respondGenerationJobError(c, err, "Failed to queue invoice generation")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, responses.SuccessResponse{
+		Status:  "success",
+		Message: "Invoice generation queued",
+		Data:    toInvoiceGenerationJobResponse(*job),
+	})
+}
+
+// GetInvoiceGenerationJobs godoc
+// @Summary List invoice generation jobs
+// @Tags Invoices
+// @Produce json
+// @Param usage_month query string false "Usage month (YYYY-MM)"
+// @Param status query string false "QUEUED, RUNNING, COMPLETED or FAILED"
+// @Security BearerAuth
+// @Success 200 {array} responses.InvoiceGenerationJobResponse
+// @Router /api/invoices/generation-jobs [get]
+func GetInvoiceGenerationJobs(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Tenant ID required",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	query := config.DB.Where("tenant_id = ?", tenantID)
+	if usageMonth := c.Query("usage_month"); usageMonth != "" {
+		query = query.Where("usage_month = ?", usageMonth)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var jobs []models.InvoiceGenerationJob
+	if err := query.Order("created_at DESC").Limit(50).Find(&jobs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to fetch generation jobs",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	jobList := make([]responses.InvoiceGenerationJobResponse, 0, len(jobs))
+	for _, job := range jobs {
+		jobList = append(jobList, toInvoiceGenerationJobResponse(job))
+	}
+
+	c.JSON(http.StatusOK, responses.SuccessResponse{
+		Status:  "success",
+		Message: "Generation jobs retrieved successfully",
+		Data:    jobList,
+	})
+}
+
+// GetInvoiceGenerationJob godoc
+// @Summary Get invoice generation job
+// @Description Status and progress of a generation job
+// @Tags Invoices
+// @Produce json
+// @Param id path string true "Job ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.InvoiceGenerationJobResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/invoices/generation-jobs/{id} [get]
+func GetInvoiceGenerationJob(c *gin.Context) {
+	tenantID, jobID, ok := generationJobParams(c)
+	if !ok {
+		return
+	}
+
+	job, err := services.NewInvoiceGenerationJobService().GetJob(tenantID, jobID)
+	if err != nil {
+		respondGenerationJobError(c, err, "Failed to fetch generation job")
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.SuccessResponse{
+		Status:  "success",
+		Message: "Generation job retrieved successfully",
+		Data:    toInvoiceGenerationJobResponse(*job),
+	})
+}
+
+// GetInvoiceGenerationJobItems godoc
+// @Summary List invoice generation job items
+// @Description Outcome per customer of a generation job; use status=FAILED or SKIPPED for the customers that were not billed and why
+// @Tags Invoices
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param status query string false "PENDING, GENERATED, SKIPPED or FAILED"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(50)
+// @Security BearerAuth
+// @Success 200 {array} models.InvoiceGenerationJobItem
+// @Router /api/invoices/generation-jobs/{id}/items [get]
+func GetInvoiceGenerationJobItems(c *gin.Context) {
+	tenantID, jobID, ok := generationJobParams(c)
+	if !ok {
+		return
+	}
+
+	if _, err := services.NewInvoiceGenerationJobService().GetJob(tenantID, jobID); err != nil {
+		respondGenerationJobError(c, err, "Failed to fetch generation job")
+		return
+	}
+
+	query := config.DB.Model(&models.InvoiceGenerationJobItem{}).Where("job_id = ?", jobID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	// Pagination
+	page := 1
+	pageSize := 50
+	if p := c.Query("page"); p != "" {
+		var pageNum int
+		if _, err := fmt.Sscanf(p, "%d", &pageNum); err == nil && pageNum > 0 {
+			page = pageNum
+		}
+	}
+
+	if ps := c.Query("page_size"); ps != "" {
+		var pageSizeNum int
+		if _, err := fmt.Sscanf(ps, "%d", &pageSizeNum); err == nil && pageSizeNum > 0 && pageSizeNum <= 100 {
+			pageSize = pageSizeNum
+		}
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var items []models.InvoiceGenerationJobItem
+	if err := query.Order("processed_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to fetch generation job items",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.SuccessResponse{
+		Status:  "success",
+		Message: "Generation job items retrieved successfully",
+		Data: map[string]interface{}{
+			"items": items,
+			"pagination": map[string]interface{}{
+				"page":        page,
+				"page_size":   pageSize,
+				"total":       total,
+				"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+			},
+		},
+	})
+}
+
+// RetryInvoiceGenerationJob godoc
+// @Summary Retry invoice generation job
+// @Description Queue a failed job, or the failed customers of a completed job, again. Customers already billed are not billed twice.
+// @Tags Invoices
+// @Produce json
+// @Param id path string true "Job ID"
+// @Security BearerAuth
+// @Success 202 {object} responses.InvoiceGenerationJobResponse
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/invoices/generation-jobs/{id}/retry [post]
+func RetryInvoiceGenerationJob(c *gin.Context) {
+	tenantID, jobID, ok := generationJobParams(c)
+	if !ok {
+		return
+	}
+
+	job, err := services.NewInvoiceGenerationJobService().Retry(tenantID, jobID)
+	if err != nil {
+		respondGenerationJobError(c, err, "Failed to retry generation job")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, responses.SuccessResponse{
+		Status:  "success",
+		Message: "Invoice generation queued",
+		Data:    toInvoiceGenerationJobResponse(*job),
+	})
+}
+
+// generationJobParams reads the tenant and job of a generation job request
+func generationJobParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Tenant ID required",
+			Error:   err.Error(),
+		})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Invalid job ID",
+			Error:   err.Error(),
+		})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return tenantID, jobID, true
+}
+
+func toInvoiceGenerationJobResponse(job models.InvoiceGenerationJob) responses.InvoiceGenerationJobResponse {
+	response := responses.InvoiceGenerationJobResponse{
+		ID:              job.ID,
+		UsageMonth:      job.UsageMonth,
+		Status:          job.Status,
+		TriggerType:     job.TriggerType,
+		EstimateMissing: job.EstimateMissing,
+		TotalItems:      job.TotalItems,
+		ProcessedItems:  job.ProcessedItems,
+		Success:         job.SuccessCount,
+		Skipped:         job.SkippedCount,
+		Failed:          job.FailedCount,
+		Estimated:       job.EstimatedCount,
+		TotalAmount:     job.TotalAmount,
+		ErrorMessage:    job.ErrorMessage,
+		HistoryID:       job.HistoryID,
+		CreatedAt:       job.CreatedAt,
+		StartedAt:       job.StartedAt,
+		FinishedAt:      job.FinishedAt,
+	}
+	if job.TotalItems > 0 {
+		response.Progress = float64(job.ProcessedItems*10000/job.TotalItems) / 100
+	}
+	if job.Status == models.GenerationJobCompleted {
+		response.Progress = 100
+	}
+	return response
+}
+
+func respondGenerationJobError(c *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrGenerationJobNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrGenerationJobActive), errors.Is(err, services.ErrGenerationJobNotFailed):
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, responses.ErrorResponse{
+		Status:  "error",
+		Message: message,
+		Error:   err.Error(),
+	})
+}
@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DownloadInvoicePDF godoc
+// @Summary Download invoice PDF
+// @Description Download a printable invoice with the tenant's branding
+// @Tags Invoices
+// @Produce application/pdf
+// @Param id path string true "Invoice ID"
+// @Security BearerAuth
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/invoices/{id}/pdf [get]
+func DownloadInvoicePDF(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+
+	var invoice models.Invoice
+	if err := config.DB.Where("id = ? AND tenant_id = ?", invoiceID, tenantID).First(&invoice).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice tidak ditemukan"})
+		return
+	}
+
+	renderInvoicePDF(c, tenantID, invoice)
+}
+
+// DownloadInvoiceBatchPDF godoc
+// @Summary Download invoice batch PDF
+// @Description Download all monthly invoices of a usage month in reading route order, one invoice per page
+// @Tags Invoices
+// @Produce application/pdf
+// @Param usage_month query string true "Usage month (YYYY-MM)"
+// @Param route_id query string false "Only invoices of this reading route"
+// @Security BearerAuth
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/invoices/batch-pdf [get]
+func DownloadInvoiceBatchPDF(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	usageMonth := c.Query("usage_month")
+	if _, err := time.Parse("2006-01", usageMonth); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "usage_month harus berformat YYYY-MM"})
+		return
+	}
+
+	filename := "tagihan-" + usageMonth
+	var routeID *uuid.UUID
+	if value := c.Query("route_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid route ID"})
+			return
+		}
+
+		var route models.ReadingRoute
+		if err := config.DB.Where("id = ? AND tenant_id = ?", id, tenantID).First(&route).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rute pembacaan tidak ditemukan"})
+			return
+		}
+		routeID = &id
+		filename += "-" + route.Code
+	}
+
+	service := services.NewInvoicePDFService()
+	invoices, err := service.RouteInvoices(tenantID, usageMonth, routeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data tagihan"})
+		return
+	}
+
+	data, err := service.RenderInvoices(tenantID, invoices)
+	if err != nil {
+		if errors.Is(err, services.ErrNoInvoicesToPrint) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat PDF tagihan"})
+		return
+	}
+
+	sendPDF(c, filename+".pdf", data)
+}
+
+// DownloadPaymentReceipt godoc
+// @Summary Download payment receipt
+// @Description Download a printable receipt for a payment
+// @Tags Payments
+// @Produce application/pdf
+// @Param id path string true "Payment ID"
+// @Security BearerAuth
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/payments/{id}/receipt [get]
+func DownloadPaymentReceipt(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	var payment models.Payment
+	if err := config.DB.Preload("PaymentMethod").Preload("Receiver").
+		Where("id = ? AND tenant_id = ?", paymentID, tenantID).First(&payment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pembayaran tidak ditemukan"})
+		return
+	}
+
+	renderPaymentReceipt(c, tenantID, payment)
+}
+
+// CustomerDownloadInvoicePDF downloads an invoice of the logged in customer
+func CustomerDownloadInvoicePDF(c *gin.Context) {
+	customerID := c.MustGet("customer_id").(uuid.UUID)
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+
+	var invoice models.Invoice
+	if err := config.DB.Where("id = ? AND customer_id = ? AND tenant_id = ?", c.Param("id"), customerID, tenantID).
+		First(&invoice).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tagihan tidak ditemukan"})
+		return
+	}
+
+	renderInvoicePDF(c, tenantID, invoice)
+}
+
+// CustomerDownloadPaymentReceipt downloads a payment receipt of the logged in customer
+func CustomerDownloadPaymentReceipt(c *gin.Context) {
+	customerID := c.MustGet("customer_id").(uuid.UUID)
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+
+	var payment models.Payment
+	if err := config.DB.Preload("PaymentMethod").Preload("Receiver").
+		Where("id = ? AND tenant_id = ? AND invoice_id IN (SELECT id FROM invoices WHERE customer_id = ?)",
+			c.Param("id"), tenantID, customerID).
+		First(&payment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pembayaran tidak ditemukan"})
+		return
+	}
+
+	renderPaymentReceipt(c, tenantID, payment)
+}
+
+func renderInvoicePDF(c *gin.Context, tenantID uuid.UUID, invoice models.Invoice) {
+	data, err := services.NewInvoicePDFService().RenderInvoices(tenantID, []models.Invoice{invoice})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat PDF tagihan"})
+		return
+	}
+
+	filename := invoice.InvoiceNumber
+	if filename == "" {
+		filename = "tagihan-" + invoice.ID.String()
+	}
+	sendPDF(c, filename+".pdf", data)
+}
+
+func renderPaymentReceipt(c *gin.Context, tenantID uuid.UUID, payment models.Payment) {
+	// Transfers waiting for verification or rejected get no receipt
+	if payment.Status != models.PaymentRecordCompleted {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Kuitansi hanya tersedia untuk pembayaran yang sudah diverifikasi"})
+		return
+	}
+
+	data, err := services.NewInvoicePDFService().RenderReceipt(tenantID, payment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat PDF kuitansi"})
+		return
+	}
+
+	sendPDF(c, "kuitansi-"+payment.ID.String()+".pdf", data)
+}
+
+func sendPDF(c *gin.Context, filename string, data []byte) {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/pdf", data)
+}
@@ -0,0 +1,766 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Default interval between meter calibrations when none is given
+const defaultCalibrationIntervalMonths = 12
+
+var errMeterNumberTaken = errors.New("meter number already used by another customer")
+
+type MeterController struct {
+	DB *gorm.DB
+}
+
+func NewMeterController(db *gorm.DB) *MeterController {
+	return &MeterController{DB: db}
+}
+
+// meterSnapshot is the subset of meter fields stored in MeterHistory old/new values
+type meterSnapshot struct {
+	MeterNumber    string     `json:"meter_number"`
+	Brand          string     `json:"brand,omitempty"`
+	Model          string     `json:"model,omitempty"`
+	Status         string     `json:"status"`
+	InitialReading float64    `json:"initial_reading"`
+	FinalReading   *float64   `json:"final_reading,omitempty"`
+	LastCalibDate  *time.Time `json:"last_calib_date,omitempty"`
+	NextCalibDate  *time.Time `json:"next_calib_date,omitempty"`
+}
+
+func snapshotMeter(meter *models.Meter, finalReading *float64) string {
+	data, err := json.Marshal(meterSnapshot{
+		MeterNumber:    meter.MeterNumber,
+		Brand:          meter.Brand,
+		Model:          meter.Model,
+		Status:         meter.Status,
+		InitialReading: meter.InitialReading,
+		FinalReading:   finalReading,
+		LastCalibDate:  meter.LastCalibDate,
+		NextCalibDate:  meter.NextCalibDate,
+	})
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func recordMeterHistory(tx *gorm.DB, meter *models.Meter, action, oldValue, newValue string, performedBy uuid.UUID, notes string) error {
+	history := models.MeterHistory{
+		TenantID:    meter.TenantID,
+		MeterID:     meter.ID,
+		CustomerID:  meter.CustomerID,
+		Action:      action,
+		OldValue:    oldValue,
+		NewValue:    newValue,
+		PerformedBy: performedBy,
+		Notes:       notes,
+	}
+	return tx.Create(&history).Error
+}
+
+func parseMeterDate(value string) (time.Time, error) {
+	return time.Parse("2006-01-02", value)
+}
+
+func (ctrl *MeterController) findMeter(c *gin.Context, tenantID uuid.UUID) (*models.Meter, bool) {
+	meterID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meter ID"})
+		return nil, false
+	}
+
+	var meter models.Meter
+	if err := ctrl.DB.Preload("Customer").Where("id = ? AND tenant_id = ?", meterID, tenantID).First(&meter).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meter not found"})
+		return nil, false
+	}
+	return &meter, true
+}
+
+func (ctrl *MeterController) meterNumberExists(tenantID uuid.UUID, meterNumber string) bool {
+	var count int64
+	ctrl.DB.Model(&models.Meter{}).Where("tenant_id = ? AND meter_number = ?", tenantID, meterNumber).Count(&count)
+	return count > 0
+}
+
+// syncCustomerMeterNumber keeps the legacy Customer.MeterNumber column in line with the active meter
+func syncCustomerMeterNumber(tx *gorm.DB, customerID uuid.UUID, meterNumber string) error {
+	var conflict int64
+	tx.Model(&models.Customer{}).Where("meter_number = ? AND id <> ?", meterNumber, customerID).Count(&conflict)
+	if conflict > 0 {
+		return errMeterNumberTaken
+	}
+	return tx.Model(&models.Customer{}).Where("id = ?", customerID).Update("meter_number", meterNumber).Error
+}
+
+// GetMeters godoc
+// @Summary List meters
+// @Description Get all meters for the tenant, optionally filtered by customer or status
+// @Tags Meters
+// @Produce json
+// @Param customer_id query string false "Filter by customer ID"
+// @Param status query string false "Filter by status (active, inactive, broken, replaced)"
+// @Security BearerAuth
+// @Success 200 {array} responses.MeterResponse
+// @Router /api/meters [get]
+func (ctrl *MeterController) GetMeters(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.DB.Preload("Customer").Where("tenant_id = ?", tenantID)
+	if customerID := c.Query("customer_id"); customerID != "" {
+		query = query.Where("customer_id = ?", customerID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var meters []models.Meter
+	if err := query.Order("install_date DESC").Find(&meters).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch meters"})
+		return
+	}
+
+	meterResponses := make([]responses.MeterResponse, len(meters))
+	for i, meter := range meters {
+		meterResponses[i] = responses.ToMeterResponse(&meter)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": meterResponses, "total": len(meterResponses)})
+}
+
+// GetCalibrationDue godoc
+// @Summary List meters due for calibration
+// @Description Get active meters whose next calibration date falls within the given number of days
+// @Tags Meters
+// @Produce json
+// @Param days query int false "Look-ahead window in days (default 30)"
+// @Security BearerAuth
+// @Success 200 {array} responses.MeterResponse
+// @Router /api/meters/calibration-due [get]
+func (ctrl *MeterController) GetCalibrationDue(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days parameter"})
+		return
+	}
+	until := time.Now().AddDate(0, 0, days)
+
+	var meters []models.Meter
+	if err := ctrl.DB.Preload("Customer").
+		Where("tenant_id = ? AND status = ? AND next_calib_date IS NOT NULL AND next_calib_date <= ?", tenantID, models.MeterStatusActive, until).
+		Order("next_calib_date ASC").Find(&meters).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch meters"})
+		return
+	}
+
+	meterResponses := make([]responses.MeterResponse, len(meters))
+	for i, meter := range meters {
+		meterResponses[i] = responses.ToMeterResponse(&meter)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": meterResponses, "total": len(meterResponses)})
+}
+
+// GetMeter godoc
+// @Summary Get meter
+// @Tags Meters
+// @Produce json
+// @Param id path string true "Meter ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meters/{id} [get]
+func (ctrl *MeterController) GetMeter(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	meter, ok := ctrl.findMeter(c, tenantID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": responses.ToMeterResponse(meter)})
+}
+
+// CreateMeter godoc
+// @Summary Install meter
+// @Description Register a newly installed meter for a customer
+// @Tags Meters
+// @Accept json
+// @Produce json
+// @Param request body requests.CreateMeterRequest true "Create meter request"
+// @Security BearerAuth
+// @Success 201 {object} responses.MeterResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/meters [post]
+func (ctrl *MeterController) CreateMeter(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req requests.CreateMeterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerID, err := uuid.Parse(req.CustomerID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+
+	installDate, err := parseMeterDate(req.InstallDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid install date format. Use YYYY-MM-DD"})
+		return
+	}
+
+	var customer models.Customer
+	if err := ctrl.DB.Where("id = ? AND tenant_id = ?", customerID, tenantID).First(&customer).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+		return
+	}
+
+	if services.FindActiveMeter(ctrl.DB, tenantID, customerID) != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Customer already has an active meter. Use replace instead"})
+		return
+	}
+
+	if ctrl.meterNumberExists(tenantID, req.MeterNumber) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Meter number already exists"})
+		return
+	}
+
+	nextCalib := installDate.AddDate(0, defaultCalibrationIntervalMonths, 0)
+	meter := models.Meter{
+		TenantID:       tenantID,
+		CustomerID:     customerID,
+		MeterNumber:    req.MeterNumber,
+		Brand:          req.Brand,
+		Model:          req.Model,
+		InstallDate:    installDate,
+		NextCalibDate:  &nextCalib,
+		InitialReading: req.InitialReading,
+		Status:         models.MeterStatusActive,
+		Notes:          req.Notes,
+	}
+
+	err = ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&meter).Error; err != nil {
+			return err
+		}
+		if err := syncCustomerMeterNumber(tx, customerID, meter.MeterNumber); err != nil {
+			return err
+		}
+		return recordMeterHistory(tx, &meter, models.MeterActionInstall, "", snapshotMeter(&meter, nil), userID, req.Notes)
+	})
+	if errors.Is(err, errMeterNumberTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Meter number already used by another customer"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create meter"})
+		return
+	}
+
+	meter.Customer = customer
+	c.JSON(http.StatusCreated, gin.H{"message": "Meter installed successfully", "data": responses.ToMeterResponse(&meter)})
+}
+
+// UpdateMeter godoc
+// @Summary Update meter
+// @Description Update meter details. Replacement and retirement have their own endpoints
+// @Tags Meters
+// @Accept json
+// @Produce json
+// @Param id path string true "Meter ID"
+// @Param request body requests.UpdateMeterRequest true "Update meter request"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterResponse
+// @Router /api/meters/{id} [put]
+func (ctrl *MeterController) UpdateMeter(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req requests.UpdateMeterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	meter, ok := ctrl.findMeter(c, tenantID)
+	if !ok {
+		return
+	}
+
+	if meter.Status == models.MeterStatusReplaced {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Replaced meters cannot be modified"})
+		return
+	}
+	if req.Status == models.MeterStatusReplaced || (req.Status == models.MeterStatusInactive && meter.Status != models.MeterStatusInactive) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use the replace or retire endpoint to close a meter"})
+		return
+	}
+
+	oldValue := snapshotMeter(meter, nil)
+
+	if req.Brand != "" {
+		meter.Brand = req.Brand
+	}
+	if req.Model != "" {
+		meter.Model = req.Model
+	}
+	if req.LastCalibDate != nil {
+		date, err := parseMeterDate(*req.LastCalibDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid last calibration date format. Use YYYY-MM-DD"})
+			return
+		}
+		meter.LastCalibDate = &date
+	}
+	if req.NextCalibDate != nil {
+		date, err := parseMeterDate(*req.NextCalibDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid next calibration date format. Use YYYY-MM-DD"})
+			return
+		}
+		meter.NextCalibDate = &date
+	}
+	if req.Status != "" {
+		meter.Status = req.Status
+	}
+	if req.Notes != "" {
+		meter.Notes = req.Notes
+	}
+
+	err = ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Customer").Save(meter).Error; err != nil {
+			return err
+		}
+		return recordMeterHistory(tx, meter, models.MeterActionUpdate, oldValue, snapshotMeter(meter, nil), userID, req.Notes)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update meter"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Meter updated successfully", "data": responses.ToMeterResponse(meter)})
+}
+
+// ReplaceMeter godoc
+// @Summary Replace meter
+// @Description Close the current meter with a final reading and install a new meter for the same customer
+// @Tags Meters
+// @Accept json
+// @Produce json
+// @Param id path string true "Meter ID"
+// @Param request body requests.ReplaceMeterRequest true "Replace meter request"
+// @Security BearerAuth
+// @Success 201 {object} responses.MeterResponse
+// @Router /api/meters/{id}/replace [post]
+func (ctrl *MeterController) ReplaceMeter(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req requests.ReplaceMeterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	oldMeter, ok := ctrl.findMeter(c, tenantID)
+	if !ok {
+		return
+	}
+
+	if oldMeter.Status == models.MeterStatusReplaced || oldMeter.Status == models.MeterStatusInactive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Meter is no longer in service"})
+		return
+	}
+
+	// Final reading cannot go below the last recorded reading on this meter
+	var lastUsage models.WaterUsage
+	if err := ctrl.DB.Where("meter_id = ?", oldMeter.ID).Order("usage_month DESC").First(&lastUsage).Error; err == nil {
+		if req.FinalReading < lastUsage.MeterEnd {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Final reading is lower than the last recorded reading"})
+			return
+		}
+	} else if req.FinalReading < oldMeter.InitialReading {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Final reading is lower than the initial reading"})
+		return
+	}
+
+	replaceDate := time.Now()
+	if req.ReplaceDate != "" {
+		replaceDate, err = parseMeterDate(req.ReplaceDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid replace date format. Use YYYY-MM-DD"})
+			return
+		}
+	}
+
+	if ctrl.meterNumberExists(tenantID, req.NewMeterNumber) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Meter number already exists"})
+		return
+	}
+
+	notes := req.Reason
+	if req.Notes != "" {
+		notes = req.Reason + " - " + req.Notes
+	}
+
+	nextCalib := replaceDate.AddDate(0, defaultCalibrationIntervalMonths, 0)
+	newMeter := models.Meter{
+		TenantID:       tenantID,
+		CustomerID:     oldMeter.CustomerID,
+		MeterNumber:    req.NewMeterNumber,
+		Brand:          req.NewBrand,
+		Model:          req.NewModel,
+		InstallDate:    replaceDate,
+		NextCalibDate:  &nextCalib,
+		InitialReading: req.NewInitialReading,
+		Status:         models.MeterStatusActive,
+		Notes:          req.Notes,
+	}
+
+	err = ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		oldValue := snapshotMeter(oldMeter, nil)
+		oldMeter.Status = models.MeterStatusReplaced
+		if err := tx.Model(&models.Meter{}).Where("id = ?", oldMeter.ID).Update("status", oldMeter.Status).Error; err != nil {
+			return err
+		}
+		finalReading := req.FinalReading
+		if err := recordMeterHistory(tx, oldMeter, models.MeterActionReplace, oldValue, snapshotMeter(oldMeter, &finalReading), userID, notes); err != nil {
+			return err
+		}
+
+		if err := tx.Create(&newMeter).Error; err != nil {
+			return err
+		}
+		if err := syncCustomerMeterNumber(tx, newMeter.CustomerID, newMeter.MeterNumber); err != nil {
+			return err
+		}
+		return recordMeterHistory(tx, &newMeter, models.MeterActionInstall, snapshotMeter(oldMeter, &finalReading), snapshotMeter(&newMeter, nil), userID, notes)
+	})
+	if errors.Is(err, errMeterNumberTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Meter number already used by another customer"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replace meter"})
+		return
+	}
+
+	newMeter.Customer = oldMeter.Customer
+	c.JSON(http.StatusCreated, gin.H{
+		"message":       "Meter replaced successfully",
+		"data":          responses.ToMeterResponse(&newMeter),
+		"old_meter":     responses.ToMeterResponse(oldMeter),
+		"final_reading": req.FinalReading,
+	})
+}
+
+// CalibrateMeter godoc
+// @Summary Record meter calibration
+// @Description Record a completed calibration and schedule the next one
+// @Tags Meters
+// @Accept json
+// @Produce json
+// @Param id path string true "Meter ID"
+// @Param request body requests.CalibrateMeterRequest true "Calibrate meter request"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterResponse
+// @Router /api/meters/{id}/calibrate [post]
+func (ctrl *MeterController) CalibrateMeter(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req requests.CalibrateMeterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	meter, ok := ctrl.findMeter(c, tenantID)
+	if !ok {
+		return
+	}
+
+	if meter.Status == models.MeterStatusReplaced || meter.Status == models.MeterStatusInactive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Meter is no longer in service"})
+		return
+	}
+
+	calibDate, err := parseMeterDate(req.CalibrationDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calibration date format. Use YYYY-MM-DD"})
+		return
+	}
+
+	var nextCalib time.Time
+	if req.NextCalibDate != "" {
+		nextCalib, err = parseMeterDate(req.NextCalibDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid next calibration date format. Use YYYY-MM-DD"})
+			return
+		}
+		if !nextCalib.After(calibDate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Next calibration date must be after calibration date"})
+			return
+		}
+	} else {
+		interval := req.IntervalMonths
+		if interval == 0 {
+			interval = defaultCalibrationIntervalMonths
+		}
+		nextCalib = calibDate.AddDate(0, interval, 0)
+	}
+
+	oldValue := snapshotMeter(meter, nil)
+	meter.LastCalibDate = &calibDate
+	meter.NextCalibDate = &nextCalib
+
+	err = ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Meter{}).Where("id = ?", meter.ID).Updates(map[string]interface{}{
+			"last_calib_date": calibDate,
+			"next_calib_date": nextCalib,
+		}).Error; err != nil {
+			return err
+		}
+		return recordMeterHistory(tx, meter, models.MeterActionCalibrate, oldValue, snapshotMeter(meter, nil), userID, req.Notes)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record calibration"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Meter calibration recorded", "data": responses.ToMeterResponse(meter)})
+}
+
+// ScheduleCalibration godoc
+// @Summary Schedule meter calibration
+// @Description Set the next calibration date of a meter
+// @Tags Meters
+// @Accept json
+// @Produce json
+// @Param id path string true "Meter ID"
+// @Param request body requests.ScheduleCalibrationRequest true "Schedule calibration request"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterResponse
+// @Router /api/meters/{id}/calibration-schedule [put]
+func (ctrl *MeterController) ScheduleCalibration(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req requests.ScheduleCalibrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	meter, ok := ctrl.findMeter(c, tenantID)
+	if !ok {
+		return
+	}
+
+	nextCalib, err := parseMeterDate(req.NextCalibDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid next calibration date format. Use YYYY-MM-DD"})
+		return
+	}
+
+	oldValue := snapshotMeter(meter, nil)
+	meter.NextCalibDate = &nextCalib
+
+	err = ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Meter{}).Where("id = ?", meter.ID).Update("next_calib_date", nextCalib).Error; err != nil {
+			return err
+		}
+		return recordMeterHistory(tx, meter, models.MeterActionScheduleCalibration, oldValue, snapshotMeter(meter, nil), userID, req.Notes)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule calibration"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calibration scheduled", "data": responses.ToMeterResponse(meter)})
+}
+
+// RetireMeter godoc
+// @Summary Retire meter
+// @Description Take a meter out of service without installing a replacement
+// @Tags Meters
+// @Accept json
+// @Produce json
+// @Param id path string true "Meter ID"
+// @Param request body requests.RetireMeterRequest true "Retire meter request"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterResponse
+// @Router /api/meters/{id}/retire [post]
+func (ctrl *MeterController) RetireMeter(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req requests.RetireMeterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	meter, ok := ctrl.findMeter(c, tenantID)
+	if !ok {
+		return
+	}
+
+	if meter.Status == models.MeterStatusReplaced || meter.Status == models.MeterStatusInactive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Meter is already out of service"})
+		return
+	}
+
+	notes := req.Reason
+	if req.Notes != "" {
+		notes = req.Reason + " - " + req.Notes
+	}
+
+	oldValue := snapshotMeter(meter, nil)
+	meter.Status = models.MeterStatusInactive
+	finalReading := req.FinalReading
+
+	err = ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Meter{}).Where("id = ?", meter.ID).Update("status", meter.Status).Error; err != nil {
+			return err
+		}
+		return recordMeterHistory(tx, meter, models.MeterActionRetire, oldValue, snapshotMeter(meter, &finalReading), userID, notes)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retire meter"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Meter retired successfully", "data": responses.ToMeterResponse(meter)})
+}
+
+// GetMeterHistory godoc
+// @Summary Get meter history
+// @Description Get lifecycle history of a meter
+// @Tags Meters
+// @Produce json
+// @Param id path string true "Meter ID"
+// @Security BearerAuth
+// @Success 200 {array} responses.MeterHistoryResponse
+// @Router /api/meters/{id}/history [get]
+func (ctrl *MeterController) GetMeterHistory(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	meter, ok := ctrl.findMeter(c, tenantID)
+	if !ok {
+		return
+	}
+
+	var history []models.MeterHistory
+	if err := ctrl.DB.Preload("Meter").Preload("Customer").Preload("User").
+		Where("meter_id = ? AND tenant_id = ?", meter.ID, tenantID).
+		Order("created_at ASC").Find(&history).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch meter history"})
+		return
+	}
+
+	historyResponses := make([]responses.MeterHistoryResponse, len(history))
+	for i, h := range history {
+		historyResponses[i] = responses.ToMeterHistoryResponse(&h)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": historyResponses, "total": len(historyResponses)})
+}
+
+// DeleteMeter godoc
+// @Summary Delete meter
+// @Description Delete a meter registered by mistake. Meters with readings must be retired instead
+// @Tags Meters
+// @Param id path string true "Meter ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /api/meters/{id} [delete]
+func (ctrl *MeterController) DeleteMeter(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	meter, ok := ctrl.findMeter(c, tenantID)
+	if !ok {
+		return
+	}
+
+	var readingCount int64
+	ctrl.DB.Model(&models.WaterUsage{}).Where("meter_id = ?", meter.ID).Count(&readingCount)
+	if readingCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete meter with recorded readings. Retire it instead"})
+		return
+	}
+
+	if err := ctrl.DB.Where("id = ? AND tenant_id = ?", meter.ID, tenantID).Delete(&models.Meter{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete meter"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Meter deleted successfully"})
+}
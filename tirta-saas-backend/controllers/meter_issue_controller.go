@@ -0,0 +1,639 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/services"
+	"github.com/adipras/tirta-saas-backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// meterIssueTransitions lists the statuses an issue may move to from its current status
+var meterIssueTransitions = map[string][]string{
+	models.MeterIssueStatusOpen:       {models.MeterIssueStatusInProgress, models.MeterIssueStatusClosed},
+	models.MeterIssueStatusInProgress: {models.MeterIssueStatusResolved},
+	models.MeterIssueStatusResolved:   {models.MeterIssueStatusClosed, models.MeterIssueStatusInProgress},
+}
+
+func canTransitionMeterIssue(from, to string) bool {
+	for _, next := range meterIssueTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// isMeterFaultIssue reports whether an issue type means the meter can no longer be read reliably
+func isMeterFaultIssue(issueType string) bool {
+	return issueType == models.MeterIssueBroken || issueType == models.MeterIssueStuck
+}
+
+type MeterIssueController struct {
+	DB *gorm.DB
+}
+
+func NewMeterIssueController(db *gorm.DB) *MeterIssueController {
+	return &MeterIssueController{DB: db}
+}
+
+func (ctrl *MeterIssueController) issueQuery() *gorm.DB {
+	return ctrl.DB.Preload("Meter").Preload("Meter.Customer").Preload("Reporter").
+		Preload("ReportingCustomer").Preload("Assignee").Preload("Resolver")
+}
+
+func (ctrl *MeterIssueController) findIssue(c *gin.Context, tenantID uuid.UUID) (*models.MeterIssue, bool) {
+	issueID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid issue ID"})
+		return nil, false
+	}
+
+	var issue models.MeterIssue
+	if err := ctrl.issueQuery().Where("id = ? AND tenant_id = ?", issueID, tenantID).First(&issue).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meter issue not found"})
+		return nil, false
+	}
+	return &issue, true
+}
+
+// reload refreshes an issue with its relations after an update
+func (ctrl *MeterIssueController) reload(issue *models.MeterIssue) {
+	var fresh models.MeterIssue
+	if err := ctrl.issueQuery().Where("id = ?", issue.ID).First(&fresh).Error; err == nil {
+		*issue = fresh
+	}
+}
+
+// ensureAssignee stops service staff from working issues assigned to someone else
+func ensureAssignee(c *gin.Context, issue *models.MeterIssue, userID uuid.UUID) bool {
+	if c.GetString("role") != string(constants.RoleService) {
+		return true
+	}
+	if issue.AssignedTo == nil || *issue.AssignedTo != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Issue is not assigned to you"})
+		return false
+	}
+	return true
+}
+
+// createMeterIssue stores a new issue and marks the meter broken for faults that prevent reading
+func (ctrl *MeterIssueController) createMeterIssue(issue *models.MeterIssue, meter *models.Meter) error {
+	return ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(issue).Error; err != nil {
+			return err
+		}
+		if isMeterFaultIssue(issue.IssueType) && meter.Status == models.MeterStatusActive {
+			return tx.Model(&models.Meter{}).Where("id = ?", meter.ID).Update("status", models.MeterStatusBroken).Error
+		}
+		return nil
+	})
+}
+
+func saveMeterIssuePhoto(c *gin.Context, issue *models.MeterIssue) (string, error) {
+	file, err := c.FormFile("photo")
+	if err != nil {
+		return "", fmt.Errorf("no file uploaded")
+	}
+
+	uploadConfig := utils.DefaultImageUploadConfig()
+	uploadConfig.UploadDir = fmt.Sprintf("uploads/tenants/%s/meter-issues", issue.TenantID.String())
+
+	return utils.SaveUploadedFile(file, uploadConfig)
+}
+
+// ReportMeterIssue godoc
+// @Summary Report meter issue
+// @Description Report a problem with a meter found in the field
+// @Tags Meter Issues
+// @Accept json
+// @Produce json
+// @Param request body requests.ReportMeterIssueRequest true "Report meter issue request"
+// @Security BearerAuth
+// @Success 201 {object} responses.MeterIssueResponse
+// @Router /api/meter-issues [post]
+func (ctrl *MeterIssueController) ReportMeterIssue(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req requests.ReportMeterIssueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	meterID, err := uuid.Parse(req.MeterID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meter ID"})
+		return
+	}
+
+	var meter models.Meter
+	if err := ctrl.DB.Where("id = ? AND tenant_id = ?", meterID, tenantID).First(&meter).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meter not found"})
+		return
+	}
+
+	priority := req.Priority
+	if priority == "" {
+		priority = models.MeterIssuePriorityNormal
+	}
+
+	issue := models.MeterIssue{
+		TenantID:     tenantID,
+		MeterID:      meter.ID,
+		ReportedBy:   &userID,
+		ReporterType: models.MeterIssueReporterUser,
+		IssueType:    req.IssueType,
+		Description:  req.Description,
+		Status:       models.MeterIssueStatusOpen,
+		Priority:     priority,
+		PhotoURL:     req.PhotoURL,
+	}
+
+	if err := ctrl.createMeterIssue(&issue, &meter); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to report meter issue"})
+		return
+	}
+
+	ctrl.reload(&issue)
+	c.JSON(http.StatusCreated, gin.H{"message": "Meter issue reported successfully", "data": responses.ToMeterIssueResponse(&issue)})
+}
+
+// GetMeterIssues godoc
+// @Summary List meter issues
+// @Description Get meter issues for the tenant. Service staff only see issues assigned to them unless all=true
+// @Tags Meter Issues
+// @Produce json
+// @Param status query string false "Filter by status"
+// @Param priority query string false "Filter by priority"
+// @Param meter_id query string false "Filter by meter ID"
+// @Param assigned_to query string false "Filter by assignee user ID"
+// @Security BearerAuth
+// @Success 200 {array} responses.MeterIssueResponse
+// @Router /api/meter-issues [get]
+func (ctrl *MeterIssueController) GetMeterIssues(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.issueQuery().Where("tenant_id = ?", tenantID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if priority := c.Query("priority"); priority != "" {
+		query = query.Where("priority = ?", priority)
+	}
+	if meterID := c.Query("meter_id"); meterID != "" {
+		query = query.Where("meter_id = ?", meterID)
+	}
+	if assignedTo := c.Query("assigned_to"); assignedTo != "" {
+		query = query.Where("assigned_to = ?", assignedTo)
+	} else if c.GetString("role") == string(constants.RoleService) && c.Query("all") != "true" {
+		query = query.Where("assigned_to = ?", c.MustGet("user_id").(uuid.UUID))
+	}
+
+	var issues []models.MeterIssue
+	if err := query.Order("created_at DESC").Find(&issues).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch meter issues"})
+		return
+	}
+
+	issueResponses := make([]responses.MeterIssueResponse, len(issues))
+	for i, issue := range issues {
+		issueResponses[i] = responses.ToMeterIssueResponse(&issue)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": issueResponses, "total": len(issueResponses)})
+}
+
+// GetMeterIssue godoc
+// @Summary Get meter issue
+// @Tags Meter Issues
+// @Produce json
+// @Param id path string true "Issue ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterIssueResponse
+// @Router /api/meter-issues/{id} [get]
+func (ctrl *MeterIssueController) GetMeterIssue(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	issue, ok := ctrl.findIssue(c, tenantID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": responses.ToMeterIssueResponse(issue)})
+}
+
+// AssignMeterIssue godoc
+// @Summary Assign meter issue
+// @Description Assign a meter issue to a service staff member
+// @Tags Meter Issues
+// @Accept json
+// @Produce json
+// @Param id path string true "Issue ID"
+// @Param request body requests.AssignMeterIssueRequest true "Assign meter issue request"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterIssueResponse
+// @Router /api/meter-issues/{id}/assign [put]
+func (ctrl *MeterIssueController) AssignMeterIssue(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.AssignMeterIssueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	issue, ok := ctrl.findIssue(c, tenantID)
+	if !ok {
+		return
+	}
+
+	if issue.Status == models.MeterIssueStatusClosed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Closed issues cannot be reassigned"})
+		return
+	}
+
+	assigneeID, err := uuid.Parse(req.AssignedTo)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assignee ID"})
+		return
+	}
+
+	var assignee models.User
+	if err := ctrl.DB.Where("id = ? AND tenant_id = ?", assigneeID, tenantID).First(&assignee).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assignee not found"})
+		return
+	}
+	if !constants.HasPermission(constants.UserRole(assignee.Role), constants.PermManageRepairs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Assignee is not allowed to handle repairs"})
+		return
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"assigned_to": assignee.ID,
+		"assigned_at": now,
+	}
+	if req.Priority != "" {
+		updates["priority"] = req.Priority
+	}
+
+	if err := ctrl.DB.Model(&models.MeterIssue{}).Where("id = ?", issue.ID).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign meter issue"})
+		return
+	}
+
+	ctrl.reload(issue)
+	c.JSON(http.StatusOK, gin.H{"message": "Meter issue assigned successfully", "data": responses.ToMeterIssueResponse(issue)})
+}
+
+// StartMeterIssue godoc
+// @Summary Start work on meter issue
+// @Description Move an open issue to in_progress. Unassigned issues are assigned to the current user
+// @Tags Meter Issues
+// @Produce json
+// @Param id path string true "Issue ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterIssueResponse
+// @Router /api/meter-issues/{id}/start [post]
+func (ctrl *MeterIssueController) StartMeterIssue(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	issue, ok := ctrl.findIssue(c, tenantID)
+	if !ok {
+		return
+	}
+
+	if !canTransitionMeterIssue(issue.Status, models.MeterIssueStatusInProgress) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Cannot start work on issue with status %s", issue.Status)})
+		return
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":     models.MeterIssueStatusInProgress,
+		"started_at": now,
+	}
+	if issue.AssignedTo == nil {
+		updates["assigned_to"] = userID
+		updates["assigned_at"] = now
+	} else if !ensureAssignee(c, issue, userID) {
+		return
+	}
+
+	if err := ctrl.DB.Model(&models.MeterIssue{}).Where("id = ?", issue.ID).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update meter issue"})
+		return
+	}
+
+	ctrl.reload(issue)
+	c.JSON(http.StatusOK, gin.H{"message": "Work on meter issue started", "data": responses.ToMeterIssueResponse(issue)})
+}
+
+// ResolveMeterIssue godoc
+// @Summary Resolve meter issue
+// @Description Record the repair result. The meter returns to active unless another fault is still open
+// @Tags Meter Issues
+// @Accept json
+// @Produce json
+// @Param id path string true "Issue ID"
+// @Param request body requests.ResolveMeterIssueRequest true "Resolve meter issue request"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterIssueResponse
+// @Router /api/meter-issues/{id}/resolve [post]
+func (ctrl *MeterIssueController) ResolveMeterIssue(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req requests.ResolveMeterIssueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	issue, ok := ctrl.findIssue(c, tenantID)
+	if !ok {
+		return
+	}
+
+	if !canTransitionMeterIssue(issue.Status, models.MeterIssueStatusResolved) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Cannot resolve issue with status %s", issue.Status)})
+		return
+	}
+	if !ensureAssignee(c, issue, userID) {
+		return
+	}
+
+	resolution := req.Resolution
+	if req.Notes != "" {
+		resolution = req.Resolution + "\n" + req.Notes
+	}
+
+	meterStatus := req.MeterStatus
+	if meterStatus == "" {
+		meterStatus = models.MeterStatusActive
+	}
+
+	now := time.Now()
+	err = ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.MeterIssue{}).Where("id = ?", issue.ID).Updates(map[string]interface{}{
+			"status":      models.MeterIssueStatusResolved,
+			"resolved_by": userID,
+			"resolved_at": now,
+			"resolution":  resolution,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Only meters still in service change status; replaced or retired meters stay closed
+		if issue.Meter.Status != models.MeterStatusActive && issue.Meter.Status != models.MeterStatusBroken {
+			return nil
+		}
+
+		if meterStatus == models.MeterStatusActive {
+			var openFaults int64
+			tx.Model(&models.MeterIssue{}).
+				Where("meter_id = ? AND id <> ? AND issue_type IN ? AND status IN ?", issue.MeterID, issue.ID,
+					[]string{models.MeterIssueBroken, models.MeterIssueStuck},
+					[]string{models.MeterIssueStatusOpen, models.MeterIssueStatusInProgress}).
+				Count(&openFaults)
+			if openFaults > 0 {
+				meterStatus = models.MeterStatusBroken
+			}
+		}
+
+		return tx.Model(&models.Meter{}).Where("id = ?", issue.MeterID).Update("status", meterStatus).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve meter issue"})
+		return
+	}
+
+	ctrl.reload(issue)
+	c.JSON(http.StatusOK, gin.H{"message": "Meter issue resolved", "data": responses.ToMeterIssueResponse(issue)})
+}
+
+// CloseMeterIssue godoc
+// @Summary Close meter issue
+// @Description Close a resolved issue, or an open issue that needs no work
+// @Tags Meter Issues
+// @Produce json
+// @Param id path string true "Issue ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterIssueResponse
+// @Router /api/meter-issues/{id}/close [post]
+func (ctrl *MeterIssueController) CloseMeterIssue(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	issue, ok := ctrl.findIssue(c, tenantID)
+	if !ok {
+		return
+	}
+
+	if !canTransitionMeterIssue(issue.Status, models.MeterIssueStatusClosed) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Cannot close issue with status %s", issue.Status)})
+		return
+	}
+
+	if err := ctrl.DB.Model(&models.MeterIssue{}).Where("id = ?", issue.ID).Updates(map[string]interface{}{
+		"status":    models.MeterIssueStatusClosed,
+		"closed_at": time.Now(),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close meter issue"})
+		return
+	}
+
+	ctrl.reload(issue)
+	c.JSON(http.StatusOK, gin.H{"message": "Meter issue closed", "data": responses.ToMeterIssueResponse(issue)})
+}
+
+// UploadMeterIssuePhoto godoc
+// @Summary Upload meter issue photo
+// @Tags Meter Issues
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Issue ID"
+// @Param photo formData file true "Photo of the meter"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterIssueResponse
+// @Router /api/meter-issues/{id}/photo [post]
+func (ctrl *MeterIssueController) UploadMeterIssuePhoto(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	issue, ok := ctrl.findIssue(c, tenantID)
+	if !ok {
+		return
+	}
+
+	ctrl.updatePhoto(c, issue)
+}
+
+func (ctrl *MeterIssueController) updatePhoto(c *gin.Context, issue *models.MeterIssue) {
+	if issue.Status == models.MeterIssueStatusClosed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Closed issues cannot be modified"})
+		return
+	}
+
+	filePath, err := saveMeterIssuePhoto(c, issue)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ctrl.DB.Model(&models.MeterIssue{}).Where("id = ?", issue.ID).Update("photo_url", filePath).Error; err != nil {
+		utils.DeleteFile(filePath)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save photo"})
+		return
+	}
+
+	if issue.PhotoURL != "" {
+		utils.DeleteFile(issue.PhotoURL)
+	}
+
+	ctrl.reload(issue)
+	c.JSON(http.StatusOK, gin.H{"message": "Photo uploaded successfully", "data": responses.ToMeterIssueResponse(issue)})
+}
+
+// CustomerReportMeterIssue godoc
+// @Summary Report meter issue (customer)
+// @Description Customer reports a problem with their installed meter
+// @Tags Customer Self-Service
+// @Accept json
+// @Produce json
+// @Param request body requests.CustomerReportMeterIssueRequest true "Report meter issue request"
+// @Security BearerAuth
+// @Success 201 {object} responses.MeterIssueResponse
+// @Router /api/customer/meter-issues [post]
+func (ctrl *MeterIssueController) CustomerReportMeterIssue(c *gin.Context) {
+	customerID := c.MustGet("customer_id").(uuid.UUID)
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+
+	var req requests.CustomerReportMeterIssueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	meter := services.FindActiveMeter(ctrl.DB, tenantID, customerID)
+	if meter == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meter tidak ditemukan"})
+		return
+	}
+
+	issue := models.MeterIssue{
+		TenantID:             tenantID,
+		MeterID:              meter.ID,
+		ReportedByCustomerID: &customerID,
+		ReporterType:         models.MeterIssueReporterCustomer,
+		IssueType:            req.IssueType,
+		Description:          req.Description,
+		Status:               models.MeterIssueStatusOpen,
+		Priority:             models.MeterIssuePriorityNormal,
+	}
+
+	if err := ctrl.createMeterIssue(&issue, meter); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal melaporkan gangguan meter"})
+		return
+	}
+
+	ctrl.reload(&issue)
+	c.JSON(http.StatusCreated, gin.H{"message": "Laporan gangguan meter berhasil dikirim", "data": responses.ToMeterIssueResponse(&issue)})
+}
+
+// GetCustomerMeterIssues godoc
+// @Summary List own meter issues (customer)
+// @Tags Customer Self-Service
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} responses.MeterIssueResponse
+// @Router /api/customer/meter-issues [get]
+func (ctrl *MeterIssueController) GetCustomerMeterIssues(c *gin.Context) {
+	customerID := c.MustGet("customer_id").(uuid.UUID)
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+
+	var issues []models.MeterIssue
+	if err := ctrl.issueQuery().
+		Joins("JOIN meters ON meters.id = meter_issues.meter_id").
+		Where("meter_issues.tenant_id = ? AND meters.customer_id = ?", tenantID, customerID).
+		Order("meter_issues.created_at DESC").Find(&issues).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data"})
+		return
+	}
+
+	issueResponses := make([]responses.MeterIssueResponse, len(issues))
+	for i, issue := range issues {
+		issueResponses[i] = responses.ToMeterIssueResponse(&issue)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": issueResponses, "total": len(issueResponses)})
+}
+
+// CustomerUploadMeterIssuePhoto godoc
+// @Summary Upload photo for own meter issue (customer)
+// @Tags Customer Self-Service
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Issue ID"
+// @Param photo formData file true "Photo of the meter"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterIssueResponse
+// @Router /api/customer/meter-issues/{id}/photo [post]
+func (ctrl *MeterIssueController) CustomerUploadMeterIssuePhoto(c *gin.Context) {
+	customerID := c.MustGet("customer_id").(uuid.UUID)
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+
+	issue, ok := ctrl.findIssue(c, tenantID)
+	if !ok {
+		return
+	}
+
+	if issue.ReportedByCustomerID == nil || *issue.ReportedByCustomerID != customerID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Laporan tidak ditemukan"})
+		return
+	}
+
+	ctrl.updatePhoto(c, issue)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/adipras/tirta-saas-backend/config"
 	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/services"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -390,3 +391,89 @@ func GetSystemMetrics(c *gin.Context) {
 		Data:    metrics,
 	})
 }
+
+// GetSchedulerStatus shows which instance holds each scheduler lease and the recent runs of the
+// scheduled jobs with the instance that executed them (Platform Owner only)
+func GetSchedulerStatus(c *gin.Context) {
+	var leases []models.SchedulerLease
+	if err := config.DB.Order("name ASC").Find(&leases).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to fetch scheduler leases",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var dbNow time.Time
+	config.DB.Raw("SELECT NOW(3)").Scan(&dbNow)
+
+	leaseList := make([]map[string]interface{}, 0, len(leases))
+	for _, lease := range leases {
+		leaseList = append(leaseList, map[string]interface{}{
+			"name":        lease.Name,
+			"holder":      lease.Holder,
+			"acquired_at": lease.AcquiredAt,
+			"renewed_at":  lease.RenewedAt,
+			"expires_at":  lease.ExpiresAt,
+			"active":      lease.ExpiresAt.After(dbNow),
+		})
+	}
+
+	query := config.DB.Model(&models.SchedulerJobRun{})
+	if jobName := c.Query("job_name"); jobName != "" {
+		query = query.Where("job_name = ?", jobName)
+	}
+	if instance := c.Query("instance"); instance != "" {
+		query = query.Where("instance = ?", instance)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	// Pagination
+	page := 1
+	pageSize := 50
+	if p := c.Query("page"); p != "" {
+		var pageNum int
+		if _, err := fmt.Sscanf(p, "%d", &pageNum); err == nil && pageNum > 0 {
+			page = pageNum
+		}
+	}
+
+	if ps := c.Query("page_size"); ps != "" {
+		var pageSizeNum int
+		if _, err := fmt.Sscanf(ps, "%d", &pageSizeNum); err == nil && pageSizeNum > 0 && pageSizeNum <= 100 {
+			pageSize = pageSizeNum
+		}
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var runs []models.SchedulerJobRun
+	if err := query.Order("started_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&runs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to fetch scheduler job runs",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.SuccessResponse{
+		Status:  "success",
+		Message: "Scheduler status retrieved successfully",
+		Data: map[string]interface{}{
+			"instance": services.InstanceID(),
+			"leases":   leaseList,
+			"runs":     runs,
+			"pagination": map[string]interface{}{
+				"page":        page,
+				"page_size":   pageSize,
+				"total":       total,
+				"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+			},
+		},
+	})
+}
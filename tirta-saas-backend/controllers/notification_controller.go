@@ -2,19 +2,25 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
-	"time"
 
 	"github.com/adipras/tirta-saas-backend/config"
 	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/adipras/tirta-saas-backend/requests"
 	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/services"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// Template codes are upper case letters, digits and underscores, e.g. INVOICE_ISSUED
+var templateCodePattern = regexp.MustCompile(`^[A-Z0-9_]+$`)
+
 // ListNotificationTemplates lists all notification templates for a tenant
 func ListNotificationTemplates(c *gin.Context) {
 	tenantID := c.MustGet("tenant_id").(uuid.UUID)
@@ -90,9 +96,19 @@ func CreateNotificationTemplate(c *gin.Context) {
 		return
 	}
 	
+	code := strings.ToUpper(req.Code)
+	if !templateCodePattern.MatchString(code) {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Invalid template code",
+			Error:   "Template code may only contain letters, digits and underscores",
+		})
+		return
+	}
+	
 	// Check if template code already exists for this tenant
 	var existingTemplate models.NotificationTemplate
-	if err := config.DB.Where("tenant_id = ? AND code = ?", tenantID, req.Code).First(&existingTemplate).Error; err == nil {
+	if err := config.DB.Where("tenant_id = ? AND code = ?", tenantID, code).First(&existingTemplate).Error; err == nil {
 		c.JSON(http.StatusConflict, responses.ErrorResponse{
 			Status:  "error",
 			Message: "Template with this code already exists",
@@ -101,12 +117,26 @@ func CreateNotificationTemplate(c *gin.Context) {
 		return
 	}
 	
+	// Templates may only reference declared variables
+	declared, err := services.ResolveTemplateVariables(code, req.Variables)
+	if err == nil {
+		err = services.NotificationRendererForTenant(tenantID).ValidateTemplate(req.Subject, req.Body, req.HTMLBody, declared)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Invalid notification template",
+			Error:   err.Error(),
+		})
+		return
+	}
+	
 	// Convert variables to JSON
-	variablesJSON, _ := json.Marshal(req.Variables)
+	variablesJSON, _ := json.Marshal(declared)
 	
 	template := models.NotificationTemplate{
 		TenantID:    tenantID,
-		Code:        strings.ToUpper(req.Code),
+		Code:        code,
 		Name:        req.Name,
 		Description: req.Description,
 		Channel:     models.NotificationChannel(req.Channel),
@@ -209,6 +239,22 @@ func UpdateNotificationTemplate(c *gin.Context) {
 		template.Language = req.Language
 	}
 	
+	// Templates may only reference declared variables
+	var declared []string
+	json.Unmarshal([]byte(template.Variables), &declared)
+	declared, err := services.ResolveTemplateVariables(template.Code, declared)
+	if err == nil {
+		err = services.NotificationRendererForTenant(tenantID).ValidateTemplate(template.Subject, template.Body, template.HTMLBody, declared)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Invalid notification template",
+			Error:   err.Error(),
+		})
+		return
+	}
+	
 	if err := config.DB.Save(&template).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
 			Status:  "error",
@@ -275,6 +321,110 @@ func DeleteNotificationTemplate(c *gin.Context) {
 	})
 }
 
+// PreviewNotificationTemplate renders an unsaved template with sample data
+func PreviewNotificationTemplate(c *gin.Context) {
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+	
+	var req requests.PreviewNotificationTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+		return
+	}
+	
+	template := models.NotificationTemplate{
+		TenantID: tenantID,
+		Code:     strings.ToUpper(req.Code),
+		Subject:  req.Subject,
+		Body:     req.Body,
+		HTMLBody: req.HTMLBody,
+	}
+	
+	respondTemplatePreview(c, template, req.Variables, req.SampleData)
+}
+
+// PreviewSavedNotificationTemplate renders a saved template with sample data
+func PreviewSavedNotificationTemplate(c *gin.Context) {
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+	templateID := c.Param("id")
+	
+	var template models.NotificationTemplate
+	if err := config.DB.Where("id = ? AND tenant_id = ?", templateID, tenantID).First(&template).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Notification template not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	
+	var req requests.PreviewNotificationTemplateRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Status:  "error",
+				Message: "Invalid request body",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+	
+	var declared []string
+	json.Unmarshal([]byte(template.Variables), &declared)
+	
+	respondTemplatePreview(c, template, declared, req.SampleData)
+}
+
+// respondTemplatePreview validates a template and renders it with example values for the declared
+// variables, overridden by the given sample data
+func respondTemplatePreview(c *gin.Context, template models.NotificationTemplate, declared []string, sampleData map[string]interface{}) {
+	renderer := services.NotificationRendererForTenant(template.TenantID)
+	
+	declared, err := services.ResolveTemplateVariables(template.Code, declared)
+	if err == nil {
+		err = renderer.ValidateTemplate(template.Subject, template.Body, template.HTMLBody, declared)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Invalid notification template",
+			Error:   err.Error(),
+		})
+		return
+	}
+	
+	data := services.SampleTemplateVariables(declared)
+	for key, value := range sampleData {
+		data[key] = value
+	}
+	
+	rendered, err := renderer.RenderTemplate(template, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to render notification template",
+			Error:   err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, responses.SuccessResponse{
+		Status:  "success",
+		Message: "Notification template rendered successfully",
+		Data: responses.NotificationPreviewResponse{
+			Subject:    rendered.Subject,
+			Body:       rendered.Body,
+			HTMLBody:   rendered.HTMLBody,
+			Variables:  declared,
+			SampleData: data,
+		},
+	})
+}
+
 // SendNotification sends a notification to a recipient
 func SendNotification(c *gin.Context) {
 	tenantID := c.MustGet("tenant_id").(uuid.UUID)
@@ -290,7 +440,7 @@ func SendNotification(c *gin.Context) {
 	}
 	
 	var template *models.NotificationTemplate
-	var subject, body string
+	var subject, body, htmlBody string
 	
 	// Get template if template code is provided
 	if req.TemplateCode != "" {
@@ -304,17 +454,19 @@ func SendNotification(c *gin.Context) {
 			return
 		}
 		template = &tmpl
-		subject = template.Subject
-		body = template.Body
 		
-		// Replace variables in template
-		if req.Variables != nil {
-			for key, value := range req.Variables {
-				placeholder := fmt.Sprintf("{{%s}}", key)
-				subject = strings.ReplaceAll(subject, placeholder, fmt.Sprint(value))
-				body = strings.ReplaceAll(body, placeholder, fmt.Sprint(value))
-			}
+		rendered, err := services.NotificationRendererForTenant(tenantID).RenderTemplate(tmpl, req.Variables)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Status:  "error",
+				Message: "Failed to render notification template",
+				Error:   err.Error(),
+			})
+			return
 		}
+		subject = rendered.Subject
+		body = rendered.Body
+		htmlBody = rendered.HTMLBody
 	} else {
 		// Use custom subject and body
 		subject = req.CustomSubject
@@ -337,6 +489,8 @@ func SendNotification(c *gin.Context) {
 		recipientName = user.Name
 		if req.Channel == "EMAIL" {
 			destination = user.Email
+		} else if req.Channel == "IN_APP" {
+			destination = user.ID.String()
 		}
 	} else if req.RecipientType == "CUSTOMER" {
 		var customer models.Customer
@@ -351,8 +505,10 @@ func SendNotification(c *gin.Context) {
 		recipientName = customer.Name
 		if req.Channel == "EMAIL" {
 			destination = customer.Email
-		} else if req.Channel == "SMS" {
+		} else if req.Channel == "SMS" || req.Channel == "WHATSAPP" {
 			destination = customer.Phone
+		} else if req.Channel == "IN_APP" {
+			destination = customer.ID.String()
 		}
 	}
 	
@@ -365,7 +521,7 @@ func SendNotification(c *gin.Context) {
 		return
 	}
 	
-	// Create notification log
+	// Queue notification log, the notification worker delivers it
 	notificationLog := models.NotificationLog{
 		TenantID:      tenantID,
 		RecipientType: req.RecipientType,
@@ -375,14 +531,14 @@ func SendNotification(c *gin.Context) {
 		Destination:   destination,
 		Subject:       subject,
 		Body:          body,
-		Status:        "PENDING",
+		HTMLBody:      htmlBody,
 	}
 	
 	if template != nil {
 		notificationLog.TemplateID = &template.ID
 	}
 	
-	if err := config.DB.Create(&notificationLog).Error; err != nil {
+	if err := services.NewNotificationDispatcher().Enqueue(&notificationLog); err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
 			Status:  "error",
 			Message: "Failed to create notification log",
@@ -391,33 +547,142 @@ func SendNotification(c *gin.Context) {
 		return
 	}
 	
-	// TODO: Actual sending logic (email, SMS, etc.) would go here
-	// For now, we'll just mark it as sent
-	now := time.Now()
-	notificationLog.Status = "SENT"
-	notificationLog.SentAt = &now
-	config.DB.Save(&notificationLog)
+	c.JSON(http.StatusAccepted, responses.SuccessResponse{
+		Status:  "success",
+		Message: "Notification queued for delivery",
+		Data:    toNotificationLogResponse(notificationLog),
+	})
+}
+
+// ListNotificationLogs lists sent and queued notifications for a tenant
+func ListNotificationLogs(c *gin.Context) {
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+	
+	query := config.DB.Model(&models.NotificationLog{}).Where("tenant_id = ?", tenantID)
+	
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	
+	if channel := c.Query("channel"); channel != "" {
+		query = query.Where("channel = ?", channel)
+	}
+	
+	if recipientID := c.Query("recipient_id"); recipientID != "" {
+		query = query.Where("recipient_id = ?", recipientID)
+	}
+	
+	// Pagination
+	page := 1
+	pageSize := 50
+	if p := c.Query("page"); p != "" {
+		var pageNum int
+		if _, err := fmt.Sscanf(p, "%d", &pageNum); err == nil && pageNum > 0 {
+			page = pageNum
+		}
+	}
+	
+	if ps := c.Query("page_size"); ps != "" {
+		var pageSizeNum int
+		if _, err := fmt.Sscanf(ps, "%d", &pageSizeNum); err == nil && pageSizeNum > 0 && pageSizeNum <= 100 {
+			pageSize = pageSizeNum
+		}
+	}
+	
+	var total int64
+	query.Count(&total)
+	
+	var logs []models.NotificationLog
+	if err := query.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to fetch notification logs",
+			Error:   err.Error(),
+		})
+		return
+	}
+	
+	logList := make([]responses.NotificationLogResponse, 0, len(logs))
+	for _, notificationLog := range logs {
+		logList = append(logList, toNotificationLogResponse(notificationLog))
+	}
 	
 	c.JSON(http.StatusOK, responses.SuccessResponse{
 		Status:  "success",
-		Message: "Notification sent successfully (queued for delivery)",
-		Data: responses.NotificationLogResponse{
-			ID:            notificationLog.ID,
-			TenantID:      notificationLog.TenantID,
-			TemplateID:    notificationLog.TemplateID,
-			RecipientType: notificationLog.RecipientType,
-			RecipientID:   notificationLog.RecipientID,
-			RecipientName: notificationLog.RecipientName,
-			Channel:       string(notificationLog.Channel),
-			Destination:   notificationLog.Destination,
-			Subject:       notificationLog.Subject,
-			Status:        notificationLog.Status,
-			SentAt:        notificationLog.SentAt,
-			DeliveredAt:   notificationLog.DeliveredAt,
-			FailedAt:      notificationLog.FailedAt,
-			ErrorMessage:  notificationLog.ErrorMessage,
-			RetryCount:    notificationLog.RetryCount,
-			CreatedAt:     notificationLog.CreatedAt,
+		Message: "Notification logs retrieved successfully",
+		Data: map[string]interface{}{
+			"logs": logList,
+			"pagination": map[string]interface{}{
+				"page":        page,
+				"page_size":   pageSize,
+				"total":       total,
+				"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+			},
 		},
 	})
 }
+
+// RetryNotification puts a failed notification back in the delivery queue
+func RetryNotification(c *gin.Context) {
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+	
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Invalid notification ID",
+			Error:   err.Error(),
+		})
+		return
+	}
+	
+	notificationLog, err := services.NewNotificationDispatcher().Retry(tenantID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, responses.ErrorResponse{
+				Status:  "error",
+				Message: "Notification not found",
+				Error:   err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to retry notification",
+			Error:   err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, responses.SuccessResponse{
+		Status:  "success",
+		Message: "Notification queued for retry",
+		Data:    toNotificationLogResponse(*notificationLog),
+	})
+}
+
+func toNotificationLogResponse(notificationLog models.NotificationLog) responses.NotificationLogResponse {
+	return responses.NotificationLogResponse{
+		ID:            notificationLog.ID,
+		TenantID:      notificationLog.TenantID,
+		TemplateID:    notificationLog.TemplateID,
+		RecipientType: notificationLog.RecipientType,
+		RecipientID:   notificationLog.RecipientID,
+		RecipientName: notificationLog.RecipientName,
+		Channel:       string(notificationLog.Channel),
+		Destination:   notificationLog.Destination,
+		Subject:       notificationLog.Subject,
+		Status:        notificationLog.Status,
+		SentAt:        notificationLog.SentAt,
+		DeliveredAt:   notificationLog.DeliveredAt,
+		FailedAt:      notificationLog.FailedAt,
+		ErrorMessage:  notificationLog.ErrorMessage,
+		RetryCount:    notificationLog.RetryCount,
+		NextRetryAt:   notificationLog.NextRetryAt,
+		Provider:      notificationLog.Provider,
+		ProviderMsgID: notificationLog.ProviderMsgID,
+		EventCode:     notificationLog.EventCode,
+		ReferenceID:   notificationLog.ReferenceID,
+		CreatedAt:     notificationLog.CreatedAt,
+	}
+}
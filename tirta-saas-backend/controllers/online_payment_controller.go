@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// PaymentGatewayWebhook godoc
+// @Summary Payment gateway webhook
+// @Description Receives payment status changes from a payment gateway. The signature is verified before anything is applied; a settled payment is posted once, repeated calls are acknowledged without posting again.
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param provider path string true "Gateway name, e.g. simulated"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/public/payment-gateway/{provider}/webhook [post]
+func PaymentGatewayWebhook(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	transaction, err := services.NewOnlinePaymentService().HandleWebhook(c.Param("provider"), c.Request.Header, body)
+	if err != nil {
+		log.Printf("⚠️  Payment gateway webhook from %s rejected: %v", c.Param("provider"), err)
+		respondOnlinePaymentError(c, err, "Gagal memproses webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":         "ok",
+		"transaction_id": transaction.ID,
+		"payment_status": transaction.Status,
+	})
+}
+
+// ListPaymentTransactions godoc
+// @Summary List online payment transactions
+// @Description List the tenant's online payments through the payment gateway, newest first
+// @Tags Payments
+// @Produce json
+// @Param status query string false "PENDING, PAID, EXPIRED, FAILED or REVIEW"
+// @Param customer_id query string false "Customer ID"
+// @Security BearerAuth
+// @Success 200 {array} models.PaymentTransaction
+// @Router /api/payments/online-transactions [get]
+func ListPaymentTransactions(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := config.DB.Where("tenant_id = ?", tenantID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if customerID := c.Query("customer_id"); customerID != "" {
+		query = query.Where("customer_id = ?", customerID)
+	}
+
+	var transactions []models.PaymentTransaction
+	if err := query.Order("created_at desc").Find(&transactions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data transaksi"})
+		return
+	}
+
+	c.JSON(http.StatusOK, transactions)
+}
+
+// SyncPaymentTransaction godoc
+// @Summary Check online payment status
+// @Description Ask the gateway for the status of a pending online payment and apply it, for when a webhook was missed
+// @Tags Payments
+// @Produce json
+// @Param id path string true "Transaction ID"
+// @Security BearerAuth
+// @Success 200 {object} models.PaymentTransaction
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/payments/online-transactions/{id}/sync [post]
+func SyncPaymentTransaction(c *gin.Context) {
+	transaction, ok := loadPaymentTransaction(c)
+	if !ok {
+		return
+	}
+
+	synced, err := services.NewOnlinePaymentService().SyncTransaction(transaction)
+	if err != nil {
+		respondOnlinePaymentError(c, err, "Gagal memeriksa status pembayaran")
+		return
+	}
+
+	c.JSON(http.StatusOK, synced)
+}
+
+// SimulatePaymentTransaction godoc
+// @Summary Simulate online payment
+// @Description Make the simulated gateway settle, expire or fail one of its payments. The signed webhook goes through the same verification as a real one. Only for transactions of the simulated provider.
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param id path string true "Transaction ID"
+// @Param request body requests.SimulatePaymentTransactionRequest true "Status"
+// @Security BearerAuth
+// @Success 200 {object} models.PaymentTransaction
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/payments/online-transactions/{id}/simulate [post]
+func SimulatePaymentTransaction(c *gin.Context) {
+	var req requests.SimulatePaymentTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	transaction, ok := loadPaymentTransaction(c)
+	if !ok {
+		return
+	}
+
+	simulated, err := services.NewOnlinePaymentService().SimulateTransaction(transaction, services.GatewayStatus(req.Status))
+	if err != nil {
+		respondOnlinePaymentError(c, err, "Gagal mensimulasikan pembayaran")
+		return
+	}
+
+	c.JSON(http.StatusOK, simulated)
+}
+
+func loadPaymentTransaction(c *gin.Context) (*models.PaymentTransaction, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	var transaction models.PaymentTransaction
+	if err := config.DB.Where("id = ? AND tenant_id = ?", c.Param("id"), tenantID).First(&transaction).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaksi pembayaran tidak ditemukan"})
+		return nil, false
+	}
+	return &transaction, true
+}
+
+func respondOnlinePaymentError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhookSignature):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTransactionNotFound), errors.Is(err, services.ErrUnknownPaymentGateway),
+		errors.Is(err, services.ErrGatewayReferenceUnknown):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOnlinePaymentExceedsDue), errors.Is(err, services.ErrNotSimulatedTransaction),
+		errors.Is(err, services.ErrGatewayChannelDisabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		respondPaymentPostingError(c, err, message)
+	}
+}
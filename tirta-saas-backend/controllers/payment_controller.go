@@ -2,13 +2,14 @@ package controllers
 
 import (
 	"github.com/adipras/tirta-saas-backend/helpers"
-	"fmt"
+	"errors"
 	"net/http"
 
 	"github.com/adipras/tirta-saas-backend/config"
 	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/adipras/tirta-saas-backend/requests"
 	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -47,78 +48,24 @@ func CreatePayment(c *gin.Context) {
 
 	}
 
-	// Ambil invoice terkait
-	var invoice models.Invoice
-	if err := config.DB.Where("id = ? AND tenant_id = ?", req.InvoiceID, tenantID).
-		First(&invoice).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice tidak ditemukan"})
-		return
-	}
-
-	if invoice.IsPaid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Tagihan sudah lunas"})
-		return
-	}
-
-	// Business rule validations
-	if req.Amount <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Payment amount must be greater than zero"})
-		return
-	}
-
-	if req.Amount > 999999 { // Max payment amount validation
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Payment amount exceeds maximum allowed limit"})
-		return
-	}
-
-	if invoice.TotalPaid+req.Amount > invoice.TotalAmount {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("Pembayaran melebihi total tagihan. Sisa tagihan: %.2f", invoice.TotalAmount-invoice.TotalPaid),
-		})
-		return
-	}
-
-	// Buat record pembayaran
-	payment := models.Payment{
+	payment, _, err := services.NewPaymentPostingService().PostPayment(services.PostPaymentInput{
+		TenantID:  tenantID,
 		InvoiceID: req.InvoiceID,
 		Amount:    req.Amount,
-		TenantID:  tenantID,
-	}
-	if err := config.DB.Create(&payment).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mencatat pembayaran"})
-		return
-	}
-
-	// Hitung total bayar baru
-	var totalPaid float64
-	config.DB.Model(&models.Payment{}).
-		Where("invoice_id = ?", req.InvoiceID).
-		Select("SUM(amount)").Scan(&totalPaid)
-
-	// Update invoice
-	invoice.TotalPaid = totalPaid
-	invoice.IsPaid = totalPaid >= invoice.TotalAmount
-	if err := config.DB.Save(&invoice).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui status invoice"})
+		Notes:     req.Notes,
+	})
+	if err != nil {
+		respondPaymentPostingError(c, err, "Gagal mencatat pembayaran")
 		return
 	}
 
-	// Jika invoice pendaftaran dan sudah lunas → aktifkan customer
-	if invoice.Type == "registration" && invoice.IsPaid {
-		if err := config.DB.Model(&models.Customer{}).
-			Where("id = ? AND tenant_id = ?", invoice.CustomerID, tenantID).
-			Update("is_active", true).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengaktifkan pelanggan"})
-			return
-		}
-	}
-
 	// Kirim response
 	res := responses.PaymentResponse{
-		ID:        payment.ID,
-		InvoiceID: payment.InvoiceID,
-		Amount:    payment.Amount,
-		PaidAt:    payment.CreatedAt,
+		ID:           payment.ID,
+		InvoiceID:    payment.InvoiceID,
+		Amount:       payment.Amount,
+		CreditAmount: payment.CreditAmount,
+		PaidAt:       payment.CreatedAt,
 	}
 	c.JSON(http.StatusCreated, res)
 }
@@ -243,13 +190,6 @@ func UpdatePayment(c *gin.Context) {
 		return
 	}
 
-	var payment models.Payment
-	if err := config.DB.Where("id = ? AND tenant_id = ?", paymentID, tenantID).
-		First(&payment).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Pembayaran tidak ditemukan"})
-		return
-	}
-
 	type UpdatePaymentInput struct {
 		Amount float64 `json:"amount" binding:"required,min=0"`
 	}
@@ -260,55 +200,12 @@ func UpdatePayment(c *gin.Context) {
 		return
 	}
 
-	// Get the invoice to validate the update
-	var invoice models.Invoice
-	if err := config.DB.Where("id = ? AND tenant_id = ?", payment.InvoiceID, tenantID).First(&invoice).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data invoice"})
-		return
-	}
-
-	// Calculate total paid excluding current payment
-	var totalPaidExcludingCurrent float64
-	config.DB.Model(&models.Payment{}).
-		Where("invoice_id = ? AND id != ?", payment.InvoiceID, paymentID).
-		Select("COALESCE(SUM(amount), 0)").Scan(&totalPaidExcludingCurrent)
-
-	// Business rule validations
-	if input.Amount <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Payment amount must be greater than zero"})
-		return
-	}
-
-	if input.Amount > 999999 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Payment amount exceeds maximum allowed limit"})
-		return
-	}
-
-	// Validate new amount
-	if totalPaidExcludingCurrent+input.Amount > invoice.TotalAmount {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("Pembayaran melebihi total tagihan. Maksimal: %.2f", invoice.TotalAmount-totalPaidExcludingCurrent),
-		})
-		return
-	}
-
-	// Update payment
-	payment.Amount = input.Amount
-	if err := config.DB.Save(&payment).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui pembayaran"})
+	payment, err := services.NewPaymentPostingService().UpdatePaymentAmount(tenantID, paymentID, input.Amount)
+	if err != nil {
+		respondPaymentPostingError(c, err, "Gagal memperbarui pembayaran")
 		return
 	}
 
-	// Update invoice total paid
-	var newTotalPaid float64
-	config.DB.Model(&models.Payment{}).
-		Where("invoice_id = ?", payment.InvoiceID).
-		Select("SUM(amount)").Scan(&newTotalPaid)
-
-	invoice.TotalPaid = newTotalPaid
-	invoice.IsPaid = newTotalPaid >= invoice.TotalAmount
-	config.DB.Save(&invoice)
-
 	c.JSON(http.StatusOK, payment)
 }
 
@@ -330,41 +227,26 @@ func DeletePayment(c *gin.Context) {
 		return
 	}
 
-	var payment models.Payment
-	if err := config.DB.Where("id = ? AND tenant_id = ?", paymentID, tenantID).
-		First(&payment).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Pembayaran tidak ditemukan"})
+	if err := services.NewPaymentPostingService().DeletePayment(tenantID, paymentID); err != nil {
+		respondPaymentPostingError(c, err, "Gagal menghapus pembayaran")
 		return
 	}
 
-	// Store invoice ID before deleting payment
-	invoiceID := payment.InvoiceID
-
-	// Delete payment
-	if err := config.DB.Delete(&payment).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus pembayaran"})
-		return
-	}
+	c.JSON(http.StatusOK, gin.H{"message": "Pembayaran berhasil dihapus"})
+}
 
-	// Update invoice total paid
-	var invoice models.Invoice
-	if err := config.DB.Where("id = ? AND tenant_id = ?", invoiceID, tenantID).First(&invoice).Error; err == nil {
-		var newTotalPaid float64
-		config.DB.Model(&models.Payment{}).
-			Where("invoice_id = ?", invoiceID).
-			Select("COALESCE(SUM(amount), 0)").Scan(&newTotalPaid)
-
-		invoice.TotalPaid = newTotalPaid
-		invoice.IsPaid = newTotalPaid >= invoice.TotalAmount
-		config.DB.Save(&invoice)
-
-		// If this was a registration invoice and is no longer paid, deactivate customer
-		if invoice.Type == "registration" && !invoice.IsPaid {
-			config.DB.Model(&models.Customer{}).
-				Where("id = ?", invoice.CustomerID).
-				Update("is_active", false)
-		}
+// respondPaymentPostingError maps a payment posting error to its HTTP response
+func respondPaymentPostingError(c *gin.Context, err error, message string) {
+	switch {
+	case services.IsPaymentNotFound(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvoiceAlreadyPaid), errors.Is(err, services.ErrPaymentAmountInvalid),
+		errors.Is(err, services.ErrPaymentAmountTooLarge), errors.Is(err, services.ErrPaymentCreditUsed),
+		errors.Is(err, services.ErrCreditPaymentReadOnly), errors.Is(err, services.ErrAllocatedPaymentLocked),
+		errors.Is(err, services.ErrNoOutstandingInvoices), errors.Is(err, services.ErrPaymentNotPending),
+		errors.Is(err, services.ErrPaymentNotCompleted), errors.Is(err, services.ErrInvoiceVoided):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
 	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Pembayaran berhasil dihapus"})
 }
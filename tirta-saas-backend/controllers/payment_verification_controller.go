@@ -0,0 +1,297 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/services"
+	"github.com/adipras/tirta-saas-backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Transfer proofs may be photos or PDF statements
+var transferProofTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/jpg":       true,
+	"image/png":       true,
+	"application/pdf": true,
+}
+
+// CustomerSubmitTransferProof godoc
+// @Summary Upload bank transfer proof
+// @Description Report a bank transfer for one of the customer's invoices with a photo or PDF of the transfer. The payment stays pending and does not reduce the invoice until finance approves it.
+// @Tags Customer Self Service
+// @Accept multipart/form-data
+// @Produce json
+// @Param invoice_id formData string true "Invoice ID"
+// @Param amount formData number true "Amount transferred"
+// @Param reference_number formData string false "Transfer reference"
+// @Param payment_method_id formData string false "Payment method used"
+// @Param notes formData string false "Notes"
+// @Param proof_file formData file true "Transfer proof (JPG, PNG or PDF, max 5MB)"
+// @Security BearerAuth
+// @Success 201 {object} models.Payment
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/customer/payments/transfer [post]
+func CustomerSubmitTransferProof(c *gin.Context) {
+	customerID := c.MustGet("customer_id").(uuid.UUID)
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+
+	invoiceID, err := uuid.Parse(c.PostForm("invoice_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+
+	amount, err := strconv.ParseFloat(c.PostForm("amount"), 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount"})
+		return
+	}
+
+	input := services.TransferProofInput{
+		TenantID:        tenantID,
+		CustomerID:      customerID,
+		InvoiceID:       invoiceID,
+		Amount:          amount,
+		ReferenceNumber: c.PostForm("reference_number"),
+		Notes:           c.PostForm("notes"),
+	}
+
+	if methodID := c.PostForm("payment_method_id"); methodID != "" {
+		var method models.PaymentMethod
+		if err := config.DB.Where("id = ? AND tenant_id = ? AND is_active = ?", methodID, tenantID, true).First(&method).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Metode pembayaran tidak ditemukan"})
+			return
+		}
+		input.PaymentMethodID = &method.ID
+	}
+
+	file, err := c.FormFile("proof_file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bukti transfer wajib diunggah"})
+		return
+	}
+
+	uploadConfig := utils.DefaultImageUploadConfig()
+	uploadConfig.AllowedTypes = transferProofTypes
+	uploadConfig.UploadDir = fmt.Sprintf("uploads/tenants/%s/payment-proofs", tenantID.String())
+	proofPath, err := utils.SaveUploadedFile(file, uploadConfig)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	input.ProofImageURL = proofPath
+
+	payment, err := services.NewPaymentPostingService().SubmitTransferProof(input)
+	if err != nil {
+		utils.DeleteFile(proofPath)
+		if services.IsPaymentNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tagihan tidak ditemukan"})
+			return
+		}
+		respondPaymentPostingError(c, err, "Gagal menyimpan bukti transfer")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Bukti transfer diterima dan menunggu verifikasi",
+		"payment": payment,
+	})
+}
+
+// GetPaymentVerifications godoc
+// @Summary Payment verification queue
+// @Description List transfers reported by customers, oldest first. Shows the pending ones unless another status is asked for.
+// @Tags Payments
+// @Produce json
+// @Param status query string false "pending (default), completed or rejected"
+// @Security BearerAuth
+// @Success 200 {array} models.Payment
+// @Router /api/payment-verifications [get]
+func GetPaymentVerifications(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := c.DefaultQuery("status", models.PaymentRecordPending)
+
+	var payments []models.Payment
+	if err := config.DB.Preload("Invoice").Preload("PaymentMethod").
+		Where("tenant_id = ? AND status = ? AND proof_image_url <> ''", tenantID, status).
+		Order("created_at asc").Find(&payments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data pembayaran"})
+		return
+	}
+
+	c.JSON(http.StatusOK, payments)
+}
+
+// GetPaymentVerification godoc
+// @Summary Get reported transfer
+// @Tags Payments
+// @Produce json
+// @Param id path string true "Payment ID"
+// @Security BearerAuth
+// @Success 200 {object} models.Payment
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/payment-verifications/{id} [get]
+func GetPaymentVerification(c *gin.Context) {
+	payment, ok := loadReportedTransfer(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, payment)
+}
+
+// DownloadTransferProof godoc
+// @Summary Download transfer proof
+// @Description Download the photo or PDF the customer uploaded as proof of transfer
+// @Tags Payments
+// @Produce octet-stream
+// @Param id path string true "Payment ID"
+// @Security BearerAuth
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/payment-verifications/{id}/proof [get]
+func DownloadTransferProof(c *gin.Context) {
+	payment, ok := loadReportedTransfer(c)
+	if !ok {
+		return
+	}
+
+	if _, err := os.Stat(payment.ProofImageURL); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File bukti transfer tidak ditemukan"})
+		return
+	}
+
+	c.File(payment.ProofImageURL)
+}
+
+// ApproveTransferPayment godoc
+// @Summary Approve reported transfer
+// @Description Approve a pending transfer after checking the proof. The payment is applied to the invoice and the customer is notified.
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param id path string true "Payment ID"
+// @Param request body requests.ApproveTransferPaymentRequest false "Verification notes"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/payment-verifications/{id}/approve [post]
+func ApproveTransferPayment(c *gin.Context) {
+	var req requests.ApproveTransferPaymentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	tenantID, verifierID, paymentID, ok := verificationContext(c)
+	if !ok {
+		return
+	}
+
+	payment, invoice, err := services.NewPaymentPostingService().ApprovePayment(tenantID, paymentID, verifierID, req.Notes)
+	if err != nil {
+		respondPaymentPostingError(c, err, "Gagal memverifikasi pembayaran")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Pembayaran berhasil diverifikasi",
+		"payment":       payment,
+		"total_paid":    invoice.TotalPaid,
+		"is_paid":       invoice.IsPaid,
+		"credit_amount": payment.CreditAmount,
+	})
+}
+
+// RejectTransferPayment godoc
+// @Summary Reject reported transfer
+// @Description Reject a pending transfer with a reason that is sent to the customer. The invoice is unchanged.
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param id path string true "Payment ID"
+// @Param request body requests.RejectTransferPaymentRequest true "Rejection reason"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/payment-verifications/{id}/reject [post]
+func RejectTransferPayment(c *gin.Context) {
+	var req requests.RejectTransferPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID, verifierID, paymentID, ok := verificationContext(c)
+	if !ok {
+		return
+	}
+
+	payment, err := services.NewPaymentPostingService().RejectPayment(tenantID, paymentID, verifierID, req.Reason)
+	if err != nil {
+		respondPaymentPostingError(c, err, "Gagal menolak pembayaran")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pembayaran ditolak",
+		"payment": payment,
+	})
+}
+
+func loadReportedTransfer(c *gin.Context) (*models.Payment, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	var payment models.Payment
+	if err := config.DB.Preload("Invoice").Preload("PaymentMethod").
+		Where("id = ? AND tenant_id = ? AND proof_image_url <> ''", c.Param("id"), tenantID).
+		First(&payment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pembayaran tidak ditemukan"})
+		return nil, false
+	}
+	return &payment, true
+}
+
+// verificationContext returns the tenant, the verifying user and the payment of a verification request
+func verificationContext(c *gin.Context) (uuid.UUID, uuid.UUID, uuid.UUID, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+
+	userID, ok := c.Get("user_id")
+	verifierID, isUUID := userID.(uuid.UUID)
+	if !ok || !isUUID {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+
+	return tenantID, verifierID, paymentID, true
+}
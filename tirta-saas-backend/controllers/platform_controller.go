@@ -4,12 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/adipras/tirta-saas-backend/config"
 	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/adipras/tirta-saas-backend/requests"
 	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/services"
 	"github.com/adipras/tirta-saas-backend/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -618,6 +620,7 @@ func GetTenantSettings(c *gin.Context) {
 		InvoiceNumberFormat: settings.InvoiceNumberFormat,
 		InvoiceDueDays:      settings.InvoiceDueDays,
 		InvoiceFooterText:   settings.InvoiceFooterText,
+		TaxPercent:          settings.TaxPercent,
 		LatePenaltyPercent:  settings.LatePenaltyPercent,
 		LatePenaltyMaxCap:   settings.LatePenaltyMaxCap,
 		GracePeriodDays:     settings.GracePeriodDays,
@@ -633,6 +636,25 @@ func GetTenantSettings(c *gin.Context) {
 		Currency:            settings.Currency,
 		CreatedAt:           settings.CreatedAt,
 		UpdatedAt:           settings.UpdatedAt,
+
+		EstimateMissingReadings: settings.EstimateMissingReadings,
+
+		BillingDay:              settings.BillingDay,
+		BillingHour:             settings.BillingHour,
+		BillingUsageMonthOffset: settings.BillingUsageMonthOffset,
+		DueDateRule:             settings.DueDateRule,
+		DueDayOfMonth:           settings.DueDayOfMonth,
+
+		LatePenaltyPolicy:      settings.LatePenaltyPolicy,
+		LatePenaltyFixedAmount: settings.LatePenaltyFixedAmount,
+
+		PaymentAllocationOrder: settings.PaymentAllocationOrder,
+
+		NotifyInvoiceIssued:   settings.NotifyInvoiceIssued,
+		NotifyPaymentReminder: settings.NotifyPaymentReminder,
+		PaymentReminderDays:   settings.PaymentReminderDays,
+		NotifyInvoiceOverdue:  settings.NotifyInvoiceOverdue,
+		NotifyPaymentReceived: settings.NotifyPaymentReceived,
 	}
 	
 	c.JSON(http.StatusOK, responses.SuccessResponse{
@@ -691,12 +713,26 @@ func UpdateTenantSettings(c *gin.Context) {
 	if req.InvoicePrefix != "" {
 		settings.InvoicePrefix = req.InvoicePrefix
 	}
+	if req.InvoiceNumberFormat != "" {
+		if err := services.ValidateInvoiceNumberFormat(req.InvoiceNumberFormat); err != nil {
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Status:  "error",
+				Message: "Invalid invoice number format",
+				Error:   err.Error(),
+			})
+			return
+		}
+		settings.InvoiceNumberFormat = req.InvoiceNumberFormat
+	}
 	if req.InvoiceDueDays > 0 {
 		settings.InvoiceDueDays = req.InvoiceDueDays
 	}
 	if req.InvoiceFooterText != "" {
 		settings.InvoiceFooterText = req.InvoiceFooterText
 	}
+	if req.TaxPercent != nil {
+		settings.TaxPercent = *req.TaxPercent
+	}
 	if req.LatePenaltyPercent >= 0 {
 		settings.LatePenaltyPercent = req.LatePenaltyPercent
 	}
@@ -709,6 +745,12 @@ func UpdateTenantSettings(c *gin.Context) {
 	if req.MinimumBillAmount >= 0 {
 		settings.MinimumBillAmount = req.MinimumBillAmount
 	}
+	if req.LatePenaltyPolicy != "" {
+		settings.LatePenaltyPolicy = req.LatePenaltyPolicy
+	}
+	if req.LatePenaltyFixedAmount != nil {
+		settings.LatePenaltyFixedAmount = *req.LatePenaltyFixedAmount
+	}
 	if req.BankName != "" {
 		settings.BankName = req.BankName
 	}
@@ -725,11 +767,62 @@ func UpdateTenantSettings(c *gin.Context) {
 		settings.ServiceArea = req.ServiceArea
 	}
 	if req.TimeZone != "" {
+		if _, err := time.LoadLocation(req.TimeZone); err != nil {
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Status:  "error",
+				Message: "Invalid time zone",
+				Error:   err.Error(),
+			})
+			return
+		}
 		settings.TimeZone = req.TimeZone
 	}
 	if req.Language != "" {
 		settings.Language = req.Language
 	}
+	if req.EstimateMissingReadings != nil {
+		settings.EstimateMissingReadings = *req.EstimateMissingReadings
+	}
+	if req.BillingDay > 0 {
+		settings.BillingDay = req.BillingDay
+	}
+	if req.BillingHour != nil {
+		settings.BillingHour = *req.BillingHour
+	}
+	if req.BillingUsageMonthOffset != nil {
+		settings.BillingUsageMonthOffset = *req.BillingUsageMonthOffset
+	}
+	if req.DueDateRule != "" {
+		settings.DueDateRule = req.DueDateRule
+	}
+	if req.DueDayOfMonth > 0 {
+		settings.DueDayOfMonth = req.DueDayOfMonth
+	}
+	if settings.DueDateRule == models.DueDateRuleDayOfMonth && settings.DueDayOfMonth == 0 {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "due_day_of_month is required with the DAY_OF_MONTH due date rule",
+		})
+		return
+	}
+	if req.PaymentAllocationOrder != "" {
+		settings.PaymentAllocationOrder = req.PaymentAllocationOrder
+	}
+	if req.NotifyInvoiceIssued != nil {
+		settings.NotifyInvoiceIssued = *req.NotifyInvoiceIssued
+	}
+	if req.NotifyPaymentReminder != nil {
+		settings.NotifyPaymentReminder = *req.NotifyPaymentReminder
+	}
+	if req.PaymentReminderDays > 0 {
+		settings.PaymentReminderDays = req.PaymentReminderDays
+	}
+	if req.NotifyInvoiceOverdue != nil {
+		settings.NotifyInvoiceOverdue = *req.NotifyInvoiceOverdue
+	}
+	if req.NotifyPaymentReceived != nil {
+		settings.NotifyPaymentReceived = *req.NotifyPaymentReceived
+	}
 	
 	if err := config.DB.Save(&settings).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
@@ -747,6 +840,37 @@ func UpdateTenantSettings(c *gin.Context) {
 	})
 }
 
+// GetBillingCalendarPreview shows the next monthly generation runs of the tenant's billing
+// calendar, with the usage month each run bills and the due date of its invoices
+func GetBillingCalendarPreview(c *gin.Context) {
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+
+	count, err := strconv.Atoi(c.DefaultQuery("count", "6"))
+	if err != nil || count < 1 || count > 24 {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "count must be between 1 and 24",
+		})
+		return
+	}
+
+	settings := services.LoadBillingSettings(tenantID)
+	calendar := services.NewBillingCalendar(settings)
+
+	c.JSON(http.StatusOK, responses.SuccessResponse{
+		Status:  "success",
+		Message: "Billing calendar retrieved successfully",
+		Data: gin.H{
+			"timezone":                   calendar.Location().String(),
+			"billing_day":                settings.BillingDay,
+			"billing_hour":               settings.BillingHour,
+			"billing_usage_month_offset": settings.BillingUsageMonthOffset,
+			"due_date_rule":              settings.DueDateRule,
+			"next_runs":                  calendar.NextRuns(time.Now(), count),
+		},
+	})
+}
+
 // UploadTenantLogo handles logo upload for tenant
 func UploadTenantLogo(c *gin.Context) {
 	tenantID := c.MustGet("tenant_id").(uuid.UUID)
@@ -0,0 +1,334 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ReadingAnomalyController struct {
+	DB       *gorm.DB
+	readings *services.MeterReadingService
+}
+
+func NewReadingAnomalyController(db *gorm.DB) *ReadingAnomalyController {
+	return &ReadingAnomalyController{
+		DB:       db,
+		readings: services.NewMeterReadingService(),
+	}
+}
+
+func (ctrl *ReadingAnomalyController) findAnomaly(c *gin.Context, tenantID uuid.UUID) (*models.ReadingAnomaly, bool) {
+	anomalyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid anomaly ID"})
+		return nil, false
+	}
+
+	var anomaly models.ReadingAnomaly
+	if err := ctrl.DB.Preload("WaterUsage").Preload("WaterUsage.Customer").Preload("Resolver").
+		Where("id = ? AND tenant_id = ?", anomalyID, tenantID).First(&anomaly).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reading anomaly not found"})
+		return nil, false
+	}
+	return &anomaly, true
+}
+
+// requireOpenAnomaly rejects review actions on anomalies that were already closed
+func requireOpenAnomaly(c *gin.Context, anomaly *models.ReadingAnomaly) bool {
+	if anomaly.Status == models.AnomalyStatusResolved || anomaly.Status == models.AnomalyStatusIgnored {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Anomaly has already been " + anomaly.Status})
+		return false
+	}
+	return true
+}
+
+// close marks the anomaly as reviewed, which releases the reading for invoicing
+func (ctrl *ReadingAnomalyController) close(c *gin.Context, anomaly *models.ReadingAnomaly, status, resolution, notes string) bool {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	now := time.Now()
+
+	anomaly.Status = status
+	anomaly.ResolvedBy = &userID
+	anomaly.ResolvedAt = &now
+	anomaly.Resolution = resolution
+	if notes != "" {
+		if anomaly.Notes != "" {
+			anomaly.Notes += ". "
+		}
+		anomaly.Notes += notes
+	}
+
+	if err := ctrl.DB.Model(anomaly).Updates(map[string]interface{}{
+		"status":      anomaly.Status,
+		"resolved_by": anomaly.ResolvedBy,
+		"resolved_at": anomaly.ResolvedAt,
+		"resolution":  anomaly.Resolution,
+		"notes":       anomaly.Notes,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reading anomaly"})
+		return false
+	}
+	return true
+}
+
+func (ctrl *ReadingAnomalyController) respond(c *gin.Context, anomaly *models.ReadingAnomaly, message string) {
+	var fresh models.ReadingAnomaly
+	if err := ctrl.DB.Preload("WaterUsage").Preload("WaterUsage.Customer").Preload("Resolver").
+		Where("id = ?", anomaly.ID).First(&fresh).Error; err == nil {
+		anomaly = &fresh
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    responses.ToReadingAnomalyResponse(anomaly),
+	})
+}
+
+// GetReadingAnomalies godoc
+// @Summary List reading anomalies
+// @Description Review queue of flagged readings. Without a status filter only pending and investigating anomalies are returned.
+// @Tags Reading Anomalies
+// @Produce json
+// @Param status query string false "Filter by status (pending, investigating, resolved, ignored, all)"
+// @Param anomaly_type query string false "Filter by anomaly type"
+// @Param usage_month query string false "Filter by usage month (YYYY-MM)"
+// @Param customer_id query string false "Filter by customer"
+// @Param session_id query string false "Filter by reading session"
+// @Security BearerAuth
+// @Success 200 {array} responses.ReadingAnomalyResponse
+// @Router /api/reading-anomalies [get]
+func (ctrl *ReadingAnomalyController) GetReadingAnomalies(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.DB.Model(&models.ReadingAnomaly{}).
+		Joins("JOIN water_usages ON water_usages.id = reading_anomalies.water_usage_id").
+		Where("reading_anomalies.tenant_id = ?", tenantID)
+
+	switch status := c.Query("status"); status {
+	case "":
+		query = query.Where("reading_anomalies.status IN ?", services.UnresolvedAnomalyStatuses)
+	case "all":
+	default:
+		query = query.Where("reading_anomalies.status = ?", status)
+	}
+	if anomalyType := c.Query("anomaly_type"); anomalyType != "" {
+		query = query.Where("reading_anomalies.anomaly_type = ?", anomalyType)
+	}
+	if usageMonth := c.Query("usage_month"); usageMonth != "" {
+		query = query.Where("water_usages.usage_month = ?", usageMonth)
+	}
+	if customerID := c.Query("customer_id"); customerID != "" {
+		query = query.Where("water_usages.customer_id = ?", customerID)
+	}
+	if sessionID := c.Query("session_id"); sessionID != "" {
+		query = query.Where("water_usages.reading_session_id = ?", sessionID)
+	}
+
+	var anomalies []models.ReadingAnomaly
+	if err := query.Preload("WaterUsage").Preload("WaterUsage.Customer").Preload("Resolver").
+		Order("reading_anomalies.created_at ASC").Find(&anomalies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading anomalies"})
+		return
+	}
+
+	anomalyResponses := make([]responses.ReadingAnomalyResponse, len(anomalies))
+	for i, anomaly := range anomalies {
+		anomalyResponses[i] = responses.ToReadingAnomalyResponse(&anomaly)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": anomalyResponses, "total": len(anomalyResponses)})
+}
+
+// GetReadingAnomaly godoc
+// @Summary Get reading anomaly
+// @Tags Reading Anomalies
+// @Produce json
+// @Param id path string true "Anomaly ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingAnomalyResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/reading-anomalies/{id} [get]
+func (ctrl *ReadingAnomalyController) GetReadingAnomaly(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	anomaly, ok := ctrl.findAnomaly(c, tenantID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": responses.ToReadingAnomalyResponse(anomaly)})
+}
+
+// InvestigateReadingAnomaly godoc
+// @Summary Mark reading anomaly as under investigation
+// @Description Used while a re-read or field check is pending. The reading stays out of invoicing.
+// @Tags Reading Anomalies
+// @Produce json
+// @Param id path string true "Anomaly ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingAnomalyResponse
+// @Router /api/reading-anomalies/{id}/investigate [post]
+func (ctrl *ReadingAnomalyController) InvestigateReadingAnomaly(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	anomaly, ok := ctrl.findAnomaly(c, tenantID)
+	if !ok {
+		return
+	}
+	if anomaly.Status != models.AnomalyStatusPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only pending anomalies can be investigated"})
+		return
+	}
+
+	if err := ctrl.DB.Model(anomaly).Update("status", models.AnomalyStatusInvestigating).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reading anomaly"})
+		return
+	}
+
+	ctrl.respond(c, anomaly, "Anomaly is under investigation")
+}
+
+// ResolveReadingAnomaly godoc
+// @Summary Confirm an anomalous reading
+// @Description Accepts the reading as recorded and releases it for invoicing
+// @Tags Reading Anomalies
+// @Accept json
+// @Produce json
+// @Param id path string true "Anomaly ID"
+// @Param request body requests.ResolveAnomalyRequest true "Resolution"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingAnomalyResponse
+// @Router /api/reading-anomalies/{id}/resolve [post]
+func (ctrl *ReadingAnomalyController) ResolveReadingAnomaly(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.ResolveAnomalyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	anomaly, ok := ctrl.findAnomaly(c, tenantID)
+	if !ok || !requireOpenAnomaly(c, anomaly) {
+		return
+	}
+
+	if !ctrl.close(c, anomaly, models.AnomalyStatusResolved, req.Resolution, req.Notes) {
+		return
+	}
+
+	ctrl.respond(c, anomaly, "Reading confirmed")
+}
+
+// IgnoreReadingAnomaly godoc
+// @Summary Ignore a reading anomaly
+// @Description Dismisses a false positive and releases the reading for invoicing
+// @Tags Reading Anomalies
+// @Accept json
+// @Produce json
+// @Param id path string true "Anomaly ID"
+// @Param request body requests.ResolveAnomalyRequest false "Reason"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingAnomalyResponse
+// @Router /api/reading-anomalies/{id}/ignore [post]
+func (ctrl *ReadingAnomalyController) IgnoreReadingAnomaly(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The reason is optional when dismissing
+	var req requests.ResolveAnomalyRequest
+	_ = c.ShouldBindJSON(&req)
+
+	anomaly, ok := ctrl.findAnomaly(c, tenantID)
+	if !ok || !requireOpenAnomaly(c, anomaly) {
+		return
+	}
+
+	if !ctrl.close(c, anomaly, models.AnomalyStatusIgnored, req.Resolution, req.Notes) {
+		return
+	}
+
+	ctrl.respond(c, anomaly, "Anomaly ignored")
+}
+
+// CorrectReadingAnomaly godoc
+// @Summary Correct an anomalous reading
+// @Description Replaces the meter end with the verified value, reprices the usage and resolves the anomaly
+// @Tags Reading Anomalies
+// @Accept json
+// @Produce json
+// @Param id path string true "Anomaly ID"
+// @Param request body requests.CorrectAnomalyReadingRequest true "Corrected reading"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingAnomalyResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/reading-anomalies/{id}/correct [post]
+func (ctrl *ReadingAnomalyController) CorrectReadingAnomaly(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.CorrectAnomalyReadingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	anomaly, ok := ctrl.findAnomaly(c, tenantID)
+	if !ok || !requireOpenAnomaly(c, anomaly) {
+		return
+	}
+
+	usage := anomaly.WaterUsage
+	previousEnd := usage.MeterEnd
+	if err := ctrl.readings.CorrectReading(&usage, req.MeterEnd); err != nil {
+		respondReadingError(c, err)
+		return
+	}
+
+	resolution := req.Resolution
+	if resolution == "" {
+		resolution = "Meter reading corrected"
+	}
+	notes := fmt.Sprintf("Meter akhir dikoreksi dari %.2f menjadi %.2f", previousEnd, req.MeterEnd)
+	if req.Notes != "" {
+		notes += ". " + req.Notes
+	}
+
+	if !ctrl.close(c, anomaly, models.AnomalyStatusResolved, resolution, notes) {
+		return
+	}
+
+	ctrl.respond(c, anomaly, "Reading corrected")
+}
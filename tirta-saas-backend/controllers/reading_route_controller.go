@@ -0,0 +1,407 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ReadingRouteController struct {
+	DB *gorm.DB
+}
+
+func NewReadingRouteController(db *gorm.DB) *ReadingRouteController {
+	return &ReadingRouteController{DB: db}
+}
+
+func (ctrl *ReadingRouteController) findRoute(c *gin.Context, tenantID uuid.UUID) (*models.ReadingRoute, bool) {
+	routeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid route ID"})
+		return nil, false
+	}
+
+	var route models.ReadingRoute
+	if err := ctrl.DB.Preload("AssignedUser").Where("id = ? AND tenant_id = ?", routeID, tenantID).First(&route).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reading route not found"})
+		return nil, false
+	}
+	return &route, true
+}
+
+// resolveReader validates that the given user can read meters for the tenant
+func (ctrl *ReadingRouteController) resolveReader(c *gin.Context, tenantID uuid.UUID, assignedTo *string) (*uuid.UUID, bool) {
+	if assignedTo == nil || *assignedTo == "" {
+		return nil, true
+	}
+
+	readerID, err := uuid.Parse(*assignedTo)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assigned user ID"})
+		return nil, false
+	}
+
+	var reader models.User
+	if err := ctrl.DB.Where("id = ? AND tenant_id = ?", readerID, tenantID).First(&reader).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assigned user not found"})
+		return nil, false
+	}
+	if !constants.HasPermission(constants.UserRole(reader.Role), constants.PermRecordWaterUsage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Assigned user is not allowed to record meter readings"})
+		return nil, false
+	}
+	return &reader.ID, true
+}
+
+func (ctrl *ReadingRouteController) refreshCustomerCount(tx *gorm.DB, routeID uuid.UUID) error {
+	var count int64
+	tx.Model(&models.Customer{}).Where("reading_route_id = ?", routeID).Count(&count)
+	return tx.Model(&models.ReadingRoute{}).Where("id = ?", routeID).Update("customer_count", int(count)).Error
+}
+
+// GetReadingRoutes godoc
+// @Summary List reading routes
+// @Tags Reading Routes
+// @Produce json
+// @Param assigned_to query string false "Filter by assigned reader"
+// @Security BearerAuth
+// @Success 200 {array} responses.ReadingRouteResponse
+// @Router /api/reading-routes [get]
+func (ctrl *ReadingRouteController) GetReadingRoutes(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.DB.Preload("AssignedUser").Where("tenant_id = ?", tenantID)
+	if assignedTo := c.Query("assigned_to"); assignedTo != "" {
+		query = query.Where("assigned_to = ?", assignedTo)
+	}
+
+	var routes []models.ReadingRoute
+	if err := query.Order("code ASC").Find(&routes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading routes"})
+		return
+	}
+
+	routeResponses := make([]responses.ReadingRouteResponse, len(routes))
+	for i, route := range routes {
+		routeResponses[i] = responses.ToReadingRouteResponse(&route)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": routeResponses, "total": len(routeResponses)})
+}
+
+// GetReadingRoute godoc
+// @Summary Get reading route
+// @Tags Reading Routes
+// @Produce json
+// @Param id path string true "Route ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingRouteResponse
+// @Router /api/reading-routes/{id} [get]
+func (ctrl *ReadingRouteController) GetReadingRoute(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	route, ok := ctrl.findRoute(c, tenantID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": responses.ToReadingRouteResponse(route)})
+}
+
+// CreateReadingRoute godoc
+// @Summary Create reading route
+// @Tags Reading Routes
+// @Accept json
+// @Produce json
+// @Param request body requests.CreateReadingRouteRequest true "Create reading route request"
+// @Security BearerAuth
+// @Success 201 {object} responses.ReadingRouteResponse
+// @Router /api/reading-routes [post]
+func (ctrl *ReadingRouteController) CreateReadingRoute(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.CreateReadingRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing int64
+	ctrl.DB.Model(&models.ReadingRoute{}).Where("tenant_id = ? AND code = ?", tenantID, req.Code).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Reading route code already exists"})
+		return
+	}
+
+	readerID, ok := ctrl.resolveReader(c, tenantID, req.AssignedTo)
+	if !ok {
+		return
+	}
+
+	route := models.ReadingRoute{
+		TenantID:    tenantID,
+		Code:        req.Code,
+		Name:        req.Name,
+		Description: req.Description,
+		AssignedTo:  readerID,
+		ScheduleDay: req.ScheduleDay,
+		EstDuration: req.EstDuration,
+		IsActive:    true,
+	}
+
+	if err := ctrl.DB.Create(&route).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reading route"})
+		return
+	}
+
+	ctrl.DB.Preload("AssignedUser").First(&route, "id = ?", route.ID)
+	c.JSON(http.StatusCreated, gin.H{"message": "Reading route created successfully", "data": responses.ToReadingRouteResponse(&route)})
+}
+
+// UpdateReadingRoute godoc
+// @Summary Update reading route
+// @Tags Reading Routes
+// @Accept json
+// @Produce json
+// @Param id path string true "Route ID"
+// @Param request body requests.UpdateReadingRouteRequest true "Update reading route request"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingRouteResponse
+// @Router /api/reading-routes/{id} [put]
+func (ctrl *ReadingRouteController) UpdateReadingRoute(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.UpdateReadingRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	route, ok := ctrl.findRoute(c, tenantID)
+	if !ok {
+		return
+	}
+
+	readerID, ok := ctrl.resolveReader(c, tenantID, req.AssignedTo)
+	if !ok {
+		return
+	}
+
+	updates := map[string]interface{}{
+		"name":         req.Name,
+		"description":  req.Description,
+		"assigned_to":  readerID,
+		"schedule_day": req.ScheduleDay,
+		"est_duration": req.EstDuration,
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+
+	if err := ctrl.DB.Model(&models.ReadingRoute{}).Where("id = ?", route.ID).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reading route"})
+		return
+	}
+
+	ctrl.DB.Preload("AssignedUser").First(route, "id = ?", route.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Reading route updated successfully", "data": responses.ToReadingRouteResponse(route)})
+}
+
+// DeleteReadingRoute godoc
+// @Summary Delete reading route
+// @Description Delete a reading route and detach its customers
+// @Tags Reading Routes
+// @Param id path string true "Route ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /api/reading-routes/{id} [delete]
+func (ctrl *ReadingRouteController) DeleteReadingRoute(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	route, ok := ctrl.findRoute(c, tenantID)
+	if !ok {
+		return
+	}
+
+	var openSessions int64
+	ctrl.DB.Model(&models.ReadingSession{}).
+		Where("route_id = ? AND status IN ?", route.ID, []string{models.ReadingSessionScheduled, models.ReadingSessionInProgress}).
+		Count(&openSessions)
+	if openSessions > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete route with open reading sessions"})
+		return
+	}
+
+	err = ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Customer{}).Where("reading_route_id = ?", route.ID).
+			Updates(map[string]interface{}{"reading_route_id": nil, "reading_sequence": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.ReadingRoute{}, "id = ?", route.ID).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reading route"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reading route deleted successfully"})
+}
+
+// GetRouteCustomers godoc
+// @Summary List route customers
+// @Description Get customers of a reading route in visit order
+// @Tags Reading Routes
+// @Produce json
+// @Param id path string true "Route ID"
+// @Security BearerAuth
+// @Success 200 {array} map[string]interface{}
+// @Router /api/reading-routes/{id}/customers [get]
+func (ctrl *ReadingRouteController) GetRouteCustomers(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	route, ok := ctrl.findRoute(c, tenantID)
+	if !ok {
+		return
+	}
+
+	var customers []models.Customer
+	if err := ctrl.DB.Where("tenant_id = ? AND reading_route_id = ?", tenantID, route.ID).
+		Order("reading_sequence ASC").Order("name ASC").Find(&customers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch route customers"})
+		return
+	}
+
+	data := make([]gin.H, len(customers))
+	for i, customer := range customers {
+		data[i] = gin.H{
+			"sequence":     customer.ReadingSequence,
+			"customer_id":  customer.ID,
+			"name":         customer.Name,
+			"address":      customer.Address,
+			"meter_number": customer.MeterNumber,
+			"is_active":    customer.IsActive,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": data, "total": len(data)})
+}
+
+// UpdateRouteCustomers godoc
+// @Summary Set route customers
+// @Description Replace the customers of a reading route. The order of customer_ids is the visit order
+// @Tags Reading Routes
+// @Accept json
+// @Produce json
+// @Param id path string true "Route ID"
+// @Param request body requests.UpdateRouteCustomersRequest true "Route customers in visit order"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingRouteResponse
+// @Router /api/reading-routes/{id}/customers [put]
+func (ctrl *ReadingRouteController) UpdateRouteCustomers(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.UpdateRouteCustomersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	route, ok := ctrl.findRoute(c, tenantID)
+	if !ok {
+		return
+	}
+
+	customerIDs := make([]uuid.UUID, 0, len(req.CustomerIDs))
+	seen := map[uuid.UUID]bool{}
+	for _, id := range req.CustomerIDs {
+		customerID, err := uuid.Parse(id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID: " + id})
+			return
+		}
+		if seen[customerID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Duplicate customer ID: " + id})
+			return
+		}
+		seen[customerID] = true
+		customerIDs = append(customerIDs, customerID)
+	}
+
+	if len(customerIDs) > 0 {
+		var found int64
+		ctrl.DB.Model(&models.Customer{}).Where("tenant_id = ? AND id IN ?", tenantID, customerIDs).Count(&found)
+		if int(found) != len(customerIDs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Some customers were not found"})
+			return
+		}
+	}
+
+	// Customers moved from another route change that route's count too
+	var previousRoutes []uuid.UUID
+	if len(customerIDs) > 0 {
+		ctrl.DB.Model(&models.Customer{}).
+			Where("tenant_id = ? AND id IN ? AND reading_route_id IS NOT NULL AND reading_route_id <> ?", tenantID, customerIDs, route.ID).
+			Distinct().Pluck("reading_route_id", &previousRoutes)
+	}
+
+	err = ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Customer{}).Where("tenant_id = ? AND reading_route_id = ?", tenantID, route.ID).
+			Updates(map[string]interface{}{"reading_route_id": nil, "reading_sequence": 0}).Error; err != nil {
+			return err
+		}
+		for i, customerID := range customerIDs {
+			if err := tx.Model(&models.Customer{}).Where("id = ?", customerID).
+				Updates(map[string]interface{}{"reading_route_id": route.ID, "reading_sequence": i + 1}).Error; err != nil {
+				return err
+			}
+		}
+		for _, previousRoute := range previousRoutes {
+			if err := ctrl.refreshCustomerCount(tx, previousRoute); err != nil {
+				return err
+			}
+		}
+		return ctrl.refreshCustomerCount(tx, route.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update route customers"})
+		return
+	}
+
+	ctrl.DB.Preload("AssignedUser").First(route, "id = ?", route.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Route customers updated successfully", "data": responses.ToReadingRouteResponse(route)})
+}
@@ -0,0 +1,582 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/services"
+	"github.com/adipras/tirta-saas-backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ReadingSessionController struct {
+	DB       *gorm.DB
+	sessions *services.ReadingSessionService
+	readings *services.MeterReadingService
+}
+
+func NewReadingSessionController(db *gorm.DB) *ReadingSessionController {
+	return &ReadingSessionController{
+		DB:       db,
+		sessions: services.NewReadingSessionService(),
+		readings: services.NewMeterReadingService(),
+	}
+}
+
+func isMeterReader(c *gin.Context) bool {
+	return c.GetString("role") == string(constants.RoleMeterReader)
+}
+
+// findSession loads a session of the tenant. Meter readers can only access their own sessions.
+func (ctrl *ReadingSessionController) findSession(c *gin.Context, tenantID uuid.UUID) (*models.ReadingSession, bool) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return nil, false
+	}
+
+	var session models.ReadingSession
+	if err := ctrl.DB.Preload("Route").Preload("Route.AssignedUser").Preload("Reader").
+		Where("id = ? AND tenant_id = ?", sessionID, tenantID).First(&session).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reading session not found"})
+		return nil, false
+	}
+
+	if isMeterReader(c) && session.ReaderID != c.MustGet("user_id").(uuid.UUID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Reading session is assigned to another reader"})
+		return nil, false
+	}
+	return &session, true
+}
+
+func (ctrl *ReadingSessionController) reload(session *models.ReadingSession) {
+	var fresh models.ReadingSession
+	if err := ctrl.DB.Preload("Route").Preload("Route.AssignedUser").Preload("Reader").
+		Where("id = ?", session.ID).First(&fresh).Error; err == nil {
+		*session = fresh
+	}
+}
+
+func (ctrl *ReadingSessionController) listSessions(c *gin.Context, query *gorm.DB) {
+	var sessions []models.ReadingSession
+	if err := query.Preload("Route").Preload("Route.AssignedUser").Preload("Reader").
+		Order("scheduled_date DESC").Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading sessions"})
+		return
+	}
+
+	sessionResponses := make([]responses.ReadingSessionResponse, len(sessions))
+	for i, session := range sessions {
+		sessionResponses[i] = responses.ToReadingSessionResponse(&session)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": sessionResponses, "total": len(sessionResponses)})
+}
+
+// GetReadingSessions godoc
+// @Summary List reading sessions
+// @Description Meter readers only see their own sessions
+// @Tags Reading Sessions
+// @Produce json
+// @Param route_id query string false "Filter by route"
+// @Param status query string false "Filter by status"
+// @Param usage_month query string false "Filter by usage month (YYYY-MM)"
+// @Security BearerAuth
+// @Success 200 {array} responses.ReadingSessionResponse
+// @Router /api/reading-sessions [get]
+func (ctrl *ReadingSessionController) GetReadingSessions(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.DB.Where("tenant_id = ?", tenantID)
+	if routeID := c.Query("route_id"); routeID != "" {
+		query = query.Where("route_id = ?", routeID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if usageMonth := c.Query("usage_month"); usageMonth != "" {
+		query = query.Where("usage_month = ?", usageMonth)
+	}
+	if isMeterReader(c) {
+		query = query.Where("reader_id = ?", c.MustGet("user_id").(uuid.UUID))
+	}
+
+	ctrl.listSessions(c, query)
+}
+
+// GetMyReadingSessions godoc
+// @Summary List my open reading sessions
+// @Description Scheduled and in-progress sessions assigned to the current user
+// @Tags Reading Sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} responses.ReadingSessionResponse
+// @Router /api/reading-sessions/my [get]
+func (ctrl *ReadingSessionController) GetMyReadingSessions(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.DB.Where("tenant_id = ? AND reader_id = ? AND status IN ?", tenantID, c.MustGet("user_id").(uuid.UUID),
+		[]string{models.ReadingSessionScheduled, models.ReadingSessionInProgress})
+
+	ctrl.listSessions(c, query)
+}
+
+// GetReadingSession godoc
+// @Summary Get reading session
+// @Tags Reading Sessions
+// @Produce json
+// @Param id path string true "Session ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingSessionResponse
+// @Router /api/reading-sessions/{id} [get]
+func (ctrl *ReadingSessionController) GetReadingSession(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, ok := ctrl.findSession(c, tenantID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": responses.ToReadingSessionResponse(session)})
+}
+
+// StartReadingSession godoc
+// @Summary Open reading session
+// @Description Manually open the reading session of a route, e.g. when the scheduler missed it
+// @Tags Reading Sessions
+// @Accept json
+// @Produce json
+// @Param request body requests.StartReadingSessionRequest true "Start reading session request"
+// @Security BearerAuth
+// @Success 201 {object} responses.ReadingSessionResponse
+// @Router /api/reading-sessions [post]
+func (ctrl *ReadingSessionController) StartReadingSession(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.StartReadingSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	routeID, err := uuid.Parse(req.RouteID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid route ID"})
+		return
+	}
+
+	scheduledDate, err := time.Parse("2006-01-02", req.ScheduledDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled date format. Use YYYY-MM-DD"})
+		return
+	}
+
+	var route models.ReadingRoute
+	if err := ctrl.DB.Where("id = ? AND tenant_id = ?", routeID, tenantID).First(&route).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reading route not found"})
+		return
+	}
+
+	session, created, err := ctrl.sessions.OpenSession(route, scheduledDate)
+	if errors.Is(err, services.ErrRouteHasNoReader) || errors.Is(err, services.ErrRouteInactive) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open reading session"})
+		return
+	}
+	if !created {
+		ctrl.reload(session)
+		c.JSON(http.StatusConflict, gin.H{"error": "Reading session already exists for this route and month", "data": responses.ToReadingSessionResponse(session)})
+		return
+	}
+
+	ctrl.reload(session)
+	c.JSON(http.StatusCreated, gin.H{"message": "Reading session opened successfully", "data": responses.ToReadingSessionResponse(session)})
+}
+
+// GetSessionWorklist godoc
+// @Summary Get reading worklist
+// @Description Customers of the session route in visit order with previous reading and read status
+// @Tags Reading Sessions
+// @Produce json
+// @Param id path string true "Session ID"
+// @Security BearerAuth
+// @Success 200 {array} services.WorklistItem
+// @Router /api/reading-sessions/{id}/worklist [get]
+func (ctrl *ReadingSessionController) GetSessionWorklist(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, ok := ctrl.findSession(c, tenantID)
+	if !ok {
+		return
+	}
+
+	items, err := ctrl.sessions.Worklist(*session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build worklist"})
+		return
+	}
+
+	remaining := 0
+	for _, item := range items {
+		if !item.IsRead {
+			remaining++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session":   responses.ToReadingSessionResponse(session),
+		"data":      items,
+		"total":     len(items),
+		"remaining": remaining,
+	})
+}
+
+// RecordSessionReading godoc
+// @Summary Record meter reading in session
+// @Tags Reading Sessions
+// @Accept json
+// @Produce json
+// @Param id path string true "Session ID"
+// @Param request body requests.RecordMeterReadingRequest true "Meter reading"
+// @Security BearerAuth
+// @Success 201 {object} responses.WaterUsageResponse
+// @Router /api/reading-sessions/{id}/readings [post]
+func (ctrl *ReadingSessionController) RecordSessionReading(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req requests.RecordMeterReadingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, ok := ctrl.findSession(c, tenantID)
+	if !ok {
+		return
+	}
+
+	customerID, err := uuid.Parse(req.CustomerID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+
+	var meterID *uuid.UUID
+	if req.MeterID != nil && *req.MeterID != "" {
+		parsed, err := uuid.Parse(*req.MeterID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meter ID"})
+			return
+		}
+		meterID = &parsed
+	}
+
+	usage, err := ctrl.readings.RecordReading(services.RecordReadingInput{
+		TenantID:         tenantID,
+		CustomerID:       customerID,
+		UsageMonth:       req.UsageMonth,
+		MeterEnd:         req.MeterReading,
+		MeterID:          meterID,
+		ReadingSessionID: &session.ID,
+		RecordedBy:       &userID,
+		PhotoURL:         req.PhotoURL,
+		ReadingMethod:    req.ReadingMethod,
+		Notes:            req.Notes,
+	})
+	if err != nil {
+		respondReadingError(c, err)
+		return
+	}
+
+	ctrl.reload(session)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Meter reading recorded",
+		"data":    responses.ToWaterUsageResponse(usage),
+		"session": responses.ToReadingSessionResponse(session),
+	})
+}
+
+// EstimateSessionReading godoc
+// @Summary Report an unreadable meter
+// @Description Records an estimated usage from the customer's average when the reader cannot access the meter
+// @Tags Reading Sessions
+// @Accept json
+// @Produce json
+// @Param id path string true "Session ID"
+// @Param request body requests.EstimateSessionReadingRequest true "Customer and reason"
+// @Security BearerAuth
+// @Success 201 {object} responses.WaterUsageResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/reading-sessions/{id}/estimates [post]
+func (ctrl *ReadingSessionController) EstimateSessionReading(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req requests.EstimateSessionReadingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, ok := ctrl.findSession(c, tenantID)
+	if !ok {
+		return
+	}
+
+	usage, err := ctrl.readings.EstimateReading(services.EstimateReadingInput{
+		TenantID:         tenantID,
+		CustomerID:       uuid.MustParse(req.CustomerID),
+		ReadingSessionID: &session.ID,
+		RecordedBy:       &userID,
+		Reason:           req.Reason,
+	})
+	if err != nil {
+		respondReadingError(c, err)
+		return
+	}
+
+	ctrl.reload(session)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Estimated reading recorded",
+		"data":    responses.ToWaterUsageResponse(usage),
+		"session": responses.ToReadingSessionResponse(session),
+	})
+}
+
+// SyncSessionReadings godoc
+// @Summary Sync offline readings
+// @Description Apply readings captured offline in one request. Each reading is identified by its client_id so the
+// @Description upload can be retried safely; the response has a result per reading (created, duplicate, conflict, rejected, failed).
+// @Tags Reading Sessions
+// @Accept json
+// @Produce json
+// @Param id path string true "Session ID"
+// @Param request body requests.SyncReadingsRequest true "Offline readings"
+// @Security BearerAuth
+// @Success 200 {object} responses.SyncReadingsResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/reading-sessions/{id}/sync [post]
+func (ctrl *ReadingSessionController) SyncSessionReadings(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req requests.SyncReadingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, ok := ctrl.findSession(c, tenantID)
+	if !ok {
+		return
+	}
+
+	// Readings can still be synced after the session was completed, but not once it was cancelled
+	if session.Status == models.ReadingSessionCancelled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrReadingSessionClosed.Error()})
+		return
+	}
+
+	items := make([]services.SyncReadingItem, len(req.Readings))
+	for i, reading := range req.Readings {
+		items[i] = services.SyncReadingItem{
+			ClientID:      reading.ClientID,
+			CustomerID:    uuid.MustParse(reading.CustomerID),
+			MeterEnd:      reading.MeterReading,
+			CapturedAt:    reading.CapturedAt,
+			PhotoURL:      reading.PhotoURL,
+			Latitude:      reading.Latitude,
+			Longitude:     reading.Longitude,
+			ReadingMethod: reading.ReadingMethod,
+			Notes:         reading.Notes,
+			CannotRead:    reading.CannotRead,
+		}
+		if reading.MeterID != nil && *reading.MeterID != "" {
+			meterID := uuid.MustParse(*reading.MeterID)
+			items[i].MeterID = &meterID
+		}
+	}
+
+	results := ctrl.readings.SyncSessionReadings(session, userID, items)
+
+	response := responses.SyncReadingsResponse{Results: make([]responses.SyncReadingResultResponse, len(results))}
+	for i, result := range results {
+		item := responses.SyncReadingResultResponse{ClientID: result.ClientID, Status: result.Status}
+		if result.Err != nil {
+			item.Error = result.Err.Error()
+		}
+		if result.Usage != nil {
+			reading := responses.ToWaterUsageResponse(result.Usage)
+			item.Reading = &reading
+		}
+		if result.Existing != nil {
+			existing := responses.ToWaterUsageResponse(result.Existing)
+			item.ExistingReading = &existing
+		}
+		response.Results[i] = item
+
+		switch result.Status {
+		case services.SyncStatusCreated:
+			response.Created++
+		case services.SyncStatusDuplicate:
+			response.Duplicate++
+		case services.SyncStatusConflict:
+			response.Conflict++
+		case services.SyncStatusRejected:
+			response.Rejected++
+		default:
+			response.Failed++
+		}
+	}
+
+	if req.DeviceID != "" {
+		log.Printf("📲 Reading sync from device %s for session %s: %d created, %d duplicate, %d conflict, %d rejected, %d failed",
+			req.DeviceID, session.ID, response.Created, response.Duplicate, response.Conflict, response.Rejected, response.Failed)
+	}
+
+	ctrl.reload(session)
+	response.Session = responses.ToReadingSessionResponse(session)
+	c.JSON(http.StatusOK, response)
+}
+
+// UploadReadingPhoto godoc
+// @Summary Upload meter reading photo
+// @Description Upload a photo taken in the field; the returned photo_url is sent along with the reading
+// @Tags Reading Sessions
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Session ID"
+// @Param photo formData file true "Photo of the meter"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /api/reading-sessions/{id}/photos [post]
+func (ctrl *ReadingSessionController) UploadReadingPhoto(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, ok := ctrl.findSession(c, tenantID)
+	if !ok {
+		return
+	}
+
+	file, err := c.FormFile("photo")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no file uploaded"})
+		return
+	}
+
+	uploadConfig := utils.DefaultImageUploadConfig()
+	uploadConfig.UploadDir = fmt.Sprintf("uploads/tenants/%s/readings/%s", tenantID.String(), session.ID.String())
+
+	photoURL, err := utils.SaveUploadedFile(file, uploadConfig)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Photo uploaded", "photo_url": photoURL})
+}
+
+// CompleteReadingSession godoc
+// @Summary Complete reading session
+// @Description Close a session even if some customers could not be read
+// @Tags Reading Sessions
+// @Produce json
+// @Param id path string true "Session ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingSessionResponse
+// @Router /api/reading-sessions/{id}/complete [post]
+func (ctrl *ReadingSessionController) CompleteReadingSession(c *gin.Context) {
+	ctrl.closeSession(c, models.ReadingSessionCompleted, "Reading session completed")
+}
+
+// CancelReadingSession godoc
+// @Summary Cancel reading session
+// @Tags Reading Sessions
+// @Produce json
+// @Param id path string true "Session ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingSessionResponse
+// @Router /api/reading-sessions/{id}/cancel [post]
+func (ctrl *ReadingSessionController) CancelReadingSession(c *gin.Context) {
+	ctrl.closeSession(c, models.ReadingSessionCancelled, "Reading session cancelled")
+}
+
+func (ctrl *ReadingSessionController) closeSession(c *gin.Context, status, message string) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, ok := ctrl.findSession(c, tenantID)
+	if !ok {
+		return
+	}
+
+	if session.Status != models.ReadingSessionScheduled && session.Status != models.ReadingSessionInProgress {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reading session is already closed"})
+		return
+	}
+
+	updates := map[string]interface{}{
+		"status":   status,
+		"end_time": time.Now(),
+	}
+	if err := ctrl.DB.Model(&models.ReadingSession{}).Where("id = ?", session.ID).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reading session"})
+		return
+	}
+
+	ctrl.reload(session)
+	c.JSON(http.StatusOK, gin.H{"message": message, "data": responses.ToReadingSessionResponse(session)})
+}
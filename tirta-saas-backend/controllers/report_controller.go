@@ -49,7 +49,7 @@ func GetRevenueReport(c *gin.Context) {
 		query = query.Where("tenant_id = ?", tenantID)
 	}
 	
-	query = query.Where("created_at BETWEEN ? AND ?", startDate, endDate)
+	query = query.Where("status = ? AND created_at BETWEEN ? AND ?", models.PaymentRecordCompleted, startDate, endDate)
 
 	var totalRevenue float64
 	var paymentCount int64
@@ -71,7 +71,7 @@ func GetRevenueReport(c *gin.Context) {
 		methodQuery = methodQuery.Where("tenant_id = ?", tenantID)
 	}
 	
-	methodQuery.Where("created_at BETWEEN ? AND ?", startDate, endDate).
+	methodQuery.Where("status = ? AND created_at BETWEEN ? AND ?", models.PaymentRecordCompleted, startDate, endDate).
 		Group("payment_method").
 		Scan(&revenueByMethod)
 
@@ -237,7 +237,7 @@ func GetPaymentReport(c *gin.Context) {
 		query = query.Where("tenant_id = ?", tenantID)
 	}
 	
-	query = query.Where("created_at BETWEEN ? AND ?", startDate, endDate)
+	query = query.Where("status = ? AND created_at BETWEEN ? AND ?", models.PaymentRecordCompleted, startDate, endDate)
 
 	var totalAmount float64
 	var paymentCount int64
@@ -259,7 +259,7 @@ func GetPaymentReport(c *gin.Context) {
 		trendQuery = trendQuery.Where("tenant_id = ?", tenantID)
 	}
 	
-	trendQuery.Where("created_at BETWEEN ? AND ?", startDate, endDate).
+	trendQuery.Where("status = ? AND created_at BETWEEN ? AND ?", models.PaymentRecordCompleted, startDate, endDate).
 		Group("DATE(created_at)").
 		Order("date ASC").
 		Scan(&dailyPayments)
@@ -292,7 +292,7 @@ func GetOutstandingReport(c *gin.Context) {
 		return
 	}
 
-	query := config.DB.Model(&models.Invoice{}).Where("is_paid = ?", false)
+	query := config.DB.Model(&models.Invoice{}).Where("is_paid = ? AND status <> ?", false, models.InvoiceStatusVoid)
 	
 	if hasSpecificTenant {
 		query = query.Where("tenant_id = ?", tenantID)
@@ -304,19 +304,40 @@ func GetOutstandingReport(c *gin.Context) {
 	query.Count(&invoiceCount)
 	query.Select("COALESCE(SUM(total_amount - total_paid), 0)").Scan(&totalOutstanding)
 
+	// Arrears under an active installment plan are reported apart from the rest
+	var inPlanOutstanding float64
+	inPlanQuery := config.DB.Model(&models.Invoice{}).
+		Joins("JOIN installment_plans ON installment_plans.id = invoices.installment_plan_id").
+		Where("invoices.is_paid = ? AND invoices.status <> ? AND installment_plans.status = ?", false, models.InvoiceStatusVoid, models.InstallmentPlanActive)
+	planQuery := config.DB.Model(&models.InstallmentPlan{}).Where("status = ?", models.InstallmentPlanActive)
+	missedQuery := config.DB.Model(&models.Installment{}).
+		Joins("JOIN installment_plans ON installment_plans.id = installments.plan_id").
+		Where("installments.status = ? AND installment_plans.status = ?", models.InstallmentMissed, models.InstallmentPlanActive)
+	if hasSpecificTenant {
+		inPlanQuery = inPlanQuery.Where("invoices.tenant_id = ?", tenantID)
+		planQuery = planQuery.Where("tenant_id = ?", tenantID)
+		missedQuery = missedQuery.Where("installment_plans.tenant_id = ?", tenantID)
+	}
+	inPlanQuery.Select("COALESCE(SUM(invoices.total_amount - invoices.total_paid), 0)").Scan(&inPlanOutstanding)
+
+	var activePlanCount, missedInstallments int64
+	planQuery.Count(&activePlanCount)
+	missedQuery.Count(&missedInstallments)
+
 	// Get oldest unpaid invoices
 	var oldestInvoices []struct {
-		InvoiceID   string    `json:"invoice_id"`
-		CustomerID  string    `json:"customer_id"`
-		TotalAmount float64   `json:"total_amount"`
-		TotalPaid   float64   `json:"total_paid"`
-		Outstanding float64   `json:"outstanding"`
-		CreatedAt   time.Time `json:"created_at"`
+		InvoiceID         string    `json:"invoice_id"`
+		CustomerID        string    `json:"customer_id"`
+		TotalAmount       float64   `json:"total_amount"`
+		TotalPaid         float64   `json:"total_paid"`
+		Outstanding       float64   `json:"outstanding"`
+		InstallmentPlanID *string   `json:"installment_plan_id"`
+		CreatedAt         time.Time `json:"created_at"`
 	}
 
 	oldestQuery := config.DB.Model(&models.Invoice{}).
-		Select("id as invoice_id, customer_id, total_amount, total_paid, (total_amount - total_paid) as outstanding, created_at").
-		Where("is_paid = ?", false)
+		Select("id as invoice_id, customer_id, total_amount, total_paid, (total_amount - total_paid) as outstanding, installment_plan_id, created_at").
+		Where("is_paid = ? AND status <> ?", false, models.InvoiceStatusVoid)
 	
 	if hasSpecificTenant {
 		oldestQuery = oldestQuery.Where("tenant_id = ?", tenantID)
@@ -327,8 +348,11 @@ func GetOutstandingReport(c *gin.Context) {
 		Scan(&oldestInvoices)
 
 	c.JSON(http.StatusOK, gin.H{
-		"total_outstanding": totalOutstanding,
-		"unpaid_count":      invoiceCount,
-		"oldest_invoices":   oldestInvoices,
+		"total_outstanding":        totalOutstanding,
+		"unpaid_count":             invoiceCount,
+		"in_plan_outstanding":      inPlanOutstanding,
+		"active_installment_plans": activePlanCount,
+		"missed_installments":      missedInstallments,
+		"oldest_invoices":          oldestInvoices,
 	})
 }
@@ -1,12 +1,14 @@
 package controllers
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 
+	"github.com/adipras/tirta-saas-backend/helpers"
 	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/adipras/tirta-saas-backend/requests"
 	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/services"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -338,7 +340,12 @@ func (ctrl *TariffController) SimulateBill(c *gin.Context) {
 		return
 	}
 
-	tenantID := c.GetString("tenant_id")
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	categoryUUID, err := uuid.Parse(req.CategoryID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
@@ -352,69 +359,31 @@ func (ctrl *TariffController) SimulateBill(c *gin.Context) {
 		return
 	}
 
-	// Get progressive rates for this category
-	var rates []models.ProgressiveRate
-	if err := ctrl.DB.Where("category_id = ? AND is_active = ?", categoryUUID, true).
-		Order("min_volume ASC").Find(&rates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch progressive rates"})
+	// Calculate bill with the same engine used for real usage and invoices
+	calc, err := services.NewTariffEngine().CalculateForCategory(tenantID, categoryUUID, req.UsageVolume)
+	if errors.Is(err, services.ErrNoProgressiveRates) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No active progressive rates found for this category"})
 		return
 	}
-
-	if len(rates) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No active progressive rates found for this category"})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch progressive rates"})
 		return
 	}
 
-	// Calculate bill
-	remainingVolume := req.UsageVolume
-	totalAmount := 0.0
-	var breakdown []responses.BillSimulationBreakdown
-
-	for _, rate := range rates {
-		if remainingVolume <= 0 {
-			break
-		}
-
-		var volumeInTier float64
-		tierMax := remainingVolume
-
-		if rate.MaxVolume != nil && remainingVolume > (*rate.MaxVolume-rate.MinVolume) {
-			tierMax = *rate.MaxVolume - rate.MinVolume
+	breakdown := make([]responses.BillSimulationBreakdown, len(calc.Breakdown))
+	for i, tier := range calc.Breakdown {
+		breakdown[i] = responses.BillSimulationBreakdown{
+			TierRange:    tier.TierRange,
+			Volume:       tier.Volume,
+			PricePerUnit: tier.PricePerUnit,
+			Amount:       tier.Amount,
 		}
-
-		if tierMax < 0 {
-			continue
-		}
-
-		volumeInTier = tierMax
-		if volumeInTier > remainingVolume {
-			volumeInTier = remainingVolume
-		}
-
-		tierAmount := volumeInTier * rate.PricePerUnit
-		totalAmount += tierAmount
-
-		tierRange := fmt.Sprintf("%.0f - ", rate.MinVolume)
-		if rate.MaxVolume != nil {
-			tierRange += fmt.Sprintf("%.0f m³", *rate.MaxVolume)
-		} else {
-			tierRange += "unlimited m³"
-		}
-
-		breakdown = append(breakdown, responses.BillSimulationBreakdown{
-			TierRange:    tierRange,
-			Volume:       volumeInTier,
-			PricePerUnit: rate.PricePerUnit,
-			Amount:       tierAmount,
-		})
-
-		remainingVolume -= volumeInTier
 	}
 
 	response := responses.BillSimulationResponse{
 		Category:    responses.ToTariffCategoryResponse(&category),
 		UsageVolume: req.UsageVolume,
-		TotalAmount: totalAmount,
+		TotalAmount: calc.TotalAmount,
 		Breakdown:   breakdown,
 	}
 
@@ -1,16 +1,18 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
-	"time"
 
 	"github.com/adipras/tirta-saas-backend/config"
 	"github.com/adipras/tirta-saas-backend/helpers"
 	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/adipras/tirta-saas-backend/requests"
 	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // CreateWaterUsage godoc
@@ -42,95 +44,93 @@ func CreateWaterUsage(c *gin.Context) {
 		return
 	}
 
-	// Business rule validation: Check reasonable meter reading
-	if req.MeterEnd < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Meter end reading cannot be negative"})
-		return
-	}
-
-	if req.MeterEnd > 99999999 { // 8 digit max reasonable meter reading
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Meter reading exceeds maximum allowed value"})
-		return
+	var recordedBy *uuid.UUID
+	if userID, ok := c.Get("user_id"); ok {
+		if id, ok := userID.(uuid.UUID); ok {
+			recordedBy = &id
+		}
 	}
 
-	// Hitung bulan sebelumnya
-	prevMonth, err := time.Parse("2006-01", req.UsageMonth)
+	usage, err := services.NewMeterReadingService().RecordReading(services.RecordReadingInput{
+		TenantID:         tenantID,
+		CustomerID:       req.CustomerID,
+		UsageMonth:       req.UsageMonth,
+		MeterEnd:         req.MeterEnd,
+		MeterID:          req.MeterID,
+		ReadingSessionID: req.ReadingSessionID,
+		RecordedBy:       recordedBy,
+		Notes:            req.Notes,
+	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Format bulan tidak valid. Gunakan YYYY-MM"})
+		respondReadingError(c, err)
 		return
 	}
-	prevMonth = prevMonth.AddDate(0, -1, 0)
-	prevMonthStr := prevMonth.Format("2006-01")
-
-	// Ambil meter_end bulan sebelumnya
-	var lastUsage models.WaterUsage
-	meterStart := 0.0
-	if err := config.DB.Where("customer_id = ? AND usage_month = ? AND tenant_id = ?", req.CustomerID, prevMonthStr, tenantID).
-		First(&lastUsage).Error; err == nil {
-		meterStart = lastUsage.MeterEnd
-	}
 
-	if req.MeterEnd < meterStart {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Meter akhir lebih kecil dari meter sebelumnya"})
-		return
-	}
+	response := responses.ToWaterUsageResponse(usage)
+	c.JSON(http.StatusCreated, response)
+}
 
-	// Ambil data customer
-	var customer models.Customer
-	if err := config.DB.Where("id = ? AND tenant_id = ?", req.CustomerID, tenantID).First(&customer).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Pelanggan tidak ditemukan"})
+// EstimateWaterUsage godoc
+// @Summary Estimate water usage
+// @Description Record an estimated usage from the customer's average when the meter could not be read. The next actual reading trues up the difference on the following invoice.
+// @Tags Water Usage
+// @Accept json
+// @Produce json
+// @Param request body requests.EstimateWaterUsageRequest true "Estimate water usage request"
+// @Security BearerAuth
+// @Success 201 {object} responses.WaterUsageResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/water-usage/estimate [post]
+func EstimateWaterUsage(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Ambil tarif aktif untuk subscription pelanggan
-	var rate models.WaterRate
-	if err := config.DB.
-		Where("subscription_id = ? AND active = ?", customer.SubscriptionID, true).
-		Order("effective_date DESC").
-		First(&rate).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Tarif air aktif tidak ditemukan"})
+	var req requests.EstimateWaterUsageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	UsageM3 := req.MeterEnd - meterStart
-
-	// Business rule validation: Check reasonable usage amount
-	if UsageM3 < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Calculated usage cannot be negative"})
-		return
+	var recordedBy *uuid.UUID
+	if userID, ok := c.Get("user_id"); ok {
+		if id, ok := userID.(uuid.UUID); ok {
+			recordedBy = &id
+		}
 	}
 
-	if UsageM3 > 1000 { // Max 1000 m3 per month seems reasonable
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Usage amount exceeds reasonable limit (1000 m3/month)"})
+	usage, err := services.NewMeterReadingService().EstimateReading(services.EstimateReadingInput{
+		TenantID:   tenantID,
+		CustomerID: req.CustomerID,
+		UsageMonth: req.UsageMonth,
+		RecordedBy: recordedBy,
+		Reason:     req.Reason,
+	})
+	if err != nil {
+		respondReadingError(c, err)
 		return
 	}
 
-	usage := models.WaterUsage{
-		CustomerID:       req.CustomerID,
-		UsageMonth:       req.UsageMonth,
-		MeterStart:       meterStart,
-		MeterEnd:         req.MeterEnd,
-		UsageM3:          UsageM3,
-		AmountCalculated: UsageM3 * rate.Amount,
-		TenantID:         tenantID,
-	}
+	c.JSON(http.StatusCreated, responses.ToWaterUsageResponse(usage))
+}
 
-	if err := config.DB.Create(&usage).Error; err != nil {
+// respondReadingError maps meter reading service errors to HTTP responses
+func respondReadingError(c *gin.Context, err error) {
+	switch {
+	case services.IsReadingNotFound(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrReadingAlreadyExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoActiveWaterRate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tarif air aktif tidak ditemukan"})
+	case services.IsReadingValidationError(err):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan data"})
-		return
 	}
-
-	response := responses.WaterUsageResponse{
-		ID:               usage.ID,
-		CustomerID:       usage.CustomerID,
-		UsageMonth:       usage.UsageMonth,
-		MeterStart:       usage.MeterStart,
-		MeterEnd:         usage.MeterEnd,
-		UsageM3:          usage.UsageM3,
-		AmountCalculated: usage.AmountCalculated,
-		CreatedAt:        usage.CreatedAt,
-	}
-	c.JSON(http.StatusCreated, response)
 }
 
 // GetWaterUsages godoc
@@ -167,16 +167,7 @@ func GetWaterUsages(c *gin.Context) {
 	// Convert to response format
 	usageResponses := make([]responses.WaterUsageResponse, len(records))
 	for i, record := range records {
-		usageResponses[i] = responses.WaterUsageResponse{
-			ID:               record.ID,
-			CustomerID:       record.CustomerID,
-			UsageMonth:       record.UsageMonth,
-			MeterStart:       record.MeterStart,
-			MeterEnd:         record.MeterEnd,
-			UsageM3:          record.UsageM3,
-			AmountCalculated: record.AmountCalculated,
-			CreatedAt:        record.CreatedAt,
-		}
+		usageResponses[i] = responses.ToWaterUsageResponse(&record)
 	}
 
 	response := responses.WaterUsageListResponse{
@@ -206,16 +197,7 @@ func GetWaterUsageByID(c *gin.Context) {
 		return
 	}
 
-	response := responses.WaterUsageResponse{
-		ID:               usage.ID,
-		CustomerID:       usage.CustomerID,
-		UsageMonth:       usage.UsageMonth,
-		MeterStart:       usage.MeterStart,
-		MeterEnd:         usage.MeterEnd,
-		UsageM3:          usage.UsageM3,
-		AmountCalculated: usage.AmountCalculated,
-		CreatedAt:        usage.CreatedAt,
-	}
+	response := responses.ToWaterUsageResponse(&usage)
 	c.JSON(http.StatusOK, response)
 }
 
@@ -246,66 +228,12 @@ func UpdateWaterUsage(c *gin.Context) {
 		return
 	}
 
-	// Business rule validations
-	if input.MeterEnd < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Meter end reading cannot be negative"})
-		return
-	}
-
-	if input.MeterEnd > 99999999 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Meter reading exceeds maximum allowed value"})
-		return
-	}
-
-	if input.MeterEnd < usage.MeterStart {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Meter akhir tidak boleh lebih kecil dari awal"})
-		return
-	}
-
-	// Ambil data customer
-	var customer models.Customer
-	if err := config.DB.Where("id = ? AND tenant_id = ?", usage.CustomerID, tenantID).First(&customer).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Pelanggan tidak ditemukan"})
+	if err := services.NewMeterReadingService().UpdateReading(&usage, input.MeterEnd); err != nil {
+		respondReadingError(c, err)
 		return
 	}
 
-	// Ambil tarif aktif untuk subscription pelanggan
-	var rate models.WaterRate
-	if err := config.DB.
-		Where("subscription_id = ? AND active = ?", customer.SubscriptionID, true).
-		Order("effective_date DESC").
-		First(&rate).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Tarif air aktif tidak ditemukan"})
-		return
-	}
-
-	UsageM3 := input.MeterEnd - usage.MeterStart
-
-	// Business rule validation: Check reasonable usage amount
-	if UsageM3 > 1000 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Usage amount exceeds reasonable limit (1000 m3/month)"})
-		return
-	}
-
-	usage.MeterEnd = input.MeterEnd
-	usage.UsageM3 = UsageM3
-	usage.AmountCalculated = UsageM3 * rate.Amount
-
-	if err := config.DB.Save(&usage).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui data"})
-		return
-	}
-
-	response := responses.WaterUsageResponse{
-		ID:               usage.ID,
-		CustomerID:       usage.CustomerID,
-		UsageMonth:       usage.UsageMonth,
-		MeterStart:       usage.MeterStart,
-		MeterEnd:         usage.MeterEnd,
-		UsageM3:          usage.UsageM3,
-		AmountCalculated: usage.AmountCalculated,
-		CreatedAt:        usage.CreatedAt,
-	}
+	response := responses.ToWaterUsageResponse(&usage)
 	c.JSON(http.StatusOK, response)
 }
 
@@ -332,5 +260,11 @@ func DeleteWaterUsage(c *gin.Context) {
 		return
 	}
 
+	// Hapus anomali yang masih menunggu review untuk pembacaan ini
+	config.DB.Where("water_usage_id = ?", usage.ID).Delete(&models.ReadingAnomaly{})
+
+	// Estimasi yang dikoreksi oleh pembacaan ini menunggu pembacaan aktual berikutnya
+	config.DB.Model(&models.WaterUsage{}).Where("true_up_usage_id = ?", usage.ID).Update("true_up_usage_id", nil)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Data berhasil dihapus"})
 }
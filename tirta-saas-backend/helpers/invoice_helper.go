@@ -3,25 +3,29 @@ package helpers
 import (
 	"github.com/adipras/tirta-saas-backend/config"
 	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/services"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // CreateRegistrationInvoice membuat invoice untuk pendaftaran pelanggan baru
 func CreateRegistrationInvoice(customerID, tenantID uuid.UUID, amount float64) (*models.Invoice, error) {
 	invoice := models.Invoice{
-		CustomerID:  customerID,
-		TenantID:    tenantID,
-		Type:        "registration",
-		Abonemen:    0,
-		PricePerM3:  0,
-		UsageM3:     0,
-		UsageMonth:  "-", // tidak relevan untuk registration
-		TotalAmount: amount,
-		IsPaid:      false,
-		TotalPaid:   0,
+		CustomerID: customerID,
+		TenantID:   tenantID,
+		Type:       "registration",
+		UsageM3:    0,
+		UsageMonth: "-", // tidak relevan untuk registration
+		IsPaid:     false,
+		TotalPaid:  0,
 	}
 
-	if err := config.DB.Create(&invoice).Error; err != nil {
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := services.CreateInvoice(tx, &invoice, services.RegistrationInvoiceLines(tenantID, amount)); err != nil {
+			return err
+		}
+		return services.RecordInvoiceCharges(tx, invoice)
+	}); err != nil {
 		return nil, err
 	}
 	return &invoice, nil
@@ -3,6 +3,8 @@ package main
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/adipras/tirta-saas-backend/config"
 	_ "github.com/adipras/tirta-saas-backend/docs"
@@ -55,14 +57,51 @@ func main() {
 		}
 	}
 
+	// Scheduled jobs run on one instance only: the one holding the scheduler lease
+	leader := services.NewLeaderElector("scheduler")
+	if err := leader.Start(); err != nil {
+		log.Printf("⚠️  Warning: Failed to start leader election: %v", err)
+	}
+
+	// Customers billed before the account ledger existed get their history posted once, by the leader
+	leader.RunExclusive("ledger-backfill", time.Now(), services.NewCustomerLedgerService().BackfillLedgers)
+
 	// Start invoice scheduler for automatic monthly generation
 	if os.Getenv("ENABLE_INVOICE_SCHEDULER") != "false" {
-		scheduler := services.NewInvoiceScheduler()
+		scheduler := services.NewInvoiceScheduler(leader)
 		if err := scheduler.Start(); err != nil {
 			log.Printf("⚠️  Warning: Failed to start invoice scheduler: %v", err)
 		}
 	}
 
+	// Start reading scheduler to open meter reading sessions on each route's schedule day
+	if os.Getenv("ENABLE_READING_SCHEDULER") != "false" {
+		readingScheduler := services.NewReadingScheduler(leader)
+		if err := readingScheduler.Start(); err != nil {
+			log.Printf("⚠️  Warning: Failed to start reading scheduler: %v", err)
+		}
+	}
+
+	// Start notification worker to deliver queued notifications through the channel providers
+	if os.Getenv("ENABLE_NOTIFICATION_WORKER") != "false" {
+		notificationWorker := services.NewNotificationWorker()
+		if err := notificationWorker.Start(); err != nil {
+			log.Printf("⚠️  Warning: Failed to start notification worker: %v", err)
+		}
+	}
+
+	// Start invoice generation workers to process the queued generation jobs in the background
+	if os.Getenv("ENABLE_INVOICE_GENERATION_WORKER") != "false" {
+		size, _ := strconv.Atoi(os.Getenv("INVOICE_GENERATION_WORKERS"))
+		if size <= 0 {
+			size = 2
+		}
+		generationWorker := services.NewInvoiceGenerationWorker(size)
+		if err := generationWorker.Start(); err != nil {
+			log.Printf("⚠️  Warning: Failed to start invoice generation worker: %v", err)
+		}
+	}
+
 	// Get port configuration
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -112,7 +151,13 @@ func main() {
 	// Master Data & Settings Routes
 	routes.ServiceAreaRoutes(r)
 	routes.PaymentMethodRoutes(r)
+	routes.BankStatementRoutes(r)
+	routes.InstallmentPlanRoutes(r)
 	routes.TariffRoutes(r)
+	routes.MeterRoutes(r)
+	routes.MeterIssueRoutes(r)
+	routes.ReadingRouteRoutes(r)
+	routes.ReadingAnomalyRoutes(r)
 	routes.UserManagementRoutes(r)
 
 	logger.Info("🚀 Server ready and listening", map[string]interface{}{
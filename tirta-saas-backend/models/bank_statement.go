@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Bank statement file formats
+const (
+	BankStatementFormatAuto    = "AUTO"
+	BankStatementFormatCSV     = "CSV" // generic CSV with a header row
+	BankStatementFormatBCA     = "BCA"
+	BankStatementFormatMandiri = "MANDIRI"
+	BankStatementFormatBRI     = "BRI"
+	BankStatementFormatBNI     = "BNI"
+)
+
+// Reconciliation status of a statement line
+const (
+	StatementLineMatched   = "MATCHED"
+	StatementLineException = "EXCEPTION" // needs manual matching
+	StatementLineIgnored   = "IGNORED"   // not a customer payment, e.g. interest or a transfer between own accounts
+)
+
+// How a statement line was matched
+const (
+	StatementMatchPendingPayment = "PENDING_PAYMENT" // approved a transfer proof the customer uploaded
+	StatementMatchReference      = "REFERENCE"       // invoice number or transfer reference in the description
+	StatementMatchManual         = "MANUAL"
+)
+
+// BankStatementImport is one bank mutation file uploaded for a tenant bank account
+type BankStatementImport struct {
+	BaseModel
+	TenantID      uuid.UUID    `gorm:"type:char(36);not null;index" json:"tenant_id"`
+	BankAccountID uuid.UUID    `gorm:"type:char(36);not null;index" json:"bank_account_id"`
+	BankAccount   *BankAccount `gorm:"foreignKey:BankAccountID" json:"bank_account,omitempty"`
+	Format        string       `gorm:"type:varchar(20);not null" json:"format"`
+	FileName      string       `gorm:"type:varchar(255)" json:"file_name"`
+	ImportedBy    *uuid.UUID   `gorm:"type:char(36)" json:"imported_by"`
+
+	// Credit lines stored; debits and lines already imported before are skipped
+	TotalLines     int     `gorm:"default:0" json:"total_lines"`
+	MatchedLines   int     `gorm:"default:0" json:"matched_lines"`
+	ExceptionLines int     `gorm:"default:0" json:"exception_lines"`
+	SkippedLines   int     `gorm:"default:0" json:"skipped_lines"`
+	DuplicateLines int     `gorm:"default:0" json:"duplicate_lines"`
+	TotalCredit    float64 `gorm:"default:0" json:"total_credit"`
+
+	Lines []BankStatementLine `gorm:"foreignKey:ImportID" json:"lines,omitempty"`
+}
+
+// BankStatementLine is one incoming transfer of a bank statement and what it was matched to
+type BankStatementLine struct {
+	BaseModel
+	TenantID        uuid.UUID `gorm:"type:char(36);not null;index:idx_statement_line_status" json:"tenant_id"`
+	ImportID        uuid.UUID `gorm:"type:char(36);not null;index" json:"import_id"`
+	BankAccountID   uuid.UUID `gorm:"type:char(36);not null;uniqueIndex:idx_statement_line_fingerprint" json:"bank_account_id"`
+	LineNumber      int       `gorm:"not null" json:"line_number"`
+	TransactionDate time.Time `gorm:"type:date;not null" json:"transaction_date"`
+	Description     string    `gorm:"type:varchar(500)" json:"description"`
+	ReferenceNumber string    `gorm:"type:varchar(100)" json:"reference_number"`
+	Amount          float64   `gorm:"not null" json:"amount"`
+	// Hash of the line's content, so importing an overlapping statement does not post it twice
+	Fingerprint string `gorm:"type:char(40);not null;uniqueIndex:idx_statement_line_fingerprint" json:"-"`
+
+	Status    string     `gorm:"type:varchar(20);not null;index:idx_statement_line_status" json:"status"`
+	MatchType string     `gorm:"type:varchar(20)" json:"match_type,omitempty"`
+	PaymentID *uuid.UUID `gorm:"type:char(36);index" json:"payment_id,omitempty"`
+	InvoiceID *uuid.UUID `gorm:"type:char(36);index" json:"invoice_id,omitempty"`
+	// Best guess for lines left as exception: the only open invoice with this amount, or the only
+	// transfer proof of this amount uploaded around the transfer date
+	SuggestedInvoiceID *uuid.UUID `gorm:"type:char(36)" json:"suggested_invoice_id,omitempty"`
+	SuggestedPaymentID *uuid.UUID `gorm:"type:char(36)" json:"suggested_payment_id,omitempty"`
+	Notes              string     `gorm:"type:varchar(255)" json:"notes,omitempty"`
+	MatchedBy          *uuid.UUID `gorm:"type:char(36)" json:"matched_by,omitempty"`
+	MatchedAt          *time.Time `json:"matched_at,omitempty"`
+}
@@ -23,6 +23,10 @@ type Customer struct {
 	ServiceArea    *ServiceArea `gorm:"foreignKey:ServiceAreaID" json:"service_area,omitempty"`
 	ReadingRouteID *uuid.UUID `gorm:"type:char(36);index" json:"reading_route_id"`
 	ReadingRoute   *ReadingRoute `gorm:"foreignKey:ReadingRouteID" json:"reading_route,omitempty"`
+	ReadingSequence int       `gorm:"default:0" json:"reading_sequence"` // visit order within the reading route
+
+	// Unapplied credit from overpayments, refunds and write-offs, used for the next invoices
+	CreditBalance float64 `gorm:"default:0" json:"credit_balance"`
 	
 	// Relationships
 	Meters []Meter `gorm:"foreignKey:CustomerID" json:"-"`
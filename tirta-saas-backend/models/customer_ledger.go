@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LedgerEntryType is the kind of movement on a customer account
+type LedgerEntryType string
+
+const (
+	// Debits, increase what the customer owes
+	LedgerEntryInvoice         LedgerEntryType = "INVOICE"
+	LedgerEntryPenalty         LedgerEntryType = "PENALTY"
+	LedgerEntryPaymentReversal LedgerEntryType = "PAYMENT_REVERSAL"
+
+	// Credits, decrease what the customer owes
+	LedgerEntryPayment  LedgerEntryType = "PAYMENT"
+	LedgerEntryRefund   LedgerEntryType = "REFUND"
+	LedgerEntryWriteOff LedgerEntryType = "WRITE_OFF"
+
+	// Debit or credit depending on its sign, e.g. true-up of estimated usage
+	LedgerEntryAdjustment LedgerEntryType = "ADJUSTMENT"
+
+	// Invoice adjustment documents
+	LedgerEntryCreditNote LedgerEntryType = "CREDIT_NOTE"
+	LedgerEntryDebitNote  LedgerEntryType = "DEBIT_NOTE"
+	LedgerEntryVoid       LedgerEntryType = "VOID"
+)
+
+// CustomerLedgerEntry is one debit or credit on a customer's account. The running balance of
+// the entries is what the customer owes; a negative balance is credit in the customer's favour.
+type CustomerLedgerEntry struct {
+	BaseModel
+
+	TenantID   uuid.UUID       `gorm:"type:char(36);not null;index:idx_ledger_customer,priority:1" json:"tenant_id"`
+	CustomerID uuid.UUID       `gorm:"type:char(36);not null;index:idx_ledger_customer,priority:2" json:"customer_id"`
+	EntryDate  time.Time       `gorm:"not null;index:idx_ledger_customer,priority:3" json:"entry_date"`
+	Type       LedgerEntryType `gorm:"type:varchar(20);not null" json:"type"`
+	Debit      float64         `gorm:"default:0" json:"debit"`
+	Credit     float64         `gorm:"default:0" json:"credit"`
+
+	InvoiceID *uuid.UUID `gorm:"type:char(36);index" json:"invoice_id,omitempty"`
+	PaymentID *uuid.UUID `gorm:"type:char(36);index" json:"payment_id,omitempty"`
+
+	Description string     `gorm:"type:varchar(255)" json:"description"`
+	CreatedBy   *uuid.UUID `gorm:"type:char(36)" json:"created_by,omitempty"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Order in which a customer payment pays the outstanding invoices
+const (
+	AllocationPenaltyFirst   = "PENALTY_FIRST"   // penalties of all invoices, oldest first, then their principal
+	AllocationPrincipalFirst = "PRINCIPAL_FIRST" // invoice by invoice, oldest first, principal before penalty
+)
+
+// CustomerPayment is money received from a customer at once and allocated across the customer's
+// outstanding invoices. Each allocation line has its own Payment on the invoice it pays.
+type CustomerPayment struct {
+	BaseModel
+
+	TenantID   uuid.UUID `gorm:"type:char(36);not null;index" json:"tenant_id"`
+	CustomerID uuid.UUID `gorm:"type:char(36);not null;index" json:"customer_id"`
+	Customer   Customer  `gorm:"foreignKey:CustomerID;references:ID" json:"customer,omitempty"`
+
+	Amount          float64   `gorm:"not null" json:"amount"`            // amount received
+	AllocatedAmount float64   `gorm:"default:0" json:"allocated_amount"` // paid to invoices
+	CreditAmount    float64   `gorm:"default:0" json:"credit_amount"`    // left over, added to the customer's credit balance
+	AllocationOrder string    `gorm:"type:varchar(20)" json:"allocation_order"`
+	PaidAt          time.Time `gorm:"not null" json:"paid_at"`
+
+	PaymentMethodID *uuid.UUID     `gorm:"type:char(36);index" json:"payment_method_id"`
+	PaymentMethod   *PaymentMethod `gorm:"foreignKey:PaymentMethodID" json:"payment_method,omitempty"`
+	ReceivedBy      *uuid.UUID     `gorm:"type:char(36)" json:"received_by"`
+	Receiver        *User          `gorm:"foreignKey:ReceivedBy" json:"receiver,omitempty"`
+	ReferenceNumber string         `gorm:"type:varchar(100)" json:"reference_number"`
+	Notes           string         `gorm:"type:text" json:"notes"`
+
+	Allocations []PaymentAllocation `gorm:"foreignKey:CustomerPaymentID" json:"allocations,omitempty"`
+}
+
+func (p *CustomerPayment) BeforeCreate(tx *gorm.DB) (err error) {
+	if err = p.BaseModel.BeforeCreate(tx); err != nil {
+		return
+	}
+	if p.PaidAt.IsZero() {
+		p.PaidAt = time.Now()
+	}
+	return
+}
+
+// PaymentAllocation is the part of a customer payment that went to one invoice
+type PaymentAllocation struct {
+	BaseModel
+
+	TenantID          uuid.UUID `gorm:"type:char(36);not null;index" json:"tenant_id"`
+	CustomerPaymentID uuid.UUID `gorm:"type:char(36);not null;index" json:"customer_payment_id"`
+	PaymentID         uuid.UUID `gorm:"type:char(36);not null;index" json:"payment_id"`
+	InvoiceID         uuid.UUID `gorm:"type:char(36);not null;index" json:"invoice_id"`
+	Invoice           *Invoice  `gorm:"foreignKey:InvoiceID" json:"invoice,omitempty"`
+
+	PenaltyAmount   float64 `gorm:"default:0" json:"penalty_amount"`
+	PrincipalAmount float64 `gorm:"default:0" json:"principal_amount"`
+	Amount          float64 `gorm:"not null" json:"amount"`
+	Sequence        int     `gorm:"default:0" json:"sequence"` // allocation order within the customer payment
+}
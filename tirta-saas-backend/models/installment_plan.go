@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Installment plan statuses
+const (
+	InstallmentPlanActive    = "ACTIVE"    // current, penalties of its invoices are suspended
+	InstallmentPlanCompleted = "COMPLETED" // all consolidated arrears paid
+	InstallmentPlanDefaulted = "DEFAULTED" // too many installments missed, penalties resume
+	InstallmentPlanCancelled = "CANCELLED"
+)
+
+// Installment statuses
+const (
+	InstallmentScheduled = "SCHEDULED"
+	InstallmentPaid      = "PAID"
+	InstallmentMissed    = "MISSED" // unpaid past its due date and the grace period
+)
+
+// InstallmentPlan consolidates overdue invoices of a customer into a repayment schedule. Payments
+// are still posted to the invoices; the plan counts what was paid on them since it was agreed and
+// covers the installments in order.
+type InstallmentPlan struct {
+	BaseModel
+	TenantID   uuid.UUID `gorm:"type:char(36);not null;index" json:"tenant_id"`
+	CustomerID uuid.UUID `gorm:"type:char(36);not null;index" json:"customer_id"`
+	Customer   *Customer `gorm:"foreignKey:CustomerID" json:"customer,omitempty"`
+	Status     string    `gorm:"type:varchar(20);not null;index" json:"status"`
+
+	TotalAmount      float64 `gorm:"not null" json:"total_amount"` // arrears consolidated when the plan was agreed
+	PaidAmount       float64 `gorm:"default:0" json:"paid_amount"`
+	InstallmentCount int     `gorm:"not null" json:"installment_count"`
+	// Missed installments after which the plan defaults
+	MaxMissed   int `gorm:"default:2" json:"max_missed"`
+	MissedCount int `gorm:"default:0" json:"missed_count"`
+
+	Notes     string     `gorm:"type:text" json:"notes"`
+	CreatedBy *uuid.UUID `gorm:"type:char(36)" json:"created_by,omitempty"`
+	// When the plan completed, defaulted or was cancelled
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	EndReason string     `gorm:"type:varchar(255)" json:"end_reason,omitempty"`
+
+	Invoices     []InstallmentPlanInvoice `gorm:"foreignKey:PlanID" json:"invoices,omitempty"`
+	Installments []Installment            `gorm:"foreignKey:PlanID" json:"installments,omitempty"`
+}
+
+// InstallmentPlanInvoice is an overdue invoice consolidated into a plan
+type InstallmentPlanInvoice struct {
+	BaseModel
+	PlanID    uuid.UUID `gorm:"type:char(36);not null;index" json:"plan_id"`
+	InvoiceID uuid.UUID `gorm:"type:char(36);not null;index" json:"invoice_id"`
+	Invoice   *Invoice  `gorm:"foreignKey:InvoiceID" json:"invoice,omitempty"`
+	Amount    float64   `gorm:"not null" json:"amount"` // outstanding when the plan was agreed
+}
+
+// Installment is one scheduled repayment of a plan
+type Installment struct {
+	BaseModel
+	PlanID     uuid.UUID  `gorm:"type:char(36);not null;index" json:"plan_id"`
+	Sequence   int        `gorm:"not null" json:"sequence"`
+	DueDate    time.Time  `gorm:"type:date;not null" json:"due_date"`
+	Amount     float64    `gorm:"not null" json:"amount"`
+	PaidAmount float64    `gorm:"default:0" json:"paid_amount"`
+	Status     string     `gorm:"type:varchar(20);not null" json:"status"`
+	PaidAt     *time.Time `json:"paid_at,omitempty"`
+	// First time the installment was found missed; kept when it is paid late
+	MissedAt *time.Time `json:"missed_at,omitempty"`
+}
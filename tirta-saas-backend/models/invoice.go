@@ -14,34 +14,53 @@ const (
 	PaymentStatusPartial PaymentStatus = "PARTIAL"
 	PaymentStatusPaid    PaymentStatus = "PAID"
 	PaymentStatusOverdue PaymentStatus = "OVERDUE"
+	PaymentStatusVoid    PaymentStatus = "VOID"
+)
+
+// InvoiceStatus is the document state of an invoice. Issued invoices are never edited; their
+// amount only changes through credit and debit notes, and a void keeps the number in use.
+type InvoiceStatus string
+
+const (
+	InvoiceStatusIssued InvoiceStatus = "ISSUED"
+	InvoiceStatusVoid   InvoiceStatus = "VOID"
 )
 
 type Invoice struct {
 	BaseModel
 
 	// Invoice Identity
-	InvoiceNumber string    `gorm:"type:varchar(50);unique;index" json:"invoice_number"` // INV-YYYYMM-XXXX
+	InvoiceNumber string    `gorm:"type:varchar(50);uniqueIndex:idx_tenant_invoice_number,priority:2" json:"invoice_number"` // tenant's InvoiceNumberFormat, unique per tenant
 	
 	// Customer & Tenant
 	CustomerID  uuid.UUID `gorm:"type:char(36);not null" json:"customer_id"`
 	Customer    Customer  `gorm:"foreignKey:CustomerID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"customer"`
-	TenantID    uuid.UUID `gorm:"type:char(36);index" json:"tenant_id"`
+	TenantID    uuid.UUID `gorm:"type:char(36);index;uniqueIndex:idx_tenant_invoice_number,priority:1" json:"tenant_id"`
 	
 	// Usage Details
 	UsageMonth  string    `gorm:"type:varchar(7);index" json:"usage_month"` // YYYY-MM
 	UsageM3     float64   `json:"usage_m3"`
 	PricePerM3  float64   `json:"price_per_m3"`
 	
-	// Charges
-	Abonemen      float64 `json:"abonemen"`       // Monthly subscription fee
-	WaterCharge   float64 `json:"water_charge"`   // Water usage charge
-	PenaltyAmount float64 `gorm:"default:0" json:"penalty_amount"` // Late payment penalty
+	// Charges, filled from the invoice lines
+	Abonemen         float64 `json:"abonemen"`                           // Monthly subscription fee
+	WaterCharge      float64 `json:"water_charge"`                       // Water usage charge
+	PenaltyAmount    float64 `gorm:"default:0" json:"penalty_amount"`    // Late payment penalty
+	AdjustmentAmount float64 `gorm:"default:0" json:"adjustment_amount"` // True-up of previously estimated usage, may be negative
+	
+	// Tariff tier breakdown of WaterCharge (JSON array), kept for audit
+	TariffBreakdown string `gorm:"type:text" json:"tariff_breakdown,omitempty"`
 	
-	// Totals
-	SubTotal    float64 `json:"sub_total"`    // Before penalty
-	TotalAmount float64 `json:"total_amount"` // After penalty
+	// Totals, the sums of the invoice lines
+	SubTotal    float64 `json:"sub_total"`                   // Before tax and penalty
+	TaxAmount   float64 `gorm:"default:0" json:"tax_amount"` // Tax on the charges
+	TotalAmount float64 `json:"total_amount"`                // After tax, penalty, credit and debit notes
 	TotalPaid   float64 `gorm:"default:0" json:"total_paid"`
 	
+	// Credit and debit notes issued against the invoice, a void credits the full amount
+	CreditNoteTotal float64 `gorm:"default:0" json:"credit_note_total"`
+	DebitNoteTotal  float64 `gorm:"default:0" json:"debit_note_total"`
+	
 	// Payment Status
 	PaymentStatus PaymentStatus `gorm:"type:varchar(20);default:'UNPAID';index" json:"payment_status"`
 	IsPaid        bool          `gorm:"default:false" json:"is_paid"` // For backward compatibility
@@ -53,4 +72,18 @@ type Invoice struct {
 	// Type & Notes
 	Type  string `gorm:"type:enum('registration','monthly');not null" json:"type"`
 	Notes string `gorm:"type:text" json:"notes"`
+	
+	// Document state
+	Status              InvoiceStatus `gorm:"type:varchar(10);default:'ISSUED';index" json:"status"`
+	VoidedAt            *time.Time    `json:"voided_at,omitempty"`
+	VoidedBy            *uuid.UUID    `gorm:"type:char(36)" json:"voided_by,omitempty"`
+	VoidReason          string        `gorm:"type:varchar(255)" json:"void_reason,omitempty"`
+	ReplacesInvoiceID   *uuid.UUID    `gorm:"type:char(36);index" json:"replaces_invoice_id,omitempty"`    // rebilled from this voided invoice
+	ReplacedByInvoiceID *uuid.UUID    `gorm:"type:char(36)" json:"replaced_by_invoice_id,omitempty"` // voided and rebilled as this invoice
+	
+	// Installment plan the invoice's arrears were consolidated into
+	InstallmentPlanID *uuid.UUID `gorm:"type:char(36);index" json:"installment_plan_id,omitempty"`
+
+	// Charges billed, in order
+	Lines []InvoiceLine `gorm:"foreignKey:InvoiceID" json:"lines,omitempty"`
 }
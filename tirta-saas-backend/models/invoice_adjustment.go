@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InvoiceAdjustmentType is the kind of document that changes an issued invoice
+type InvoiceAdjustmentType string
+
+const (
+	InvoiceAdjustmentCreditNote InvoiceAdjustmentType = "CREDIT_NOTE" // lowers the amount due
+	InvoiceAdjustmentDebitNote  InvoiceAdjustmentType = "DEBIT_NOTE"  // raises the amount due
+	InvoiceAdjustmentVoid       InvoiceAdjustmentType = "VOID"        // cancels the invoice, possibly rebilled
+)
+
+// InvoiceAdjustment is a credit note, debit note or void issued against an invoice. Together with
+// the invoice it explains every change of the amount billed.
+type InvoiceAdjustment struct {
+	BaseModel
+	TenantID   uuid.UUID             `gorm:"type:char(36);not null;uniqueIndex:idx_tenant_adjustment_number" json:"tenant_id"`
+	InvoiceID  uuid.UUID             `gorm:"type:char(36);not null;index" json:"invoice_id"`
+	CustomerID uuid.UUID             `gorm:"type:char(36);not null;index" json:"customer_id"`
+	Type       InvoiceAdjustmentType `gorm:"type:varchar(20);not null" json:"type"`
+	Number     string                `gorm:"type:varchar(50);not null;uniqueIndex:idx_tenant_adjustment_number" json:"number"` // CN-, DN- or VD-YYYYMM-XXXX
+	Amount     float64               `gorm:"not null" json:"amount"`
+	Reason     string                `gorm:"type:varchar(255);not null" json:"reason"`
+	IssuedAt   time.Time             `gorm:"not null" json:"issued_at"`
+	CreatedBy  *uuid.UUID            `gorm:"type:char(36)" json:"created_by,omitempty"`
+
+	// New invoice issued from the corrected water usage when a void was a rebill
+	ReplacementInvoiceID *uuid.UUID `gorm:"type:char(36)" json:"replacement_invoice_id,omitempty"`
+}
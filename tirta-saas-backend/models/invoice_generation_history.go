@@ -27,4 +27,5 @@ type InvoiceGenerationHistory struct {
 	ExecutionTimeMs int    `gorm:"default:0" json:"execution_time_ms"`
 	TriggerType     string `gorm:"type:varchar(20)" json:"trigger_type"` // scheduled, manual
 	TriggeredBy     *uuid.UUID `gorm:"type:char(36)" json:"triggered_by,omitempty"` // User ID if manual
+	JobID           *uuid.UUID `gorm:"type:char(36);index" json:"job_id,omitempty"` // InvoiceGenerationJob of the run
 }
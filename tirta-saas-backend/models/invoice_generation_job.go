@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invoice generation job statuses
+const (
+	GenerationJobQueued    = "QUEUED"
+	GenerationJobRunning   = "RUNNING"
+	GenerationJobCompleted = "COMPLETED"
+	GenerationJobFailed    = "FAILED" // the job could not run, e.g. no usage recorded for the month; can be retried
+)
+
+// Invoice generation job item statuses
+const (
+	GenerationItemPending   = "PENDING"
+	GenerationItemGenerated = "GENERATED"
+	GenerationItemSkipped   = "SKIPPED" // already invoiced or waiting for anomaly review
+	GenerationItemFailed    = "FAILED"
+)
+
+// InvoiceGenerationJob is one monthly invoice run of a tenant, processed in the background by the
+// generation workers. Every water usage record to bill is an item, so a job interrupted by a
+// restart continues with the items still pending.
+type InvoiceGenerationJob struct {
+	BaseModel
+	TenantID        uuid.UUID  `gorm:"type:char(36);not null;index:idx_generation_job_tenant" json:"tenant_id"`
+	UsageMonth      string     `gorm:"type:varchar(7);not null;index:idx_generation_job_tenant" json:"usage_month"` // YYYY-MM
+	CustomerIDs     string     `gorm:"type:text" json:"-"`                                                          // JSON array, empty for all customers
+	EstimateMissing bool       `gorm:"default:false" json:"estimate_missing"`
+	TriggerType     string     `gorm:"type:varchar(20);not null" json:"trigger_type"` // scheduled, manual
+	TriggeredBy     *uuid.UUID `gorm:"type:char(36)" json:"triggered_by,omitempty"`
+
+	Status       string `gorm:"type:varchar(20);not null;index" json:"status"`
+	Prepared     bool   `gorm:"default:false" json:"-"` // items created
+	ErrorMessage string `gorm:"type:text" json:"error_message,omitempty"`
+
+	TotalItems     int     `gorm:"default:0" json:"total_items"`
+	ProcessedItems int     `gorm:"default:0" json:"processed_items"`
+	SuccessCount   int     `gorm:"default:0" json:"success_count"`
+	SkippedCount   int     `gorm:"default:0" json:"skipped_count"`
+	FailedCount    int     `gorm:"default:0" json:"failed_count"`
+	EstimatedCount int     `gorm:"default:0" json:"estimated_count"`
+	TotalAmount    float64 `gorm:"type:decimal(15,2);default:0" json:"total_amount"`
+
+	WorkerID    string     `gorm:"type:varchar(100)" json:"-"`
+	HeartbeatAt *time.Time `json:"heartbeat_at,omitempty"` // a running job without heartbeat is taken over by another worker
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	HistoryID   *uuid.UUID `gorm:"type:char(36)" json:"history_id,omitempty"` // InvoiceGenerationHistory written when the job ended
+}
+
+// InvoiceGenerationJobItem is the outcome for one customer of a generation job
+type InvoiceGenerationJobItem struct {
+	BaseModel
+	JobID       uuid.UUID  `gorm:"type:char(36);not null;uniqueIndex:idx_generation_item_usage;uniqueIndex:idx_generation_item_customer;index:idx_generation_item_status" json:"job_id"`
+	TenantID    uuid.UUID  `gorm:"type:char(36);not null;index" json:"tenant_id"`
+	CustomerID  uuid.UUID  `gorm:"type:char(36);not null;uniqueIndex:idx_generation_item_customer" json:"customer_id"`
+	UsageID     *uuid.UUID `gorm:"type:char(36);uniqueIndex:idx_generation_item_usage" json:"usage_id,omitempty"` // nil when the usage could not be estimated
+	Status      string     `gorm:"type:varchar(20);not null;index:idx_generation_item_status" json:"status"`
+	InvoiceID   *uuid.UUID `gorm:"type:char(36)" json:"invoice_id,omitempty"`
+	Amount      float64    `gorm:"type:decimal(15,2);default:0" json:"amount"`
+	Message     string     `gorm:"type:text" json:"message,omitempty"`
+	ProcessedAt *time.Time `json:"processed_at,omitempty"`
+}
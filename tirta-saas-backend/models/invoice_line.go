@@ -0,0 +1,46 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// InvoiceLineType is what an invoice line charges for
+type InvoiceLineType string
+
+const (
+	InvoiceLineWater        InvoiceLineType = "WATER" // one line per tariff block
+	InvoiceLineAbonemen     InvoiceLineType = "ABONEMEN"
+	InvoiceLineMaintenance  InvoiceLineType = "MAINTENANCE"
+	InvoiceLineRegistration InvoiceLineType = "REGISTRATION"
+	InvoiceLineMeterRental  InvoiceLineType = "METER_RENTAL"
+	InvoiceLineService      InvoiceLineType = "SERVICE" // one-off service charge
+	InvoiceLineTrueUp       InvoiceLineType = "TRUE_UP" // correction of earlier estimated usage, may be negative
+	InvoiceLinePenalty      InvoiceLineType = "PENALTY"
+	InvoiceLineCreditNote   InvoiceLineType = "CREDIT_NOTE" // negative
+	InvoiceLineDebitNote    InvoiceLineType = "DEBIT_NOTE"
+	InvoiceLineVoid         InvoiceLineType = "VOID"  // negative, cancels the remaining amount
+	InvoiceLineOther        InvoiceLineType = "OTHER" // difference on invoices issued before lines existed
+)
+
+// InvoiceLine is one charge of an invoice. The invoice's totals are the sums of its lines; the
+// fixed charge columns of Invoice are kept filled from them for older clients. Credit notes,
+// debit notes and voids add lines, so the lines always explain the amount billed.
+type InvoiceLine struct {
+	BaseModel
+	TenantID  uuid.UUID `gorm:"type:char(36);not null;index" json:"tenant_id"`
+	InvoiceID uuid.UUID `gorm:"type:char(36);not null;index:idx_invoice_line_position" json:"invoice_id"`
+	Position  int       `gorm:"not null;default:0;index:idx_invoice_line_position" json:"position"`
+
+	Type        InvoiceLineType `gorm:"type:varchar(20);not null" json:"type"`
+	Description string          `gorm:"type:varchar(255)" json:"description"`
+	Quantity    float64         `gorm:"type:decimal(12,3);default:1" json:"quantity"`
+	Unit        string          `gorm:"type:varchar(10)" json:"unit,omitempty"` // m3, bulan
+	UnitPrice   float64         `gorm:"type:decimal(15,2);default:0" json:"unit_price"`
+	Amount      float64         `gorm:"type:decimal(15,2);default:0" json:"amount"` // before tax
+	TaxPercent  float64         `gorm:"type:decimal(5,2);default:0" json:"tax_percent"`
+	TaxAmount   float64         `gorm:"type:decimal(15,2);default:0" json:"tax_amount"`
+	Total       float64         `gorm:"type:decimal(15,2);default:0" json:"total"` // amount plus tax
+
+	// Credit note, debit note or void that added the line
+	AdjustmentID *uuid.UUID `gorm:"type:char(36);index" json:"adjustment_id,omitempty"`
+}
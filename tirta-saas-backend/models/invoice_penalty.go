@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Late penalty policies of a tenant
+const (
+	PenaltyPolicyPercent = "PERCENT" // percentage of the unpaid principal, once per invoice
+	PenaltyPolicyFixed   = "FIXED"   // fixed amount, once per invoice
+	PenaltyPolicyPerDay  = "PER_DAY" // subscription type's LateFeePerDay for every day overdue
+)
+
+// InvoicePenalty is one late penalty charged for an overdue invoice over a period of days. It is
+// billed on a later invoice of the customer, whose PenaltyAmount is the sum of its lines. A period
+// starts only once per overdue invoice, so the same days are never charged twice.
+type InvoicePenalty struct {
+	BaseModel
+	TenantID   uuid.UUID `gorm:"type:char(36);not null;index" json:"tenant_id"`
+	CustomerID uuid.UUID `gorm:"type:char(36);not null;index" json:"customer_id"`
+	InvoiceID  uuid.UUID `gorm:"type:char(36);not null;uniqueIndex:idx_invoice_penalty_period" json:"invoice_id"` // overdue invoice
+	// Invoice the penalty is billed on
+	ChargedInvoiceID *uuid.UUID `gorm:"type:char(36);index" json:"charged_invoice_id,omitempty"`
+
+	Policy      string    `gorm:"type:varchar(10);not null" json:"policy"`
+	PeriodStart time.Time `gorm:"type:date;not null;uniqueIndex:idx_invoice_penalty_period" json:"period_start"`
+	PeriodEnd   time.Time `gorm:"type:date;not null" json:"period_end"`
+	Days        int       `gorm:"not null" json:"days"`
+	Base        float64   `gorm:"default:0" json:"base"` // unpaid principal the penalty was computed on
+	Rate        float64   `gorm:"default:0" json:"rate"` // percent, fixed amount or fee per day
+	Amount      float64   `gorm:"not null" json:"amount"`
+	Capped      bool      `gorm:"default:false" json:"capped"` // reduced to stay within the invoice's cap
+}
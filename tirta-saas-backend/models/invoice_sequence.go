@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InvoiceSequence is the last invoice or adjustment number issued by a tenant in a numbering
+// period. The row is locked while the document is created, so numbers are issued in order across
+// instances, and it is rolled back together with a document that fails to save, so the sequence
+// has no gaps.
+type InvoiceSequence struct {
+	TenantID uuid.UUID `gorm:"type:char(36);primaryKey" json:"tenant_id"`
+	// Invoices: YYYY-MM, YYYY or ALL, after the tokens of the number format. Adjustments: their
+	// number prefix, e.g. CN-202501.
+	Period     string    `gorm:"type:varchar(20);primaryKey" json:"period"`
+	LastNumber int       `gorm:"not null;default:0" json:"last_number"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -30,23 +30,31 @@ type Meter struct {
 
 type MeterIssue struct {
 	BaseModel
-	TenantID    uuid.UUID  `gorm:"type:char(36);not null;index:idx_tenant_meter_issue" json:"tenant_id"`
-	MeterID     uuid.UUID  `gorm:"type:char(36);not null;index:idx_meter_issue" json:"meter_id"`
-	ReportedBy  uuid.UUID  `gorm:"type:char(36);not null" json:"reported_by"`
-	IssueType   string     `gorm:"type:varchar(50);not null" json:"issue_type"` // broken, leak, stuck, incorrect
-	Description string     `gorm:"type:text;not null" json:"description"`
-	Status      string     `gorm:"type:varchar(20);default:'open';not null" json:"status"`
-	Priority    string     `gorm:"type:varchar(20);default:'normal';not null" json:"priority"`
-	ResolvedBy  *uuid.UUID `gorm:"type:char(36)" json:"resolved_by"`
-	ResolvedAt  *time.Time `gorm:"type:datetime" json:"resolved_at"`
-	Resolution  string     `gorm:"type:text" json:"resolution"`
-	PhotoURL    string     `gorm:"type:varchar(500)" json:"photo_url"`
+	TenantID             uuid.UUID  `gorm:"type:char(36);not null;index:idx_tenant_meter_issue" json:"tenant_id"`
+	MeterID              uuid.UUID  `gorm:"type:char(36);not null;index:idx_meter_issue" json:"meter_id"`
+	ReportedBy           *uuid.UUID `gorm:"type:char(36)" json:"reported_by"`                              // staff reporter
+	ReportedByCustomerID *uuid.UUID `gorm:"type:char(36);index" json:"reported_by_customer_id"`            // customer reporter
+	ReporterType         string     `gorm:"type:varchar(20);default:'user';not null" json:"reporter_type"` // user, customer
+	IssueType            string     `gorm:"type:varchar(50);not null" json:"issue_type"`                   // broken, leak, stuck, incorrect
+	Description          string     `gorm:"type:text;not null" json:"description"`
+	Status               string     `gorm:"type:varchar(20);default:'open';not null" json:"status"`
+	Priority             string     `gorm:"type:varchar(20);default:'normal';not null" json:"priority"`
+	AssignedTo           *uuid.UUID `gorm:"type:char(36);index" json:"assigned_to"`
+	AssignedAt           *time.Time `gorm:"type:datetime" json:"assigned_at"`
+	StartedAt            *time.Time `gorm:"type:datetime" json:"started_at"`
+	ResolvedBy           *uuid.UUID `gorm:"type:char(36)" json:"resolved_by"`
+	ResolvedAt           *time.Time `gorm:"type:datetime" json:"resolved_at"`
+	Resolution           string     `gorm:"type:text" json:"resolution"`
+	ClosedAt             *time.Time `gorm:"type:datetime" json:"closed_at"`
+	PhotoURL             string     `gorm:"type:varchar(500)" json:"photo_url"`
 
 	// Relationships
-	Tenant   Tenant   `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
-	Meter    Meter    `gorm:"foreignKey:MeterID;constraint:OnDelete:CASCADE" json:"meter"`
-	Reporter User     `gorm:"foreignKey:ReportedBy" json:"reporter"`
-	Resolver *User    `gorm:"foreignKey:ResolvedBy" json:"resolver,omitempty"`
+	Tenant            Tenant    `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Meter             Meter     `gorm:"foreignKey:MeterID;constraint:OnDelete:CASCADE" json:"meter"`
+	Reporter          *User     `gorm:"foreignKey:ReportedBy" json:"reporter,omitempty"`
+	ReportingCustomer *Customer `gorm:"foreignKey:ReportedByCustomerID" json:"reporting_customer,omitempty"`
+	Assignee          *User     `gorm:"foreignKey:AssignedTo" json:"assignee,omitempty"`
+	Resolver          *User     `gorm:"foreignKey:ResolvedBy" json:"resolver,omitempty"`
 }
 
 type MeterHistory struct {
@@ -54,7 +62,7 @@ type MeterHistory struct {
 	TenantID    uuid.UUID `gorm:"type:char(36);not null;index:idx_tenant_meter_history" json:"tenant_id"`
 	MeterID     uuid.UUID `gorm:"type:char(36);not null;index:idx_meter_history" json:"meter_id"`
 	CustomerID  uuid.UUID `gorm:"type:char(36);not null" json:"customer_id"`
-	Action      string    `gorm:"type:varchar(50);not null" json:"action"` // install, replace, remove, calibrate
+	Action      string    `gorm:"type:varchar(50);not null" json:"action"` // install, update, replace, calibrate, schedule_calibration, retire
 	OldValue    string    `gorm:"type:text" json:"old_value"`
 	NewValue    string    `gorm:"type:text" json:"new_value"`
 	PerformedBy uuid.UUID `gorm:"type:char(36);not null" json:"performed_by"`
@@ -75,6 +83,16 @@ const (
 	MeterStatusReplaced  = "replaced"
 )
 
+// Meter history actions
+const (
+	MeterActionInstall             = "install"
+	MeterActionUpdate              = "update"
+	MeterActionReplace             = "replace"
+	MeterActionCalibrate           = "calibrate"
+	MeterActionScheduleCalibration = "schedule_calibration"
+	MeterActionRetire              = "retire"
+)
+
 // Meter issue types
 const (
 	MeterIssueBroken    = "broken"
@@ -92,6 +110,12 @@ const (
 	MeterIssueStatusClosed     = "closed"
 )
 
+// Meter issue reporter types
+const (
+	MeterIssueReporterUser     = "user"
+	MeterIssueReporterCustomer = "customer"
+)
+
 // Meter issue priority
 const (
 	MeterIssuePriorityLow      = "low"
@@ -16,13 +16,31 @@ const (
 	ChannelWhatsApp NotificationChannel = "WHATSAPP"
 )
 
+// Notification log statuses
+const (
+	NotificationStatusPending   = "PENDING"
+	NotificationStatusSending   = "SENDING" // claimed by a dispatcher worker
+	NotificationStatusSent      = "SENT"
+	NotificationStatusDelivered = "DELIVERED"
+	NotificationStatusFailed    = "FAILED"
+)
+
+// Well-known template codes used by the billing notifications
+const (
+	NotificationCodeInvoiceIssued   = "INVOICE_ISSUED"
+	NotificationCodePaymentReminder = "PAYMENT_REMINDER"
+	NotificationCodeInvoiceOverdue  = "INVOICE_OVERDUE"
+	NotificationCodePaymentReceived = "PAYMENT_RECEIVED"
+	NotificationCodePaymentRejected = "PAYMENT_REJECTED"
+)
+
 // NotificationTemplate represents a reusable notification template
 type NotificationTemplate struct {
 	BaseModel
-	TenantID uuid.UUID `gorm:"type:char(36);not null;index" json:"tenant_id"`
+	TenantID uuid.UUID `gorm:"type:char(36);not null;uniqueIndex:idx_tenant_template_code" json:"tenant_id"`
 	
 	// Template Details
-	Code        string              `gorm:"type:varchar(50);not null;uniqueIndex:idx_tenant_code" json:"code"`
+	Code        string              `gorm:"type:varchar(50);not null;uniqueIndex:idx_tenant_template_code" json:"code"`
 	Name        string              `gorm:"type:varchar(100);not null" json:"name"`
 	Description string              `gorm:"type:text" json:"description"`
 	Channel     NotificationChannel `gorm:"type:varchar(20);not null" json:"channel"`
@@ -59,11 +77,12 @@ type NotificationLog struct {
 	Destination string              `gorm:"type:varchar(255);not null" json:"destination"` // Email address, phone number, etc.
 	
 	// Content
-	Subject string `gorm:"type:varchar(200)" json:"subject"`
-	Body    string `gorm:"type:longtext;not null" json:"body"`
+	Subject  string `gorm:"type:varchar(200)" json:"subject"`
+	Body     string `gorm:"type:longtext;not null" json:"body"`
+	HTMLBody string `gorm:"type:longtext" json:"html_body,omitempty"` // For email
 	
 	// Status
-	Status       string     `gorm:"type:varchar(20);not null;default:'PENDING';index" json:"status"` // PENDING, SENT, FAILED, DELIVERED
+	Status       string     `gorm:"type:varchar(20);not null;default:'PENDING';index" json:"status"` // PENDING, SENDING, SENT, FAILED, DELIVERED
 	SentAt       *time.Time `json:"sent_at,omitempty"`
 	DeliveredAt  *time.Time `json:"delivered_at,omitempty"`
 	FailedAt     *time.Time `json:"failed_at,omitempty"`
@@ -77,6 +96,10 @@ type NotificationLog struct {
 	Provider       string `gorm:"type:varchar(50)" json:"provider,omitempty"`
 	ProviderMsgID  string `gorm:"type:varchar(255)" json:"provider_msg_id,omitempty"`
 	
+	// Event Source (set for automatic notifications, one per event and reference)
+	EventCode   string     `gorm:"type:varchar(50);index:idx_notification_event" json:"event_code,omitempty"`
+	ReferenceID *uuid.UUID `gorm:"type:char(36);index:idx_notification_event" json:"reference_id,omitempty"` // invoice or payment
+	
 	// Metadata (JSON for additional info)
 	Metadata string `gorm:"type:json" json:"metadata,omitempty"`
 	
@@ -8,12 +8,20 @@ import (
 	"gorm.io/gorm"
 )
 
+// Payment record statuses; only completed payments count towards an invoice. Transfers uploaded
+// by customers stay pending until finance approves or rejects the proof.
+const (
+	PaymentRecordCompleted = "completed"
+	PaymentRecordPending   = "pending"
+	PaymentRecordRejected  = "rejected"
+)
+
 type Payment struct {
 	TenantID  uuid.UUID `gorm:"type:char(36);not null;index" json:"tenant_id"`
 	InvoiceID uuid.UUID `gorm:"type:char(36);not null;index" json:"invoice_id"`
 	Invoice   Invoice   `gorm:"foreignKey:InvoiceID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"invoice"`
 	Amount    float64   `gorm:"not null" json:"amount"`
-	Penalty   float64   `gorm:"default:0" json:"penalty"`
+	Penalty   float64   `gorm:"default:0" json:"penalty"` // part of Amount that paid the invoice's penalty
 	PaidAt    time.Time `gorm:"not null" json:"paid_at"`
 	
 	// Additional fields for Phase 6
@@ -27,6 +35,14 @@ type Payment struct {
 	VerifiedBy      *uuid.UUID     `gorm:"type:char(36)" json:"verified_by"`
 	VerifiedAt      *time.Time     `gorm:"type:datetime" json:"verified_at"`
 	Status          string         `gorm:"type:varchar(20);default:'completed';not null" json:"status"`
+	RejectionReason string         `gorm:"type:varchar(255)" json:"rejection_reason,omitempty"`
+
+	// Part of the amount received that exceeded the invoice and went to the customer's credit
+	CreditAmount float64 `gorm:"default:0" json:"credit_amount"`
+	// Paid from the customer's credit balance instead of money received
+	FromCredit bool `gorm:"default:false" json:"from_credit"`
+	// Allocation of a customer payment that paid several invoices at once
+	CustomerPaymentID *uuid.UUID `gorm:"type:char(36);index" json:"customer_payment_id,omitempty"`
 
 	BaseModel
 }
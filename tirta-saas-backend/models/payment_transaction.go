@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentTransactionStatus is the state of an online payment at the gateway
+type PaymentTransactionStatus string
+
+const (
+	PaymentTransactionPending PaymentTransactionStatus = "PENDING"
+	PaymentTransactionPaid    PaymentTransactionStatus = "PAID"
+	PaymentTransactionExpired PaymentTransactionStatus = "EXPIRED"
+	PaymentTransactionFailed  PaymentTransactionStatus = "FAILED"
+	// The gateway settled a different amount than requested; nothing is posted until finance
+	// checks the payment with the provider and records it manually
+	PaymentTransactionReview PaymentTransactionStatus = "REVIEW"
+)
+
+// Online payment channels offered through the gateway
+const (
+	GatewayChannelVirtualAccount = "VIRTUAL_ACCOUNT"
+	GatewayChannelQRIS           = "QRIS"
+	GatewayChannelEWallet        = "E_WALLET"
+	GatewayChannelCard           = "CARD"
+)
+
+// PaymentTransaction is an online payment of an invoice through a payment gateway. The Payment is
+// only posted once the gateway confirms settlement.
+type PaymentTransaction struct {
+	BaseModel
+
+	TenantID   uuid.UUID `gorm:"type:char(36);not null;index" json:"tenant_id"`
+	CustomerID uuid.UUID `gorm:"type:char(36);not null;index" json:"customer_id"`
+	InvoiceID  uuid.UUID `gorm:"type:char(36);not null;index" json:"invoice_id"`
+	Amount     float64   `gorm:"not null" json:"amount"`
+
+	Provider          string                   `gorm:"type:varchar(30);not null;uniqueIndex:idx_provider_reference,priority:1" json:"provider"`
+	ProviderReference string                   `gorm:"type:varchar(100);not null;uniqueIndex:idx_provider_reference,priority:2" json:"provider_reference"`
+	Channel           string                   `gorm:"type:varchar(20);not null" json:"channel"`
+	Status            PaymentTransactionStatus `gorm:"type:varchar(20);not null;default:'PENDING';index" json:"status"`
+
+	// Payment instructions returned by the gateway, depending on the channel
+	VirtualAccountNumber string     `gorm:"type:varchar(50)" json:"virtual_account_number,omitempty"`
+	BankCode             string     `gorm:"type:varchar(20)" json:"bank_code,omitempty"`
+	QRISPayload          string     `gorm:"type:text" json:"qris_payload,omitempty"`
+	PaymentURL           string     `gorm:"type:varchar(500)" json:"payment_url,omitempty"`
+	ExpiresAt            *time.Time `json:"expires_at,omitempty"`
+
+	// Settlement
+	PaidAt        *time.Time `json:"paid_at,omitempty"`
+	PaymentID     *uuid.UUID `gorm:"type:char(36);index" json:"payment_id,omitempty"`
+	FailureReason string     `gorm:"type:varchar(255)" json:"failure_reason,omitempty"`
+	LastPayload   string     `gorm:"type:text" json:"-"` // last webhook or status response, kept for support
+}
@@ -31,6 +31,7 @@ type ReadingSession struct {
 	RouteID         uuid.UUID  `gorm:"type:char(36);not null;index:idx_route_session" json:"route_id"`
 	ReaderID        uuid.UUID  `gorm:"type:char(36);not null" json:"reader_id"`
 	ScheduledDate   time.Time  `gorm:"type:date;not null" json:"scheduled_date"`
+	UsageMonth      string     `gorm:"type:varchar(7);index" json:"usage_month"` // YYYY-MM the readings are recorded for
 	StartTime       *time.Time `gorm:"type:datetime" json:"start_time"`
 	EndTime         *time.Time `gorm:"type:datetime" json:"end_time"`
 	Status          string     `gorm:"type:varchar(20);default:'scheduled';not null" json:"status"`
@@ -50,7 +51,7 @@ type ReadingAnomaly struct {
 	BaseModel
 	TenantID       uuid.UUID  `gorm:"type:char(36);not null;index:idx_tenant_anomaly" json:"tenant_id"`
 	WaterUsageID   uuid.UUID  `gorm:"type:char(36);not null;index:idx_usage_anomaly" json:"water_usage_id"`
-	AnomalyType    string     `gorm:"type:varchar(50);not null" json:"anomaly_type"` // high_usage, low_usage, no_usage, negative
+	AnomalyType    string     `gorm:"type:varchar(50);not null" json:"anomaly_type"` // high_usage, low_usage, no_usage, negative, stuck
 	ExpectedValue  float64    `gorm:"type:decimal(10,2)" json:"expected_value"`
 	ActualValue    float64    `gorm:"type:decimal(10,2)" json:"actual_value"`
 	Deviation      float64    `gorm:"type:decimal(10,2)" json:"deviation"`
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// SchedulerLease is a named lock held by one instance of the backend until it expires. The holder
+// renews it while it is alive; another instance takes it over once it has expired.
+type SchedulerLease struct {
+	Name       string    `gorm:"type:varchar(100);primaryKey" json:"name"`
+	Holder     string    `gorm:"type:varchar(150);not null" json:"holder"` // instance ID
+	AcquiredAt time.Time `gorm:"type:datetime(3);not null" json:"acquired_at"`
+	RenewedAt  time.Time `gorm:"type:datetime(3);not null" json:"renewed_at"`
+	ExpiresAt  time.Time `gorm:"type:datetime(3);not null;index" json:"expires_at"`
+}
+
+// Scheduler job run statuses
+const (
+	JobRunRunning   = "RUNNING"
+	JobRunSucceeded = "SUCCEEDED"
+	JobRunFailed    = "FAILED"
+)
+
+// SchedulerJobRun records one execution of a scheduled job and the instance that ran it. The slot
+// is the scheduled minute; it is unique per job, so a tick runs once even while leadership changes.
+type SchedulerJobRun struct {
+	BaseModel
+	JobName      string     `gorm:"type:varchar(100);not null;index:idx_job_run_name;uniqueIndex:idx_job_run_slot" json:"job_name"`
+	Slot         time.Time  `gorm:"not null;uniqueIndex:idx_job_run_slot" json:"slot"`
+	Instance     string     `gorm:"type:varchar(150);not null;index" json:"instance"`
+	Status       string     `gorm:"type:varchar(20);not null" json:"status"`
+	StartedAt    time.Time  `gorm:"not null;index:idx_job_run_name" json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	DurationMs   int64      `gorm:"default:0" json:"duration_ms"`
+	Summary      string     `gorm:"type:varchar(500)" json:"summary,omitempty"`
+	ErrorMessage string     `gorm:"type:text" json:"error_message,omitempty"`
+}
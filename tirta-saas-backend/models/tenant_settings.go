@@ -5,6 +5,12 @@ import (
 	"gorm.io/gorm"
 )
 
+// Due date rules of the billing calendar
+const (
+	DueDateRuleDaysAfterIssue = "DAYS_AFTER_ISSUE" // InvoiceDueDays after the invoice is issued
+	DueDateRuleDayOfMonth     = "DAY_OF_MONTH"     // a fixed day of the month
+)
+
 type TenantSettings struct {
 	BaseModel
 	TenantID uuid.UUID `gorm:"type:char(36);not null;uniqueIndex" json:"tenant_id"`
@@ -23,15 +29,24 @@ type TenantSettings struct {
 	
 	// Invoice Configuration
 	InvoicePrefix       string  `gorm:"type:varchar(10)" json:"invoice_prefix"`
-	InvoiceNumberFormat string  `gorm:"type:varchar(50);default:'INV-{YEAR}{MONTH}-{NUMBER}'" json:"invoice_number_format"`
+	InvoiceNumberFormat string  `gorm:"type:varchar(50);default:'{PREFIX}-{YEAR}{MONTH}-{NUMBER}'" json:"invoice_number_format"` // tokens {PREFIX}, {YEAR}, {MONTH}, {AREA}, {NUMBER}; {MONTH} needs {YEAR}
 	InvoiceDueDays      int     `gorm:"default:7" json:"invoice_due_days"`
 	InvoiceFooterText   string  `gorm:"type:text" json:"invoice_footer_text"`
+	TaxPercent          float64 `gorm:"type:decimal(5,2);default:0" json:"tax_percent"` // tax added to water, fee and registration lines
 	
 	// Payment Configuration
 	LatePenaltyPercent  float64 `gorm:"type:decimal(5,2);default:2.0" json:"late_penalty_percent"`
 	LatePenaltyMaxCap   float64 `gorm:"type:decimal(15,2)" json:"late_penalty_max_cap"`
 	GracePeriodDays     int     `gorm:"default:3" json:"grace_period_days"`
 	MinimumBillAmount   float64 `gorm:"type:decimal(15,2);default:0" json:"minimum_bill_amount"`
+
+	// How overdue invoices are penalized, PERCENT of the unpaid principal or a FIXED amount. See
+	// services.PenaltyEngine.Assess for how it combines with the subscription type's late fees.
+	LatePenaltyPolicy      string  `gorm:"type:varchar(10);default:'PERCENT'" json:"late_penalty_policy"`
+	LatePenaltyFixedAmount float64 `gorm:"type:decimal(15,2);default:0" json:"late_penalty_fixed_amount"`
+
+	// Order in which a payment covering several invoices pays them, PENALTY_FIRST or PRINCIPAL_FIRST
+	PaymentAllocationOrder string `gorm:"type:varchar(20);default:'PENALTY_FIRST'" json:"payment_allocation_order"`
 	
 	// Payment Methods (JSON array of enabled methods) - no default, set in BeforeCreate
 	PaymentMethods string `gorm:"type:json" json:"payment_methods"`
@@ -47,6 +62,23 @@ type TenantSettings struct {
 	TimeZone        string `gorm:"type:varchar(50);default:'Asia/Jakarta'" json:"timezone"`
 	Language        string `gorm:"type:varchar(10);default:'id'" json:"language"`
 	Currency        string `gorm:"type:varchar(3);default:'IDR'" json:"currency"`
+
+	// Meter Reading
+	EstimateMissingReadings bool `gorm:"default:false" json:"estimate_missing_readings"` // estimate unread meters before monthly invoicing
+
+	// Billing Calendar (evaluated in TimeZone)
+	BillingDay              int    `gorm:"default:1" json:"billing_day"`                 // day of month the monthly invoices are generated, 1-28
+	BillingHour             int    `gorm:"default:0" json:"billing_hour"`                // hour of the billing day, 0-23
+	BillingUsageMonthOffset int    `gorm:"default:1" json:"billing_usage_month_offset"` // months between the usage billed and the run, 0 bills the month of the run
+	DueDateRule             string `gorm:"type:varchar(20);default:'DAYS_AFTER_ISSUE'" json:"due_date_rule"`
+	DueDayOfMonth           int    `gorm:"default:0" json:"due_day_of_month"` // with DAY_OF_MONTH, the first such day after the invoice is issued
+	
+	// Billing Notifications (sent with the tenant's templates INVOICE_ISSUED, PAYMENT_REMINDER, INVOICE_OVERDUE, PAYMENT_RECEIVED)
+	NotifyInvoiceIssued   bool `gorm:"default:false" json:"notify_invoice_issued"`
+	NotifyPaymentReminder bool `gorm:"default:false" json:"notify_payment_reminder"`
+	PaymentReminderDays   int  `gorm:"default:3" json:"payment_reminder_days"` // days before the due date
+	NotifyInvoiceOverdue  bool `gorm:"default:false" json:"notify_invoice_overdue"`
+	NotifyPaymentReceived bool `gorm:"default:false" json:"notify_payment_received"` // also PAYMENT_REJECTED for rejected transfer proofs
 	
 	// Additional Settings (JSON for flexible configuration)
 	CustomSettings string `gorm:"type:json" json:"custom_settings"`
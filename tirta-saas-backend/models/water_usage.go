@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -12,7 +14,7 @@ type WaterUsage struct {
 	MeterEnd         float64   `json:"meter_end"`
 	UsageM3          float64   `json:"usage_m3"`
 	AmountCalculated float64   `json:"amount_calculated"` // hasil UsageM3 * tarif
-	TenantID         uuid.UUID `gorm:"type:char(36);not null;index" json:"tenant_id"`
+	TenantID         uuid.UUID `gorm:"type:char(36);not null;index;uniqueIndex:idx_usage_client_reading" json:"tenant_id"`
 	
 	// Additional fields for Phase 6
 	MeterID           *uuid.UUID        `gorm:"type:char(36);index" json:"meter_id"`
@@ -27,6 +29,23 @@ type WaterUsage struct {
 	IsAnomaly         bool              `gorm:"default:false" json:"is_anomaly"`
 	AnomalyDetails    *ReadingAnomaly   `gorm:"foreignKey:WaterUsageID" json:"anomaly_details,omitempty"`
 
+	// Field capture details sent by the reader app, used to apply offline syncs idempotently
+	ClientReadingID *string    `gorm:"type:varchar(64);uniqueIndex:idx_usage_client_reading" json:"client_reading_id,omitempty"`
+	CapturedAt      *time.Time `gorm:"type:datetime" json:"captured_at,omitempty"`
+	Latitude        *float64   `gorm:"type:decimal(10,7)" json:"latitude,omitempty"`
+	Longitude       *float64   `gorm:"type:decimal(10,7)" json:"longitude,omitempty"`
+
+	// True-up of estimated months, set on the first actual reading after one or more estimates.
+	// TrueUpAmount is added to the invoice of this reading; estimates point to it via TrueUpUsageID.
+	TrueUpM3      float64    `gorm:"type:decimal(10,2);default:0" json:"true_up_m3"`
+	TrueUpAmount  float64    `gorm:"type:decimal(15,2);default:0" json:"true_up_amount"`
+	TrueUpUsageID *uuid.UUID `gorm:"type:char(36);index" json:"true_up_usage_id,omitempty"`
+
+	// Tariff used to price this reading
+	TariffMethod     string     `gorm:"type:varchar(20)" json:"tariff_method"` // flat, progressive
+	TariffCategoryID *uuid.UUID `gorm:"type:char(36);index" json:"tariff_category_id,omitempty"`
+	TariffBreakdown  string     `gorm:"type:text" json:"tariff_breakdown,omitempty"` // JSON array of tiers
+
 	BaseModel
 }
 
@@ -34,3 +53,10 @@ type WaterUsage struct {
 func (WaterUsage) TableName() string {
 	return "water_usages"
 }
+
+// Reading methods
+const (
+	ReadingMethodManual    = "manual"
+	ReadingMethodAutomatic = "automatic"
+	ReadingMethodEstimated = "estimated"
+)
@@ -7,6 +7,8 @@ type BulkInvoiceGenerationRequest struct {
 	UsageMonth  string      `json:"usage_month" binding:"required" example:"2025-01"` // Format: YYYY-MM
 	CustomerIDs []uuid.UUID `json:"customer_ids"`                                      // Optional: specific customers, empty = all
 	Preview     bool        `json:"preview"`                                           // If true, only preview without creating
+
+	EstimateMissing bool `json:"estimate_missing"` // Estimate usage of active customers without a reading for the month
 }
 
 // InvoicePreviewRequest represents invoice preview request (alias for clarity)
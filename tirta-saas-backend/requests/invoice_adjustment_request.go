@@ -0,0 +1,15 @@
+package requests
+
+// InvoiceAdjustmentRequest is a credit or debit note issued against an invoice
+type InvoiceAdjustmentRequest struct {
+	Amount float64 `json:"amount" binding:"required,gt=0,max=999999999" doc:"Amount of the note" example:"25000"`
+	Reason string  `json:"reason" binding:"required,max=255" doc:"Reason printed on the note" example:"Salah catat angka meter"`
+
+	// Debit notes only
+	LineType string `json:"line_type" binding:"omitempty,oneof=SERVICE METER_RENTAL MAINTENANCE" doc:"Bill the debit note as a service, meter rental or maintenance charge" example:"SERVICE"`
+}
+
+// VoidInvoiceRequest voids an invoice or rebills it from corrected water usage
+type VoidInvoiceRequest struct {
+	Reason string `json:"reason" binding:"required,max=255" doc:"Reason kept with the voided invoice" example:"Pelanggan sudah berhenti berlangganan"`
+}
@@ -20,12 +20,32 @@ type UpdateMeterRequest struct {
 }
 
 type ReplaceMeterRequest struct {
-	NewMeterNumber string  `json:"new_meter_number" binding:"required"`
-	NewBrand       string  `json:"new_brand"`
-	NewModel       string  `json:"new_model"`
-	FinalReading   float64 `json:"final_reading" binding:"required,gte=0"`
-	Reason         string  `json:"reason" binding:"required"`
-	Notes          string  `json:"notes"`
+	NewMeterNumber    string  `json:"new_meter_number" binding:"required"`
+	NewBrand          string  `json:"new_brand"`
+	NewModel          string  `json:"new_model"`
+	NewInitialReading float64 `json:"new_initial_reading" binding:"gte=0"`
+	ReplaceDate       string  `json:"replace_date"` // YYYY-MM-DD, defaults to today
+	FinalReading      float64 `json:"final_reading" binding:"required,gte=0"`
+	Reason            string  `json:"reason" binding:"required"`
+	Notes             string  `json:"notes"`
+}
+
+type CalibrateMeterRequest struct {
+	CalibrationDate string `json:"calibration_date" binding:"required"` // YYYY-MM-DD
+	NextCalibDate   string `json:"next_calib_date"`                     // YYYY-MM-DD, optional
+	IntervalMonths  int    `json:"interval_months" binding:"omitempty,min=1,max=120"`
+	Notes           string `json:"notes"`
+}
+
+type ScheduleCalibrationRequest struct {
+	NextCalibDate string `json:"next_calib_date" binding:"required"` // YYYY-MM-DD
+	Notes         string `json:"notes"`
+}
+
+type RetireMeterRequest struct {
+	FinalReading float64 `json:"final_reading" binding:"gte=0"`
+	Reason       string  `json:"reason" binding:"required"`
+	Notes        string  `json:"notes"`
 }
 
 type ReportMeterIssueRequest struct {
@@ -36,7 +56,18 @@ type ReportMeterIssueRequest struct {
 	PhotoURL    string `json:"photo_url"`
 }
 
+type CustomerReportMeterIssueRequest struct {
+	IssueType   string `json:"issue_type" binding:"required,oneof=broken leak stuck incorrect other"`
+	Description string `json:"description" binding:"required"`
+}
+
+type AssignMeterIssueRequest struct {
+	AssignedTo string `json:"assigned_to" binding:"required"`
+	Priority   string `json:"priority" binding:"omitempty,oneof=low normal high critical"`
+}
+
 type ResolveMeterIssueRequest struct {
-	Resolution string `json:"resolution" binding:"required"`
-	Notes      string `json:"notes"`
+	Resolution  string `json:"resolution" binding:"required"`
+	MeterStatus string `json:"meter_status" binding:"omitempty,oneof=active broken"` // meter condition after repair, defaults to active
+	Notes       string `json:"notes"`
 }
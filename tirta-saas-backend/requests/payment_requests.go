@@ -9,3 +9,64 @@ type CreatePaymentRequest struct {
 	PaymentDate   string    `json:"payment_date,omitempty" format:"date" doc:"Payment date (ISO format)" example:"2025-01-15"`
 	Notes         string    `json:"notes,omitempty" maxLength:"500" doc:"Additional notes for this payment" example:"Paid in full"`
 }
+
+type CreateCustomerCreditRequest struct {
+	Type        string  `json:"type" binding:"required,oneof=REFUND WRITE_OFF ADJUSTMENT" enum:"REFUND,WRITE_OFF,ADJUSTMENT" doc:"Kind of credit" example:"WRITE_OFF"`
+	Amount      float64 `json:"amount" binding:"required,gt=0" doc:"Credit amount in IDR" example:"25000"`
+	Description string  `json:"description" binding:"required,max=255" doc:"Reason for the credit" example:"Kompensasi gangguan suplai air"`
+}
+
+type CreateCustomerPaymentRequest struct {
+	CustomerID      uuid.UUID  `json:"customer_id" binding:"required" format:"uuid" doc:"Customer who paid" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Amount          float64    `json:"amount" binding:"required,gt=0" doc:"Amount received in IDR, allocated oldest invoice first" example:"450000"`
+	PaymentMethodID *uuid.UUID `json:"payment_method_id" format:"uuid" doc:"Payment method used"`
+	ReferenceNumber string     `json:"reference_number" binding:"max=100" doc:"Transfer or receipt reference" example:"TRX-20250115-001"`
+	Notes           string     `json:"notes,omitempty" maxLength:"500" doc:"Additional notes for this payment" example:"Tunggakan 3 bulan"`
+}
+
+type OnlinePaymentRequest struct {
+	InvoiceID uuid.UUID `json:"invoice_id" binding:"required" format:"uuid" doc:"Invoice ID to pay" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Channel   string    `json:"channel" binding:"omitempty,oneof=VIRTUAL_ACCOUNT QRIS E_WALLET CARD" enum:"VIRTUAL_ACCOUNT,QRIS,E_WALLET,CARD" doc:"Online payment channel, QRIS when empty" example:"QRIS"`
+	Amount    float64   `json:"amount" binding:"omitempty,gt=0" doc:"Amount to pay in IDR, the remaining amount of the invoice when empty" example:"150000"`
+}
+
+type SimulatePaymentTransactionRequest struct {
+	Status string `json:"status" binding:"required,oneof=SETTLED EXPIRED FAILED" enum:"SETTLED,EXPIRED,FAILED" doc:"Status the simulated gateway reports" example:"SETTLED"`
+}
+
+type ApproveTransferPaymentRequest struct {
+	Notes string `json:"notes,omitempty" binding:"max=500" doc:"Verification notes" example:"Dana sudah masuk rekening BRI"`
+}
+
+type RejectTransferPaymentRequest struct {
+	Reason string `json:"reason" binding:"required,max=255" doc:"Reason shown to the customer" example:"Nominal pada bukti transfer tidak sesuai"`
+}
+
+type MatchStatementLineRequest struct {
+	InvoiceID *uuid.UUID `json:"invoice_id" format:"uuid" doc:"Open invoice to post the transfer to"`
+	PaymentID *uuid.UUID `json:"payment_id" format:"uuid" doc:"Pending transfer proof the transfer confirms"`
+}
+
+type IgnoreStatementLineRequest struct {
+	Notes string `json:"notes" binding:"required,max=255" doc:"Why the transfer is not a customer payment" example:"Bunga bank"`
+}
+
+type CreateInstallmentPlanRequest struct {
+	CustomerID       uuid.UUID   `json:"customer_id" binding:"required" format:"uuid" doc:"Customer with arrears"`
+	InvoiceIDs       []uuid.UUID `json:"invoice_ids" binding:"required,min=1" doc:"Overdue invoices to consolidate"`
+	InstallmentCount int         `json:"installment_count" binding:"required,min=2,max=24" doc:"Number of monthly installments" example:"6"`
+	FirstDueDate     string      `json:"first_due_date" doc:"Due date of the first installment (YYYY-MM-DD), one month from today by default" example:"2025-02-10"`
+	MaxMissed        int         `json:"max_missed" binding:"omitempty,min=1,max=12" doc:"Missed installments after which the plan defaults, 2 by default" example:"2"`
+	Notes            string      `json:"notes" doc:"Terms agreed with the customer"`
+}
+
+type InstallmentPaymentRequest struct {
+	Amount          float64    `json:"amount" binding:"required,gt=0" doc:"Amount received" example:"150000"`
+	PaymentMethodID *uuid.UUID `json:"payment_method_id" format:"uuid" doc:"Payment method ID"`
+	ReferenceNumber string     `json:"reference_number" binding:"max=100" doc:"Payment reference number"`
+	Notes           string     `json:"notes" doc:"Payment notes"`
+}
+
+type CancelInstallmentPlanRequest struct {
+	Reason string `json:"reason" binding:"required,max=255" doc:"Why the plan is cancelled" example:"Pelanggan melunasi sekaligus"`
+}
@@ -83,14 +83,19 @@ type UpdateTenantSettingsRequest struct {
 	
 	// Invoice Configuration
 	InvoicePrefix       string `json:"invoice_prefix" binding:"omitempty,max=10"`
+	InvoiceNumberFormat string `json:"invoice_number_format" binding:"omitempty,max=50"` // tokens {PREFIX}, {YEAR}, {MONTH}, {AREA}, {NUMBER}; {MONTH} needs {YEAR}
 	InvoiceDueDays      int    `json:"invoice_due_days" binding:"omitempty,min=1,max=90"`
 	InvoiceFooterText   string `json:"invoice_footer_text"`
+	TaxPercent          *float64 `json:"tax_percent" binding:"omitempty,min=0,max=100"`
 	
 	// Payment Configuration
 	LatePenaltyPercent float64 `json:"late_penalty_percent" binding:"omitempty,min=0,max=100"`
 	LatePenaltyMaxCap  float64 `json:"late_penalty_max_cap" binding:"omitempty,min=0"`
 	GracePeriodDays    int     `json:"grace_period_days" binding:"omitempty,min=0,max=30"`
 	MinimumBillAmount  float64 `json:"minimum_bill_amount" binding:"omitempty,min=0"`
+
+	LatePenaltyPolicy      string   `json:"late_penalty_policy" binding:"omitempty,oneof=PERCENT FIXED PER_DAY"`
+	LatePenaltyFixedAmount *float64 `json:"late_penalty_fixed_amount" binding:"omitempty,min=0"`
 	
 	// Bank Account
 	BankName        string `json:"bank_name"`
@@ -102,6 +107,26 @@ type UpdateTenantSettingsRequest struct {
 	ServiceArea    string `json:"service_area"`
 	TimeZone       string `json:"timezone"`
 	Language       string `json:"language" binding:"omitempty,oneof=id en"`
+
+	// Meter Reading
+	EstimateMissingReadings *bool `json:"estimate_missing_readings"`
+
+	// Billing Calendar
+	BillingDay              int    `json:"billing_day" binding:"omitempty,min=1,max=28"`
+	BillingHour             *int   `json:"billing_hour" binding:"omitempty,min=0,max=23"`
+	BillingUsageMonthOffset *int   `json:"billing_usage_month_offset" binding:"omitempty,min=0,max=3"`
+	DueDateRule             string `json:"due_date_rule" binding:"omitempty,oneof=DAYS_AFTER_ISSUE DAY_OF_MONTH"`
+	DueDayOfMonth           int    `json:"due_day_of_month" binding:"omitempty,min=1,max=31"`
+
+	// Payment Allocation
+	PaymentAllocationOrder string `json:"payment_allocation_order" binding:"omitempty,oneof=PENALTY_FIRST PRINCIPAL_FIRST"`
+
+	// Billing Notifications
+	NotifyInvoiceIssued   *bool `json:"notify_invoice_issued"`
+	NotifyPaymentReminder *bool `json:"notify_payment_reminder"`
+	PaymentReminderDays   int   `json:"payment_reminder_days" binding:"omitempty,min=1,max=30"`
+	NotifyInvoiceOverdue  *bool `json:"notify_invoice_overdue"`
+	NotifyPaymentReceived *bool `json:"notify_payment_received"`
 }
 
 // BulkCustomerImportRequest represents request for bulk customer import
@@ -111,7 +136,7 @@ type BulkCustomerImportRequest struct {
 
 // CreateNotificationTemplateRequest represents request to create notification template
 type CreateNotificationTemplateRequest struct {
-	Code        string   `json:"code" binding:"required,min=3,max=50,alphanum"`
+	Code        string   `json:"code" binding:"required,min=3,max=50"` // letters, digits and underscores
 	Name        string   `json:"name" binding:"required,min=3,max=100"`
 	Description string   `json:"description"`
 	Channel     string   `json:"channel" binding:"required,oneof=EMAIL SMS IN_APP WHATSAPP"`
@@ -122,6 +147,17 @@ type CreateNotificationTemplateRequest struct {
 	Language    string   `json:"language" binding:"omitempty,oneof=id en"`
 }
 
+// PreviewNotificationTemplateRequest represents request to preview a notification template.
+// Content fields are only used when previewing an unsaved template.
+type PreviewNotificationTemplateRequest struct {
+	Code       string                 `json:"code"`
+	Subject    string                 `json:"subject"`
+	Body       string                 `json:"body"`
+	HTMLBody   string                 `json:"html_body"`
+	Variables  []string               `json:"variables"`
+	SampleData map[string]interface{} `json:"sample_data"` // missing variables get example values
+}
+
 // UpdateNotificationTemplateRequest represents request to update notification template
 type UpdateNotificationTemplateRequest struct {
 	Name        string   `json:"name" binding:"omitempty,min=3,max=100"`
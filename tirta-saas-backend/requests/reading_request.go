@@ -1,5 +1,7 @@
 package requests
 
+import "time"
+
 type CreateReadingRouteRequest struct {
 	Code        string  `json:"code" binding:"required"`
 	Name        string  `json:"name" binding:"required"`
@@ -18,6 +20,10 @@ type UpdateReadingRouteRequest struct {
 	IsActive    *bool   `json:"is_active"`
 }
 
+type UpdateRouteCustomersRequest struct {
+	CustomerIDs []string `json:"customer_ids" binding:"required"` // in visit order
+}
+
 type StartReadingSessionRequest struct {
 	RouteID       string `json:"route_id" binding:"required"`
 	ScheduledDate string `json:"scheduled_date" binding:"required"`
@@ -27,7 +33,7 @@ type RecordMeterReadingRequest struct {
 	CustomerID    string  `json:"customer_id" binding:"required"`
 	MeterID       *string `json:"meter_id"`
 	SessionID     *string `json:"session_id"`
-	UsageMonth    string  `json:"usage_month" binding:"required"`
+	UsageMonth    string  `json:"usage_month"` // defaults to the session month
 	MeterReading  float64 `json:"meter_reading" binding:"required,gte=0"`
 	PhotoURL      string  `json:"photo_url"`
 	ReadingMethod string  `json:"reading_method" binding:"omitempty,oneof=manual automatic estimated"`
@@ -39,7 +45,39 @@ type BatchRecordReadingsRequest struct {
 	Readings  []RecordMeterReadingRequest `json:"readings" binding:"required,min=1,dive"`
 }
 
+// SyncReadingItemRequest is a reading captured offline. ClientID is generated on the device and
+// stays the same when the upload is retried.
+type SyncReadingItemRequest struct {
+	ClientID      string    `json:"client_id" binding:"required,max=64"`
+	CustomerID    string    `json:"customer_id" binding:"required,uuid"`
+	MeterID       *string   `json:"meter_id" binding:"omitempty,uuid"`
+	MeterReading  float64   `json:"meter_reading" binding:"gte=0"`
+	CapturedAt    time.Time `json:"captured_at" binding:"required"`
+	PhotoURL      string    `json:"photo_url" binding:"max=500"`
+	Latitude      *float64  `json:"latitude" binding:"omitempty,gte=-90,lte=90"`
+	Longitude     *float64  `json:"longitude" binding:"omitempty,gte=-180,lte=180"`
+	ReadingMethod string    `json:"reading_method" binding:"omitempty,oneof=manual automatic estimated"`
+	Notes         string    `json:"notes"`
+	CannotRead    bool      `json:"cannot_read"` // meter not accessible, usage is estimated from history
+}
+
+type EstimateSessionReadingRequest struct {
+	CustomerID string `json:"customer_id" binding:"required,uuid"`
+	Reason     string `json:"reason"`
+}
+
+type SyncReadingsRequest struct {
+	DeviceID string                   `json:"device_id"`
+	Readings []SyncReadingItemRequest `json:"readings" binding:"required,min=1,max=500,dive"`
+}
+
 type ResolveAnomalyRequest struct {
 	Resolution string `json:"resolution" binding:"required"`
 	Notes      string `json:"notes"`
 }
+
+type CorrectAnomalyReadingRequest struct {
+	MeterEnd   float64 `json:"meter_end" binding:"gte=0"`
+	Resolution string  `json:"resolution"`
+	Notes      string  `json:"notes"`
+}
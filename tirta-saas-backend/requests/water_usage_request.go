@@ -5,10 +5,19 @@ import (
 )
 
 type CreateWaterUsageRequest struct {
+	CustomerID uuid.UUID  `json:"customer_id" binding:"required" format:"uuid" doc:"Customer ID" example:"123e4567-e89b-12d3-a456-426614174000"`
+	UsageMonth string     `json:"usage_month" binding:"required,len=7" pattern:"^[0-9]{4}-[0-9]{2}$" doc:"Usage month in YYYY-MM format" example:"2025-01"`
+	MeterEnd   float64    `json:"meter_end" binding:"required,gte=0" minimum:"0" doc:"Meter end reading in m³" example:"150.5"`
+	Notes      string     `json:"notes,omitempty" maxLength:"500" doc:"Additional notes for this reading" example:"Normal monthly reading"`
+	MeterID    *uuid.UUID `json:"meter_id,omitempty" format:"uuid" doc:"Meter ID, defaults to the customer's active meter"`
+
+	ReadingSessionID *uuid.UUID `json:"reading_session_id,omitempty" format:"uuid" doc:"Reading session the reading was taken in"`
+}
+
+type EstimateWaterUsageRequest struct {
 	CustomerID uuid.UUID `json:"customer_id" binding:"required" format:"uuid" doc:"Customer ID" example:"123e4567-e89b-12d3-a456-426614174000"`
 	UsageMonth string    `json:"usage_month" binding:"required,len=7" pattern:"^[0-9]{4}-[0-9]{2}$" doc:"Usage month in YYYY-MM format" example:"2025-01"`
-	MeterEnd   float64   `json:"meter_end" binding:"required,gte=0" minimum:"0" doc:"Meter end reading in m³" example:"150.5"`
-	Notes      string    `json:"notes,omitempty" maxLength:"500" doc:"Additional notes for this reading" example:"Normal monthly reading"`
+	Reason     string    `json:"reason,omitempty" maxLength:"500" doc:"Why the meter could not be read" example:"Pagar terkunci"`
 }
 
 type UpdateWaterUsageRequest struct {
@@ -3,6 +3,7 @@ package responses
 import (
 	"time"
 
+	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/google/uuid"
 )
 
@@ -12,6 +13,7 @@ type BulkInvoiceGenerationResponse struct {
 	Message      string                    `json:"message"`
 	Success      int                       `json:"success"`
 	Skipped      int                       `json:"skipped"`
+	Estimated    int                       `json:"estimated,omitempty"` // estimated readings created for unread meters
 	Failed       int                       `json:"failed"`
 	TotalAmount  float64                   `json:"total_amount"`
 	Invoices     []InvoicePreviewItem      `json:"invoices,omitempty"`
@@ -31,10 +33,14 @@ type InvoicePreviewItem struct {
 	WaterCharge   float64    `json:"water_charge"`
 	Abonemen      float64    `json:"abonemen"`
 	PenaltyAmount float64    `json:"penalty_amount"`
+	Adjustment    float64    `json:"adjustment_amount,omitempty"`
 	SubTotal      float64    `json:"sub_total"`
+	TaxAmount     float64    `json:"tax_amount"`
 	TotalAmount   float64    `json:"total_amount"`
 	DueDate       *time.Time `json:"due_date,omitempty"`
 	Notes         string     `json:"notes,omitempty"`
+
+	Lines []models.InvoiceLine `json:"lines,omitempty"`
 }
 
 // InvoicePreviewSummary represents summary of invoice preview
@@ -45,3 +51,25 @@ type InvoicePreviewSummary struct {
 	TotalPenalty   float64 `json:"total_penalty"`
 	PeriodMonth    string  `json:"period_month"`
 }
+
+// InvoiceGenerationJobResponse represents a queued or running invoice generation job and its progress
+type InvoiceGenerationJobResponse struct {
+	ID              uuid.UUID  `json:"id"`
+	UsageMonth      string     `json:"usage_month"`
+	Status          string     `json:"status"`
+	TriggerType     string     `json:"trigger_type"`
+	EstimateMissing bool       `json:"estimate_missing"`
+	TotalItems      int        `json:"total_items"`
+	ProcessedItems  int        `json:"processed_items"`
+	Progress        float64    `json:"progress"` // percentage of customers processed
+	Success         int        `json:"success"`
+	Skipped         int        `json:"skipped"`
+	Failed          int        `json:"failed"`
+	Estimated       int        `json:"estimated"`
+	TotalAmount     float64    `json:"total_amount"`
+	ErrorMessage    string     `json:"error_message,omitempty"`
+	HistoryID       *uuid.UUID `json:"history_id,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+}
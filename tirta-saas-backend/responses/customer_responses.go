@@ -15,6 +15,7 @@ type CustomerResponse struct {
 	SubscriptionID uuid.UUID                 `json:"subscription_id" format:"uuid" doc:"Subscription type ID" example:"123e4567-e89b-12d3-a456-426614174000"`
 	Subscription   *SubscriptionTypeResponse `json:"subscription,omitempty" doc:"Subscription type details"`
 	IsActive       bool                      `json:"is_active" doc:"Active status" example:"true"`
+	CreditBalance  float64                   `json:"credit_balance" doc:"Unapplied credit in IDR" example:"0"`
 	CreatedAt      time.Time                 `json:"created_at" format:"date-time" doc:"Registration date" example:"2025-01-01T00:00:00Z"`
 }
 
@@ -2,6 +2,7 @@ package responses
 
 import (
 	"time"
+	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/google/uuid"
 )
 
@@ -17,6 +18,14 @@ type InvoiceResponse struct {
 	IsPaid      bool      `json:"is_paid"`
 	Type        string    `json:"type"`
 	CreatedAt   time.Time `json:"created_at"`
+
+	Status          models.InvoiceStatus `json:"status"`
+	CreditNoteTotal float64              `json:"credit_note_total"`
+	DebitNoteTotal  float64              `json:"debit_note_total"`
+	Notes           string               `json:"notes,omitempty"`
+
+	TaxAmount float64              `json:"tax_amount"`
+	Lines     []models.InvoiceLine `json:"lines,omitempty"` // invoice detail only
 }
 
 type InvoiceListResponse struct {
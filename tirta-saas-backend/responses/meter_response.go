@@ -9,6 +9,7 @@ import (
 
 type MeterResponse struct {
 	ID             uuid.UUID  `json:"id"`
+	CustomerID     uuid.UUID  `json:"customer_id"`
 	MeterNumber    string     `json:"meter_number"`
 	Brand          string     `json:"brand"`
 	Model          string     `json:"model"`
@@ -22,18 +23,23 @@ type MeterResponse struct {
 }
 
 type MeterIssueResponse struct {
-	ID          uuid.UUID  `json:"id"`
-	Meter       MeterResponse `json:"meter"`
-	IssueType   string     `json:"issue_type"`
-	Description string     `json:"description"`
-	Status      string     `json:"status"`
-	Priority    string     `json:"priority"`
-	PhotoURL    string     `json:"photo_url,omitempty"`
-	ReportedBy  string     `json:"reported_by"`
-	ResolvedBy  *string    `json:"resolved_by,omitempty"`
-	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
-	Resolution  string     `json:"resolution,omitempty"`
-	CreatedAt   time.Time  `json:"created_at"`
+	ID           uuid.UUID     `json:"id"`
+	Meter        MeterResponse `json:"meter"`
+	IssueType    string        `json:"issue_type"`
+	Description  string        `json:"description"`
+	Status       string        `json:"status"`
+	Priority     string        `json:"priority"`
+	PhotoURL     string        `json:"photo_url,omitempty"`
+	ReporterType string        `json:"reporter_type"`
+	ReportedBy   string        `json:"reported_by"`
+	AssignedTo   *string       `json:"assigned_to,omitempty"`
+	AssignedAt   *time.Time    `json:"assigned_at,omitempty"`
+	StartedAt    *time.Time    `json:"started_at,omitempty"`
+	ResolvedBy   *string       `json:"resolved_by,omitempty"`
+	ResolvedAt   *time.Time    `json:"resolved_at,omitempty"`
+	Resolution   string        `json:"resolution,omitempty"`
+	ClosedAt     *time.Time    `json:"closed_at,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
 }
 
 type MeterHistoryResponse struct {
@@ -51,6 +57,7 @@ type MeterHistoryResponse struct {
 func ToMeterResponse(meter *models.Meter) MeterResponse {
 	response := MeterResponse{
 		ID:             meter.ID,
+		CustomerID:     meter.CustomerID,
 		MeterNumber:    meter.MeterNumber,
 		Brand:          meter.Brand,
 		Model:          meter.Model,
@@ -71,23 +78,51 @@ func ToMeterResponse(meter *models.Meter) MeterResponse {
 
 func ToMeterIssueResponse(issue *models.MeterIssue) MeterIssueResponse {
 	response := MeterIssueResponse{
-		ID:          issue.ID,
-		Meter:       ToMeterResponse(&issue.Meter),
-		IssueType:   issue.IssueType,
-		Description: issue.Description,
-		Status:      issue.Status,
-		Priority:    issue.Priority,
-		PhotoURL:    issue.PhotoURL,
-		ReportedBy:  issue.Reporter.Email,
-		CreatedAt:   issue.CreatedAt,
+		ID:           issue.ID,
+		Meter:        ToMeterResponse(&issue.Meter),
+		IssueType:    issue.IssueType,
+		Description:  issue.Description,
+		Status:       issue.Status,
+		Priority:     issue.Priority,
+		PhotoURL:     issue.PhotoURL,
+		ReporterType: issue.ReporterType,
+		StartedAt:    issue.StartedAt,
+		ClosedAt:     issue.ClosedAt,
+		CreatedAt:    issue.CreatedAt,
 	}
-	
+
+	if issue.Reporter != nil {
+		response.ReportedBy = issue.Reporter.Email
+	} else if issue.ReportingCustomer != nil {
+		response.ReportedBy = issue.ReportingCustomer.Name
+	}
+
+	if issue.Assignee != nil {
+		assignedTo := issue.Assignee.Email
+		response.AssignedTo = &assignedTo
+		response.AssignedAt = issue.AssignedAt
+	}
+
 	if issue.Resolver != nil {
 		resolvedBy := issue.Resolver.Email
 		response.ResolvedBy = &resolvedBy
 		response.ResolvedAt = issue.ResolvedAt
 		response.Resolution = issue.Resolution
 	}
-	
+
 	return response
 }
+
+func ToMeterHistoryResponse(history *models.MeterHistory) MeterHistoryResponse {
+	return MeterHistoryResponse{
+		ID:           history.ID,
+		MeterNumber:  history.Meter.MeterNumber,
+		CustomerName: history.Customer.Name,
+		Action:       history.Action,
+		OldValue:     history.OldValue,
+		NewValue:     history.NewValue,
+		PerformedBy:  history.User.Email,
+		Notes:        history.Notes,
+		CreatedAt:    history.CreatedAt,
+	}
+}
@@ -7,8 +7,61 @@ import (
 )
 
 type PaymentResponse struct {
-	ID        uuid.UUID `json:"id"`
-	InvoiceID uuid.UUID `json:"invoice_id"`
-	Amount    float64   `json:"amount"`
-	PaidAt    time.Time `json:"paid_at"`
+	ID           uuid.UUID `json:"id"`
+	InvoiceID    uuid.UUID `json:"invoice_id"`
+	Amount       float64   `json:"amount"`
+	CreditAmount float64   `json:"credit_amount"` // overpayment added to the customer's credit balance
+	PaidAt       time.Time `json:"paid_at"`
+}
+
+type CustomerLedgerEntryResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	EntryDate   time.Time  `json:"entry_date"`
+	Type        string     `json:"type"`
+	Description string     `json:"description"`
+	Debit       float64    `json:"debit"`
+	Credit      float64    `json:"credit"`
+	Balance     float64    `json:"balance"` // running balance, negative means credit in the customer's favour
+	InvoiceID   *uuid.UUID `json:"invoice_id,omitempty"`
+	PaymentID   *uuid.UUID `json:"payment_id,omitempty"`
+}
+
+type CustomerStatementResponse struct {
+	CustomerID     uuid.UUID                     `json:"customer_id"`
+	CustomerName   string                        `json:"customer_name"`
+	MeterNumber    string                        `json:"meter_number"`
+	From           *time.Time                    `json:"from,omitempty"`
+	To             *time.Time                    `json:"to,omitempty"`
+	OpeningBalance float64                       `json:"opening_balance"`
+	TotalDebit     float64                       `json:"total_debit"`
+	TotalCredit    float64                       `json:"total_credit"`
+	ClosingBalance float64                       `json:"closing_balance"`
+	CreditBalance  float64                       `json:"credit_balance"`
+	Entries        []CustomerLedgerEntryResponse `json:"entries"`
+}
+
+type PaymentAllocationResponse struct {
+	Sequence        int       `json:"sequence"`
+	PaymentID       uuid.UUID `json:"payment_id"`
+	InvoiceID       uuid.UUID `json:"invoice_id"`
+	InvoiceNumber   string    `json:"invoice_number"`
+	UsageMonth      string    `json:"usage_month"`
+	PenaltyAmount   float64   `json:"penalty_amount"`
+	PrincipalAmount float64   `json:"principal_amount"`
+	Amount          float64   `json:"amount"`
+	InvoicePaid     bool      `json:"invoice_paid"`
+}
+
+type CustomerPaymentResponse struct {
+	ID              uuid.UUID                   `json:"id"`
+	CustomerID      uuid.UUID                   `json:"customer_id"`
+	CustomerName    string                      `json:"customer_name,omitempty"`
+	Amount          float64                     `json:"amount"`
+	AllocatedAmount float64                     `json:"allocated_amount"`
+	CreditAmount    float64                     `json:"credit_amount"` // left over, added to the customer's credit balance
+	AllocationOrder string                      `json:"allocation_order"`
+	ReferenceNumber string                      `json:"reference_number,omitempty"`
+	Notes           string                      `json:"notes,omitempty"`
+	PaidAt          time.Time                   `json:"paid_at"`
+	Allocations     []PaymentAllocationResponse `json:"allocations"`
 }
@@ -182,6 +182,7 @@ type TenantSettingsResponse struct {
 	InvoiceNumberFormat string `json:"invoice_number_format"`
 	InvoiceDueDays      int    `json:"invoice_due_days"`
 	InvoiceFooterText   string `json:"invoice_footer_text"`
+	TaxPercent          float64 `json:"tax_percent"`
 	
 	// Payment Configuration
 	LatePenaltyPercent float64 `json:"late_penalty_percent"`
@@ -189,6 +190,9 @@ type TenantSettingsResponse struct {
 	GracePeriodDays    int     `json:"grace_period_days"`
 	MinimumBillAmount  float64 `json:"minimum_bill_amount"`
 	PaymentMethods     []string `json:"payment_methods"`
+
+	LatePenaltyPolicy      string  `json:"late_penalty_policy"`
+	LatePenaltyFixedAmount float64 `json:"late_penalty_fixed_amount"`
 	
 	// Bank Account
 	BankName        string `json:"bank_name"`
@@ -201,6 +205,26 @@ type TenantSettingsResponse struct {
 	TimeZone       string `json:"timezone"`
 	Language       string `json:"language"`
 	Currency       string `json:"currency"`
+
+	// Meter Reading
+	EstimateMissingReadings bool `json:"estimate_missing_readings"`
+
+	// Billing Calendar
+	BillingDay              int    `json:"billing_day"`
+	BillingHour             int    `json:"billing_hour"`
+	BillingUsageMonthOffset int    `json:"billing_usage_month_offset"`
+	DueDateRule             string `json:"due_date_rule"`
+	DueDayOfMonth           int    `json:"due_day_of_month"`
+
+	// Payment Allocation
+	PaymentAllocationOrder string `json:"payment_allocation_order"`
+
+	// Billing Notifications
+	NotifyInvoiceIssued   bool `json:"notify_invoice_issued"`
+	NotifyPaymentReminder bool `json:"notify_payment_reminder"`
+	PaymentReminderDays   int  `json:"payment_reminder_days"`
+	NotifyInvoiceOverdue  bool `json:"notify_invoice_overdue"`
+	NotifyPaymentReceived bool `json:"notify_payment_received"`
 	
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -224,6 +248,15 @@ type NotificationTemplateResponse struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// NotificationPreviewResponse represents a rendered notification template
+type NotificationPreviewResponse struct {
+	Subject    string                 `json:"subject"`
+	Body       string                 `json:"body"`
+	HTMLBody   string                 `json:"html_body,omitempty"`
+	Variables  []string               `json:"variables"`
+	SampleData map[string]interface{} `json:"sample_data"`
+}
+
 // NotificationLogResponse represents a notification log entry
 type NotificationLogResponse struct {
 	ID            uuid.UUID  `json:"id"`
@@ -241,6 +274,11 @@ type NotificationLogResponse struct {
 	FailedAt      *time.Time `json:"failed_at,omitempty"`
 	ErrorMessage  string     `json:"error_message,omitempty"`
 	RetryCount    int        `json:"retry_count"`
+	NextRetryAt   *time.Time `json:"next_retry_at,omitempty"`
+	Provider      string     `json:"provider,omitempty"`
+	ProviderMsgID string     `json:"provider_msg_id,omitempty"`
+	EventCode     string     `json:"event_code,omitempty"`
+	ReferenceID   *uuid.UUID `json:"reference_id,omitempty"`
 	CreatedAt     time.Time  `json:"created_at"`
 }
 
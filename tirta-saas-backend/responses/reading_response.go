@@ -8,22 +8,25 @@ import (
 )
 
 type ReadingRouteResponse struct {
-	ID            uuid.UUID `json:"id"`
-	Code          string    `json:"code"`
-	Name          string    `json:"name"`
-	Description   string    `json:"description"`
-	AssignedUser  *string   `json:"assigned_user,omitempty"`
-	ScheduleDay   int       `json:"schedule_day"`
-	EstDuration   int       `json:"est_duration"`
-	CustomerCount int       `json:"customer_count"`
-	IsActive      bool      `json:"is_active"`
+	ID            uuid.UUID  `json:"id"`
+	Code          string     `json:"code"`
+	Name          string     `json:"name"`
+	Description   string     `json:"description"`
+	AssignedTo    *uuid.UUID `json:"assigned_to,omitempty"`
+	AssignedUser  *string    `json:"assigned_user,omitempty"`
+	ScheduleDay   int        `json:"schedule_day"`
+	EstDuration   int        `json:"est_duration"`
+	CustomerCount int        `json:"customer_count"`
+	IsActive      bool       `json:"is_active"`
 }
 
 type ReadingSessionResponse struct {
 	ID             uuid.UUID  `json:"id"`
 	Route          ReadingRouteResponse `json:"route"`
+	ReaderID       uuid.UUID  `json:"reader_id"`
 	ReaderName     string     `json:"reader_name"`
 	ScheduledDate  time.Time  `json:"scheduled_date"`
+	UsageMonth     string     `json:"usage_month"`
 	StartTime      *time.Time `json:"start_time"`
 	EndTime        *time.Time `json:"end_time"`
 	Status         string     `json:"status"`
@@ -35,6 +38,8 @@ type ReadingSessionResponse struct {
 
 type ReadingAnomalyResponse struct {
 	ID            uuid.UUID  `json:"id"`
+	WaterUsageID  uuid.UUID  `json:"water_usage_id"`
+	CustomerID    uuid.UUID  `json:"customer_id"`
 	CustomerName  string     `json:"customer_name"`
 	MeterNumber   string     `json:"meter_number"`
 	UsageMonth    string     `json:"usage_month"`
@@ -50,12 +55,31 @@ type ReadingAnomalyResponse struct {
 	CreatedAt     time.Time  `json:"created_at"`
 }
 
+type SyncReadingResultResponse struct {
+	ClientID        string              `json:"client_id"`
+	Status          string              `json:"status"` // created, duplicate, conflict, rejected, failed
+	Error           string              `json:"error,omitempty"`
+	Reading         *WaterUsageResponse `json:"reading,omitempty"`
+	ExistingReading *WaterUsageResponse `json:"existing_reading,omitempty"`
+}
+
+type SyncReadingsResponse struct {
+	Results   []SyncReadingResultResponse `json:"results"`
+	Created   int                         `json:"created"`
+	Duplicate int                         `json:"duplicate"`
+	Conflict  int                         `json:"conflict"`
+	Rejected  int                         `json:"rejected"`
+	Failed    int                         `json:"failed"`
+	Session   ReadingSessionResponse      `json:"session"`
+}
+
 func ToReadingRouteResponse(route *models.ReadingRoute) ReadingRouteResponse {
 	response := ReadingRouteResponse{
 		ID:            route.ID,
 		Code:          route.Code,
 		Name:          route.Name,
 		Description:   route.Description,
+		AssignedTo:    route.AssignedTo,
 		ScheduleDay:   route.ScheduleDay,
 		EstDuration:   route.EstDuration,
 		CustomerCount: route.CustomerCount,
@@ -74,8 +98,10 @@ func ToReadingSessionResponse(session *models.ReadingSession) ReadingSessionResp
 	return ReadingSessionResponse{
 		ID:             session.ID,
 		Route:          ToReadingRouteResponse(&session.Route),
+		ReaderID:       session.ReaderID,
 		ReaderName:     session.Reader.Email,
 		ScheduledDate:  session.ScheduledDate,
+		UsageMonth:     session.UsageMonth,
 		StartTime:      session.StartTime,
 		EndTime:        session.EndTime,
 		Status:         session.Status,
@@ -89,6 +115,8 @@ func ToReadingSessionResponse(session *models.ReadingSession) ReadingSessionResp
 func ToReadingAnomalyResponse(anomaly *models.ReadingAnomaly) ReadingAnomalyResponse {
 	response := ReadingAnomalyResponse{
 		ID:            anomaly.ID,
+		WaterUsageID:  anomaly.WaterUsageID,
+		CustomerID:    anomaly.WaterUsage.CustomerID,
 		CustomerName:  anomaly.WaterUsage.Customer.Name,
 		MeterNumber:   anomaly.WaterUsage.Customer.MeterNumber,
 		UsageMonth:    anomaly.WaterUsage.UsageMonth,
@@ -1,22 +1,69 @@
 package responses
 
 import (
+	"encoding/json"
 	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/google/uuid"
 )
 
 type WaterUsageResponse struct {
-	ID               uuid.UUID `json:"id"`
-	CustomerID       uuid.UUID `json:"customer_id"`
-	UsageMonth       string    `json:"usage_month"`
-	MeterStart       float64   `json:"meter_start"`
-	MeterEnd         float64   `json:"meter_end"`
-	UsageM3          float64   `json:"usage_m3"`
-	AmountCalculated float64   `json:"amount_calculated"`
-	CreatedAt        time.Time `json:"created_at"`
+	ID               uuid.UUID       `json:"id"`
+	CustomerID       uuid.UUID       `json:"customer_id"`
+	MeterID          *uuid.UUID      `json:"meter_id,omitempty"`
+	UsageMonth       string          `json:"usage_month"`
+	MeterStart       float64         `json:"meter_start"`
+	MeterEnd         float64         `json:"meter_end"`
+	UsageM3          float64         `json:"usage_m3"`
+	AmountCalculated float64         `json:"amount_calculated"`
+	ReadingMethod    string          `json:"reading_method,omitempty"`
+	TariffMethod     string          `json:"tariff_method,omitempty"`
+	TariffBreakdown  json.RawMessage `json:"tariff_breakdown,omitempty"`
+	TrueUpM3         float64         `json:"true_up_m3,omitempty"`
+	TrueUpAmount     float64         `json:"true_up_amount,omitempty"`
+	IsAnomaly        bool            `json:"is_anomaly"`
+	Notes            string          `json:"notes,omitempty"`
+	PhotoURL         string          `json:"photo_url,omitempty"`
+	ClientReadingID  *string         `json:"client_reading_id,omitempty"`
+	CapturedAt       *time.Time      `json:"captured_at,omitempty"`
+	Latitude         *float64        `json:"latitude,omitempty"`
+	Longitude        *float64        `json:"longitude,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
 }
 
 type WaterUsageListResponse struct {
 	UsageRecords []WaterUsageResponse `json:"usage_records"`
 	Total        int                  `json:"total"`
-}
\ No newline at end of file
+}
+
+func ToWaterUsageResponse(usage *models.WaterUsage) WaterUsageResponse {
+	response := WaterUsageResponse{
+		ID:               usage.ID,
+		CustomerID:       usage.CustomerID,
+		MeterID:          usage.MeterID,
+		UsageMonth:       usage.UsageMonth,
+		MeterStart:       usage.MeterStart,
+		MeterEnd:         usage.MeterEnd,
+		UsageM3:          usage.UsageM3,
+		AmountCalculated: usage.AmountCalculated,
+		ReadingMethod:    usage.ReadingMethod,
+		TariffMethod:     usage.TariffMethod,
+		TrueUpM3:         usage.TrueUpM3,
+		TrueUpAmount:     usage.TrueUpAmount,
+		IsAnomaly:        usage.IsAnomaly,
+		Notes:            usage.Notes,
+		PhotoURL:         usage.PhotoURL,
+		ClientReadingID:  usage.ClientReadingID,
+		CapturedAt:       usage.CapturedAt,
+		Latitude:         usage.Latitude,
+		Longitude:        usage.Longitude,
+		CreatedAt:        usage.CreatedAt,
+	}
+
+	if usage.TariffBreakdown != "" {
+		response.TariffBreakdown = json.RawMessage(usage.TariffBreakdown)
+	}
+
+	return response
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func BankStatementRoutes(r *gin.Engine) {
+	// Bank statement import and reconciliation (finance and admins)
+	api := r.Group("/api/bank-statements")
+	api.Use(middleware.JWTAuthMiddleware(), middleware.RequirePermission(constants.PermManagePayments))
+	{
+		api.POST("/import", controllers.ImportBankStatement)
+		api.GET("", controllers.GetBankStatementImports)
+		api.GET("/exceptions", controllers.GetStatementExceptions)
+		api.GET("/:id", controllers.GetBankStatementImport)
+
+		// Manual matching of the exceptions
+		api.POST("/lines/:id/match", controllers.MatchStatementLine)
+		api.POST("/lines/:id/ignore", controllers.IgnoreStatementLine)
+	}
+}
@@ -17,4 +17,8 @@ func CustomerRoutes(r *gin.Engine) {
 	group.DELETE(":id", controllers.DeleteCustomer)
 	group.POST(":id/activate", controllers.ActivateCustomer)
 	group.POST(":id/deactivate", controllers.DeactivateCustomer)
+
+	// Account ledger
+	group.GET(":id/statement", controllers.GetCustomerStatement)
+	group.POST(":id/ledger/credits", controllers.CreateCustomerCredit)
 }
@@ -1,12 +1,15 @@
 package routes
 
 import (
+	"github.com/adipras/tirta-saas-backend/config"
 	"github.com/adipras/tirta-saas-backend/controllers"
 	"github.com/adipras/tirta-saas-backend/middleware"
 	"github.com/gin-gonic/gin"
 )
 
 func CustomerSelfServiceRoutes(r *gin.Engine) {
+	meterIssueController := controllers.NewMeterIssueController(config.DB)
+
 	group := r.Group("/api/customer")
 	group.Use(middleware.CustomerJWTAuthMiddleware())
 
@@ -19,7 +22,21 @@ func CustomerSelfServiceRoutes(r *gin.Engine) {
 	group.GET("/invoices", controllers.GetCustomerInvoices)
 	group.GET("/payments", controllers.GetCustomerPayments)
 	group.GET("/water-usage", controllers.GetCustomerWaterUsage)
+	group.GET("/statement", controllers.CustomerGetStatement)
+	group.GET("/invoices/:id/pdf", controllers.CustomerDownloadInvoicePDF)
+	group.GET("/payments/:id/receipt", controllers.CustomerDownloadPaymentReceipt)
 
 	// Payment
 	group.POST("/payments", controllers.CustomerMakePayment)
-}
\ No newline at end of file
+	group.POST("/payments/transfer", controllers.CustomerSubmitTransferProof)
+	group.GET("/payment-transactions/:id", controllers.CustomerGetPaymentTransaction)
+
+	// Installment plans
+	group.GET("/installment-plans", controllers.CustomerGetInstallmentPlans)
+	group.GET("/installment-plans/:id", controllers.CustomerGetInstallmentPlan)
+
+	// Meter issue reports
+	group.POST("/meter-issues", meterIssueController.CustomerReportMeterIssue)
+	group.GET("/meter-issues", meterIssueController.GetCustomerMeterIssues)
+	group.POST("/meter-issues/:id/photo", meterIssueController.CustomerUploadMeterIssuePhoto)
+}
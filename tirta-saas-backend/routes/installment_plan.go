@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func InstallmentPlanRoutes(r *gin.Engine) {
+	// Repayment plans for customer arrears (finance and admins)
+	api := r.Group("/api/installment-plans")
+	api.Use(middleware.JWTAuthMiddleware(), middleware.RequirePermission(constants.PermManagePayments))
+	{
+		api.POST("", controllers.CreateInstallmentPlan)
+		api.GET("", controllers.GetInstallmentPlans)
+		api.GET("/:id", controllers.GetInstallmentPlan)
+		api.POST("/:id/payments", controllers.PayInstallmentPlan)
+		api.POST("/:id/cancel", controllers.CancelInstallmentPlan)
+	}
+}
@@ -10,16 +10,32 @@ func InvoiceRoutes(r *gin.Engine) {
 	group := r.Group("/api/invoices")
 	group.Use(middleware.JWTAuthMiddleware(), middleware.AdminOnly())
 
-	// Legacy single generation
+	// Immediate generation of a month, through the same service as the generation jobs
 	group.POST("generate-monthly", controllers.GenerateMonthlyInvoice)
 	
 	// New bulk generation endpoints
 	group.POST("/bulk-generate", controllers.BulkGenerateInvoices)
 	group.POST("/preview-generation", controllers.PreviewInvoiceGeneration)
+	group.GET("/generation-jobs", controllers.GetInvoiceGenerationJobs)
+	group.GET("/generation-jobs/:id", controllers.GetInvoiceGenerationJob)
+	group.GET("/generation-jobs/:id/items", controllers.GetInvoiceGenerationJobItems)
+	group.POST("/generation-jobs/:id/retry", controllers.RetryInvoiceGenerationJob)
+	
+	// Printable invoices
+	group.GET("/batch-pdf", controllers.DownloadInvoiceBatchPDF)
+	group.GET(":id/pdf", controllers.DownloadInvoicePDF)
 	
 	// CRUD operations
 	group.GET("", controllers.GetInvoices)
 	group.GET(":id", controllers.GetInvoice)
 	group.PUT(":id", controllers.UpdateInvoice)
 	group.DELETE(":id", controllers.DeleteInvoice)
+	
+	// Adjustments of issued invoices
+	group.GET(":id/adjustments", controllers.GetInvoiceAdjustments)
+	group.GET(":id/penalties", controllers.GetInvoicePenalties)
+	group.POST(":id/void", controllers.VoidInvoice)
+	group.POST(":id/credit-notes", controllers.IssueCreditNote)
+	group.POST(":id/debit-notes", controllers.IssueDebitNote)
+	group.POST(":id/rebill", controllers.RebillInvoice)
 }
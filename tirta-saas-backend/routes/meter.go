@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func MeterRoutes(r *gin.Engine) {
+	meterController := controllers.NewMeterController(config.DB)
+
+	api := r.Group("/api/meters")
+	api.Use(middleware.JWTAuthMiddleware())
+	{
+		// Meter registry
+		api.GET("", middleware.RequirePermission(constants.PermViewCustomers), meterController.GetMeters)
+		api.GET("/calibration-due", middleware.RequirePermission(constants.PermViewCustomers), meterController.GetCalibrationDue)
+		api.GET("/:id", middleware.RequirePermission(constants.PermViewCustomers), meterController.GetMeter)
+		api.GET("/:id/history", middleware.RequirePermission(constants.PermViewCustomers), meterController.GetMeterHistory)
+
+		// Lifecycle actions (installation staff)
+		api.POST("", middleware.RequirePermission(constants.PermManageInstallations), meterController.CreateMeter)
+		api.PUT("/:id", middleware.RequirePermission(constants.PermManageInstallations), meterController.UpdateMeter)
+		api.POST("/:id/replace", middleware.RequirePermission(constants.PermManageInstallations), meterController.ReplaceMeter)
+		api.POST("/:id/calibrate", middleware.RequirePermission(constants.PermManageInstallations), meterController.CalibrateMeter)
+		api.PUT("/:id/calibration-schedule", middleware.RequirePermission(constants.PermManageInstallations), meterController.ScheduleCalibration)
+		api.POST("/:id/retire", middleware.RequirePermission(constants.PermManageInstallations), meterController.RetireMeter)
+		api.DELETE("/:id", middleware.RequirePermission(constants.PermManageInstallations), meterController.DeleteMeter)
+	}
+}
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func MeterIssueRoutes(r *gin.Engine) {
+	meterIssueController := controllers.NewMeterIssueController(config.DB)
+
+	// Field staff (meter readers, service, installers) can report and document issues
+	reporters := middleware.RequirePermission(constants.PermRecordWaterUsage, constants.PermManageRepairs, constants.PermManageInstallations)
+	repairs := middleware.RequirePermission(constants.PermManageRepairs)
+
+	api := r.Group("/api/meter-issues")
+	api.Use(middleware.JWTAuthMiddleware())
+	{
+		api.GET("", middleware.RequirePermission(constants.PermViewCustomers), meterIssueController.GetMeterIssues)
+		api.GET("/:id", middleware.RequirePermission(constants.PermViewCustomers), meterIssueController.GetMeterIssue)
+		api.POST("", reporters, meterIssueController.ReportMeterIssue)
+		api.POST("/:id/photo", reporters, meterIssueController.UploadMeterIssuePhoto)
+
+		// Work order workflow: open -> in_progress -> resolved -> closed
+		api.PUT("/:id/assign", middleware.RequireRole(constants.RoleTenantAdmin, constants.RolePlatformOwner), meterIssueController.AssignMeterIssue)
+		api.POST("/:id/start", repairs, meterIssueController.StartMeterIssue)
+		api.POST("/:id/resolve", repairs, meterIssueController.ResolveMeterIssue)
+		api.POST("/:id/close", repairs, meterIssueController.CloseMeterIssue)
+	}
+}
@@ -2,8 +2,10 @@
 package routes
 
 import (
+	"github.com/adipras/tirta-saas-backend/constants"
 	"github.com/adipras/tirta-saas-backend/controllers"
 	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/adipras/tirta-saas-backend/services"
 	"github.com/gin-gonic/gin"
 )
 
@@ -14,7 +16,31 @@ func PaymentRoutes(r *gin.Engine) {
 	group.POST("", controllers.CreatePayment)
 	group.GET("", controllers.GetAllPayments)
 	group.GET(":id", controllers.GetPayment)
+	group.GET(":id/receipt", controllers.DownloadPaymentReceipt)
 	group.PUT(":id", controllers.UpdatePayment)
 	group.DELETE(":id", controllers.DeletePayment)
 	group.GET("customer/:customer_id", controllers.GetPaymentHistoryByCustomerID)
+
+	// One payment allocated across a customer's outstanding invoices
+	group.POST("customer-payments", controllers.CreateCustomerPayment)
+	group.GET("customer-payments", controllers.ListCustomerPayments)
+	group.GET("customer-payments/:id", controllers.GetCustomerPayment)
+	group.GET("customer-payments/:id/receipt", controllers.DownloadCustomerPaymentReceipt)
+
+	// Online payments through the payment gateway
+	group.GET("online-transactions", controllers.ListPaymentTransactions)
+	group.POST("online-transactions/:id/sync", controllers.SyncPaymentTransaction)
+	if services.SimulatedGatewayEnabled() {
+		group.POST("online-transactions/:id/simulate", controllers.SimulatePaymentTransaction)
+	}
+
+	// Verification queue of bank transfers reported by customers (finance and admins)
+	verifications := r.Group("/api/payment-verifications")
+	verifications.Use(middleware.JWTAuthMiddleware(), middleware.RequirePermission(constants.PermManagePayments))
+
+	verifications.GET("", controllers.GetPaymentVerifications)
+	verifications.GET(":id", controllers.GetPaymentVerification)
+	verifications.GET(":id/proof", controllers.DownloadTransferProof)
+	verifications.POST(":id/approve", controllers.ApproveTransferPayment)
+	verifications.POST(":id/reject", controllers.RejectTransferPayment)
 }
@@ -51,6 +51,7 @@ func PlatformRoutes(r *gin.Engine) {
 		platform.GET("/logs/errors", controllers.GetErrorLogs)
 		platform.GET("/system/health", controllers.GetSystemHealth)
 		platform.GET("/system/metrics", controllers.GetSystemMetrics)
+		platform.GET("/system/scheduler", controllers.GetSchedulerStatus)
 	}
 	
 	// Tenant-specific settings routes - requires tenant admin role
@@ -61,14 +62,19 @@ func PlatformRoutes(r *gin.Engine) {
 		// Tenant Settings
 		tenant.GET("/settings", controllers.GetTenantSettings)
 		tenant.PUT("/settings", controllers.UpdateTenantSettings)
+		tenant.GET("/settings/billing-calendar", controllers.GetBillingCalendarPreview)
 		tenant.POST("/settings/logo", controllers.UploadTenantLogo)
 		
 		// Notification System
 		tenant.GET("/notifications/templates", controllers.ListNotificationTemplates)
 		tenant.POST("/notifications/templates", controllers.CreateNotificationTemplate)
+		tenant.POST("/notifications/templates/preview", controllers.PreviewNotificationTemplate)
+		tenant.POST("/notifications/templates/:id/preview", controllers.PreviewSavedNotificationTemplate)
 		tenant.PUT("/notifications/templates/:id", controllers.UpdateNotificationTemplate)
 		tenant.DELETE("/notifications/templates/:id", controllers.DeleteNotificationTemplate)
 		tenant.POST("/notifications/send", controllers.SendNotification)
+		tenant.GET("/notifications/logs", controllers.ListNotificationLogs)
+		tenant.POST("/notifications/logs/:id/retry", controllers.RetryNotification)
 		
 		// Customer Bulk Operations
 		tenant.POST("/customers/bulk-import", controllers.BulkImportCustomers)
@@ -14,5 +14,8 @@ func PublicRoutes(r *gin.Engine) {
 		
 		// Platform payment settings for subscription payments (public access)
 		public.GET("/platform-payment-settings", controllers.GetPlatformPaymentSettings)
+
+		// Payment gateway webhooks, authenticated by the gateway's signature
+		public.POST("/payment-gateway/:provider/webhook", controllers.PaymentGatewayWebhook)
 	}
 }
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func ReadingAnomalyRoutes(r *gin.Engine) {
+	anomalyController := controllers.NewReadingAnomalyController(config.DB)
+
+	anomalies := r.Group("/api/reading-anomalies")
+	anomalies.Use(middleware.JWTAuthMiddleware())
+	{
+		anomalies.GET("", middleware.RequirePermission(constants.PermViewWaterUsage), anomalyController.GetReadingAnomalies)
+		anomalies.GET("/:id", middleware.RequirePermission(constants.PermViewWaterUsage), anomalyController.GetReadingAnomaly)
+
+		// Review actions (admin only)
+		anomalies.POST("/:id/investigate", middleware.AdminOnly(), anomalyController.InvestigateReadingAnomaly)
+		anomalies.POST("/:id/resolve", middleware.AdminOnly(), anomalyController.ResolveReadingAnomaly)
+		anomalies.POST("/:id/ignore", middleware.AdminOnly(), anomalyController.IgnoreReadingAnomaly)
+		anomalies.POST("/:id/correct", middleware.AdminOnly(), anomalyController.CorrectReadingAnomaly)
+	}
+}
@@ -0,0 +1,48 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func ReadingRouteRoutes(r *gin.Engine) {
+	routeController := controllers.NewReadingRouteController(config.DB)
+	sessionController := controllers.NewReadingSessionController(config.DB)
+
+	routes := r.Group("/api/reading-routes")
+	routes.Use(middleware.JWTAuthMiddleware())
+	{
+		routes.GET("", middleware.RequirePermission(constants.PermViewWaterUsage), routeController.GetReadingRoutes)
+		routes.GET("/:id", middleware.RequirePermission(constants.PermViewWaterUsage), routeController.GetReadingRoute)
+		routes.GET("/:id/customers", middleware.RequirePermission(constants.PermViewWaterUsage), routeController.GetRouteCustomers)
+
+		// Route management (admin only)
+		routes.POST("", middleware.AdminOnly(), routeController.CreateReadingRoute)
+		routes.PUT("/:id", middleware.AdminOnly(), routeController.UpdateReadingRoute)
+		routes.PUT("/:id/customers", middleware.AdminOnly(), routeController.UpdateRouteCustomers)
+		routes.DELETE("/:id", middleware.AdminOnly(), routeController.DeleteReadingRoute)
+	}
+
+	sessions := r.Group("/api/reading-sessions")
+	sessions.Use(middleware.JWTAuthMiddleware())
+	{
+		sessions.GET("", middleware.RequirePermission(constants.PermViewWaterUsage), sessionController.GetReadingSessions)
+		sessions.GET("/my", middleware.RequirePermission(constants.PermRecordWaterUsage), sessionController.GetMyReadingSessions)
+		sessions.GET("/:id", middleware.RequirePermission(constants.PermViewWaterUsage), sessionController.GetReadingSession)
+
+		// Field work for the assigned meter reader
+		sessions.GET("/:id/worklist", middleware.RequirePermission(constants.PermRecordWaterUsage), sessionController.GetSessionWorklist)
+		sessions.POST("/:id/readings", middleware.RequirePermission(constants.PermRecordWaterUsage), sessionController.RecordSessionReading)
+		sessions.POST("/:id/estimates", middleware.RequirePermission(constants.PermRecordWaterUsage), sessionController.EstimateSessionReading)
+		sessions.POST("/:id/sync", middleware.RequirePermission(constants.PermRecordWaterUsage), sessionController.SyncSessionReadings)
+		sessions.POST("/:id/photos", middleware.RequirePermission(constants.PermRecordWaterUsage), sessionController.UploadReadingPhoto)
+		sessions.POST("/:id/complete", middleware.RequirePermission(constants.PermRecordWaterUsage), sessionController.CompleteReadingSession)
+
+		// Session management (admin only)
+		sessions.POST("", middleware.AdminOnly(), sessionController.StartReadingSession)
+		sessions.POST("/:id/cancel", middleware.AdminOnly(), sessionController.CancelReadingSession)
+	}
+}
@@ -12,6 +12,7 @@ func WaterUsageRoutes(r *gin.Engine) {
 	group.Use(middleware.JWTAuthMiddleware(), middleware.AdminOnly())
 
 	group.POST("", controllers.CreateWaterUsage)
+	group.POST("estimate", controllers.EstimateWaterUsage)
 	group.GET("", controllers.GetWaterUsages)
 	group.GET(":id", controllers.GetWaterUsageByID)
 	group.PUT(":id", controllers.UpdateWaterUsage)
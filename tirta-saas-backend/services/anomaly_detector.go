@@ -0,0 +1,158 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+)
+
+// Anomaly detection defaults
+const (
+	anomalyLookbackMonths  = 6    // months of history used for the rolling average
+	anomalyMinHistory      = 3    // fewer months than this only use the absolute ceiling
+	anomalyStdDevThreshold = 2.5  // flag readings further than this many standard deviations from the mean
+	anomalyMinRelativeDiff = 0.5  // and at least 50% away from the mean, so stable customers aren't flagged for small changes
+	anomalyUsageCeiling    = 1000 // m3 per month that always needs a review
+)
+
+// UsageStats summarizes a customer's recent consumption
+type UsageStats struct {
+	Months       int     `json:"months"`
+	Average      float64 `json:"average"`
+	StdDev       float64 `json:"std_dev"`
+	PreviousUsed float64 `json:"previous_used"`
+	HasPrevious  bool    `json:"has_previous"`
+}
+
+// AnomalyDetector compares readings with the customer's rolling history
+type AnomalyDetector struct{}
+
+// NewAnomalyDetector creates new anomaly detector
+func NewAnomalyDetector() *AnomalyDetector {
+	return &AnomalyDetector{}
+}
+
+// UnresolvedAnomalyStatuses are the anomaly statuses that hold a reading back from invoicing
+var UnresolvedAnomalyStatuses = []string{models.AnomalyStatusPending, models.AnomalyStatusInvestigating}
+
+// HistoryStats returns usage statistics of the months before usageMonth. Estimated readings are
+// left out so that an estimate never becomes the baseline for the next one.
+func (d *AnomalyDetector) HistoryStats(tenantID, customerID uuid.UUID, usageMonth string) UsageStats {
+	var history []models.WaterUsage
+	config.DB.Where("tenant_id = ? AND customer_id = ? AND usage_month < ? AND reading_method <> ?",
+		tenantID, customerID, usageMonth, models.ReadingMethodEstimated).
+		Order("usage_month DESC").Limit(anomalyLookbackMonths).Find(&history)
+
+	stats := UsageStats{Months: len(history)}
+	if len(history) == 0 {
+		return stats
+	}
+
+	stats.PreviousUsed = history[0].UsageM3
+	stats.HasPrevious = true
+
+	sum := 0.0
+	for _, h := range history {
+		sum += h.UsageM3
+	}
+	stats.Average = sum / float64(len(history))
+
+	variance := 0.0
+	for _, h := range history {
+		variance += (h.UsageM3 - stats.Average) * (h.UsageM3 - stats.Average)
+	}
+	stats.StdDev = math.Sqrt(variance / float64(len(history)))
+
+	return stats
+}
+
+// Detect checks a reading and returns an unsaved anomaly, or nil when the reading looks normal
+func (d *AnomalyDetector) Detect(usage *models.WaterUsage) *models.ReadingAnomaly {
+	// Estimates are produced from history, so comparing them with history tells nothing
+	if usage.ReadingMethod == models.ReadingMethodEstimated {
+		return nil
+	}
+
+	stats := d.HistoryStats(usage.TenantID, usage.CustomerID, usage.UsageMonth)
+
+	newAnomaly := func(anomalyType string, expected float64, notes string) *models.ReadingAnomaly {
+		return &models.ReadingAnomaly{
+			TenantID:      usage.TenantID,
+			WaterUsageID:  usage.ID,
+			AnomalyType:   anomalyType,
+			ExpectedValue: math.Round(expected*100) / 100,
+			ActualValue:   usage.UsageM3,
+			Deviation:     math.Round((usage.UsageM3-expected)*100) / 100,
+			Status:        models.AnomalyStatusPending,
+			Notes:         notes,
+		}
+	}
+
+	if usage.UsageM3 < 0 {
+		return newAnomaly(models.AnomalyTypeNegative, stats.Average, "Pemakaian negatif")
+	}
+
+	if usage.UsageM3 > anomalyUsageCeiling {
+		return newAnomaly(models.AnomalyTypeHighUsage, stats.Average,
+			fmt.Sprintf("Pemakaian melebihi batas wajar %d m³/bulan", anomalyUsageCeiling))
+	}
+
+	if stats.Months < anomalyMinHistory || stats.Average <= 0 {
+		return nil
+	}
+
+	if usage.UsageM3 == 0 {
+		if stats.HasPrevious && stats.PreviousUsed == 0 {
+			return newAnomaly(models.AnomalyTypeStuck, stats.Average, "Meter tidak bergerak dua bulan berturut-turut")
+		}
+		return newAnomaly(models.AnomalyTypeNoUsage, stats.Average, "Tidak ada pemakaian, biasanya ada pemakaian")
+	}
+
+	diff := usage.UsageM3 - stats.Average
+	if math.Abs(diff) < anomalyStdDevThreshold*stats.StdDev || math.Abs(diff) < anomalyMinRelativeDiff*stats.Average {
+		return nil
+	}
+
+	notes := fmt.Sprintf("%.0f%% dari rata-rata %d bulan (%.2f m³, simpangan baku %.2f)",
+		usage.UsageM3/stats.Average*100, stats.Months, stats.Average, stats.StdDev)
+	if diff > 0 {
+		return newAnomaly(models.AnomalyTypeHighUsage, stats.Average, notes)
+	}
+	return newAnomaly(models.AnomalyTypeLowUsage, stats.Average, notes)
+}
+
+// Evaluate replaces any unresolved anomaly of the reading with a fresh detection result and
+// keeps WaterUsage.IsAnomaly in sync. It returns the new anomaly, if any.
+func (d *AnomalyDetector) Evaluate(usage *models.WaterUsage) (*models.ReadingAnomaly, error) {
+	if err := config.DB.Where("water_usage_id = ? AND status IN ?", usage.ID, UnresolvedAnomalyStatuses).
+		Delete(&models.ReadingAnomaly{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to clear previous anomaly: %w", err)
+	}
+
+	anomaly := d.Detect(usage)
+	usage.IsAnomaly = anomaly != nil
+
+	if anomaly != nil {
+		if err := config.DB.Create(anomaly).Error; err != nil {
+			return nil, fmt.Errorf("failed to save anomaly: %w", err)
+		}
+	}
+
+	if err := config.DB.Model(&models.WaterUsage{}).Where("id = ?", usage.ID).Update("is_anomaly", usage.IsAnomaly).Error; err != nil {
+		return nil, err
+	}
+
+	return anomaly, nil
+}
+
+// HasUnresolvedAnomaly reports whether a reading still waits for review
+func HasUnresolvedAnomaly(usageID uuid.UUID) bool {
+	var count int64
+	config.DB.Model(&models.ReadingAnomaly{}).
+		Where("water_usage_id = ? AND status IN ?", usageID, UnresolvedAnomalyStatuses).
+		Count(&count)
+	return count > 0
+}
@@ -0,0 +1,397 @@
+package services
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrBankAccountNotFound     = errors.New("Rekening bank tidak ditemukan")
+	ErrStatementLineNotFound   = errors.New("Baris mutasi tidak ditemukan")
+	ErrStatementLineReconciled = errors.New("Baris mutasi sudah dicocokkan atau diabaikan")
+	ErrStatementAmountMismatch = errors.New("Nominal mutasi tidak sama dengan nominal bukti transfer")
+	ErrStatementMatchTarget    = errors.New("Pilih tepat satu tagihan atau pembayaran untuk dicocokkan")
+)
+
+// How many days a transfer proof may be uploaded before or after the bank booked the transfer and
+// still be suggested for it on the amount alone
+const (
+	proofDaysBeforeTransfer = 3
+	proofDaysAfterTransfer  = 7
+)
+
+// StatementImportInput is a bank mutation file uploaded for one of the tenant's bank accounts
+type StatementImportInput struct {
+	TenantID      uuid.UUID
+	BankAccountID uuid.UUID
+	Format        string
+	FileName      string
+	Data          []byte
+	ImportedBy    uuid.UUID
+}
+
+// StatementMatchInput is the invoice or pending payment finance picked for an exception line
+type StatementMatchInput struct {
+	InvoiceID *uuid.UUID
+	PaymentID *uuid.UUID
+}
+
+// statementMatch is what a statement line was matched to
+type statementMatch struct {
+	kind             string
+	paymentID        *uuid.UUID // pending transfer proof to approve
+	invoiceID        *uuid.UUID // invoice to post a new payment to
+	suggested        *uuid.UUID
+	suggestedPayment *uuid.UUID
+	note             string
+}
+
+// BankReconciliationService imports bank statements and matches the incoming transfers to
+// payments. A line is posted automatically only when its description or reference names exactly
+// one pending transfer proof or open invoice; everything else, including lines that only fit on
+// amount and date, is left on the exceptions list for finance with a suggestion where there is one.
+type BankReconciliationService struct {
+	notifier *BillingNotifier
+}
+
+// NewBankReconciliationService creates new bank reconciliation service
+func NewBankReconciliationService() *BankReconciliationService {
+	return &BankReconciliationService{
+		notifier: NewBillingNotifier(),
+	}
+}
+
+// Import reads a bank statement, stores its incoming transfers and reconciles them. Lines that were
+// already imported with an earlier, overlapping statement are skipped.
+func (s *BankReconciliationService) Import(input StatementImportInput) (*models.BankStatementImport, error) {
+	var account models.BankAccount
+	if err := config.DB.Where("id = ? AND tenant_id = ?", input.BankAccountID, input.TenantID).First(&account).Error; err != nil {
+		return nil, ErrBankAccountNotFound
+	}
+
+	format, entries, err := ParseBankStatement(input.Format, input.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := models.BankStatementImport{
+		TenantID:      input.TenantID,
+		BankAccountID: account.ID,
+		Format:        format,
+		FileName:      input.FileName,
+		ImportedBy:    &input.ImportedBy,
+	}
+	if err := config.DB.Create(&statement).Error; err != nil {
+		return nil, err
+	}
+
+	methodID := bankTransferMethod(input.TenantID)
+	occurrences := make(map[string]int)
+
+	for _, entry := range entries {
+		if entry.Credit <= 0 {
+			statement.SkippedLines++
+			continue
+		}
+
+		// Identical transfers on the same day are told apart by their position in the file
+		key := fmt.Sprintf("%s|%.2f|%s|%s", entry.Date.Format("2006-01-02"), entry.Credit, entry.Description, entry.Reference)
+		occurrences[key]++
+		fingerprint := statementFingerprint(account.ID, key, occurrences[key])
+
+		var existing int64
+		config.DB.Model(&models.BankStatementLine{}).
+			Where("bank_account_id = ? AND fingerprint = ?", account.ID, fingerprint).Count(&existing)
+		if existing > 0 {
+			statement.DuplicateLines++
+			continue
+		}
+
+		line := models.BankStatementLine{
+			TenantID:        input.TenantID,
+			ImportID:        statement.ID,
+			BankAccountID:   account.ID,
+			LineNumber:      entry.LineNumber,
+			TransactionDate: entry.Date,
+			Description:     truncate(entry.Description, 500),
+			ReferenceNumber: truncate(entry.Reference, 100),
+			Amount:          entry.Credit,
+			Fingerprint:     fingerprint,
+			Status:          models.StatementLineException,
+		}
+		if err := config.DB.Create(&line).Error; err != nil {
+			log.Printf("❌ Failed to store statement line %d: %v", entry.LineNumber, err)
+			statement.SkippedLines++
+			continue
+		}
+
+		statement.TotalLines++
+		statement.TotalCredit = roundMoney(statement.TotalCredit + line.Amount)
+
+		if s.autoMatch(&line, input.ImportedBy, methodID) {
+			statement.MatchedLines++
+		} else {
+			statement.ExceptionLines++
+		}
+	}
+
+	if err := config.DB.Model(&statement).Updates(map[string]interface{}{
+		"total_lines":     statement.TotalLines,
+		"matched_lines":   statement.MatchedLines,
+		"exception_lines": statement.ExceptionLines,
+		"skipped_lines":   statement.SkippedLines,
+		"duplicate_lines": statement.DuplicateLines,
+		"total_credit":    statement.TotalCredit,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	log.Printf("🏦 Imported %s statement for tenant %s: %d transfers, %d matched, %d exceptions, %d duplicates",
+		format, input.TenantID, statement.TotalLines, statement.MatchedLines, statement.ExceptionLines, statement.DuplicateLines)
+	return &statement, nil
+}
+
+// autoMatch posts a line when it has a confident match and otherwise records why it was left as an
+// exception. It reports whether the line was matched.
+func (s *BankReconciliationService) autoMatch(line *models.BankStatementLine, userID uuid.UUID, methodID *uuid.UUID) bool {
+	match := findStatementMatch(*line)
+	if match.paymentID == nil && match.invoiceID == nil {
+		config.DB.Model(line).Updates(map[string]interface{}{
+			"suggested_invoice_id": match.suggested,
+			"suggested_payment_id": match.suggestedPayment,
+			"notes":                match.note,
+		})
+		return false
+	}
+
+	if err := s.post(line, match, userID, methodID); err != nil {
+		config.DB.Model(line).Updates(map[string]interface{}{
+			"suggested_invoice_id": match.invoiceID,
+			"notes":                truncate("Gagal diposting otomatis: "+err.Error(), 255),
+		})
+		return false
+	}
+	return true
+}
+
+// findStatementMatch looks for the payment a line pays. Only a reference is confident enough to
+// post: a pending transfer proof whose reference is on the line, or an open invoice whose number
+// is in the description. A transfer proof uploaded around the transfer date or an open invoice of
+// the same amount is only suggested, since another customer may well transfer the same amount.
+func findStatementMatch(line models.BankStatementLine) statementMatch {
+	text := strings.ToUpper(line.Description + " " + line.ReferenceNumber)
+
+	var pending []models.Payment
+	config.DB.Where("tenant_id = ? AND status = ? AND amount = ?", line.TenantID, models.PaymentRecordPending, line.Amount).
+		Order("created_at ASC").Find(&pending)
+
+	for _, payment := range pending {
+		reference := strings.ToUpper(strings.TrimSpace(payment.ReferenceNumber))
+		if len(reference) >= 4 && strings.Contains(text, reference) {
+			return statementMatch{kind: models.StatementMatchPendingPayment, paymentID: &payment.ID}
+		}
+	}
+
+	var byNumber []models.Invoice
+	config.DB.Where("tenant_id = ? AND is_paid = ? AND status <> ? AND invoice_number <> '' AND ? LIKE CONCAT('%', UPPER(invoice_number), '%')",
+		line.TenantID, false, models.InvoiceStatusVoid, text).Find(&byNumber)
+	if len(byNumber) == 1 {
+		return statementMatch{kind: models.StatementMatchReference, invoiceID: &byNumber[0].ID}
+	}
+
+	var byAmount []models.Invoice
+	config.DB.Where("tenant_id = ? AND is_paid = ? AND status <> ? AND total_amount - total_paid BETWEEN ? AND ?",
+		line.TenantID, false, models.InvoiceStatusVoid, line.Amount-0.005, line.Amount+0.005).Find(&byAmount)
+
+	var aroundTransfer []models.Payment
+	from := line.TransactionDate.AddDate(0, 0, -proofDaysBeforeTransfer)
+	to := line.TransactionDate.AddDate(0, 0, proofDaysAfterTransfer+1)
+	for _, payment := range pending {
+		if !payment.CreatedAt.Before(from) && payment.CreatedAt.Before(to) {
+			aroundTransfer = append(aroundTransfer, payment)
+		}
+	}
+
+	match := statementMatch{}
+	switch {
+	case len(aroundTransfer) == 1:
+		match.suggestedPayment = &aroundTransfer[0].ID
+		match.note = "Hanya cocok berdasarkan nominal dan tanggal bukti transfer, periksa pengirim"
+	case len(aroundTransfer) > 1:
+		match.note = fmt.Sprintf("%d bukti transfer dengan nominal yang sama", len(aroundTransfer))
+	case len(byAmount) == 1:
+		match.suggested = &byAmount[0].ID
+		match.note = "Hanya cocok berdasarkan nominal, periksa pengirim"
+	case len(byAmount) > 1:
+		match.note = fmt.Sprintf("%d tagihan dengan nominal yang sama", len(byAmount))
+	default:
+		match.note = "Tidak ada tagihan atau bukti transfer yang cocok"
+	}
+	return match
+}
+
+// post applies a match in one transaction that locks the line, so a line is never posted twice
+func (s *BankReconciliationService) post(line *models.BankStatementLine, match statementMatch, userID uuid.UUID, methodID *uuid.UUID) error {
+	var payment *models.Payment
+	var invoice *models.Invoice
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND tenant_id = ?", line.ID, line.TenantID).First(line).Error; err != nil {
+			return ErrStatementLineNotFound
+		}
+		if line.Status != models.StatementLineException {
+			return ErrStatementLineReconciled
+		}
+
+		note := fmt.Sprintf("Mutasi bank %s: %s", line.TransactionDate.Format("02/01/2006"), line.Description)
+
+		var err error
+		if match.paymentID != nil {
+			payment, invoice, err = approvePayment(tx, line.TenantID, *match.paymentID, userID, "")
+		} else {
+			if err := validatePaymentAmount(line.Amount); err != nil {
+				return err
+			}
+			reference := line.ReferenceNumber
+			if reference == "" {
+				reference = truncate(line.Description, 100)
+			}
+			payment, invoice, err = postPayment(tx, PostPaymentInput{
+				TenantID:        line.TenantID,
+				InvoiceID:       *match.invoiceID,
+				Amount:          line.Amount,
+				Notes:           note,
+				PaymentMethodID: methodID,
+				ReferenceNumber: reference,
+				ReceivedBy:      &userID,
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		line.Status = models.StatementLineMatched
+		line.MatchType = match.kind
+		line.PaymentID = &payment.ID
+		line.InvoiceID = &invoice.ID
+		line.MatchedBy = &userID
+		line.MatchedAt = &now
+		return tx.Model(line).Updates(map[string]interface{}{
+			"status":     line.Status,
+			"match_type": line.MatchType,
+			"payment_id": payment.ID,
+			"invoice_id": invoice.ID,
+			"matched_by": userID,
+			"matched_at": now,
+			"notes":      "",
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.notifier.PaymentReceived(*payment, *invoice)
+	return nil
+}
+
+// MatchLine posts an exception line to the invoice or pending transfer proof finance picked
+func (s *BankReconciliationService) MatchLine(tenantID, lineID, userID uuid.UUID, input StatementMatchInput) (*models.BankStatementLine, error) {
+	if (input.InvoiceID == nil) == (input.PaymentID == nil) {
+		return nil, ErrStatementMatchTarget
+	}
+
+	var line models.BankStatementLine
+	if err := config.DB.Where("id = ? AND tenant_id = ?", lineID, tenantID).First(&line).Error; err != nil {
+		return nil, ErrStatementLineNotFound
+	}
+
+	if input.PaymentID != nil {
+		var payment models.Payment
+		if err := config.DB.Where("id = ? AND tenant_id = ?", *input.PaymentID, tenantID).First(&payment).Error; err != nil {
+			return nil, ErrPaymentNotFound
+		}
+		if roundMoney(payment.Amount) != roundMoney(line.Amount) {
+			return nil, ErrStatementAmountMismatch
+		}
+	}
+
+	match := statementMatch{kind: models.StatementMatchManual, paymentID: input.PaymentID, invoiceID: input.InvoiceID}
+	if err := s.post(&line, match, userID, bankTransferMethod(tenantID)); err != nil {
+		return nil, err
+	}
+
+	s.recountImport(line.ImportID)
+	return &line, nil
+}
+
+// IgnoreLine takes a line that is not a customer payment off the exceptions list
+func (s *BankReconciliationService) IgnoreLine(tenantID, lineID, userID uuid.UUID, notes string) (*models.BankStatementLine, error) {
+	var line models.BankStatementLine
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND tenant_id = ?", lineID, tenantID).First(&line).Error; err != nil {
+			return ErrStatementLineNotFound
+		}
+		if line.Status != models.StatementLineException {
+			return ErrStatementLineReconciled
+		}
+
+		now := time.Now()
+		line.Status = models.StatementLineIgnored
+		line.Notes = notes
+		line.MatchedBy = &userID
+		line.MatchedAt = &now
+		return tx.Model(&line).Updates(map[string]interface{}{
+			"status":     line.Status,
+			"notes":      notes,
+			"matched_by": userID,
+			"matched_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.recountImport(line.ImportID)
+	return &line, nil
+}
+
+// recountImport refreshes the matched and exception counts of an import after manual work
+func (s *BankReconciliationService) recountImport(importID uuid.UUID) {
+	var matched, exceptions int64
+	config.DB.Model(&models.BankStatementLine{}).Where("import_id = ? AND status = ?", importID, models.StatementLineMatched).Count(&matched)
+	config.DB.Model(&models.BankStatementLine{}).Where("import_id = ? AND status = ?", importID, models.StatementLineException).Count(&exceptions)
+	config.DB.Model(&models.BankStatementImport{}).Where("id = ?", importID).Updates(map[string]interface{}{
+		"matched_lines":   matched,
+		"exception_lines": exceptions,
+	})
+}
+
+// bankTransferMethod returns the tenant's active bank transfer payment method, if any, to record
+// reconciled transfers with
+func bankTransferMethod(tenantID uuid.UUID) *uuid.UUID {
+	var method models.PaymentMethod
+	if err := config.DB.Where("tenant_id = ? AND type = ? AND is_active = ?", tenantID, models.PaymentMethodTypeBankTransfer, true).
+		Order("display_order ASC").First(&method).Error; err != nil {
+		return nil
+	}
+	return &method.ID
+}
+
+func statementFingerprint(bankAccountID uuid.UUID, key string, occurrence int) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s|%s|%d", bankAccountID, key, occurrence)))
+	return hex.EncodeToString(sum[:])
+}
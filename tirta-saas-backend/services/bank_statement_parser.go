@@ -0,0 +1,300 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+)
+
+var ErrStatementFormatUnknown = errors.New("Format mutasi bank tidak dikenali, pastikan file memiliki baris judul kolom tanggal dan nominal")
+
+// StatementEntry is one mutation read from a bank statement file
+type StatementEntry struct {
+	LineNumber  int
+	Date        time.Time
+	Description string
+	Reference   string
+	Credit      float64
+	Debit       float64
+}
+
+// Header names of the statement columns, lower case. Covers the CSV exports of KlikBCA Bisnis,
+// Mandiri MCM, BRI CMS and BNIDirect as well as hand made spreadsheets.
+var statementColumnNames = map[string][]string{
+	"date":        {"tanggal", "tanggal transaksi", "tgl", "tgl transaksi", "tgl_tran", "tanggal mutasi", "date", "transaction date", "post date", "posting date"},
+	"description": {"keterangan", "deskripsi", "uraian", "uraian transaksi", "desk_tran", "description", "transaction description", "remark", "remarks"},
+	"reference":   {"referensi", "no referensi", "no. referensi", "no ref", "no. ref", "ref", "reference", "reference no", "reference no.", "journal no", "journal no."},
+	"credit":      {"kredit", "credit", "mutasi kredit", "mutasi_kredit", "credit amount", "cr"},
+	"debit":       {"debet", "debit", "mutasi debet", "mutasi_debet", "debit amount", "db"},
+	"amount":      {"jumlah", "amount", "nominal", "mutasi"},
+	"direction":   {"d/k", "db/cr", "dk", "cr/db", "jenis", "type"},
+}
+
+// ParseBankStatement reads the mutations of a bank statement file. With AUTO the bank is detected
+// from the column headers. Dates are read day first, as all Indonesian banks export them.
+func ParseBankStatement(format string, data []byte) (string, []StatementEntry, error) {
+	records, err := readStatementRecords(data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	headerRow := -1
+	var columns map[string][]int
+	for i := 0; i < len(records) && i < 50; i++ {
+		found := statementColumns(records[i])
+		if len(found["date"]) > 0 && (len(found["credit"]) > 0 || len(found["amount"]) > 0) {
+			headerRow, columns = i, found
+			break
+		}
+	}
+	if headerRow < 0 {
+		return "", nil, ErrStatementFormatUnknown
+	}
+
+	if format == "" || format == models.BankStatementFormatAuto {
+		format = detectStatementFormat(records[headerRow])
+	}
+
+	// KlikBCA leaves the year out of the transaction date, it is in the period above the table
+	defaultYear := time.Now().Year()
+	for _, record := range records[:headerRow] {
+		if year := statementYear(strings.Join(record, " ")); year > 0 {
+			defaultYear = year
+		}
+	}
+
+	var entries []StatementEntry
+	for i := headerRow + 1; i < len(records); i++ {
+		record := records[i]
+
+		date, ok := parseStatementDate(cell(record, columns["date"]), defaultYear)
+		if !ok {
+			// Opening and closing balance rows, totals and pending (PEND) mutations
+			continue
+		}
+
+		entry := StatementEntry{
+			LineNumber:  i + 1,
+			Date:        date,
+			Description: cell(record, columns["description"]),
+			Reference:   cell(record, columns["reference"]),
+		}
+
+		if len(columns["credit"]) > 0 || len(columns["debit"]) > 0 {
+			entry.Credit, _ = parseStatementAmount(cell(record, columns["credit"]))
+			entry.Debit, _ = parseStatementAmount(cell(record, columns["debit"]))
+		} else {
+			raw := cell(record, columns["amount"])
+			amount, debit := parseStatementAmount(raw)
+			direction := strings.ToUpper(cell(record, columns["direction"]))
+			if direction == "" {
+				direction = strings.ToUpper(unnamedDirection(records[headerRow], record, columns["amount"]))
+			}
+			if strings.HasPrefix(direction, "D") {
+				debit = true
+			}
+			if debit {
+				entry.Debit = amount
+			} else {
+				entry.Credit = amount
+			}
+		}
+
+		if entry.Credit == 0 && entry.Debit == 0 {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return format, entries, nil
+}
+
+// readStatementRecords splits the file into rows, detecting comma, semicolon or tab separation
+func readStatementRecords(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	sample := data
+	if len(sample) > 4096 {
+		sample = sample[:4096]
+	}
+	separator := ','
+	best := bytes.Count(sample, []byte(","))
+	for _, candidate := range []rune{';', '\t'} {
+		if count := bytes.Count(sample, []byte(string(candidate))); count > best {
+			separator, best = candidate, count
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = separator
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("file mutasi tidak dapat dibaca: %w", err)
+	}
+	return records, nil
+}
+
+// statementColumns returns the indexes of the known columns in a header row
+func statementColumns(record []string) map[string][]int {
+	columns := make(map[string][]int)
+	for i, value := range record {
+		name := strings.ToLower(strings.TrimSpace(value))
+		for column, names := range statementColumnNames {
+			for _, known := range names {
+				if name == known {
+					columns[column] = append(columns[column], i)
+				}
+			}
+		}
+	}
+	return columns
+}
+
+// unnamedDirection is the DB/CR marker KlikBCA puts in the column without header right after the
+// amount
+func unnamedDirection(header, record []string, amountColumns []int) string {
+	if len(amountColumns) == 0 {
+		return ""
+	}
+	next := amountColumns[len(amountColumns)-1] + 1
+	if next >= len(header) || strings.TrimSpace(header[next]) != "" {
+		return ""
+	}
+	return cell(record, []int{next})
+}
+
+func detectStatementFormat(header []string) string {
+	joined := strings.ToLower(strings.Join(header, "|"))
+	switch {
+	case strings.Contains(joined, "cabang") && strings.Contains(joined, "jumlah"):
+		return models.BankStatementFormatBCA
+	case strings.Contains(joined, "account no") && strings.Contains(joined, "val. date"):
+		return models.BankStatementFormatMandiri
+	case strings.Contains(joined, "tgl_tran") || strings.Contains(joined, "mutasi_kredit"):
+		return models.BankStatementFormatBRI
+	case strings.Contains(joined, "journal no"):
+		return models.BankStatementFormatBNI
+	}
+	return models.BankStatementFormatCSV
+}
+
+// cell joins the values of the given columns, e.g. the two description columns of Mandiri
+func cell(record []string, indexes []int) string {
+	var parts []string
+	for _, index := range indexes {
+		if index < len(record) {
+			if value := strings.TrimSpace(record[index]); value != "" {
+				parts = append(parts, value)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+var statementDateLayouts = []string{
+	"02/01/2006", "02/01/06", "2006-01-02", "02-01-2006", "02-01-06", "02.01.2006",
+	"02 Jan 2006", "02-Jan-2006", "02-Jan-06", "2 Jan 2006",
+	"02/01/2006 15:04:05", "02/01/2006 15:04", "2006-01-02 15:04:05", "02-01-2006 15:04:05",
+	"02/01/06 15:04", "02-Jan-2006 15:04:05",
+}
+
+var yearPattern = regexp.MustCompile(`\b(20\d{2})\b`)
+
+func statementYear(text string) int {
+	matches := yearPattern.FindAllString(text, -1)
+	if len(matches) == 0 {
+		return 0
+	}
+	year, _ := strconv.Atoi(matches[len(matches)-1])
+	return year
+}
+
+func parseStatementDate(value string, defaultYear int) (time.Time, bool) {
+	value = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "'"))
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	for _, layout := range statementDateLayouts {
+		if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return truncateToDay(date), true
+		}
+	}
+
+	// dd/mm without the year
+	if date, err := time.ParseInLocation("02/01", value, time.Local); err == nil {
+		return time.Date(defaultYear, date.Month(), date.Day(), 0, 0, 0, 0, time.Local), true
+	}
+	return time.Time{}, false
+}
+
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// parseStatementAmount reads an amount written with either Indonesian (1.500.000,00) or English
+// (1,500,000.00) separators. A trailing DB, D or a minus sign marks a debit.
+func parseStatementAmount(value string) (float64, bool) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	value = strings.TrimPrefix(value, "RP")
+	value = strings.TrimSpace(value)
+
+	debit := false
+	switch {
+	case strings.HasSuffix(value, "DB"):
+		debit, value = true, strings.TrimSuffix(value, "DB")
+	case strings.HasSuffix(value, "CR"):
+		value = strings.TrimSuffix(value, "CR")
+	case strings.HasSuffix(value, "D"):
+		debit, value = true, strings.TrimSuffix(value, "D")
+	case strings.HasSuffix(value, "K"), strings.HasSuffix(value, "C"):
+		value = value[:len(value)-1]
+	}
+	value = strings.ReplaceAll(strings.TrimSpace(value), " ", "")
+	if strings.HasPrefix(value, "-") || (strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")")) {
+		debit = true
+		value = strings.Trim(value, "-()")
+	}
+	if value == "" {
+		return 0, debit
+	}
+
+	lastComma := strings.LastIndex(value, ",")
+	lastDot := strings.LastIndex(value, ".")
+	switch {
+	case lastComma >= 0 && lastDot >= 0:
+		if lastComma > lastDot {
+			value = strings.ReplaceAll(value, ".", "")
+			value = strings.Replace(value, ",", ".", 1)
+		} else {
+			value = strings.ReplaceAll(value, ",", "")
+		}
+	case lastComma >= 0:
+		if strings.Count(value, ",") == 1 && len(value)-lastComma-1 <= 2 {
+			value = strings.Replace(value, ",", ".", 1)
+		} else {
+			value = strings.ReplaceAll(value, ",", "")
+		}
+	case lastDot >= 0:
+		if strings.Count(value, ".") > 1 || len(value)-lastDot-1 == 3 {
+			value = strings.ReplaceAll(value, ".", "")
+		}
+	}
+
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, debit
+	}
+	return roundMoney(amount), debit
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+)
+
+func TestParseStatementAmount(t *testing.T) {
+	tests := []struct {
+		value  string
+		amount float64
+		debit  bool
+	}{
+		{"1.500.000,00", 1500000, false},
+		{"1,500,000.00", 1500000, false},
+		{"1.500.000", 1500000, false},
+		{"1,500,000", 1500000, false},
+		{"150.000", 150000, false},
+		{"150,000", 150000, false},
+		{"150000", 150000, false},
+		{"150000.5", 150000.5, false},
+		{"150000,50", 150000.5, false},
+		{"1234,5", 1234.5, false},
+		{"Rp 75.000", 75000, false},
+		{"Rp. 75.000", 75000, false},
+		{"75.000,00 CR", 75000, false},
+		{"75.000,00 K", 75000, false},
+		{"75,000.00 C", 75000, false},
+		{"75.000,00 DB", 75000, true},
+		{"75,000.00 D", 75000, true},
+		{"-75.000", 75000, true},
+		{"(75,000.00)", 75000, true},
+		{"", 0, false},
+		{"-", 0, true},
+		{"abc", 0, false},
+	}
+
+	for _, tt := range tests {
+		amount, debit := parseStatementAmount(tt.value)
+		if amount != tt.amount || debit != tt.debit {
+			t.Errorf("parseStatementAmount(%q) = %v, %v; want %v, %v", tt.value, amount, debit, tt.amount, tt.debit)
+		}
+	}
+}
+
+func TestParseStatementDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		ok    bool
+	}{
+		{"05/02/2025", time.Date(2025, 2, 5, 0, 0, 0, 0, time.Local), true},
+		{"'05/02/2025", time.Date(2025, 2, 5, 0, 0, 0, 0, time.Local), true},
+		{"05/02/25", time.Date(2025, 2, 5, 0, 0, 0, 0, time.Local), true},
+		{"2025-02-05", time.Date(2025, 2, 5, 0, 0, 0, 0, time.Local), true},
+		{"05-02-2025", time.Date(2025, 2, 5, 0, 0, 0, 0, time.Local), true},
+		{"05.02.2025", time.Date(2025, 2, 5, 0, 0, 0, 0, time.Local), true},
+		{"05 Feb 2025", time.Date(2025, 2, 5, 0, 0, 0, 0, time.Local), true},
+		{"05-Feb-25", time.Date(2025, 2, 5, 0, 0, 0, 0, time.Local), true},
+		{"05/02/2025 13:45:10", time.Date(2025, 2, 5, 0, 0, 0, 0, time.Local), true},
+		{"2025-02-05 13:45:10", time.Date(2025, 2, 5, 0, 0, 0, 0, time.Local), true},
+		{"05/02", time.Date(2024, 2, 5, 0, 0, 0, 0, time.Local), true},
+		{"29/02", time.Date(2024, 2, 29, 0, 0, 0, 0, time.Local), true},
+		{"PEND", time.Time{}, false},
+		{"Saldo Awal", time.Time{}, false},
+		{"", time.Time{}, false},
+		{"31/02/2025", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseStatementDate(tt.value, 2024)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parseStatementDate(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestStatementColumns(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		want   map[string][]int
+	}{
+		{
+			name:   "bca",
+			header: []string{"Tanggal", "Keterangan", "Cabang", "Jumlah", "", "Saldo"},
+			want:   map[string][]int{"date": {0}, "description": {1}, "amount": {3}},
+		},
+		{
+			name:   "mandiri with two description columns",
+			header: []string{"Account No", "Date", "Val. Date", "Transaction Code", "Description", "Description", "Reference No.", "Debit", "Credit"},
+			want:   map[string][]int{"date": {1}, "description": {4, 5}, "reference": {6}, "debit": {7}, "credit": {8}},
+		},
+		{
+			name:   "bri",
+			header: []string{"TGL_TRAN", "DESK_TRAN", "MUTASI_DEBET", "MUTASI_KREDIT"},
+			want:   map[string][]int{"date": {0}, "description": {1}, "debit": {2}, "credit": {3}},
+		},
+		{
+			name:   "padded and mixed case",
+			header: []string{" Tgl Transaksi ", "URAIAN", "Nominal", "D/K"},
+			want:   map[string][]int{"date": {0}, "description": {1}, "amount": {2}, "direction": {3}},
+		},
+	}
+
+	for _, tt := range tests {
+		got := statementColumns(tt.header)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: statementColumns = %v; want %v", tt.name, got, tt.want)
+			continue
+		}
+		for column, indexes := range tt.want {
+			if len(got[column]) != len(indexes) {
+				t.Errorf("%s: column %s = %v; want %v", tt.name, column, got[column], indexes)
+				continue
+			}
+			for i := range indexes {
+				if got[column][i] != indexes[i] {
+					t.Errorf("%s: column %s = %v; want %v", tt.name, column, got[column], indexes)
+				}
+			}
+		}
+	}
+}
+
+func TestDetectStatementFormat(t *testing.T) {
+	tests := []struct {
+		header []string
+		want   string
+	}{
+		{[]string{"Tanggal", "Keterangan", "Cabang", "Jumlah", "Saldo"}, models.BankStatementFormatBCA},
+		{[]string{"Account No", "Date", "Val. Date", "Description", "Debit", "Credit"}, models.BankStatementFormatMandiri},
+		{[]string{"TGL_TRAN", "DESK_TRAN", "MUTASI_DEBET", "MUTASI_KREDIT"}, models.BankStatementFormatBRI},
+		{[]string{"Post Date", "Description", "Journal No.", "Debit", "Credit"}, models.BankStatementFormatBNI},
+		{[]string{"Tanggal", "Keterangan", "Kredit"}, models.BankStatementFormatCSV},
+	}
+
+	for _, tt := range tests {
+		if got := detectStatementFormat(tt.header); got != tt.want {
+			t.Errorf("detectStatementFormat(%v) = %s; want %s", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestParseBankStatement(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		format  string
+		entries []StatementEntry
+	}{
+		{
+			name: "bca without year in the dates",
+			data: "Informasi Rekening - Mutasi Rekening\n" +
+				"Periode : 01/02/2025 - 28/02/2025\n" +
+				"Tanggal,Keterangan,Cabang,Jumlah,,Saldo\n" +
+				"'05/02,TRSF E-BANKING CR INV-202502-0001,0000,\"150,000.00\",CR,\"1,150,000.00\"\n" +
+				"'06/02,BIAYA ADM,0000,\"10,000.00\",DB,\"1,140,000.00\"\n" +
+				"PEND,TRSF E-BANKING CR,0000,\"20,000.00\",CR,\n" +
+				"Saldo Akhir,,,,,\"1,140,000.00\"\n",
+			format: models.BankStatementFormatBCA,
+			entries: []StatementEntry{
+				{LineNumber: 4, Date: time.Date(2025, 2, 5, 0, 0, 0, 0, time.Local), Description: "TRSF E-BANKING CR INV-202502-0001", Credit: 150000},
+				{LineNumber: 5, Date: time.Date(2025, 2, 6, 0, 0, 0, 0, time.Local), Description: "BIAYA ADM", Debit: 10000},
+			},
+		},
+		{
+			name: "semicolon separated with credit and debit columns",
+			data: "\xef\xbb\xbfTanggal;Keterangan;No Ref;Debet;Kredit\n" +
+				"05/02/2025;Transfer Budi;REF123;;75.000,00\n" +
+				"06/02/2025;Biaya;;5.000,00;\n" +
+				"07/02/2025;Kosong;;;\n",
+			format: models.BankStatementFormatCSV,
+			entries: []StatementEntry{
+				{LineNumber: 2, Date: time.Date(2025, 2, 5, 0, 0, 0, 0, time.Local), Description: "Transfer Budi", Reference: "REF123", Credit: 75000},
+				{LineNumber: 3, Date: time.Date(2025, 2, 6, 0, 0, 0, 0, time.Local), Description: "Biaya", Debit: 5000},
+			},
+		},
+		{
+			name: "amount with direction column",
+			data: "Tanggal,Uraian,Nominal,D/K\n" +
+				"05/02/2025,Setoran,150000,K\n" +
+				"06/02/2025,Tarik,20000,D\n",
+			format: models.BankStatementFormatCSV,
+			entries: []StatementEntry{
+				{LineNumber: 2, Date: time.Date(2025, 2, 5, 0, 0, 0, 0, time.Local), Description: "Setoran", Credit: 150000},
+				{LineNumber: 3, Date: time.Date(2025, 2, 6, 0, 0, 0, 0, time.Local), Description: "Tarik", Debit: 20000},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		format, entries, err := ParseBankStatement(models.BankStatementFormatAuto, []byte(tt.data))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if format != tt.format {
+			t.Errorf("%s: format = %s; want %s", tt.name, format, tt.format)
+		}
+		if len(entries) != len(tt.entries) {
+			t.Errorf("%s: %d entries = %+v; want %d", tt.name, len(entries), entries, len(tt.entries))
+			continue
+		}
+		for i, want := range tt.entries {
+			got := entries[i]
+			if got.LineNumber != want.LineNumber || !got.Date.Equal(want.Date) || got.Description != want.Description ||
+				got.Reference != want.Reference || got.Credit != want.Credit || got.Debit != want.Debit {
+				t.Errorf("%s: entry %d = %+v; want %+v", tt.name, i, got, want)
+			}
+		}
+	}
+
+	if _, _, err := ParseBankStatement(models.BankStatementFormatAuto, []byte("a,b,c\n1,2,3\n")); err != ErrStatementFormatUnknown {
+		t.Errorf("file without header: err = %v; want %v", err, ErrStatementFormatUnknown)
+	}
+}
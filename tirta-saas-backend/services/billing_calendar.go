@@ -0,0 +1,135 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+)
+
+// BillingCalendar tells when a tenant's monthly invoices are generated, which usage month a run
+// bills and when the invoices fall due. All dates are evaluated in the tenant's time zone.
+type BillingCalendar struct {
+	location    *time.Location
+	day         int
+	hour        int
+	monthOffset int
+	dueRule     string
+	dueDays     int
+	dueDay      int
+}
+
+// BillingRun is one scheduled monthly generation
+type BillingRun struct {
+	RunAt      time.Time `json:"run_at"`
+	UsageMonth string    `json:"usage_month"` // YYYY-MM
+	DueDate    time.Time `json:"due_date"`
+}
+
+// NewBillingCalendar creates the billing calendar of tenant settings. An unknown time zone falls
+// back to the server's local time.
+func NewBillingCalendar(settings models.TenantSettings) *BillingCalendar {
+	location := time.Local
+	if settings.TimeZone != "" {
+		loaded, err := time.LoadLocation(settings.TimeZone)
+		if err != nil {
+			log.Printf("⚠️  Unknown time zone %q for tenant %s, using server time", settings.TimeZone, settings.TenantID)
+		} else {
+			location = loaded
+		}
+	}
+
+	calendar := &BillingCalendar{
+		location:    location,
+		day:         settings.BillingDay,
+		hour:        settings.BillingHour,
+		monthOffset: settings.BillingUsageMonthOffset,
+		dueRule:     settings.DueDateRule,
+		dueDays:     settings.InvoiceDueDays,
+		dueDay:      settings.DueDayOfMonth,
+	}
+	if calendar.day < 1 {
+		calendar.day = 1
+	} else if calendar.day > 28 {
+		calendar.day = 28
+	}
+	if calendar.hour < 0 || calendar.hour > 23 {
+		calendar.hour = 0
+	}
+	if calendar.monthOffset < 0 {
+		calendar.monthOffset = 0
+	}
+	if calendar.dueDays <= 0 {
+		calendar.dueDays = 14
+	}
+	return calendar
+}
+
+// Location returns the time zone of the calendar
+func (b *BillingCalendar) Location() *time.Location {
+	return b.location
+}
+
+// RunAt returns the generation time of a month
+func (b *BillingCalendar) RunAt(year int, month time.Month) time.Time {
+	return time.Date(year, month, b.day, b.hour, 0, 0, 0, b.location)
+}
+
+// CurrentRun returns this month's run when it is due at now
+func (b *BillingCalendar) CurrentRun(now time.Time) (time.Time, bool) {
+	local := now.In(b.location)
+	runAt := b.RunAt(local.Year(), local.Month())
+	return runAt, !local.Before(runAt)
+}
+
+// UsageMonth returns the usage month billed by a run
+func (b *BillingCalendar) UsageMonth(runAt time.Time) string {
+	local := runAt.In(b.location)
+	return time.Date(local.Year(), local.Month()-time.Month(b.monthOffset), 1, 0, 0, 0, 0, b.location).Format("2006-01")
+}
+
+// DueDate returns the due date of an invoice issued at issuedAt
+func (b *BillingCalendar) DueDate(issuedAt time.Time) time.Time {
+	local := issuedAt.In(b.location)
+	if b.dueRule != models.DueDateRuleDayOfMonth || b.dueDay < 1 {
+		return local.AddDate(0, 0, b.dueDays)
+	}
+
+	due := time.Date(local.Year(), local.Month(), clampDay(local.Year(), local.Month(), b.dueDay), 0, 0, 0, 0, b.location)
+	if !due.After(truncateToDay(local)) {
+		next := time.Date(local.Year(), local.Month()+1, 1, 0, 0, 0, 0, b.location)
+		due = time.Date(next.Year(), next.Month(), clampDay(next.Year(), next.Month(), b.dueDay), 0, 0, 0, 0, b.location)
+	}
+	return due
+}
+
+// NextRuns returns the next count runs after from, including this month's run when it is still
+// ahead
+func (b *BillingCalendar) NextRuns(from time.Time, count int) []BillingRun {
+	local := from.In(b.location)
+	month := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, b.location)
+	if runAt := b.RunAt(month.Year(), month.Month()); !runAt.After(local) {
+		month = month.AddDate(0, 1, 0)
+	}
+
+	runs := make([]BillingRun, 0, count)
+	for i := 0; i < count; i++ {
+		runAt := b.RunAt(month.Year(), month.Month())
+		runs = append(runs, BillingRun{
+			RunAt:      runAt,
+			UsageMonth: b.UsageMonth(runAt),
+			DueDate:    b.DueDate(runAt),
+		})
+		month = month.AddDate(0, 1, 0)
+	}
+	return runs
+}
+
+// clampDay keeps a day of month within the month, e.g. day 31 is the 30th in April
+func clampDay(year int, month time.Month, day int) int {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day > last {
+		return last
+	}
+	return day
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+)
+
+func TestBillingCalendarCurrentRun(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		day   int
+		now   time.Time
+		runAt time.Time
+		due   bool
+	}{
+		{
+			name:  "before the billing hour",
+			day:   1,
+			now:   time.Date(2025, 3, 1, 5, 59, 0, 0, jakarta),
+			runAt: time.Date(2025, 3, 1, 6, 0, 0, 0, jakarta),
+		},
+		{
+			name:  "at the billing hour",
+			day:   1,
+			now:   time.Date(2025, 3, 1, 6, 0, 0, 0, jakarta),
+			runAt: time.Date(2025, 3, 1, 6, 0, 0, 0, jakarta),
+			due:   true,
+		},
+		{
+			name:  "server in UTC still on the last day of the previous month",
+			day:   1,
+			now:   time.Date(2025, 2, 28, 23, 30, 0, 0, time.UTC),
+			runAt: time.Date(2025, 3, 1, 6, 0, 0, 0, jakarta),
+			due:   true,
+		},
+		{
+			name:  "server in UTC before the billing hour in Jakarta",
+			day:   1,
+			now:   time.Date(2025, 2, 28, 22, 0, 0, 0, time.UTC),
+			runAt: time.Date(2025, 3, 1, 6, 0, 0, 0, jakarta),
+		},
+		{
+			name:  "billing day past the 28th is the 28th",
+			day:   31,
+			now:   time.Date(2025, 2, 28, 12, 0, 0, 0, jakarta),
+			runAt: time.Date(2025, 2, 28, 6, 0, 0, 0, jakarta),
+			due:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		calendar := NewBillingCalendar(models.TenantSettings{TimeZone: "Asia/Jakarta", BillingDay: tt.day, BillingHour: 6})
+		runAt, due := calendar.CurrentRun(tt.now)
+		if !runAt.Equal(tt.runAt) || due != tt.due {
+			t.Errorf("%s: CurrentRun = %v, %v; want %v, %v", tt.name, runAt, due, tt.runAt, tt.due)
+		}
+	}
+}
+
+func TestBillingCalendarUsageMonth(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		offset int
+		runAt  time.Time
+		want   string
+	}{
+		{"previous month", 1, time.Date(2025, 3, 1, 6, 0, 0, 0, jakarta), "2025-02"},
+		{"previous month across the year", 1, time.Date(2025, 1, 1, 0, 0, 0, 0, jakarta), "2024-12"},
+		{"month of the run", 0, time.Date(2025, 1, 28, 0, 0, 0, 0, jakarta), "2025-01"},
+		{"two months back", 2, time.Date(2025, 2, 1, 0, 0, 0, 0, jakarta), "2024-12"},
+		{"run given in UTC is read in the tenant's time zone", 1, time.Date(2025, 2, 28, 17, 30, 0, 0, time.UTC), "2025-02"},
+		{"negative offset is the month of the run", -1, time.Date(2025, 3, 1, 6, 0, 0, 0, jakarta), "2025-03"},
+	}
+
+	for _, tt := range tests {
+		calendar := NewBillingCalendar(models.TenantSettings{TimeZone: "Asia/Jakarta", BillingUsageMonthOffset: tt.offset})
+		if got := calendar.UsageMonth(tt.runAt); got != tt.want {
+			t.Errorf("%s: UsageMonth = %s; want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBillingCalendarDueDate(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		settings models.TenantSettings
+		issuedAt time.Time
+		want     time.Time
+	}{
+		{
+			name:     "days after issue",
+			settings: models.TenantSettings{InvoiceDueDays: 10},
+			issuedAt: time.Date(2025, 1, 25, 6, 0, 0, 0, jakarta),
+			want:     time.Date(2025, 2, 4, 6, 0, 0, 0, jakarta),
+		},
+		{
+			name:     "fourteen days by default",
+			settings: models.TenantSettings{},
+			issuedAt: time.Date(2025, 2, 20, 6, 0, 0, 0, jakarta),
+			want:     time.Date(2025, 3, 6, 6, 0, 0, 0, jakarta),
+		},
+		{
+			name:     "day of month later this month",
+			settings: models.TenantSettings{DueDateRule: models.DueDateRuleDayOfMonth, DueDayOfMonth: 20},
+			issuedAt: time.Date(2025, 3, 1, 6, 0, 0, 0, jakarta),
+			want:     time.Date(2025, 3, 20, 0, 0, 0, 0, jakarta),
+		},
+		{
+			name:     "day of month passed moves to next month",
+			settings: models.TenantSettings{DueDateRule: models.DueDateRuleDayOfMonth, DueDayOfMonth: 20},
+			issuedAt: time.Date(2025, 3, 20, 6, 0, 0, 0, jakarta),
+			want:     time.Date(2025, 4, 20, 0, 0, 0, 0, jakarta),
+		},
+		{
+			name:     "day 31 in February",
+			settings: models.TenantSettings{DueDateRule: models.DueDateRuleDayOfMonth, DueDayOfMonth: 31},
+			issuedAt: time.Date(2025, 1, 31, 6, 0, 0, 0, jakarta),
+			want:     time.Date(2025, 2, 28, 0, 0, 0, 0, jakarta),
+		},
+		{
+			name:     "day 31 in a leap February",
+			settings: models.TenantSettings{DueDateRule: models.DueDateRuleDayOfMonth, DueDayOfMonth: 31},
+			issuedAt: time.Date(2024, 2, 1, 6, 0, 0, 0, jakarta),
+			want:     time.Date(2024, 2, 29, 0, 0, 0, 0, jakarta),
+		},
+		{
+			name:     "day 31 in April",
+			settings: models.TenantSettings{DueDateRule: models.DueDateRuleDayOfMonth, DueDayOfMonth: 31},
+			issuedAt: time.Date(2025, 4, 5, 6, 0, 0, 0, jakarta),
+			want:     time.Date(2025, 4, 30, 0, 0, 0, 0, jakarta),
+		},
+		{
+			name:     "issued late in UTC is already the next day in Jakarta",
+			settings: models.TenantSettings{DueDateRule: models.DueDateRuleDayOfMonth, DueDayOfMonth: 20},
+			issuedAt: time.Date(2025, 3, 19, 18, 0, 0, 0, time.UTC),
+			want:     time.Date(2025, 4, 20, 0, 0, 0, 0, jakarta),
+		},
+	}
+
+	for _, tt := range tests {
+		tt.settings.TimeZone = "Asia/Jakarta"
+		if got := NewBillingCalendar(tt.settings).DueDate(tt.issuedAt); !got.Equal(tt.want) {
+			t.Errorf("%s: DueDate = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+)
+
+// BillingNotificationVariables lists the variables available to each billing notification template
+var BillingNotificationVariables = map[string][]string{
+	models.NotificationCodeInvoiceIssued:   invoiceNotificationVariables,
+	models.NotificationCodePaymentReminder: invoiceNotificationVariables,
+	models.NotificationCodeInvoiceOverdue:  invoiceNotificationVariables,
+	models.NotificationCodePaymentReceived: append(append([]string{}, invoiceNotificationVariables...), "payment_amount", "payment_date"),
+	models.NotificationCodePaymentRejected: append(append([]string{}, invoiceNotificationVariables...), "payment_amount", "payment_date", "rejection_reason"),
+}
+
+var invoiceNotificationVariables = []string{
+	"customer_name", "meter_number", "invoice_number", "usage_month", "usage_m3",
+	"total_amount", "total_paid", "amount_due", "due_date", "days_until_due", "days_overdue",
+	"company_name", "company_phone", "bank_name", "bank_account_name", "bank_account_no",
+}
+
+// ResolveTemplateVariables returns the variables a template with the given code may use. Billing
+// templates default to every variable of their event and may not declare variables the event does
+// not provide; other templates use the declared list as is.
+func ResolveTemplateVariables(code string, declared []string) ([]string, error) {
+	available, isBilling := BillingNotificationVariables[strings.ToUpper(code)]
+	if !isBilling {
+		return declared, nil
+	}
+	if len(declared) == 0 {
+		return available, nil
+	}
+
+	provided := make(map[string]bool, len(available))
+	for _, name := range available {
+		provided[name] = true
+	}
+	for _, name := range declared {
+		if !provided[name] {
+			return nil, fmt.Errorf("variable %s is not provided for %s notifications", name, code)
+		}
+	}
+	return declared, nil
+}
+
+// SampleTemplateVariables returns example values for previewing a template
+func SampleTemplateVariables(names []string) map[string]interface{} {
+	dueDate := time.Now().AddDate(0, 0, 7)
+	samples := map[string]interface{}{
+		"customer_name":     "Budi Santoso",
+		"meter_number":      "MTR-000123",
+		"invoice_number":    "INV-" + time.Now().Format("200601") + "-0001",
+		"usage_month":       time.Now().AddDate(0, -1, 0).Format("2006-01"),
+		"usage_m3":          18.5,
+		"total_amount":      152500.0,
+		"total_paid":        50000.0,
+		"amount_due":        102500.0,
+		"due_date":          dueDate,
+		"days_until_due":    7,
+		"days_overdue":      3,
+		"company_name":      "PDAM Tirta",
+		"company_phone":     "0211234567",
+		"bank_name":         "Bank BRI",
+		"bank_account_name": "PDAM Tirta",
+		"bank_account_no":   "1234567890",
+		"payment_amount":    50000.0,
+		"payment_date":      time.Now(),
+		"rejection_reason":  "Nominal pada bukti transfer tidak sesuai",
+	}
+
+	variables := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		if value, ok := samples[name]; ok {
+			variables[name] = value
+		} else {
+			variables[name] = "[" + name + "]"
+		}
+	}
+	return variables
+}
+
+// BillingNotifier sends the tenant's billing notification templates to customers when invoices
+// are issued, near their due date, become overdue or are paid. Every event is sent at most once.
+type BillingNotifier struct {
+	dispatcher *NotificationDispatcher
+}
+
+// NewBillingNotifier creates new billing notifier
+func NewBillingNotifier() *BillingNotifier {
+	return &BillingNotifier{
+		dispatcher: NewNotificationDispatcher(),
+	}
+}
+
+// billingEvent is one invoice, or payment of an invoice, to notify about
+type billingEvent struct {
+	invoice models.Invoice
+	payment *models.Payment
+}
+
+// InvoicesIssued notifies customers about newly generated invoices
+func (n *BillingNotifier) InvoicesIssued(tenantID uuid.UUID, invoices []models.Invoice) int {
+	return n.notify(tenantID, models.NotificationCodeInvoiceIssued, invoiceEvents(invoices))
+}
+
+// InvoicesOverdue notifies customers about invoices that passed their due date
+func (n *BillingNotifier) InvoicesOverdue(tenantID uuid.UUID, invoices []models.Invoice) int {
+	return n.notify(tenantID, models.NotificationCodeInvoiceOverdue, invoiceEvents(invoices))
+}
+
+// PaymentReceived confirms a payment to the customer
+func (n *BillingNotifier) PaymentReceived(payment models.Payment, invoice models.Invoice) int {
+	return n.notify(invoice.TenantID, models.NotificationCodePaymentReceived, []billingEvent{{invoice: invoice, payment: &payment}})
+}
+
+// PaymentRejected tells the customer an uploaded transfer proof was rejected and why
+func (n *BillingNotifier) PaymentRejected(payment models.Payment, invoice models.Invoice) int {
+	return n.notify(invoice.TenantID, models.NotificationCodePaymentRejected, []billingEvent{{invoice: invoice, payment: &payment}})
+}
+
+// SendPaymentReminders reminds customers of unpaid invoices due within the tenant's reminder period
+func (n *BillingNotifier) SendPaymentReminders(tenantID uuid.UUID) int {
+	var settings models.TenantSettings
+	if err := config.DB.Where("tenant_id = ?", tenantID).First(&settings).Error; err != nil || !settings.NotifyPaymentReminder {
+		return 0
+	}
+
+	days := settings.PaymentReminderDays
+	if days <= 0 {
+		days = 3
+	}
+
+	now := time.Now()
+	var invoices []models.Invoice
+	if err := config.DB.Where("tenant_id = ? AND payment_status IN ? AND due_date > ? AND due_date <= ?",
+		tenantID, []models.PaymentStatus{models.PaymentStatusUnpaid, models.PaymentStatusPartial},
+		now, now.AddDate(0, 0, days)).Find(&invoices).Error; err != nil {
+		log.Printf("❌ Failed to fetch invoices for payment reminders: %v", err)
+		return 0
+	}
+
+	return n.notify(tenantID, models.NotificationCodePaymentReminder, invoiceEvents(invoices))
+}
+
+func invoiceEvents(invoices []models.Invoice) []billingEvent {
+	events := make([]billingEvent, len(invoices))
+	for i, invoice := range invoices {
+		events[i] = billingEvent{invoice: invoice}
+	}
+	return events
+}
+
+// notify renders the template of the event code for every event and queues the notifications.
+// Nothing is sent when the tenant disabled the event or has no active template for it.
+func (n *BillingNotifier) notify(tenantID uuid.UUID, code string, events []billingEvent) int {
+	if len(events) == 0 {
+		return 0
+	}
+
+	var settings models.TenantSettings
+	if err := config.DB.Where("tenant_id = ?", tenantID).First(&settings).Error; err != nil {
+		return 0
+	}
+	if !billingNotificationEnabled(settings, code) {
+		return 0
+	}
+
+	var template models.NotificationTemplate
+	if err := config.DB.Where("tenant_id = ? AND code = ? AND is_active = ?", tenantID, code, true).
+		First(&template).Error; err != nil {
+		log.Printf("⚠️  Billing notification %s is enabled for tenant %s but has no active template", code, tenantID)
+		return 0
+	}
+
+	queued := 0
+	for _, event := range events {
+		if err := n.queue(settings, template, event); err != nil {
+			log.Printf("❌ Failed to queue %s notification for invoice %s: %v", code, event.invoice.ID, err)
+			continue
+		}
+		queued++
+	}
+
+	return queued
+}
+
+// queue renders and enqueues one notification unless it was already sent for the event
+func (n *BillingNotifier) queue(settings models.TenantSettings, template models.NotificationTemplate, event billingEvent) error {
+	referenceID := event.invoice.ID
+	if event.payment != nil {
+		referenceID = event.payment.ID
+	}
+
+	var sent int64
+	config.DB.Model(&models.NotificationLog{}).
+		Where("tenant_id = ? AND event_code = ? AND reference_id = ?", settings.TenantID, template.Code, referenceID).
+		Count(&sent)
+	if sent > 0 {
+		return nil
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("id = ? AND tenant_id = ?", event.invoice.CustomerID, settings.TenantID).First(&customer).Error; err != nil {
+		return fmt.Errorf("customer not found: %w", err)
+	}
+
+	destination := customerDestination(customer, template.Channel)
+	if destination == "" {
+		return fmt.Errorf("customer %s has no contact for channel %s", customer.ID, template.Channel)
+	}
+
+	variables := billingEventVariables(settings, customer, event)
+	rendered, err := NewNotificationRenderer(settings.Language, settings.Currency).RenderTemplate(template, variables)
+	if err != nil {
+		return fmt.Errorf("failed to render template %s: %w", template.Code, err)
+	}
+
+	return n.dispatcher.Enqueue(&models.NotificationLog{
+		TenantID:      settings.TenantID,
+		TemplateID:    &template.ID,
+		RecipientType: "CUSTOMER",
+		RecipientID:   customer.ID,
+		RecipientName: customer.Name,
+		Channel:       template.Channel,
+		Destination:   destination,
+		Subject:       rendered.Subject,
+		Body:          rendered.Body,
+		HTMLBody:      rendered.HTMLBody,
+		EventCode:     template.Code,
+		ReferenceID:   &referenceID,
+	})
+}
+
+func billingNotificationEnabled(settings models.TenantSettings, code string) bool {
+	switch code {
+	case models.NotificationCodeInvoiceIssued:
+		return settings.NotifyInvoiceIssued
+	case models.NotificationCodePaymentReminder:
+		return settings.NotifyPaymentReminder
+	case models.NotificationCodeInvoiceOverdue:
+		return settings.NotifyInvoiceOverdue
+	case models.NotificationCodePaymentReceived, models.NotificationCodePaymentRejected:
+		return settings.NotifyPaymentReceived
+	}
+	return false
+}
+
+// customerDestination returns the customer's address for a channel
+func customerDestination(customer models.Customer, channel models.NotificationChannel) string {
+	switch channel {
+	case models.ChannelEmail:
+		return customer.Email
+	case models.ChannelSMS, models.ChannelWhatsApp:
+		return customer.Phone
+	case models.ChannelInApp:
+		return customer.ID.String()
+	}
+	return ""
+}
+
+// billingEventVariables returns the template variables of an event. Amounts and dates are raw
+// values so templates can format them, e.g. {{currency .amount_due}} or {{date .due_date}}.
+func billingEventVariables(settings models.TenantSettings, customer models.Customer, event billingEvent) map[string]interface{} {
+	invoice := event.invoice
+
+	variables := map[string]interface{}{
+		"customer_name":     customer.Name,
+		"meter_number":      customer.MeterNumber,
+		"invoice_number":    invoice.InvoiceNumber,
+		"usage_month":       invoice.UsageMonth,
+		"usage_m3":          invoice.UsageM3,
+		"total_amount":      invoice.TotalAmount,
+		"total_paid":        invoice.TotalPaid,
+		"amount_due":        invoice.TotalAmount - invoice.TotalPaid,
+		"due_date":          "",
+		"days_until_due":    0,
+		"days_overdue":      0,
+		"company_name":      settings.CompanyName,
+		"company_phone":     settings.Phone,
+		"bank_name":         settings.BankName,
+		"bank_account_name": settings.BankAccountName,
+		"bank_account_no":   settings.BankAccountNo,
+	}
+
+	if invoice.DueDate != nil {
+		variables["due_date"] = *invoice.DueDate
+		if until := time.Until(*invoice.DueDate); until >= 0 {
+			variables["days_until_due"] = int(math.Ceil(until.Hours() / 24))
+		} else {
+			variables["days_overdue"] = int(math.Ceil(-until.Hours() / 24))
+		}
+	}
+
+	if event.payment != nil {
+		variables["payment_amount"] = event.payment.Amount
+		variables["payment_date"] = event.payment.PaidAt
+		variables["rejection_reason"] = event.payment.RejectionReason
+	}
+
+	return variables
+}
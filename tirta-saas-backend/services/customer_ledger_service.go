@@ -0,0 +1,504 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrLedgerCustomerNotFound = errors.New("Pelanggan tidak ditemukan")
+	ErrLedgerCreditType       = errors.New("Jenis kredit harus REFUND, WRITE_OFF atau ADJUSTMENT")
+	ErrLedgerAmountInvalid    = errors.New("Jumlah kredit harus lebih dari nol")
+	ErrLedgerWriteOffExceeds  = errors.New("Penghapusan piutang melebihi sisa tagihan pelanggan")
+)
+
+// ManualCreditInput is a credit posted by the admin to a customer account
+type ManualCreditInput struct {
+	TenantID    uuid.UUID
+	CustomerID  uuid.UUID
+	Type        models.LedgerEntryType // REFUND, WRITE_OFF or ADJUSTMENT
+	Amount      float64
+	Description string
+	CreatedBy   *uuid.UUID
+}
+
+// StatementLine is a ledger entry with the account balance after it
+type StatementLine struct {
+	models.CustomerLedgerEntry
+	Balance float64
+}
+
+// CustomerStatement is the statement of account of a customer for a period
+type CustomerStatement struct {
+	Customer       models.Customer
+	From           *time.Time
+	To             *time.Time
+	OpeningBalance float64
+	TotalDebit     float64
+	TotalCredit    float64
+	ClosingBalance float64
+	Lines          []StatementLine
+}
+
+// CustomerLedgerService keeps the account ledger of customers. Invoices, penalties and adjustments
+// are debited, payments, refunds and write-offs credited. Money received beyond an invoice becomes
+// credit on the customer, which is applied to the customer's open invoices.
+type CustomerLedgerService struct{}
+
+// NewCustomerLedgerService creates new customer ledger service
+func NewCustomerLedgerService() *CustomerLedgerService {
+	return &CustomerLedgerService{}
+}
+
+// RecordInvoiceCharges debits a new invoice to its customer's ledger. Penalty and true-up
+// adjustment are posted as separate entries.
+func RecordInvoiceCharges(tx *gorm.DB, invoice models.Invoice) error {
+	entryDate := invoice.CreatedAt
+	if entryDate.IsZero() {
+		entryDate = time.Now()
+	}
+
+	label := invoice.InvoiceNumber
+	if label == "" {
+		label = invoice.Type
+	}
+
+	charge := roundMoney(invoice.TotalAmount - invoice.PenaltyAmount - invoice.AdjustmentAmount)
+	entries := []models.CustomerLedgerEntry{{
+		Type:        models.LedgerEntryInvoice,
+		Debit:       charge,
+		Description: fmt.Sprintf("Tagihan %s", label),
+	}}
+
+	if invoice.PenaltyAmount > 0 {
+		entries = append(entries, models.CustomerLedgerEntry{
+			Type:        models.LedgerEntryPenalty,
+			Debit:       invoice.PenaltyAmount,
+			Description: fmt.Sprintf("Denda keterlambatan pada tagihan %s", label),
+		})
+	}
+
+	if invoice.AdjustmentAmount != 0 {
+		adjustment := models.CustomerLedgerEntry{
+			Type:        models.LedgerEntryAdjustment,
+			Description: fmt.Sprintf("Koreksi pemakaian estimasi pada tagihan %s", label),
+		}
+		if invoice.AdjustmentAmount > 0 {
+			adjustment.Debit = invoice.AdjustmentAmount
+		} else {
+			adjustment.Credit = -invoice.AdjustmentAmount
+		}
+		entries = append(entries, adjustment)
+	}
+
+	for i := range entries {
+		entries[i].TenantID = invoice.TenantID
+		entries[i].CustomerID = invoice.CustomerID
+		entries[i].EntryDate = entryDate
+		entries[i].InvoiceID = &invoice.ID
+	}
+
+	return tx.Create(&entries).Error
+}
+
+// recordPaymentEntry credits the money received with a payment, including any part that went to
+// the customer's credit balance. Payments made from credit move no money and are not recorded.
+func recordPaymentEntry(tx *gorm.DB, payment models.Payment, customerID uuid.UUID) error {
+	if payment.FromCredit {
+		return nil
+	}
+
+	description := "Pembayaran"
+	if payment.CreditAmount > 0 {
+		description = fmt.Sprintf("Pembayaran, kelebihan %.2f menjadi saldo kredit", payment.CreditAmount)
+	}
+
+	return tx.Create(&models.CustomerLedgerEntry{
+		TenantID:    payment.TenantID,
+		CustomerID:  customerID,
+		EntryDate:   payment.PaidAt,
+		Type:        models.LedgerEntryPayment,
+		Credit:      roundMoney(payment.Amount + payment.CreditAmount),
+		InvoiceID:   &payment.InvoiceID,
+		PaymentID:   &payment.ID,
+		Description: description,
+	}).Error
+}
+
+// recordPaymentReversal debits back a payment that was corrected or removed
+func recordPaymentReversal(tx *gorm.DB, payment models.Payment, customerID uuid.UUID, description string) error {
+	if payment.FromCredit {
+		return nil
+	}
+
+	return tx.Create(&models.CustomerLedgerEntry{
+		TenantID:    payment.TenantID,
+		CustomerID:  customerID,
+		EntryDate:   time.Now(),
+		Type:        models.LedgerEntryPaymentReversal,
+		Debit:       roundMoney(payment.Amount + payment.CreditAmount),
+		InvoiceID:   &payment.InvoiceID,
+		PaymentID:   &payment.ID,
+		Description: description,
+	}).Error
+}
+
+// lockCustomer loads a customer and locks the row for the rest of the transaction. The customer
+// is always locked before its invoices so concurrent postings cannot deadlock.
+func lockCustomer(tx *gorm.DB, tenantID, customerID uuid.UUID) (*models.Customer, error) {
+	var customer models.Customer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND tenant_id = ?", customerID, tenantID).First(&customer).Error; err != nil {
+		return nil, ErrLedgerCustomerNotFound
+	}
+	return &customer, nil
+}
+
+// PostCredit credits a refund, write-off or adjustment to a customer and applies the resulting
+// credit to the customer's open invoices. A write-off pays the open invoices directly and cannot
+// exceed what they still owe, so it never becomes credit the customer can spend.
+func (s *CustomerLedgerService) PostCredit(input ManualCreditInput) (*models.CustomerLedgerEntry, error) {
+	switch input.Type {
+	case models.LedgerEntryRefund, models.LedgerEntryWriteOff, models.LedgerEntryAdjustment:
+	default:
+		return nil, ErrLedgerCreditType
+	}
+	if input.Amount <= 0 {
+		return nil, ErrLedgerAmountInvalid
+	}
+
+	entry := models.CustomerLedgerEntry{
+		TenantID:    input.TenantID,
+		CustomerID:  input.CustomerID,
+		EntryDate:   time.Now(),
+		Type:        input.Type,
+		Credit:      roundMoney(input.Amount),
+		Description: input.Description,
+		CreatedBy:   input.CreatedBy,
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		customer, err := lockCustomer(tx, input.TenantID, input.CustomerID)
+		if err != nil {
+			return err
+		}
+
+		if input.Type == models.LedgerEntryWriteOff {
+			return writeOffInvoices(tx, customer, &entry)
+		}
+
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+
+		customer.CreditBalance = roundMoney(customer.CreditBalance + entry.Credit)
+		if err := tx.Model(customer).Update("credit_balance", customer.CreditBalance).Error; err != nil {
+			return err
+		}
+
+		_, err = applyCustomerCredit(tx, customer)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// ApplyCredit pays the open invoices of a customer from the customer's credit balance, oldest
+// invoice first. It returns the invoices that received credit.
+func (s *CustomerLedgerService) ApplyCredit(tenantID, customerID uuid.UUID) ([]models.Invoice, error) {
+	var paid []models.Invoice
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		customer, err := lockCustomer(tx, tenantID, customerID)
+		if err != nil {
+			return err
+		}
+
+		paid, err = applyCustomerCredit(tx, customer)
+		return err
+	})
+	return paid, err
+}
+
+// ApplyCreditToInvoices applies the credit balance of every customer of newly issued invoices and
+// returns the invoices with their paid totals refreshed
+func (s *CustomerLedgerService) ApplyCreditToInvoices(tenantID uuid.UUID, invoices []models.Invoice) []models.Invoice {
+	if len(invoices) == 0 {
+		return invoices
+	}
+
+	customerIDs := make([]uuid.UUID, 0)
+	seen := make(map[uuid.UUID]bool)
+	for _, invoice := range invoices {
+		if !seen[invoice.CustomerID] {
+			seen[invoice.CustomerID] = true
+			customerIDs = append(customerIDs, invoice.CustomerID)
+		}
+	}
+
+	var withCredit []uuid.UUID
+	config.DB.Model(&models.Customer{}).
+		Where("tenant_id = ? AND id IN ? AND credit_balance > 0", tenantID, customerIDs).
+		Pluck("id", &withCredit)
+	if len(withCredit) == 0 {
+		return invoices
+	}
+
+	updated := make(map[uuid.UUID]models.Invoice)
+	for _, customerID := range withCredit {
+		paid, err := s.ApplyCredit(tenantID, customerID)
+		if err != nil {
+			log.Printf("❌ Failed to apply credit balance of customer %s: %v", customerID, err)
+			continue
+		}
+		for _, invoice := range paid {
+			updated[invoice.ID] = invoice
+		}
+	}
+
+	refreshed := make([]models.Invoice, len(invoices))
+	for i, invoice := range invoices {
+		if paid, ok := updated[invoice.ID]; ok {
+			invoice.TotalPaid = paid.TotalPaid
+			invoice.IsPaid = paid.IsPaid
+			invoice.PaymentStatus = paid.PaymentStatus
+			invoice.PaidDate = paid.PaidDate
+		}
+		refreshed[i] = invoice
+	}
+	return refreshed
+}
+
+// writeOffInvoices records a write-off of a locked customer and pays the customer's open invoices
+// with it, oldest first
+func writeOffInvoices(tx *gorm.DB, customer *models.Customer, entry *models.CustomerLedgerEntry) error {
+	invoices, err := lockOpenInvoices(tx, customer)
+	if err != nil {
+		return err
+	}
+
+	var outstanding float64
+	for _, invoice := range invoices {
+		outstanding += math.Max(0, invoice.TotalAmount-invoice.TotalPaid)
+	}
+	if entry.Credit > roundMoney(outstanding) {
+		return ErrLedgerWriteOffExceeds
+	}
+
+	if err := tx.Create(entry).Error; err != nil {
+		return err
+	}
+
+	notes := "Dihapusbukukan"
+	if entry.Description != "" {
+		notes += ": " + entry.Description
+	}
+	_, _, err = payInvoicesFromCredit(tx, customer, invoices, entry.Credit, notes)
+	return err
+}
+
+// applyCustomerCredit pays open invoices from the credit balance of a locked customer
+func applyCustomerCredit(tx *gorm.DB, customer *models.Customer) ([]models.Invoice, error) {
+	if customer.CreditBalance <= 0 {
+		return nil, nil
+	}
+
+	invoices, err := lockOpenInvoices(tx, customer)
+	if err != nil {
+		return nil, err
+	}
+
+	paid, used, err := payInvoicesFromCredit(tx, customer, invoices, customer.CreditBalance, "Dibayar dari saldo kredit pelanggan")
+	if err != nil {
+		return nil, err
+	}
+	customer.CreditBalance = roundMoney(customer.CreditBalance - used)
+
+	if len(paid) > 0 {
+		if err := tx.Model(customer).Update("credit_balance", customer.CreditBalance).Error; err != nil {
+			return nil, err
+		}
+		log.Printf("💳 Applied credit to %d invoice(s) of customer %s, remaining credit %.2f", len(paid), customer.ID, customer.CreditBalance)
+	}
+
+	return paid, nil
+}
+
+// payInvoicesFromCredit pays locked open invoices, oldest first, with up to amount already
+// credited on the customer ledger. It returns the invoices paid and the amount used.
+func payInvoicesFromCredit(tx *gorm.DB, customer *models.Customer, invoices []models.Invoice, amount float64, notes string) ([]models.Invoice, float64, error) {
+	order := tenantAllocationOrder(customer.TenantID)
+	left := roundMoney(amount)
+
+	var paid []models.Invoice
+	for i := range invoices {
+		if left <= 0 {
+			break
+		}
+
+		invoice := &invoices[i]
+		applied := roundMoney(math.Min(left, invoice.TotalAmount-invoice.TotalPaid))
+		if applied <= 0 {
+			continue
+		}
+
+		payment := models.Payment{
+			TenantID:   customer.TenantID,
+			InvoiceID:  invoice.ID,
+			Amount:     applied,
+			Penalty:    penaltyPortion(*invoice, applied, order),
+			FromCredit: true,
+			Status:     models.PaymentRecordCompleted,
+			Notes:      notes,
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return nil, 0, err
+		}
+		if err := applyInvoicePayments(tx, invoice); err != nil {
+			return nil, 0, err
+		}
+
+		left = roundMoney(left - applied)
+		paid = append(paid, *invoice)
+	}
+
+	return paid, roundMoney(amount - left), nil
+}
+
+// Statement returns the ledger entries of a customer within the period with their running
+// balance. Entries before the period make up the opening balance.
+func (s *CustomerLedgerService) Statement(tenantID, customerID uuid.UUID, from, to *time.Time) (*CustomerStatement, error) {
+	var customer models.Customer
+	if err := config.DB.Where("id = ? AND tenant_id = ?", customerID, tenantID).First(&customer).Error; err != nil {
+		return nil, ErrLedgerCustomerNotFound
+	}
+
+	statement := &CustomerStatement{
+		Customer: customer,
+		From:     from,
+		To:       to,
+		Lines:    []StatementLine{},
+	}
+
+	if from != nil {
+		var opening struct {
+			Debit  float64
+			Credit float64
+		}
+		config.DB.Model(&models.CustomerLedgerEntry{}).
+			Select("COALESCE(SUM(debit), 0) AS debit, COALESCE(SUM(credit), 0) AS credit").
+			Where("tenant_id = ? AND customer_id = ? AND entry_date < ?", tenantID, customerID, *from).
+			Scan(&opening)
+		statement.OpeningBalance = roundMoney(opening.Debit - opening.Credit)
+	}
+
+	query := config.DB.Where("tenant_id = ? AND customer_id = ?", tenantID, customerID)
+	if from != nil {
+		query = query.Where("entry_date >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("entry_date < ?", *to)
+	}
+
+	var entries []models.CustomerLedgerEntry
+	if err := query.Order("entry_date ASC, created_at ASC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	balance := statement.OpeningBalance
+	for _, entry := range entries {
+		balance = roundMoney(balance + entry.Debit - entry.Credit)
+		statement.TotalDebit += entry.Debit
+		statement.TotalCredit += entry.Credit
+		statement.Lines = append(statement.Lines, StatementLine{CustomerLedgerEntry: entry, Balance: balance})
+	}
+	statement.TotalDebit = roundMoney(statement.TotalDebit)
+	statement.TotalCredit = roundMoney(statement.TotalCredit)
+	statement.ClosingBalance = balance
+
+	return statement, nil
+}
+
+// BackfillLedgers creates ledger entries from the invoices and payments of customers that were
+// billed before the ledger existed. It is run at startup by the leader only.
+func (s *CustomerLedgerService) BackfillLedgers() (string, error) {
+	var customerIDs []uuid.UUID
+	if err := config.DB.Model(&models.Invoice{}).Distinct("customer_id").
+		Where("customer_id NOT IN (?)", config.DB.Model(&models.CustomerLedgerEntry{}).Distinct("customer_id")).
+		Pluck("customer_id", &customerIDs).Error; err != nil {
+		return "", err
+	}
+
+	failed := 0
+	for _, customerID := range customerIDs {
+		if err := s.backfillCustomer(customerID); err != nil {
+			failed++
+			log.Printf("❌ Failed to backfill ledger of customer %s: %v", customerID, err)
+		}
+	}
+
+	if len(customerIDs) > 0 {
+		log.Printf("📒 Backfilled ledger of %d customer(s)", len(customerIDs)-failed)
+	}
+	summary := fmt.Sprintf("%d customer(s) backfilled, %d failed", len(customerIDs)-failed, failed)
+	if failed > 0 {
+		return summary, fmt.Errorf("%d customer ledger(s) could not be backfilled", failed)
+	}
+	return summary, nil
+}
+
+func (s *CustomerLedgerService) backfillCustomer(customerID uuid.UUID) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		// Checked again under the customer lock, so a ledger is never posted twice
+		var customer models.Customer
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", customerID).First(&customer).Error; err != nil {
+			return err
+		}
+		var posted int64
+		if err := tx.Model(&models.CustomerLedgerEntry{}).Where("customer_id = ?", customerID).Count(&posted).Error; err != nil {
+			return err
+		}
+		if posted > 0 {
+			return nil
+		}
+
+		var invoices []models.Invoice
+		if err := tx.Where("customer_id = ?", customerID).Order("created_at ASC").Find(&invoices).Error; err != nil {
+			return err
+		}
+
+		for _, invoice := range invoices {
+			if err := RecordInvoiceCharges(tx, invoice); err != nil {
+				return err
+			}
+
+			var payments []models.Payment
+			if err := tx.Where("invoice_id = ? AND status = ?", invoice.ID, models.PaymentRecordCompleted).
+				Find(&payments).Error; err != nil {
+				return err
+			}
+			for _, payment := range payments {
+				if err := recordPaymentEntry(tx, payment, customerID); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// roundMoney rounds an amount to whole cents
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
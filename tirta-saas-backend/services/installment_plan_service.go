@@ -0,0 +1,477 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInstallmentPlanNotFound    = errors.New("Rencana cicilan tidak ditemukan")
+	ErrInstallmentPlanNotActive   = errors.New("Rencana cicilan sudah tidak aktif")
+	ErrInstallmentInvoices        = errors.New("Pilih minimal satu tagihan yang sudah jatuh tempo")
+	ErrInstallmentInvoiceNotDue   = errors.New("Hanya tagihan yang sudah jatuh tempo yang dapat dicicil")
+	ErrInstallmentInvoiceInPlan   = errors.New("Tagihan sudah termasuk dalam rencana cicilan yang aktif")
+	ErrInstallmentCount           = errors.New("Jumlah cicilan harus antara 2 dan 24")
+	ErrInstallmentAmountExceeds   = errors.New("Jumlah pembayaran melebihi sisa cicilan")
+	ErrInstallmentFirstDueInvalid = errors.New("Jatuh tempo cicilan pertama tidak boleh sebelum hari ini")
+)
+
+// Number of installments a plan may have
+const (
+	minInstallments = 2
+	maxInstallments = 24
+)
+
+// InstallmentPlanInput is a repayment schedule agreed with a customer for overdue invoices
+type InstallmentPlanInput struct {
+	TenantID         uuid.UUID
+	CustomerID       uuid.UUID
+	InvoiceIDs       []uuid.UUID
+	InstallmentCount int
+	FirstDueDate     *time.Time // one month from today when not set
+	MaxMissed        int        // 2 when not set
+	Notes            string
+	CreatedBy        *uuid.UUID
+}
+
+// InstallmentPaymentInput is money received for an installment plan
+type InstallmentPaymentInput struct {
+	TenantID        uuid.UUID
+	PlanID          uuid.UUID
+	CustomerID      *uuid.UUID // when set, the plan must belong to this customer
+	Amount          float64
+	PaymentMethodID *uuid.UUID
+	ReferenceNumber string
+	Notes           string
+	ReceivedBy      *uuid.UUID
+}
+
+// InstallmentPlanService manages repayment plans for customer arrears. While a plan is active no
+// late penalties accrue on its invoices; a plan that misses too many installments defaults and
+// penalties resume from that day.
+type InstallmentPlanService struct {
+	notifier *BillingNotifier
+}
+
+// NewInstallmentPlanService creates new installment plan service
+func NewInstallmentPlanService() *InstallmentPlanService {
+	return &InstallmentPlanService{
+		notifier: NewBillingNotifier(),
+	}
+}
+
+// CreatePlan consolidates overdue invoices of a customer into a plan of monthly installments.
+// The installments split the outstanding amount evenly, the last one taking the rounding.
+func (s *InstallmentPlanService) CreatePlan(input InstallmentPlanInput) (*models.InstallmentPlan, error) {
+	if len(input.InvoiceIDs) == 0 {
+		return nil, ErrInstallmentInvoices
+	}
+	if input.InstallmentCount < minInstallments || input.InstallmentCount > maxInstallments {
+		return nil, ErrInstallmentCount
+	}
+
+	today := truncateToDay(time.Now())
+	firstDue := today.AddDate(0, 1, 0)
+	if input.FirstDueDate != nil {
+		firstDue = truncateToDay(*input.FirstDueDate)
+		if firstDue.Before(today) {
+			return nil, ErrInstallmentFirstDueInvalid
+		}
+	}
+	maxMissed := input.MaxMissed
+	if maxMissed <= 0 {
+		maxMissed = 2
+	}
+
+	plan := models.InstallmentPlan{
+		TenantID:         input.TenantID,
+		CustomerID:       input.CustomerID,
+		Status:           models.InstallmentPlanActive,
+		InstallmentCount: input.InstallmentCount,
+		MaxMissed:        maxMissed,
+		Notes:            input.Notes,
+		CreatedBy:        input.CreatedBy,
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		customer, err := lockCustomer(tx, input.TenantID, input.CustomerID)
+		if err != nil {
+			return err
+		}
+
+		var invoices []models.Invoice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("tenant_id = ? AND customer_id = ? AND id IN ?", input.TenantID, customer.ID, input.InvoiceIDs).
+			Order("due_date ASC").Find(&invoices).Error; err != nil {
+			return err
+		}
+		if len(invoices) != len(uniqueIDs(input.InvoiceIDs)) {
+			return ErrPaymentInvoiceNotFound
+		}
+
+		for _, invoice := range invoices {
+			if invoice.Status == models.InvoiceStatusVoid {
+				return ErrInvoiceVoided
+			}
+			if invoice.IsPaid {
+				return ErrInvoiceAlreadyPaid
+			}
+			if invoice.DueDate == nil || !invoice.DueDate.Before(time.Now()) {
+				return ErrInstallmentInvoiceNotDue
+			}
+			if inActiveInstallmentPlan(tx, invoice) {
+				return ErrInstallmentInvoiceInPlan
+			}
+			plan.TotalAmount += invoice.TotalAmount - invoice.TotalPaid
+		}
+		plan.TotalAmount = roundMoney(plan.TotalAmount)
+
+		if err := tx.Omit("Invoices", "Installments").Create(&plan).Error; err != nil {
+			return err
+		}
+
+		for _, invoice := range invoices {
+			line := models.InstallmentPlanInvoice{
+				PlanID:    plan.ID,
+				InvoiceID: invoice.ID,
+				Amount:    roundMoney(invoice.TotalAmount - invoice.TotalPaid),
+			}
+			if err := tx.Create(&line).Error; err != nil {
+				return err
+			}
+			plan.Invoices = append(plan.Invoices, line)
+		}
+		if err := tx.Model(&models.Invoice{}).Where("id IN ?", input.InvoiceIDs).
+			Update("installment_plan_id", plan.ID).Error; err != nil {
+			return err
+		}
+
+		amount := math.Floor(plan.TotalAmount / float64(plan.InstallmentCount))
+		scheduled := 0.0
+		for i := 0; i < plan.InstallmentCount; i++ {
+			installment := models.Installment{
+				PlanID:   plan.ID,
+				Sequence: i + 1,
+				DueDate:  firstDue.AddDate(0, i, 0),
+				Amount:   amount,
+				Status:   models.InstallmentScheduled,
+			}
+			if i == plan.InstallmentCount-1 {
+				installment.Amount = roundMoney(plan.TotalAmount - scheduled)
+			}
+			scheduled += installment.Amount
+			if err := tx.Create(&installment).Error; err != nil {
+				return err
+			}
+			plan.Installments = append(plan.Installments, installment)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("📆 Installment plan %s for customer %s: %.2f in %d installments", plan.ID, plan.CustomerID, plan.TotalAmount, plan.InstallmentCount)
+	return &plan, nil
+}
+
+// GetPlan loads a plan with its invoices and installments. It does not lock or write: plans are
+// refreshed when paid through the plan and by the daily RefreshPlans.
+func (s *InstallmentPlanService) GetPlan(tenantID, planID uuid.UUID, customerID *uuid.UUID) (*models.InstallmentPlan, error) {
+	query := config.DB.Preload("Invoices.Invoice").
+		Preload("Installments", func(db *gorm.DB) *gorm.DB { return db.Order("sequence ASC") }).
+		Where("id = ? AND tenant_id = ?", planID, tenantID)
+	if customerID != nil {
+		query = query.Where("customer_id = ?", *customerID)
+	}
+
+	var plan models.InstallmentPlan
+	if err := query.First(&plan).Error; err != nil {
+		return nil, ErrInstallmentPlanNotFound
+	}
+	return &plan, nil
+}
+
+// PayInstallment posts money received for a plan to its open invoices, oldest due first, and
+// refreshes the plan. It cannot exceed what is left of the plan.
+func (s *InstallmentPlanService) PayInstallment(input InstallmentPaymentInput) (*models.InstallmentPlan, error) {
+	if err := validatePaymentAmount(input.Amount); err != nil {
+		return nil, err
+	}
+
+	var payments []models.Payment
+	var invoices []models.Invoice
+	// The customer is locked before the plan, like every other payment of the customer
+	owner, err := s.GetPlan(input.TenantID, input.PlanID, input.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockCustomer(tx, input.TenantID, owner.CustomerID); err != nil {
+			return err
+		}
+		plan, err := findPlan(tx, input.TenantID, input.PlanID, &owner.CustomerID)
+		if err != nil {
+			return err
+		}
+		if plan.Status != models.InstallmentPlanActive {
+			return ErrInstallmentPlanNotActive
+		}
+
+		var open []models.Invoice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("installment_plan_id = ? AND is_paid = ? AND status <> ?", plan.ID, false, models.InvoiceStatusVoid).
+			Order("due_date ASC").Find(&open).Error; err != nil {
+			return err
+		}
+
+		var remaining float64
+		for _, invoice := range open {
+			remaining += invoice.TotalAmount - invoice.TotalPaid
+		}
+		if roundMoney(input.Amount) > roundMoney(remaining) {
+			return ErrInstallmentAmountExceeds
+		}
+
+		left := roundMoney(input.Amount)
+		for _, invoice := range open {
+			if left <= 0 {
+				break
+			}
+			portion := roundMoney(math.Min(left, invoice.TotalAmount-invoice.TotalPaid))
+			if portion <= 0 {
+				continue
+			}
+
+			payment, paid, err := postPayment(tx, PostPaymentInput{
+				TenantID:        input.TenantID,
+				InvoiceID:       invoice.ID,
+				Amount:          portion,
+				Notes:           fmt.Sprintf("Cicilan: %s", input.Notes),
+				PaymentMethodID: input.PaymentMethodID,
+				ReferenceNumber: input.ReferenceNumber,
+				ReceivedBy:      input.ReceivedBy,
+			})
+			if err != nil {
+				return err
+			}
+			payments = append(payments, *payment)
+			invoices = append(invoices, *paid)
+			left = roundMoney(left - portion)
+		}
+
+		return refreshInstallmentPlan(tx, plan, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range payments {
+		s.notifier.PaymentReceived(payments[i], invoices[i])
+	}
+
+	return s.GetPlan(input.TenantID, input.PlanID, input.CustomerID)
+}
+
+// CancelPlan ends an active plan by agreement. Penalties of its unpaid invoices resume from today.
+func (s *InstallmentPlanService) CancelPlan(tenantID, planID uuid.UUID, reason string) (*models.InstallmentPlan, error) {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		plan, err := findPlan(tx, tenantID, planID, nil)
+		if err != nil {
+			return err
+		}
+		if plan.Status != models.InstallmentPlanActive {
+			return ErrInstallmentPlanNotActive
+		}
+
+		now := time.Now()
+		return tx.Model(plan).Updates(map[string]interface{}{
+			"status":     models.InstallmentPlanCancelled,
+			"ended_at":   now,
+			"end_reason": reason,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("📆 Installment plan %s cancelled: %s", planID, reason)
+	return s.GetPlan(tenantID, planID, nil)
+}
+
+// RefreshPlans updates the active plans of a tenant: installments past their due date and the
+// grace period are marked missed, and plans that missed too many default. Run daily.
+func (s *InstallmentPlanService) RefreshPlans(tenantID uuid.UUID) (int, error) {
+	var ids []uuid.UUID
+	if err := config.DB.Model(&models.InstallmentPlan{}).
+		Where("tenant_id = ? AND status = ?", tenantID, models.InstallmentPlanActive).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	defaulted := 0
+	for _, id := range ids {
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			plan, err := findPlan(tx, tenantID, id, nil)
+			if err != nil {
+				return err
+			}
+			if err := refreshInstallmentPlan(tx, plan, time.Now()); err != nil {
+				return err
+			}
+			if plan.Status == models.InstallmentPlanDefaulted {
+				defaulted++
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("❌ Failed to refresh installment plan %s: %v", id, err)
+		}
+	}
+
+	return defaulted, nil
+}
+
+// findPlan loads and locks a plan
+func findPlan(tx *gorm.DB, tenantID, planID uuid.UUID, customerID *uuid.UUID) (*models.InstallmentPlan, error) {
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND tenant_id = ?", planID, tenantID)
+	if customerID != nil {
+		query = query.Where("customer_id = ?", *customerID)
+	}
+
+	var plan models.InstallmentPlan
+	if err := query.First(&plan).Error; err != nil {
+		return nil, ErrInstallmentPlanNotFound
+	}
+	return &plan, nil
+}
+
+// refreshInstallmentPlan derives the paid amount of a locked plan from its invoices and covers the
+// installments with it in order. An active plan completes when nothing is left and defaults once
+// MaxMissed installments are missed and still unpaid.
+func refreshInstallmentPlan(tx *gorm.DB, plan *models.InstallmentPlan, now time.Time) error {
+	var remaining float64
+	if err := tx.Model(&models.Invoice{}).
+		Where("installment_plan_id = ? AND is_paid = ? AND status <> ?", plan.ID, false, models.InvoiceStatusVoid).
+		Select("COALESCE(SUM(total_amount - total_paid), 0)").Scan(&remaining).Error; err != nil {
+		return err
+	}
+	plan.PaidAmount = roundMoney(math.Max(0, plan.TotalAmount-remaining))
+
+	// The schedule of an ended plan is kept as it was
+	if plan.Status != models.InstallmentPlanActive {
+		return tx.Model(plan).Update("paid_amount", plan.PaidAmount).Error
+	}
+
+	var installments []models.Installment
+	if err := tx.Where("plan_id = ?", plan.ID).Order("sequence ASC").Find(&installments).Error; err != nil {
+		return err
+	}
+
+	graceDays := 0
+	var settings models.TenantSettings
+	if err := tx.Select("grace_period_days").Where("tenant_id = ?", plan.TenantID).First(&settings).Error; err == nil {
+		graceDays = settings.GracePeriodDays
+	}
+	missed := scheduleInstallments(installments, plan.PaidAmount, graceDays, now)
+	for _, installment := range installments {
+		if err := tx.Model(&models.Installment{}).Where("id = ?", installment.ID).Updates(map[string]interface{}{
+			"paid_amount": installment.PaidAmount,
+			"status":      installment.Status,
+			"paid_at":     installment.PaidAt,
+			"missed_at":   installment.MissedAt,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	updates := map[string]interface{}{
+		"paid_amount":  plan.PaidAmount,
+		"missed_count": missed,
+	}
+	plan.MissedCount = missed
+	switch {
+	case remaining <= 0:
+		plan.Status = models.InstallmentPlanCompleted
+		updates["status"] = plan.Status
+		updates["ended_at"] = now
+		log.Printf("🎉 Installment plan %s completed", plan.ID)
+	case missed >= plan.MaxMissed:
+		plan.Status = models.InstallmentPlanDefaulted
+		updates["status"] = plan.Status
+		updates["ended_at"] = now
+		updates["end_reason"] = fmt.Sprintf("%d cicilan tidak dibayar", missed)
+		log.Printf("⚠️  Installment plan %s defaulted after %d missed installments", plan.ID, missed)
+	}
+
+	return tx.Model(plan).Updates(updates).Error
+}
+
+// scheduleInstallments covers the installments of an active plan, in order, with the amount paid
+// on the plan so far. Installments not covered after their due date and the grace period are
+// missed. It returns the number of installments missed and still unpaid; one paid late no longer
+// counts, though its MissedAt is kept.
+func scheduleInstallments(installments []models.Installment, paidAmount float64, graceDays int, now time.Time) int {
+	today := truncateToDay(now)
+
+	left := paidAmount
+	missed := 0
+	for i := range installments {
+		installment := &installments[i]
+		installment.PaidAmount = roundMoney(math.Min(left, installment.Amount))
+		left = roundMoney(left - installment.PaidAmount)
+
+		switch {
+		case installment.PaidAmount >= installment.Amount:
+			if installment.Status != models.InstallmentPaid {
+				installment.Status = models.InstallmentPaid
+				installment.PaidAt = &now
+			}
+		case truncateToDay(installment.DueDate).AddDate(0, 0, graceDays).Before(today):
+			installment.Status = models.InstallmentMissed
+			installment.PaidAt = nil
+			if installment.MissedAt == nil {
+				installment.MissedAt = &now
+			}
+			missed++
+		default:
+			installment.Status = models.InstallmentScheduled
+			installment.PaidAt = nil
+		}
+	}
+	return missed
+}
+
+// inActiveInstallmentPlan reports whether an invoice is part of an active installment plan
+func inActiveInstallmentPlan(tx *gorm.DB, invoice models.Invoice) bool {
+	if invoice.InstallmentPlanID == nil {
+		return false
+	}
+
+	var active int64
+	tx.Model(&models.InstallmentPlan{}).
+		Where("id = ? AND status = ?", *invoice.InstallmentPlanID, models.InstallmentPlanActive).Count(&active)
+	return active > 0
+}
+
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool)
+	var unique []uuid.UUID
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
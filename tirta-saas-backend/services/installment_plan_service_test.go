@@ -0,0 +1,78 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+)
+
+func TestScheduleInstallments(t *testing.T) {
+	now := time.Date(2025, 2, 20, 9, 0, 0, 0, time.UTC)
+	earlier := time.Date(2025, 1, 20, 9, 0, 0, 0, time.UTC)
+
+	installments := func() []models.Installment {
+		return []models.Installment{
+			{Sequence: 1, DueDate: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC), Amount: 100, Status: models.InstallmentScheduled},
+			{Sequence: 2, DueDate: time.Date(2025, 2, 17, 0, 0, 0, 0, time.UTC), Amount: 100, Status: models.InstallmentScheduled},
+			{Sequence: 3, DueDate: time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), Amount: 100, Status: models.InstallmentScheduled},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		paid     float64
+		grace    int
+		missedAt bool
+		missed   int
+		statuses []string
+	}{
+		{
+			name:     "nothing paid",
+			grace:    3,
+			missed:   1,
+			statuses: []string{models.InstallmentMissed, models.InstallmentScheduled, models.InstallmentScheduled},
+		},
+		{
+			name:     "grace period over",
+			grace:    2,
+			missed:   2,
+			statuses: []string{models.InstallmentMissed, models.InstallmentMissed, models.InstallmentScheduled},
+		},
+		{
+			name:     "partly paid is still missed",
+			paid:     150,
+			grace:    2,
+			missed:   1,
+			statuses: []string{models.InstallmentPaid, models.InstallmentMissed, models.InstallmentScheduled},
+		},
+		{
+			name:     "missed installment paid late is no longer missed",
+			paid:     100,
+			grace:    3,
+			missedAt: true,
+			missed:   0,
+			statuses: []string{models.InstallmentPaid, models.InstallmentScheduled, models.InstallmentScheduled},
+		},
+	}
+
+	for _, tt := range tests {
+		list := installments()
+		if tt.missedAt {
+			list[0].Status = models.InstallmentMissed
+			list[0].MissedAt = &earlier
+		}
+
+		if missed := scheduleInstallments(list, tt.paid, tt.grace, now); missed != tt.missed {
+			t.Errorf("%s: missed = %d; want %d", tt.name, missed, tt.missed)
+		}
+		for i, want := range tt.statuses {
+			if list[i].Status != want {
+				t.Errorf("%s: installment %d status = %s; want %s", tt.name, list[i].Sequence, list[i].Status, want)
+			}
+		}
+		if tt.missedAt && (list[0].MissedAt == nil || !list[0].MissedAt.Equal(earlier)) {
+			t.Errorf("%s: MissedAt = %v; want %v kept", tt.name, list[0].MissedAt, earlier)
+		}
+	}
+}
@@ -0,0 +1,574 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvoiceVoided           = errors.New("Tagihan sudah dibatalkan (void)")
+	ErrInvoiceHasPayments      = errors.New("Tagihan yang sudah dibayar tidak dapat di-void, terbitkan nota kredit atau tagih ulang")
+	ErrInvoiceHasPendingProofs = errors.New("Tagihan masih memiliki bukti transfer yang menunggu verifikasi, setujui atau tolak terlebih dahulu")
+	ErrAdjustmentAmountInvalid = errors.New("Jumlah nota harus lebih dari nol")
+	ErrAdjustmentReason        = errors.New("Alasan wajib diisi")
+	ErrCreditNoteExceedsDue    = errors.New("Nota kredit melebihi sisa tagihan yang belum dibayar")
+	ErrRebillNotMonthly        = errors.New("Hanya tagihan bulanan yang dapat ditagih ulang")
+	ErrRebillUsageNotFound     = errors.New("Data pemakaian air untuk tagihan ini tidak ditemukan")
+	ErrRebillUsageInReview     = errors.New("Pembacaan meter tagihan ini masih menunggu review anomali")
+	ErrRebillAmountInvalid     = errors.New("Total tagihan ulang tidak valid")
+)
+
+// InvoiceAdjustmentInput is a credit note, debit note, void or rebill requested for an invoice
+type InvoiceAdjustmentInput struct {
+	TenantID  uuid.UUID
+	InvoiceID uuid.UUID
+	Amount    float64 // credit and debit notes only
+	Reason    string
+	CreatedBy *uuid.UUID
+
+	// Debit notes only: bill the amount as this charge, e.g. SERVICE or METER_RENTAL, instead of
+	// a plain DEBIT_NOTE line
+	LineType models.InvoiceLineType
+}
+
+// InvoiceAdjustmentService changes issued invoices through adjustment documents only. Every change
+// is a credit note, debit note or void linked to the invoice and posted to the customer ledger, so
+// the amount billed can always be explained and invoice numbers stay continuous.
+type InvoiceAdjustmentService struct {
+	tariffEngine *TariffEngine
+	notifier     *BillingNotifier
+}
+
+// NewInvoiceAdjustmentService creates new invoice adjustment service
+func NewInvoiceAdjustmentService() *InvoiceAdjustmentService {
+	return &InvoiceAdjustmentService{
+		tariffEngine: NewTariffEngine(),
+		notifier:     NewBillingNotifier(),
+	}
+}
+
+// Void cancels an invoice that has not been paid. The invoice is kept with status VOID so its
+// number stays used, and its remaining amount is credited back on the customer ledger. Transfer
+// proofs still waiting for verification must be approved or rejected first.
+func (s *InvoiceAdjustmentService) Void(input InvoiceAdjustmentInput) (*models.InvoiceAdjustment, *models.Invoice, error) {
+	if input.Reason == "" {
+		return nil, nil, ErrAdjustmentReason
+	}
+
+	var adjustment *models.InvoiceAdjustment
+	var invoice *models.Invoice
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		_, invoice, err = lockIssuedInvoice(tx, input.TenantID, input.InvoiceID)
+		if err != nil {
+			return err
+		}
+		if invoice.TotalPaid > 0 {
+			return ErrInvoiceHasPayments
+		}
+		var pending int64
+		if err := tx.Model(&models.Payment{}).Where("invoice_id = ? AND status = ?", invoice.ID, models.PaymentRecordPending).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return ErrInvoiceHasPendingProofs
+		}
+		if inActiveInstallmentPlan(tx, *invoice) {
+			return ErrInstallmentInvoiceInPlan
+		}
+
+		adjustment, err = voidInvoice(tx, invoice, input, nil)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("🚫 Invoice %s voided: %s", invoice.InvoiceNumber, input.Reason)
+	return adjustment, invoice, nil
+}
+
+// CreditNote lowers the amount due of an invoice, e.g. for a billing mistake or a goodwill
+// discount. It cannot exceed what is still unpaid.
+func (s *InvoiceAdjustmentService) CreditNote(input InvoiceAdjustmentInput) (*models.InvoiceAdjustment, *models.Invoice, error) {
+	if err := validateAdjustment(input); err != nil {
+		return nil, nil, err
+	}
+
+	var adjustment *models.InvoiceAdjustment
+	var invoice *models.Invoice
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		_, invoice, err = lockIssuedInvoice(tx, input.TenantID, input.InvoiceID)
+		if err != nil {
+			return err
+		}
+
+		amount := roundMoney(input.Amount)
+		if amount > roundMoney(invoice.TotalAmount-invoice.TotalPaid) {
+			return ErrCreditNoteExceedsDue
+		}
+
+		adjustment, err = createAdjustment(tx, *invoice, models.InvoiceAdjustmentCreditNote, amount, input)
+		if err != nil {
+			return err
+		}
+		if err := recordAdjustmentEntry(tx, *invoice, *adjustment); err != nil {
+			return err
+		}
+
+		description := fmt.Sprintf("Nota kredit %s: %s", adjustment.Number, adjustment.Reason)
+		if err := addAdjustmentLine(tx, invoice, *adjustment, models.InvoiceLineCreditNote, description, -amount); err != nil {
+			return err
+		}
+
+		return applyInvoicePayments(tx, invoice)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("🧾 Credit note %s of %.2f issued for invoice %s", adjustment.Number, adjustment.Amount, invoice.InvoiceNumber)
+	return adjustment, invoice, nil
+}
+
+// DebitNote raises the amount due of an invoice, e.g. for a charge that was left out. Credit the
+// customer has on account pays the additional amount.
+func (s *InvoiceAdjustmentService) DebitNote(input InvoiceAdjustmentInput) (*models.InvoiceAdjustment, *models.Invoice, error) {
+	if err := validateAdjustment(input); err != nil {
+		return nil, nil, err
+	}
+
+	var adjustment *models.InvoiceAdjustment
+	var invoice *models.Invoice
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		customer, locked, err := lockIssuedInvoice(tx, input.TenantID, input.InvoiceID)
+		if err != nil {
+			return err
+		}
+		invoice = locked
+
+		amount := roundMoney(input.Amount)
+		adjustment, err = createAdjustment(tx, *invoice, models.InvoiceAdjustmentDebitNote, amount, input)
+		if err != nil {
+			return err
+		}
+		if err := recordAdjustmentEntry(tx, *invoice, *adjustment); err != nil {
+			return err
+		}
+
+		lineType := models.InvoiceLineDebitNote
+		description := fmt.Sprintf("Nota debit %s: %s", adjustment.Number, adjustment.Reason)
+		if input.LineType != "" {
+			lineType = input.LineType
+			description = fmt.Sprintf("%s (%s)", adjustment.Reason, adjustment.Number)
+		}
+		if err := addAdjustmentLine(tx, invoice, *adjustment, lineType, description, amount); err != nil {
+			return err
+		}
+		if err := applyInvoicePayments(tx, invoice); err != nil {
+			return err
+		}
+
+		if _, err := applyCustomerCredit(tx, customer); err != nil {
+			return err
+		}
+		return tx.Where("id = ?", invoice.ID).First(invoice).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("🧾 Debit note %s of %.2f issued for invoice %s", adjustment.Number, adjustment.Amount, invoice.InvoiceNumber)
+	return adjustment, invoice, nil
+}
+
+// Rebill voids a monthly invoice and issues a new one priced from the corrected water usage of
+// the same month. Penalty and debit note lines and the due date are carried over, and the payments
+// of the old invoice move to the new one; whatever they paid beyond the new total becomes credit
+// on the customer.
+func (s *InvoiceAdjustmentService) Rebill(input InvoiceAdjustmentInput) (*models.InvoiceAdjustment, *models.Invoice, error) {
+	if input.Reason == "" {
+		return nil, nil, ErrAdjustmentReason
+	}
+
+	var original models.Invoice
+	if err := config.DB.Where("id = ? AND tenant_id = ?", input.InvoiceID, input.TenantID).First(&original).Error; err != nil {
+		return nil, nil, ErrPaymentInvoiceNotFound
+	}
+	if original.Status == models.InvoiceStatusVoid {
+		return nil, nil, ErrInvoiceVoided
+	}
+	if original.Type != "monthly" {
+		return nil, nil, ErrRebillNotMonthly
+	}
+
+	replacement, lines, err := s.repricedInvoice(original)
+	if err != nil {
+		return nil, nil, err
+	}
+	replacement.Notes = fmt.Sprintf("Tagihan ulang %s: %s", original.InvoiceNumber, input.Reason)
+
+	var adjustment *models.InvoiceAdjustment
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		customer, invoice, err := lockIssuedInvoice(tx, input.TenantID, input.InvoiceID)
+		if err != nil {
+			return err
+		}
+		if inActiveInstallmentPlan(tx, *invoice) {
+			return ErrInstallmentInvoiceInPlan
+		}
+
+		if err := CreateInvoice(tx, replacement, lines); err != nil {
+			return err
+		}
+		if err := RecordInvoiceCharges(tx, *replacement); err != nil {
+			return err
+		}
+
+		// Money received for the old invoice now pays the new one
+		if err := tx.Model(&models.Payment{}).Where("invoice_id = ?", invoice.ID).
+			Update("invoice_id", replacement.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PaymentAllocation{}).Where("invoice_id = ?", invoice.ID).
+			Update("invoice_id", replacement.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.InvoicePenalty{}).Where("charged_invoice_id = ?", invoice.ID).
+			Update("charged_invoice_id", replacement.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PaymentTransaction{}).
+			Where("invoice_id = ? AND status = ?", invoice.ID, models.PaymentTransactionPending).
+			Update("invoice_id", replacement.ID).Error; err != nil {
+			return err
+		}
+		if err := applyInvoicePayments(tx, invoice); err != nil {
+			return err
+		}
+
+		adjustment, err = voidInvoice(tx, invoice, input, &replacement.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := releaseExcessPayments(tx, customer, replacement); err != nil {
+			return err
+		}
+		if err := applyInvoicePayments(tx, replacement); err != nil {
+			return err
+		}
+		if _, err := applyCustomerCredit(tx, customer); err != nil {
+			return err
+		}
+		return tx.Where("id = ?", replacement.ID).First(replacement).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("🔁 Invoice %s rebilled as %s (%.2f -> %.2f)", original.InvoiceNumber, replacement.InvoiceNumber, original.TotalAmount, replacement.TotalAmount)
+	s.notifier.InvoicesIssued(input.TenantID, []models.Invoice{*replacement})
+
+	return adjustment, replacement, nil
+}
+
+// repricedInvoice prices the water usage of an invoice's month again, the same way the monthly
+// generation does, and returns the replacement invoice with its lines
+func (s *InvoiceAdjustmentService) repricedInvoice(original models.Invoice) (*models.Invoice, []models.InvoiceLine, error) {
+	var usage models.WaterUsage
+	if err := config.DB.Where("tenant_id = ? AND customer_id = ? AND usage_month = ?",
+		original.TenantID, original.CustomerID, original.UsageMonth).First(&usage).Error; err != nil {
+		return nil, nil, ErrRebillUsageNotFound
+	}
+	if HasUnresolvedAnomaly(usage.ID) {
+		return nil, nil, ErrRebillUsageInReview
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("id = ? AND tenant_id = ?", original.CustomerID, original.TenantID).First(&customer).Error; err != nil {
+		return nil, nil, ErrLedgerCustomerNotFound
+	}
+
+	var subType models.SubscriptionType
+	if err := config.DB.Where("id = ? AND tenant_id = ?", customer.SubscriptionID, original.TenantID).First(&subType).Error; err != nil {
+		return nil, nil, fmt.Errorf("subscription type not found for customer %s", customer.ID)
+	}
+
+	// Billed from the amount stored on the corrected usage, like the monthly generation
+	tariff := UsageTariff(usage)
+	if usage.AmountCalculated <= 0 && usage.UsageM3 > 0 {
+		var err error
+		tariff, err = s.tariffEngine.CalculateForCustomer(original.TenantID, customer, usage.UsageM3)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to calculate tariff: %w", err)
+		}
+	}
+
+	lines, _ := MonthlyInvoiceLines(tariff, subType, usage.TrueUpAmount, LoadBillingSettings(original.TenantID).TaxPercent)
+
+	// The penalty and debit note lines of the old invoice are billed again; its void credits them
+	originalLines, err := InvoiceLines(config.DB, original)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, line := range originalLines {
+		switch {
+		case line.Type == models.InvoiceLinePenalty:
+			lines = append(lines, invoiceLine(line.Type, line.Description, line.Quantity, line.Unit, line.UnitPrice, line.Amount, line.TaxPercent))
+		case isAdjustmentLine(line) && line.Total > 0:
+			debitNote := invoiceLine(line.Type, line.Description, line.Quantity, line.Unit, line.UnitPrice, line.Amount, line.TaxPercent)
+			debitNote.AdjustmentID = line.AdjustmentID
+			lines = append(lines, debitNote)
+		}
+	}
+
+	replacement := &models.Invoice{
+		CustomerID:      original.CustomerID,
+		TenantID:        original.TenantID,
+		UsageMonth:      original.UsageMonth,
+		UsageM3:         usage.UsageM3,
+		PaymentStatus:   models.PaymentStatusUnpaid,
+		DueDate:         original.DueDate,
+		Type:            original.Type,
+		Status:          models.InvoiceStatusIssued,
+		TariffBreakdown: tariff.BreakdownJSON(),
+
+		ReplacesInvoiceID: &original.ID,
+	}
+	ApplyInvoiceLines(replacement, lines)
+	if replacement.TotalAmount <= 0 || replacement.TotalAmount > 999999999 {
+		return nil, nil, ErrRebillAmountInvalid
+	}
+
+	return replacement, lines, nil
+}
+
+// lockIssuedInvoice locks an invoice that can still be adjusted together with its customer
+func lockIssuedInvoice(tx *gorm.DB, tenantID, invoiceID uuid.UUID) (*models.Customer, *models.Invoice, error) {
+	var invoice models.Invoice
+	if err := tx.Where("id = ? AND tenant_id = ?", invoiceID, tenantID).First(&invoice).Error; err != nil {
+		return nil, nil, ErrPaymentInvoiceNotFound
+	}
+
+	customer, locked, err := lockInvoice(tx, invoice)
+	if err != nil {
+		return nil, nil, err
+	}
+	if locked.Status == models.InvoiceStatusVoid {
+		return nil, nil, ErrInvoiceVoided
+	}
+	return customer, locked, nil
+}
+
+// voidInvoice credits the remaining amount of a locked invoice and marks it void. Its completed
+// payments must have been removed or moved to the replacement before.
+func voidInvoice(tx *gorm.DB, invoice *models.Invoice, input InvoiceAdjustmentInput, replacementID *uuid.UUID) (*models.InvoiceAdjustment, error) {
+	adjustment, err := createAdjustment(tx, *invoice, models.InvoiceAdjustmentVoid, invoice.TotalAmount, input)
+	if err != nil {
+		return nil, err
+	}
+	if replacementID != nil {
+		adjustment.ReplacementInvoiceID = replacementID
+		if err := tx.Model(adjustment).Update("replacement_invoice_id", *replacementID).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := recordAdjustmentEntry(tx, *invoice, *adjustment); err != nil {
+		return nil, err
+	}
+
+	description := fmt.Sprintf("Pembatalan %s: %s", adjustment.Number, adjustment.Reason)
+	if err := addAdjustmentLine(tx, invoice, *adjustment, models.InvoiceLineVoid, description, -invoice.TotalAmount); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invoice.Status = models.InvoiceStatusVoid
+	invoice.VoidedAt = &now
+	invoice.VoidedBy = input.CreatedBy
+	invoice.VoidReason = input.Reason
+	invoice.ReplacedByInvoiceID = replacementID
+	invoice.PaymentStatus = models.PaymentStatusVoid
+
+	// Updated directly instead of through applyInvoicePayments: a void registration invoice
+	// must not activate the customer
+	if err := tx.Model(&models.Invoice{}).Where("id = ?", invoice.ID).Updates(map[string]interface{}{
+		"status":                 invoice.Status,
+		"voided_at":              now,
+		"voided_by":              input.CreatedBy,
+		"void_reason":            input.Reason,
+		"replaced_by_invoice_id": replacementID,
+		"payment_status":         invoice.PaymentStatus,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return adjustment, nil
+}
+
+// releaseExcessPayments turns the part of a locked invoice's completed payments beyond its total
+// into credit on the customer, newest payment first
+func releaseExcessPayments(tx *gorm.DB, customer *models.Customer, invoice *models.Invoice) error {
+	var payments []models.Payment
+	if err := tx.Where("invoice_id = ? AND status = ?", invoice.ID, models.PaymentRecordCompleted).
+		Order("paid_at DESC").Find(&payments).Error; err != nil {
+		return err
+	}
+
+	var paid float64
+	for _, payment := range payments {
+		paid += payment.Amount
+	}
+	excess := roundMoney(paid - invoice.TotalAmount)
+
+	for _, payment := range payments {
+		if excess <= 0 {
+			break
+		}
+
+		released := roundMoney(math.Min(excess, payment.Amount))
+		amount := roundMoney(payment.Amount - released)
+		updates := map[string]interface{}{
+			"amount":  amount,
+			"penalty": math.Min(payment.Penalty, amount),
+		}
+		// Money received stays on the payment as its credit part; credit that was applied
+		// simply goes back to the balance
+		if !payment.FromCredit {
+			updates["credit_amount"] = roundMoney(payment.CreditAmount + released)
+		}
+		if err := tx.Model(&payment).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if payment.CustomerPaymentID != nil {
+			if err := tx.Model(&models.PaymentAllocation{}).Where("payment_id = ?", payment.ID).
+				Update("amount", amount).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.CustomerPayment{}).Where("id = ?", *payment.CustomerPaymentID).Updates(map[string]interface{}{
+				"allocated_amount": gorm.Expr("allocated_amount - ?", released),
+				"credit_amount":    gorm.Expr("credit_amount + ?", released),
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := adjustCreditBalance(tx, customer, released); err != nil {
+			return err
+		}
+		excess = roundMoney(excess - released)
+	}
+
+	return nil
+}
+
+// createAdjustment stores an adjustment document with the next number of its type for the month,
+// taken from the tenant's sequence for the number prefix like invoice numbers
+func createAdjustment(tx *gorm.DB, invoice models.Invoice, adjustmentType models.InvoiceAdjustmentType, amount float64, input InvoiceAdjustmentInput) (*models.InvoiceAdjustment, error) {
+	now := time.Now()
+
+	prefix := "VD"
+	switch adjustmentType {
+	case models.InvoiceAdjustmentCreditNote:
+		prefix = "CN"
+	case models.InvoiceAdjustmentDebitNote:
+		prefix = "DN"
+	}
+	prefix = fmt.Sprintf("%s-%s", prefix, now.Format("200601"))
+
+	sequence, err := lockSequence(tx, invoice.TenantID, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// Numbers issued before the sequence existed are skipped
+	var number string
+	next := sequence.LastNumber
+	for {
+		next++
+		number = fmt.Sprintf("%s-%04d", prefix, next)
+
+		var count int64
+		if err := tx.Unscoped().Model(&models.InvoiceAdjustment{}).
+			Where("tenant_id = ? AND number = ?", invoice.TenantID, number).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			break
+		}
+	}
+	if err := updateSequence(tx, sequence, next); err != nil {
+		return nil, err
+	}
+
+	adjustment := models.InvoiceAdjustment{
+		TenantID:   invoice.TenantID,
+		InvoiceID:  invoice.ID,
+		CustomerID: invoice.CustomerID,
+		Type:       adjustmentType,
+		Number:     number,
+		Amount:     roundMoney(amount),
+		Reason:     input.Reason,
+		IssuedAt:   now,
+		CreatedBy:  input.CreatedBy,
+	}
+	if err := tx.Create(&adjustment).Error; err != nil {
+		return nil, err
+	}
+	return &adjustment, nil
+}
+
+// recordAdjustmentEntry posts an adjustment document to the customer ledger: debit notes are
+// debited, credit notes and voids credited
+func recordAdjustmentEntry(tx *gorm.DB, invoice models.Invoice, adjustment models.InvoiceAdjustment) error {
+	if adjustment.Amount <= 0 {
+		return nil
+	}
+
+	entry := models.CustomerLedgerEntry{
+		TenantID:   invoice.TenantID,
+		CustomerID: invoice.CustomerID,
+		EntryDate:  adjustment.IssuedAt,
+		InvoiceID:  &invoice.ID,
+		CreatedBy:  adjustment.CreatedBy,
+	}
+
+	switch adjustment.Type {
+	case models.InvoiceAdjustmentCreditNote:
+		entry.Type = models.LedgerEntryCreditNote
+		entry.Credit = adjustment.Amount
+		entry.Description = fmt.Sprintf("Nota kredit %s pada tagihan %s: %s", adjustment.Number, invoice.InvoiceNumber, adjustment.Reason)
+	case models.InvoiceAdjustmentDebitNote:
+		entry.Type = models.LedgerEntryDebitNote
+		entry.Debit = adjustment.Amount
+		entry.Description = fmt.Sprintf("Nota debit %s pada tagihan %s: %s", adjustment.Number, invoice.InvoiceNumber, adjustment.Reason)
+	default:
+		entry.Type = models.LedgerEntryVoid
+		entry.Credit = adjustment.Amount
+		entry.Description = fmt.Sprintf("Pembatalan tagihan %s (%s): %s", invoice.InvoiceNumber, adjustment.Number, adjustment.Reason)
+	}
+
+	return tx.Create(&entry).Error
+}
+
+func validateAdjustment(input InvoiceAdjustmentInput) error {
+	if input.Reason == "" {
+		return ErrAdjustmentReason
+	}
+	if roundMoney(input.Amount) <= 0 {
+		return ErrAdjustmentAmountInvalid
+	}
+	return nil
+}
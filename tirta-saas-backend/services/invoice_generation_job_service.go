@@ -0,0 +1,573 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrGenerationJobNotFound  = errors.New("job pembuatan tagihan tidak ditemukan")
+	ErrGenerationJobActive    = errors.New("pembuatan tagihan untuk periode ini sedang berjalan")
+	ErrGenerationJobNotFailed = errors.New("job tidak memiliki kegagalan untuk diulang")
+
+	errGenerationItemDone  = errors.New("generation item already processed")
+	errGenerationClaimLost = errors.New("generation job was taken over by another worker")
+)
+
+const (
+	// generationBatchSize is the number of customers billed between two progress updates
+	generationBatchSize = 100
+	// generationStaleAfter is how long a running job may go without heartbeat before another
+	// worker takes it over
+	generationStaleAfter = 2 * time.Minute
+	// generationHeartbeatInterval is how often a job being prepared sends its heartbeat
+	generationHeartbeatInterval = 30 * time.Second
+)
+
+// generationClaimMu keeps the workers of this process from claiming two jobs of a tenant at once;
+// the generation-claim lease does the same between instances
+var generationClaimMu sync.Mutex
+
+// InvoiceGenerationJobInput describes a generation run to queue
+type InvoiceGenerationJobInput struct {
+	TenantID        uuid.UUID
+	UsageMonth      string      // YYYY-MM
+	CustomerIDs     []uuid.UUID // empty = all customers
+	EstimateMissing bool
+	TriggerType     string // scheduled, manual
+	TriggeredBy     *uuid.UUID
+}
+
+// InvoiceGenerationJobService queues monthly invoice runs and processes them in batches
+type InvoiceGenerationJobService struct {
+	generator *InvoiceGenerationService
+}
+
+// NewInvoiceGenerationJobService creates new invoice generation job service
+func NewInvoiceGenerationJobService() *InvoiceGenerationJobService {
+	return &InvoiceGenerationJobService{
+		generator: NewInvoiceGenerationService(),
+	}
+}
+
+// Enqueue queues a generation run. A tenant has at most one waiting or running job per month.
+func (s *InvoiceGenerationJobService) Enqueue(input InvoiceGenerationJobInput) (*models.InvoiceGenerationJob, error) {
+	var active int64
+	if err := config.DB.Model(&models.InvoiceGenerationJob{}).
+		Where("tenant_id = ? AND usage_month = ? AND status IN ?", input.TenantID, input.UsageMonth,
+			[]string{models.GenerationJobQueued, models.GenerationJobRunning}).
+		Count(&active).Error; err != nil {
+		return nil, err
+	}
+	if active > 0 {
+		return nil, ErrGenerationJobActive
+	}
+
+	customerIDs, err := json.Marshal(input.CustomerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	job := models.InvoiceGenerationJob{
+		TenantID:        input.TenantID,
+		UsageMonth:      input.UsageMonth,
+		CustomerIDs:     string(customerIDs),
+		EstimateMissing: input.EstimateMissing,
+		TriggerType:     input.TriggerType,
+		TriggeredBy:     input.TriggeredBy,
+		Status:          models.GenerationJobQueued,
+	}
+	if err := config.DB.Create(&job).Error; err != nil {
+		return nil, err
+	}
+
+	log.Printf("📥 Queued invoice generation job %s for tenant %s (%s)", job.ID, input.TenantID, input.UsageMonth)
+	return &job, nil
+}
+
+// GetJob returns a job of the tenant
+func (s *InvoiceGenerationJobService) GetJob(tenantID, jobID uuid.UUID) (*models.InvoiceGenerationJob, error) {
+	var job models.InvoiceGenerationJob
+	if err := config.DB.Where("id = ? AND tenant_id = ?", jobID, tenantID).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGenerationJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Retry queues a job that failed, or completed with failed customers, again. Its failed customers
+// are tried again; customers already billed by it are not billed twice.
+func (s *InvoiceGenerationJobService) Retry(tenantID, jobID uuid.UUID) (*models.InvoiceGenerationJob, error) {
+	job, err := s.GetJob(tenantID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != models.GenerationJobFailed && !(job.Status == models.GenerationJobCompleted && job.FailedCount > 0) {
+		return nil, ErrGenerationJobNotFailed
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.InvoiceGenerationJob{}).
+			Where("id = ? AND status = ?", job.ID, job.Status).
+			Updates(map[string]interface{}{
+				"status":        models.GenerationJobQueued,
+				"error_message": "",
+				"finished_at":   nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrGenerationJobNotFailed
+		}
+
+		// Customers whose usage could not be estimated have no usage to bill yet and stay failed
+		return tx.Model(&models.InvoiceGenerationJobItem{}).
+			Where("job_id = ? AND status = ? AND usage_id IS NOT NULL", job.ID, models.GenerationItemFailed).
+			Updates(map[string]interface{}{
+				"status":       models.GenerationItemPending,
+				"message":      "",
+				"processed_at": nil,
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetJob(tenantID, jobID)
+}
+
+// ClaimNext marks the oldest waiting job as running for a worker. Running jobs whose worker stopped
+// sending heartbeats are claimed again, so a job interrupted by a crash resumes where it stopped.
+// Returns nil when there is nothing to do.
+func (s *InvoiceGenerationJobService) ClaimNext(workerID string) (*models.InvoiceGenerationJob, error) {
+	generationClaimMu.Lock()
+	defer generationClaimMu.Unlock()
+
+	claimed, err := AcquireLease("generation-claim", InstanceID(), 15*time.Second)
+	if err != nil || !claimed {
+		return nil, err
+	}
+	defer func() {
+		if err := ReleaseLease("generation-claim", InstanceID()); err != nil {
+			log.Printf("⚠️  Failed to release generation claim lease: %v", err)
+		}
+	}()
+
+	stale := time.Now().Add(-generationStaleAfter)
+	claimable := config.DB.Where("status = ? OR (status = ? AND (heartbeat_at IS NULL OR heartbeat_at < ?))",
+		models.GenerationJobQueued, models.GenerationJobRunning, stale)
+
+	var candidates []models.InvoiceGenerationJob
+	if err := claimable.Order("created_at ASC").Limit(10).Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	for _, job := range candidates {
+		// Invoice numbers of a tenant are given out in sequence, so its jobs run one at a time
+		var busy int64
+		if err := config.DB.Model(&models.InvoiceGenerationJob{}).
+			Where("tenant_id = ? AND id <> ? AND status = ? AND heartbeat_at >= ?", job.TenantID, job.ID, models.GenerationJobRunning, stale).
+			Count(&busy).Error; err != nil {
+			return nil, err
+		}
+		if busy > 0 {
+			continue
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{
+			"status":       models.GenerationJobRunning,
+			"worker_id":    workerID,
+			"heartbeat_at": now,
+		}
+		if job.StartedAt == nil {
+			updates["started_at"] = now
+		}
+		result := config.DB.Model(&models.InvoiceGenerationJob{}).
+			Where("id = ? AND (status = ? OR (status = ? AND (heartbeat_at IS NULL OR heartbeat_at < ?)))",
+				job.ID, models.GenerationJobQueued, models.GenerationJobRunning, stale).
+			Updates(updates)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		if job.Status == models.GenerationJobRunning {
+			log.Printf("♻️  Resuming invoice generation job %s", job.ID)
+		}
+		return s.GetJob(job.TenantID, job.ID)
+	}
+
+	return nil, nil
+}
+
+// Run processes a claimed job until all its items are done. A worker that lost its claim to
+// another worker, e.g. after missing heartbeats, stops and leaves the job to the new one.
+func (s *InvoiceGenerationJobService) Run(job *models.InvoiceGenerationJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.finish(job, fmt.Errorf("unexpected error: %v", r))
+		}
+	}()
+
+	if !job.Prepared {
+		if err := s.prepare(job); err != nil {
+			s.finish(job, err)
+			return
+		}
+	}
+
+	settings := LoadBillingSettings(job.TenantID)
+	calendar := NewBillingCalendar(settings)
+
+	for {
+		if err := s.heartbeat(job); err != nil {
+			s.finish(job, err)
+			return
+		}
+		processed, err := s.processBatch(job, settings, calendar)
+		if err != nil {
+			s.finish(job, err)
+			return
+		}
+		if err := s.updateProgress(job); err != nil {
+			if errors.Is(err, errGenerationClaimLost) {
+				s.finish(job, err)
+				return
+			}
+			log.Printf("⚠️  Failed to update progress of generation job %s: %v", job.ID, err)
+		}
+		if processed == 0 {
+			break
+		}
+	}
+
+	s.finish(job, nil)
+}
+
+// claimed limits an update of a job to the worker holding its claim
+func claimed(db *gorm.DB, job *models.InvoiceGenerationJob) *gorm.DB {
+	return db.Model(&models.InvoiceGenerationJob{}).Where("id = ? AND worker_id = ?", job.ID, job.WorkerID)
+}
+
+// heartbeat tells other workers the job is still being processed. It fails when the job was
+// claimed by another worker in the meantime.
+func (s *InvoiceGenerationJobService) heartbeat(job *models.InvoiceGenerationJob) error {
+	now := time.Now()
+	result := claimed(config.DB, job).Update("heartbeat_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errGenerationClaimLost
+	}
+	job.HeartbeatAt = &now
+	return nil
+}
+
+// prepare estimates missing readings and creates an item for every usage record to bill. Items
+// are unique per customer, so a job prepared again after it was taken over keeps one per customer.
+func (s *InvoiceGenerationJobService) prepare(job *models.InvoiceGenerationJob) error {
+	// Estimating a large tenant takes longer than generationStaleAfter
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(generationHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := s.heartbeat(job); err != nil {
+					log.Printf("⚠️  Heartbeat of generation job %s failed: %v", job.ID, err)
+				}
+			}
+		}
+	}()
+
+	var customerIDs []uuid.UUID
+	if job.CustomerIDs != "" {
+		if err := json.Unmarshal([]byte(job.CustomerIDs), &customerIDs); err != nil {
+			return err
+		}
+	}
+
+	items := make([]models.InvoiceGenerationJobItem, 0)
+	now := time.Now()
+
+	// Customers whose meter could not be read are billed on an estimate instead of being skipped
+	if job.EstimateMissing {
+		estimated, failures, err := NewMeterReadingService().EstimateMissingReadings(job.TenantID, job.UsageMonth, customerIDs)
+		if err != nil {
+			return err
+		}
+		job.EstimatedCount = estimated
+		for customerID, err := range failures {
+			items = append(items, models.InvoiceGenerationJobItem{
+				JobID:       job.ID,
+				TenantID:    job.TenantID,
+				CustomerID:  customerID,
+				Status:      models.GenerationItemFailed,
+				Message:     fmt.Sprintf("Failed to estimate usage for customer %s: %v", customerID, err),
+				ProcessedAt: &now,
+			})
+		}
+	}
+
+	var usages []models.WaterUsage
+	query := config.DB.Select("id", "customer_id").Where("usage_month = ? AND tenant_id = ?", job.UsageMonth, job.TenantID)
+	if len(customerIDs) > 0 {
+		query = query.Where("customer_id IN ?", customerIDs)
+	}
+	if err := query.Find(&usages).Error; err != nil {
+		return fmt.Errorf("failed to fetch water usage: %w", err)
+	}
+	if len(usages) == 0 {
+		return ErrNoUsageForPeriod
+	}
+
+	for _, usage := range usages {
+		usageID := usage.ID
+		items = append(items, models.InvoiceGenerationJobItem{
+			JobID:      job.ID,
+			TenantID:   job.TenantID,
+			CustomerID: usage.CustomerID,
+			UsageID:    &usageID,
+			Status:     models.GenerationItemPending,
+		})
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&items, 500).Error; err != nil {
+			return err
+		}
+
+		var total int64
+		if err := tx.Model(&models.InvoiceGenerationJobItem{}).Where("job_id = ?", job.ID).Count(&total).Error; err != nil {
+			return err
+		}
+
+		result := claimed(tx, job).Updates(map[string]interface{}{
+			"prepared":        true,
+			"total_items":     total,
+			"estimated_count": job.EstimatedCount,
+			"heartbeat_at":    time.Now(),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errGenerationClaimLost
+		}
+		job.Prepared = true
+		job.TotalItems = int(total)
+		return nil
+	})
+}
+
+// processBatch bills the next pending items of a job. Each item is marked in the transaction that
+// creates its invoice, so an item is never billed twice when the job is resumed.
+func (s *InvoiceGenerationJobService) processBatch(job *models.InvoiceGenerationJob, settings models.TenantSettings, calendar *BillingCalendar) (int, error) {
+	var items []models.InvoiceGenerationJobItem
+	if err := config.DB.Where("job_id = ? AND status = ?", job.ID, models.GenerationItemPending).
+		Order("id ASC").Limit(generationBatchSize).Find(&items).Error; err != nil {
+		return 0, err
+	}
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	type pendingDraft struct {
+		item  models.InvoiceGenerationJobItem
+		draft *invoiceDraft
+	}
+	drafts := make([]pendingDraft, 0, len(items))
+
+	for _, item := range items {
+		var usage models.WaterUsage
+		if err := config.DB.Where("id = ? AND tenant_id = ?", item.UsageID, job.TenantID).First(&usage).Error; err != nil {
+			s.markItem(config.DB, item, models.GenerationItemFailed, nil, 0, fmt.Sprintf("Water usage not found for customer %s", item.CustomerID))
+			continue
+		}
+
+		draft, err := s.generator.prepareInvoice(job.TenantID, usage, settings, calendar)
+		if err != nil {
+			status := models.GenerationItemFailed
+			if IsGenerationSkip(err) {
+				status = models.GenerationItemSkipped
+			}
+			s.markItem(config.DB, item, status, nil, 0, err.Error())
+			continue
+		}
+		drafts = append(drafts, pendingDraft{item: item, draft: draft})
+	}
+
+	invoices := make([]models.Invoice, 0, len(drafts))
+	for _, pending := range drafts {
+		item := pending.item
+		draft := pending.draft
+		err := s.generator.saveInvoice(draft, func(tx *gorm.DB) error {
+			return s.markItem(tx, item, models.GenerationItemGenerated, &draft.invoice.ID, draft.invoice.TotalAmount, "")
+		})
+		if err != nil {
+			if !errors.Is(err, errGenerationItemDone) {
+				s.markItem(config.DB, item, models.GenerationItemFailed, nil, 0,
+					fmt.Sprintf("Failed to create invoice for customer %s: %v", item.CustomerID, err))
+			}
+			continue
+		}
+		invoices = append(invoices, draft.invoice)
+	}
+
+	if len(invoices) > 0 {
+		// Credit left from overpayments pays the new invoices first
+		invoices = NewCustomerLedgerService().ApplyCreditToInvoices(job.TenantID, invoices)
+		NewBillingNotifier().InvoicesIssued(job.TenantID, invoices)
+	}
+
+	return len(items), nil
+}
+
+// markItem records the outcome of an item still pending. It fails when the item was processed in
+// the meantime, which rolls back an invoice created for it a second time.
+func (s *InvoiceGenerationJobService) markItem(db *gorm.DB, item models.InvoiceGenerationJobItem, status string, invoiceID *uuid.UUID, amount float64, message string) error {
+	result := db.Model(&models.InvoiceGenerationJobItem{}).
+		Where("id = ? AND status = ?", item.ID, models.GenerationItemPending).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"invoice_id":   invoiceID,
+			"amount":       amount,
+			"message":      message,
+			"processed_at": time.Now(),
+		})
+	if result.Error != nil {
+		log.Printf("⚠️  Failed to record generation item %s: %v", item.ID, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errGenerationItemDone
+	}
+	return nil
+}
+
+// updateProgress recounts the items of a job and refreshes its heartbeat
+func (s *InvoiceGenerationJobService) updateProgress(job *models.InvoiceGenerationJob) error {
+	var counts []struct {
+		Status string
+		Count  int
+		Amount float64
+	}
+	if err := config.DB.Model(&models.InvoiceGenerationJobItem{}).
+		Select("status, COUNT(*) as count, COALESCE(SUM(amount), 0) as amount").
+		Where("job_id = ?", job.ID).Group("status").Scan(&counts).Error; err != nil {
+		return err
+	}
+
+	job.ProcessedItems, job.SuccessCount, job.SkippedCount, job.FailedCount, job.TotalAmount = 0, 0, 0, 0, 0
+	for _, count := range counts {
+		switch count.Status {
+		case models.GenerationItemGenerated:
+			job.SuccessCount = count.Count
+			job.TotalAmount = roundMoney(count.Amount)
+		case models.GenerationItemSkipped:
+			job.SkippedCount = count.Count
+		case models.GenerationItemFailed:
+			job.FailedCount = count.Count
+		}
+	}
+	job.ProcessedItems = job.SuccessCount + job.SkippedCount + job.FailedCount
+
+	now := time.Now()
+	result := claimed(config.DB, job).Updates(map[string]interface{}{
+		"processed_items": job.ProcessedItems,
+		"success_count":   job.SuccessCount,
+		"skipped_count":   job.SkippedCount,
+		"failed_count":    job.FailedCount,
+		"total_amount":    job.TotalAmount,
+		"heartbeat_at":    now,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errGenerationClaimLost
+	}
+	job.HeartbeatAt = &now
+	return nil
+}
+
+// finish ends a job and records it in the generation history. A worker that lost its claim leaves
+// the job to the worker that took it over.
+func (s *InvoiceGenerationJobService) finish(job *models.InvoiceGenerationJob, runErr error) {
+	if errors.Is(runErr, errGenerationClaimLost) {
+		log.Printf("⚠️  Invoice generation job %s was taken over by another worker, stopping", job.ID)
+		return
+	}
+
+	now := time.Now()
+	history := models.InvoiceGenerationHistory{
+		TenantID:     job.TenantID,
+		GeneratedFor: job.UsageMonth,
+		GeneratedAt:  now,
+		SuccessCount: job.SuccessCount,
+		SkippedCount: job.SkippedCount,
+		FailedCount:  job.FailedCount,
+		Status:       "success",
+		TriggerType:  job.TriggerType,
+		TriggeredBy:  job.TriggeredBy,
+		JobID:        &job.ID,
+	}
+	if job.StartedAt != nil {
+		history.ExecutionTimeMs = int(now.Sub(*job.StartedAt).Milliseconds())
+	}
+
+	job.Status = models.GenerationJobCompleted
+	if runErr != nil {
+		job.Status = models.GenerationJobFailed
+		job.ErrorMessage = runErr.Error()
+		history.Status = "failed"
+		history.ErrorMessage = runErr.Error()
+	} else if job.FailedCount > 0 {
+		history.Status = "partial"
+	}
+
+	if err := config.DB.Create(&history).Error; err != nil {
+		log.Printf("⚠️  Failed to log generation history: %v", err)
+	} else {
+		job.HistoryID = &history.ID
+	}
+
+	job.FinishedAt = &now
+	if err := claimed(config.DB, job).Updates(map[string]interface{}{
+		"status":        job.Status,
+		"error_message": job.ErrorMessage,
+		"finished_at":   now,
+		"history_id":    job.HistoryID,
+	}).Error; err != nil {
+		log.Printf("❌ Failed to finish generation job %s: %v", job.ID, err)
+		return
+	}
+
+	if runErr != nil {
+		log.Printf("❌ Invoice generation job %s failed: %v", job.ID, runErr)
+		return
+	}
+	log.Printf("✅ Invoice generation job %s: Generated %d, Skipped %d, Failed %d, Estimated %d",
+		job.ID, job.SuccessCount, job.SkippedCount, job.FailedCount, job.EstimatedCount)
+}
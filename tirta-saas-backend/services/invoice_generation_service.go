@@ -8,17 +8,25 @@ import (
 	"github.com/adipras/tirta-saas-backend/config"
 	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// ErrNoUsageForPeriod is returned when no water usage was recorded for the month to bill
+var ErrNoUsageForPeriod = errors.New("no water usage records found for the specified period")
+
 // InvoiceGenerationService handles invoice generation logic
 type InvoiceGenerationService struct {
 	numberGenerator *InvoiceNumberGenerator
+	tariffEngine    *TariffEngine
+	penaltyEngine   *PenaltyEngine
 }
 
 // NewInvoiceGenerationService creates new invoice generation service
 func NewInvoiceGenerationService() *InvoiceGenerationService {
 	return &InvoiceGenerationService{
-		numberGenerator: GetInvoiceNumberGenerator(),
+		numberGenerator: NewInvoiceNumberGenerator(),
+		tariffEngine:    NewTariffEngine(),
+		penaltyEngine:   NewPenaltyEngine(),
 	}
 }
 
@@ -28,12 +36,16 @@ type InvoiceGenerationRequest struct {
 	UsageMonth  string      // Format: YYYY-MM
 	CustomerIDs []uuid.UUID // Optional: specific customers, empty = all customers
 	DryRun      bool        // Preview mode, don't actually create
+
+	// Estimate usage for active customers whose meter was not read (ignored in preview mode)
+	EstimateMissing bool
 }
 
 // InvoiceGenerationResult contains result of invoice generation
 type InvoiceGenerationResult struct {
 	Success       int
 	Skipped       int
+	Estimated     int
 	Failed        int
 	TotalAmount   float64
 	Invoices      []models.Invoice
@@ -41,7 +53,8 @@ type InvoiceGenerationResult struct {
 	PreviewOnly   bool
 }
 
-// GenerateInvoices generates invoices for specified month and customers
+// GenerateInvoices generates invoices for specified month and customers. It runs within the
+// caller; large runs are queued as an InvoiceGenerationJob instead.
 func (s *InvoiceGenerationService) GenerateInvoices(req InvoiceGenerationRequest) (*InvoiceGenerationResult, error) {
 	result := &InvoiceGenerationResult{
 		Invoices:    []models.Invoice{},
@@ -49,15 +62,20 @@ func (s *InvoiceGenerationService) GenerateInvoices(req InvoiceGenerationRequest
 		PreviewOnly: req.DryRun,
 	}
 
-	// Get tenant settings for penalty calculation
-	var tenantSettings models.TenantSettings
-	err := config.DB.Where("tenant_id = ?", req.TenantID).First(&tenantSettings).Error
-	if err != nil {
-		// Use default settings if not found
-		tenantSettings.LatePenaltyPercent = 2.0
-		tenantSettings.LatePenaltyMaxCap = 100000
-		tenantSettings.GracePeriodDays = 3
-		tenantSettings.InvoiceDueDays = 14
+	// Get tenant settings for penalty calculation and the due date
+	tenantSettings := LoadBillingSettings(req.TenantID)
+	calendar := NewBillingCalendar(tenantSettings)
+
+	// Customers whose meter could not be read are billed on an estimate instead of being skipped
+	if req.EstimateMissing && !req.DryRun {
+		estimated, failures, err := NewMeterReadingService().EstimateMissingReadings(req.TenantID, req.UsageMonth, req.CustomerIDs)
+		result.Estimated = estimated
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		for customerID, err := range failures {
+			result.Errors = append(result.Errors, fmt.Sprintf("Failed to estimate usage for customer %s: %v", customerID, err))
+		}
 	}
 
 	// Get water usage records for the month
@@ -72,162 +90,227 @@ func (s *InvoiceGenerationService) GenerateInvoices(req InvoiceGenerationRequest
 	}
 
 	if len(usages) == 0 {
-		return result, errors.New("no water usage records found for the specified period")
-	}
-
-	// Generate invoice numbers in batch
-	invoiceNumbers, err := s.numberGenerator.GenerateInvoiceNumberBatch(req.TenantID, time.Now(), len(usages))
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate invoice numbers: %w", err)
+		return result, ErrNoUsageForPeriod
 	}
 
-	invoiceIndex := 0
-
+	drafts := make([]*invoiceDraft, 0, len(usages))
 	for _, usage := range usages {
-		// Check if invoice already exists
-		var existing models.Invoice
-		err := config.DB.Where("customer_id = ? AND usage_month = ? AND type = ?",
-			usage.CustomerID, usage.UsageMonth, "monthly").First(&existing).Error
-		if err == nil {
-			result.Skipped++
-			result.Errors = append(result.Errors, fmt.Sprintf("Invoice already exists for customer %s", usage.CustomerID))
-			continue
-		}
-
-		// Get customer details
-		var customer models.Customer
-		if err := config.DB.Where("id = ? AND tenant_id = ?", usage.CustomerID, req.TenantID).First(&customer).Error; err != nil {
-			result.Failed++
-			result.Errors = append(result.Errors, fmt.Sprintf("Customer not found: %s", usage.CustomerID))
-			continue
-		}
-
-		// Get subscription type
-		var subType models.SubscriptionType
-		if err := config.DB.Where("id = ? AND tenant_id = ?", customer.SubscriptionID, req.TenantID).First(&subType).Error; err != nil {
-			result.Failed++
-			result.Errors = append(result.Errors, fmt.Sprintf("Subscription type not found for customer: %s", usage.CustomerID))
-			continue
-		}
-
-		// Validate usage data
-		if usage.UsageM3 < 0 || usage.AmountCalculated < 0 {
-			result.Failed++
-			result.Errors = append(result.Errors, fmt.Sprintf("Invalid usage data for customer: %s", usage.CustomerID))
+		draft, err := s.prepareInvoice(req.TenantID, usage, tenantSettings, calendar)
+		if err != nil {
+			if IsGenerationSkip(err) {
+				result.Skipped++
+			} else {
+				result.Failed++
+			}
+			result.Errors = append(result.Errors, err.Error())
 			continue
 		}
+		drafts = append(drafts, draft)
+	}
 
-		// Calculate price per m3
-		pricePerM3 := 0.0
-		if usage.UsageM3 > 0 {
-			pricePerM3 = usage.AmountCalculated / usage.UsageM3
+	// A preview shows the numbers the invoices would get; saved invoices take theirs when created
+	if req.DryRun {
+		invoices := make([]*models.Invoice, len(drafts))
+		for i, draft := range drafts {
+			invoices[i] = &draft.invoice
 		}
-
-		// Calculate subtotal
-		waterCharge := usage.AmountCalculated
-		abonemen := subType.MonthlyFee
-		subTotal := waterCharge + abonemen
-
-		// Calculate late payment penalty from previous unpaid invoices
-		penaltyAmount := s.calculatePenalty(req.TenantID, usage.CustomerID, tenantSettings)
-
-		// Calculate total
-		totalAmount := subTotal + penaltyAmount
-
-		// Validate total
-		if totalAmount <= 0 || totalAmount > 999999999 {
-			result.Failed++
-			result.Errors = append(result.Errors, fmt.Sprintf("Invalid total amount for customer: %s", usage.CustomerID))
-			continue
+		if err := s.numberGenerator.Preview(req.TenantID, invoices); err != nil {
+			return nil, fmt.Errorf("failed to generate invoice numbers: %w", err)
 		}
+	}
 
-		// Calculate due date
-		dueDate := time.Now().AddDate(0, 0, tenantSettings.InvoiceDueDays)
-
-		// Create invoice
-		invoice := models.Invoice{
-			InvoiceNumber: invoiceNumbers[invoiceIndex],
-			CustomerID:    usage.CustomerID,
-			TenantID:      req.TenantID,
-			UsageMonth:    usage.UsageMonth,
-			UsageM3:       usage.UsageM3,
-			PricePerM3:    pricePerM3,
-			Abonemen:      abonemen,
-			WaterCharge:   waterCharge,
-			PenaltyAmount: penaltyAmount,
-			SubTotal:      subTotal,
-			TotalAmount:   totalAmount,
-			TotalPaid:     0,
-			PaymentStatus: models.PaymentStatusUnpaid,
-			IsPaid:        false,
-			DueDate:       &dueDate,
-			Type:          "monthly",
-			Notes:         fmt.Sprintf("Auto-generated invoice for %s", usage.UsageMonth),
-		}
-
-		invoiceIndex++
-
+	for _, draft := range drafts {
 		if !req.DryRun {
-			// Save to database
-			if err := config.DB.Create(&invoice).Error; err != nil {
+			if err := s.saveInvoice(draft, nil); err != nil {
 				result.Failed++
-				result.Errors = append(result.Errors, fmt.Sprintf("Failed to create invoice for customer %s: %v", usage.CustomerID, err))
+				result.Errors = append(result.Errors, fmt.Sprintf("Failed to create invoice for customer %s: %v", draft.invoice.CustomerID, err))
 				continue
 			}
 		}
 
 		result.Success++
-		result.TotalAmount += totalAmount
-		result.Invoices = append(result.Invoices, invoice)
+		result.TotalAmount += draft.invoice.TotalAmount
+		result.Invoices = append(result.Invoices, draft.invoice)
+	}
+
+	if !req.DryRun {
+		// Credit left from overpayments pays the new invoices first
+		result.Invoices = NewCustomerLedgerService().ApplyCreditToInvoices(req.TenantID, result.Invoices)
+		NewBillingNotifier().InvoicesIssued(req.TenantID, result.Invoices)
 	}
 
 	return result, nil
 }
 
-// calculatePenalty calculates late payment penalty for a customer
-func (s *InvoiceGenerationService) calculatePenalty(tenantID, customerID uuid.UUID, settings models.TenantSettings) float64 {
-	// Find unpaid invoices past due date
-	var unpaidInvoices []models.Invoice
-	now := time.Now()
-	gracePeriod := time.Duration(settings.GracePeriodDays) * 24 * time.Hour
+// invoiceDraft is a monthly invoice priced for one water usage record, with its lines and the
+// late penalties billed on it
+type invoiceDraft struct {
+	invoice   models.Invoice
+	lines     []models.InvoiceLine
+	penalties []models.InvoicePenalty
+}
 
-	config.DB.Where("tenant_id = ? AND customer_id = ? AND payment_status != ? AND due_date < ?",
-		tenantID, customerID, models.PaymentStatusPaid, now.Add(-gracePeriod)).
-		Find(&unpaidInvoices)
+// generationSkip is a usage record that is not billed, as opposed to one that failed
+type generationSkip struct {
+	reason string
+}
+
+func (e *generationSkip) Error() string {
+	return e.reason
+}
+
+// IsGenerationSkip reports whether a generation error only means the usage was not billed this time
+func IsGenerationSkip(err error) bool {
+	var skip *generationSkip
+	return errors.As(err, &skip)
+}
 
-	if len(unpaidInvoices) == 0 {
-		return 0
+// prepareInvoice prices the invoice of a usage record without saving it
+func (s *InvoiceGenerationService) prepareInvoice(tenantID uuid.UUID, usage models.WaterUsage, tenantSettings models.TenantSettings, calendar *BillingCalendar) (*invoiceDraft, error) {
+	// Check if invoice already exists
+	var existing models.Invoice
+	err := config.DB.Where("customer_id = ? AND usage_month = ? AND type = ? AND status <> ?",
+		usage.CustomerID, usage.UsageMonth, "monthly", models.InvoiceStatusVoid).First(&existing).Error
+	if err == nil {
+		return nil, &generationSkip{fmt.Sprintf("Invoice already exists for customer %s", usage.CustomerID)}
 	}
 
-	totalPenalty := 0.0
-	for _, invoice := range unpaidInvoices {
-		outstanding := invoice.TotalAmount - invoice.TotalPaid
-		penalty := outstanding * (settings.LatePenaltyPercent / 100.0)
+	// Readings waiting in the anomaly review queue are billed once they are confirmed or corrected
+	if HasUnresolvedAnomaly(usage.ID) {
+		return nil, &generationSkip{fmt.Sprintf("Reading for customer %s is waiting for anomaly review", usage.CustomerID)}
+	}
+
+	// Get customer details
+	var customer models.Customer
+	if err := config.DB.Where("id = ? AND tenant_id = ?", usage.CustomerID, tenantID).First(&customer).Error; err != nil {
+		return nil, fmt.Errorf("Customer not found: %s", usage.CustomerID)
+	}
 
-		// Apply max cap
-		if penalty > settings.LatePenaltyMaxCap {
-			penalty = settings.LatePenaltyMaxCap
+	// Get subscription type
+	var subType models.SubscriptionType
+	if err := config.DB.Where("id = ? AND tenant_id = ?", customer.SubscriptionID, tenantID).First(&subType).Error; err != nil {
+		return nil, fmt.Errorf("Subscription type not found for customer: %s", usage.CustomerID)
+	}
+
+	// Validate usage data
+	if usage.UsageM3 < 0 {
+		return nil, fmt.Errorf("Invalid usage data for customer: %s", usage.CustomerID)
+	}
+
+	// Bill the amount and blocks the usage was priced with when it was read, so a rate change
+	// before billing does not make the invoice disagree with the usage. Only usage stored without
+	// an amount is priced now.
+	tariff := UsageTariff(usage)
+	if usage.AmountCalculated <= 0 && usage.UsageM3 > 0 {
+		tariff, err = s.tariffEngine.CalculateForCustomer(tenantID, customer, usage.UsageM3)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to calculate tariff for customer %s: %v", usage.CustomerID, err)
 		}
+	}
+
+	// Water blocks, monthly fees and the difference between earlier estimates and the actual reading
+	lines, adjustment := MonthlyInvoiceLines(tariff, subType, usage.TrueUpAmount, tenantSettings.TaxPercent)
+
+	notes := fmt.Sprintf("Auto-generated invoice for %s", usage.UsageMonth)
+	if usage.ReadingMethod == models.ReadingMethodEstimated {
+		notes += " (estimated usage)"
+	}
+	if adjustment != roundMoney(usage.TrueUpAmount) {
+		notes += fmt.Sprintf(". True-up credit limited to %.2f of %.2f", -adjustment, -usage.TrueUpAmount)
+	} else if adjustment != 0 {
+		notes += fmt.Sprintf(". Includes true-up of %.2f m3 from estimated months", usage.TrueUpM3)
+	}
 
-		totalPenalty += penalty
+	// Late penalties of previous invoices not charged yet
+	penalties, err := s.penaltyEngine.Assess(tenantID, usage.CustomerID, tenantSettings, subType, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to calculate penalty for customer %s: %v", usage.CustomerID, err)
+	}
+	lines = append(lines, PenaltyInvoiceLines(penalties)...)
+
+	// Calculate due date with the tenant's due date rule
+	dueDate := calendar.DueDate(time.Now())
+
+	invoice := models.Invoice{
+		CustomerID:    usage.CustomerID,
+		TenantID:      tenantID,
+		UsageMonth:    usage.UsageMonth,
+		UsageM3:       usage.UsageM3,
+		TotalPaid:     0,
+		PaymentStatus: models.PaymentStatusUnpaid,
+		IsPaid:        false,
+		DueDate:       &dueDate,
+		Type:          "monthly",
+		Notes:         notes,
+
+		TariffBreakdown: tariff.BreakdownJSON(),
 	}
+	ApplyInvoiceLines(&invoice, lines)
+	invoice.Lines = lines
 
-	return totalPenalty
+	// Validate total
+	if invoice.TotalAmount <= 0 || invoice.TotalAmount > 999999999 {
+		return nil, fmt.Errorf("Invalid total amount for customer: %s", usage.CustomerID)
+	}
+
+	return &invoiceDraft{
+		invoice:   invoice,
+		lines:     lines,
+		penalties: penalties,
+	}, nil
+}
+
+// saveInvoice numbers a draft and stores it together with the customer's ledger entries. after
+// runs in the same transaction; when it fails the invoice number is given back.
+func (s *InvoiceGenerationService) saveInvoice(draft *invoiceDraft, after func(tx *gorm.DB) error) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := CreateInvoice(tx, &draft.invoice, draft.lines); err != nil {
+			return err
+		}
+		if err := RecordPenalties(tx, draft.invoice, draft.penalties); err != nil {
+			return err
+		}
+		if err := RecordInvoiceCharges(tx, draft.invoice); err != nil {
+			return err
+		}
+		if after != nil {
+			return after(tx)
+		}
+		return nil
+	})
 }
 
 // UpdateOverdueInvoices updates payment status of overdue invoices
 func (s *InvoiceGenerationService) UpdateOverdueInvoices(tenantID uuid.UUID) error {
 	now := time.Now()
 
-	// Update invoices that are past due date and not paid
+	// Find invoices that are past due date and not paid
+	var overdue []models.Invoice
+	if err := config.DB.Where("tenant_id = ? AND due_date < ? AND payment_status = ?", 
+		tenantID, now, models.PaymentStatusUnpaid).Find(&overdue).Error; err != nil {
+		return err
+	}
+
+	if len(overdue) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(overdue))
+	for i := range overdue {
+		ids[i] = overdue[i].ID
+		overdue[i].PaymentStatus = models.PaymentStatusOverdue
+	}
+
+	// Status is checked again so a payment posted in the meantime is not overwritten
 	result := config.DB.Model(&models.Invoice{}).
-		Where("tenant_id = ? AND due_date < ? AND payment_status = ?", 
-			tenantID, now, models.PaymentStatusUnpaid).
+		Where("id IN ? AND payment_status = ?", ids, models.PaymentStatusUnpaid).
 		Update("payment_status", models.PaymentStatusOverdue)
 
 	if result.Error != nil {
 		return result.Error
 	}
 
+	NewBillingNotifier().InvoicesOverdue(tenantID, overdue)
+
 	return nil
 }
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// InvoiceGenerationWorker runs queued invoice generation jobs on a pool of goroutines
+type InvoiceGenerationWorker struct {
+	jobs     *InvoiceGenerationJobService
+	size     int
+	interval time.Duration
+	workerID string
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewInvoiceGenerationWorker creates new invoice generation worker with size goroutines
+func NewInvoiceGenerationWorker(size int) *InvoiceGenerationWorker {
+	if size < 1 {
+		size = 1
+	}
+	return &InvoiceGenerationWorker{
+		jobs:     NewInvoiceGenerationJobService(),
+		size:     size,
+		interval: 10 * time.Second,
+		workerID: InstanceID(),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start starts the worker pool
+func (w *InvoiceGenerationWorker) Start() error {
+	for i := 0; i < w.size; i++ {
+		w.wg.Add(1)
+		go w.loop(fmt.Sprintf("%s-%d", w.workerID, i+1))
+	}
+
+	log.Println("✅ Invoice generation worker started successfully")
+	log.Printf("🧾 Generation jobs: %d workers, polling every %s", w.size, w.interval)
+
+	return nil
+}
+
+// Stop stops the worker pool, waiting for the jobs in progress
+func (w *InvoiceGenerationWorker) Stop() {
+	close(w.stop)
+	w.wg.Wait()
+	log.Println("🛑 Invoice generation worker stopped")
+}
+
+// loop claims and runs jobs until the worker is stopped, waiting an interval when there is none
+func (w *InvoiceGenerationWorker) loop(workerID string) {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		job, err := w.jobs.ClaimNext(workerID)
+		if err != nil {
+			log.Printf("❌ Failed to claim invoice generation job: %v", err)
+		}
+		if job != nil {
+			log.Printf("🧾 Worker %s running invoice generation job %s (%s)", workerID, job.ID, job.UsageMonth)
+			w.jobs.Run(job)
+			continue
+		}
+
+		select {
+		case <-w.stop:
+			return
+		case <-time.After(w.interval):
+		}
+	}
+}
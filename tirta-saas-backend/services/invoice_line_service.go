@@ -0,0 +1,313 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateInvoice numbers and stores a new invoice with its lines. The invoice's amounts are taken
+// from the lines, so callers only fill in the customer, usage and dates.
+func CreateInvoice(tx *gorm.DB, invoice *models.Invoice, lines []models.InvoiceLine) error {
+	ApplyInvoiceLines(invoice, lines)
+
+	if err := NewInvoiceNumberGenerator().Assign(tx, invoice); err != nil {
+		return err
+	}
+
+	invoice.Lines = nil
+	if err := tx.Create(invoice).Error; err != nil {
+		return err
+	}
+	return createInvoiceLines(tx, invoice, lines, 0)
+}
+
+// ApplyInvoiceLines fills the totals of an invoice, and the charge columns kept for older clients,
+// from its lines
+func ApplyInvoiceLines(invoice *models.Invoice, lines []models.InvoiceLine) {
+	var water, abonemen, trueUp, penalty, subTotal, tax, total, credit, debit float64
+	for _, line := range lines {
+		total += line.Total
+
+		if isAdjustmentLine(line) {
+			if line.Total < 0 {
+				credit -= line.Total
+			} else {
+				debit += line.Total
+			}
+			continue
+		}
+		if line.Type == models.InvoiceLinePenalty {
+			penalty += line.Total
+			continue
+		}
+
+		subTotal += line.Amount
+		tax += line.TaxAmount
+		switch line.Type {
+		case models.InvoiceLineWater:
+			water += line.Amount
+		case models.InvoiceLineAbonemen:
+			abonemen += line.Amount
+		case models.InvoiceLineTrueUp:
+			trueUp += line.Amount
+		}
+	}
+
+	invoice.WaterCharge = roundMoney(water)
+	invoice.Abonemen = roundMoney(abonemen)
+	invoice.AdjustmentAmount = roundMoney(trueUp)
+	invoice.PenaltyAmount = roundMoney(penalty)
+	invoice.SubTotal = roundMoney(subTotal)
+	invoice.TaxAmount = roundMoney(tax)
+	invoice.TotalAmount = roundMoney(total)
+	invoice.CreditNoteTotal = roundMoney(credit)
+	invoice.DebitNoteTotal = roundMoney(debit)
+
+	invoice.PricePerM3 = 0
+	if invoice.UsageM3 > 0 {
+		invoice.PricePerM3 = invoice.WaterCharge / invoice.UsageM3
+	}
+}
+
+// InvoiceLines returns the lines of an invoice in order. Invoices issued before lines existed get
+// lines built from their charge columns; these are not stored.
+func InvoiceLines(db *gorm.DB, invoice models.Invoice) ([]models.InvoiceLine, error) {
+	var lines []models.InvoiceLine
+	if err := db.Where("invoice_id = ?", invoice.ID).Order("position ASC").Find(&lines).Error; err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return legacyInvoiceLines(invoice), nil
+	}
+	return lines, nil
+}
+
+// MonthlyInvoiceLines prices the monthly charges of a customer: one water line per tariff block,
+// the subscription type's monthly and maintenance fees, and the true-up of earlier estimated
+// usage. The true-up corrects water already billed, so it is taxed the same; a true-up credit
+// cannot exceed the charges. The true-up billed is returned.
+func MonthlyInvoiceLines(tariff *TariffCalculation, subType models.SubscriptionType, trueUp, taxPercent float64) ([]models.InvoiceLine, float64) {
+	var lines []models.InvoiceLine
+
+	var waterTotal float64
+	for _, tier := range tariff.Breakdown {
+		if tier.Volume == 0 && tier.Amount == 0 {
+			continue
+		}
+		description := "Pemakaian air"
+		if len(tariff.Breakdown) > 1 {
+			description = fmt.Sprintf("Pemakaian air blok %s", tier.TierRange)
+		}
+		lines = append(lines, invoiceLine(models.InvoiceLineWater, description, tier.Volume, "m3", tier.PricePerUnit, tier.Amount, taxPercent))
+		waterTotal += tier.Amount
+	}
+	if len(lines) == 0 {
+		lines = append(lines, invoiceLine(models.InvoiceLineWater, "Pemakaian air", tariff.UsageM3, "m3", tariff.PricePerM3(), tariff.TotalAmount, taxPercent))
+		waterTotal = tariff.TotalAmount
+	} else if difference := roundMoney(tariff.TotalAmount - waterTotal); difference != 0 {
+		// Rounding of the blocks is settled on the last one, so the water lines add up to the tariff
+		last := &lines[len(lines)-1]
+		*last = invoiceLine(last.Type, last.Description, last.Quantity, last.Unit, last.UnitPrice, last.Amount+difference, taxPercent)
+	}
+
+	if subType.MonthlyFee > 0 {
+		lines = append(lines, invoiceLine(models.InvoiceLineAbonemen, "Abonemen", 1, "bulan", subType.MonthlyFee, subType.MonthlyFee, taxPercent))
+	}
+	if subType.MaintenanceFee > 0 {
+		lines = append(lines, invoiceLine(models.InvoiceLineMaintenance, "Biaya pemeliharaan", 1, "bulan", subType.MaintenanceFee, subType.MaintenanceFee, taxPercent))
+	}
+
+	var charges float64
+	for _, line := range lines {
+		charges += line.Amount
+	}
+	if trueUp < -charges {
+		trueUp = -charges
+	}
+	trueUp = roundMoney(trueUp)
+	if trueUp != 0 {
+		lines = append(lines, invoiceLine(models.InvoiceLineTrueUp, "Koreksi pemakaian estimasi", 1, "", trueUp, trueUp, taxPercent))
+	}
+
+	return lines, trueUp
+}
+
+// PenaltyInvoiceLines bills late penalties, one line per penalty period
+func PenaltyInvoiceLines(penalties []models.InvoicePenalty) []models.InvoiceLine {
+	lines := make([]models.InvoiceLine, 0, len(penalties))
+	for _, penalty := range penalties {
+		description := fmt.Sprintf("Denda keterlambatan %d hari (%s s.d. %s)", penalty.Days,
+			penalty.PeriodStart.Format("02/01/2006"), penalty.PeriodEnd.Format("02/01/2006"))
+		lines = append(lines, invoiceLine(models.InvoiceLinePenalty, description, 1, "", penalty.Amount, penalty.Amount, 0))
+	}
+	return lines
+}
+
+// RegistrationInvoiceLines bills the registration fee of a new connection with the tenant's tax
+func RegistrationInvoiceLines(tenantID uuid.UUID, fee float64) []models.InvoiceLine {
+	taxPercent := LoadBillingSettings(tenantID).TaxPercent
+	return []models.InvoiceLine{
+		invoiceLine(models.InvoiceLineRegistration, "Biaya pendaftaran sambungan baru", 1, "", fee, fee, taxPercent),
+	}
+}
+
+// UsageTariff is the tariff calculation stored on a water usage record when it was read
+func UsageTariff(usage models.WaterUsage) *TariffCalculation {
+	tariff := &TariffCalculation{
+		Method:      usage.TariffMethod,
+		CategoryID:  usage.TariffCategoryID,
+		UsageM3:     usage.UsageM3,
+		TotalAmount: usage.AmountCalculated,
+	}
+	if usage.TariffBreakdown != "" {
+		json.Unmarshal([]byte(usage.TariffBreakdown), &tariff.Breakdown)
+	}
+	return tariff
+}
+
+// addAdjustmentLine adds the line of a credit note, debit note or void to a locked invoice and
+// updates its totals
+func addAdjustmentLine(tx *gorm.DB, invoice *models.Invoice, adjustment models.InvoiceAdjustment, lineType models.InvoiceLineType, description string, amount float64) error {
+	lines, err := ensureInvoiceLines(tx, invoice)
+	if err != nil {
+		return err
+	}
+
+	line := invoiceLine(lineType, description, 1, "", amount, amount, 0)
+	line.AdjustmentID = &adjustment.ID
+
+	position := 0
+	if len(lines) > 0 {
+		position = lines[len(lines)-1].Position + 1
+	}
+	added := []models.InvoiceLine{line}
+	if err := createInvoiceLines(tx, invoice, added, position); err != nil {
+		return err
+	}
+
+	lines = append(lines, added...)
+	ApplyInvoiceLines(invoice, lines)
+	invoice.Lines = lines
+
+	return tx.Model(&models.Invoice{}).Where("id = ?", invoice.ID).Updates(map[string]interface{}{
+		"total_amount":      invoice.TotalAmount,
+		"credit_note_total": invoice.CreditNoteTotal,
+		"debit_note_total":  invoice.DebitNoteTotal,
+	}).Error
+}
+
+// ensureInvoiceLines returns the stored lines of an invoice, storing the lines built from its
+// charge columns first when it was issued before lines existed
+func ensureInvoiceLines(tx *gorm.DB, invoice *models.Invoice) ([]models.InvoiceLine, error) {
+	var lines []models.InvoiceLine
+	if err := tx.Where("invoice_id = ?", invoice.ID).Order("position ASC").Find(&lines).Error; err != nil {
+		return nil, err
+	}
+	if len(lines) > 0 {
+		return lines, nil
+	}
+
+	lines = legacyInvoiceLines(*invoice)
+	if err := createInvoiceLines(tx, invoice, lines, 0); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// legacyInvoiceLines builds the lines of an invoice issued before lines existed from its charge
+// columns. Whatever the columns do not explain is an OTHER line, so the lines add up to the total.
+func legacyInvoiceLines(invoice models.Invoice) []models.InvoiceLine {
+	var lines []models.InvoiceLine
+
+	if invoice.Type == "registration" {
+		fee := roundMoney(invoice.TotalAmount + invoice.CreditNoteTotal - invoice.DebitNoteTotal)
+		lines = append(lines, invoiceLine(models.InvoiceLineRegistration, "Biaya pendaftaran sambungan baru", 1, "", fee, fee, 0))
+	} else {
+		waterCharge := invoice.WaterCharge
+		if waterCharge == 0 {
+			waterCharge = invoice.UsageM3 * invoice.PricePerM3
+		}
+		tariff := &TariffCalculation{UsageM3: invoice.UsageM3, TotalAmount: waterCharge}
+		if invoice.TariffBreakdown != "" {
+			json.Unmarshal([]byte(invoice.TariffBreakdown), &tariff.Breakdown)
+		}
+		lines, _ = MonthlyInvoiceLines(tariff, models.SubscriptionType{MonthlyFee: invoice.Abonemen}, invoice.AdjustmentAmount, 0)
+
+		if invoice.PenaltyAmount != 0 {
+			lines = append(lines, invoiceLine(models.InvoiceLinePenalty, "Denda keterlambatan", 1, "", invoice.PenaltyAmount, invoice.PenaltyAmount, 0))
+		}
+	}
+
+	if invoice.CreditNoteTotal != 0 {
+		lines = append(lines, invoiceLine(models.InvoiceLineCreditNote, "Nota kredit dan pembatalan", 1, "", -invoice.CreditNoteTotal, -invoice.CreditNoteTotal, 0))
+	}
+	if invoice.DebitNoteTotal != 0 {
+		lines = append(lines, invoiceLine(models.InvoiceLineDebitNote, "Nota debit", 1, "", invoice.DebitNoteTotal, invoice.DebitNoteTotal, 0))
+	}
+
+	var total float64
+	for _, line := range lines {
+		total += line.Total
+	}
+	if difference := roundMoney(invoice.TotalAmount - total); difference != 0 {
+		lines = append(lines, invoiceLine(models.InvoiceLineOther, "Selisih tagihan", 1, "", difference, difference, 0))
+	}
+
+	for i := range lines {
+		lines[i].TenantID = invoice.TenantID
+		lines[i].InvoiceID = invoice.ID
+		lines[i].Position = i
+	}
+	return lines
+}
+
+// createInvoiceLines stores new lines of an invoice from the given position on
+func createInvoiceLines(tx *gorm.DB, invoice *models.Invoice, lines []models.InvoiceLine, position int) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	for i := range lines {
+		lines[i].TenantID = invoice.TenantID
+		lines[i].InvoiceID = invoice.ID
+		lines[i].Position = position + i
+	}
+	if err := tx.Create(&lines).Error; err != nil {
+		return err
+	}
+	invoice.Lines = append(invoice.Lines, lines...)
+	return nil
+}
+
+// invoiceLine is a line of quantity units at unit price; amount is given since tariff blocks are
+// priced by the tariff engine. Tax is added on top of the amount.
+func invoiceLine(lineType models.InvoiceLineType, description string, quantity float64, unit string, unitPrice, amount, taxPercent float64) models.InvoiceLine {
+	amount = roundMoney(amount)
+	tax := roundMoney(amount * taxPercent / 100)
+	return models.InvoiceLine{
+		Type:        lineType,
+		Description: description,
+		Quantity:    quantity,
+		Unit:        unit,
+		UnitPrice:   unitPrice,
+		Amount:      amount,
+		TaxPercent:  taxPercent,
+		TaxAmount:   tax,
+		Total:       roundMoney(amount + tax),
+	}
+}
+
+// isAdjustmentLine reports whether a line was added by a credit note, debit note or void
+func isAdjustmentLine(line models.InvoiceLine) bool {
+	if line.AdjustmentID != nil {
+		return true
+	}
+	switch line.Type {
+	case models.InvoiceLineCreditNote, models.InvoiceLineDebitNote, models.InvoiceLineVoid:
+		return true
+	}
+	return false
+}
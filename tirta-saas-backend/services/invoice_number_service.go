@@ -1,98 +1,227 @@
 package services
 
 import (
+	"errors"
 	"fmt"
-	"sync"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/adipras/tirta-saas-backend/config"
 	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// InvoiceNumberGenerator manages invoice number generation
-type InvoiceNumberGenerator struct {
-	mu sync.Mutex
-}
+// Invoice numbering defaults, used when the tenant has not configured its own
+const (
+	DefaultInvoicePrefix       = "INV"
+	DefaultInvoiceNumberFormat = "{PREFIX}-{YEAR}{MONTH}-{NUMBER}"
+)
 
 var (
-	invoiceNumberGenerator *InvoiceNumberGenerator
-	once                   sync.Once
+	ErrInvoiceNumberFormat  = errors.New("format nomor tagihan harus memuat {NUMBER} dan hanya token {PREFIX}, {YEAR}, {MONTH}, {AREA}; {MONTH} hanya bersama {YEAR}")
+	ErrInvoiceNumberTooLong = errors.New("nomor tagihan melebihi 50 karakter, perpendek format atau prefix")
 )
 
-// GetInvoiceNumberGenerator returns singleton instance
-func GetInvoiceNumberGenerator() *InvoiceNumberGenerator {
-	once.Do(func() {
-		invoiceNumberGenerator = &InvoiceNumberGenerator{}
-	})
-	return invoiceNumberGenerator
+var invoiceNumberTokenRegexp = regexp.MustCompile(`\{[^{}]*\}`)
+
+// ValidateInvoiceNumberFormat checks a tenant's invoice number format. {NUMBER} is required and
+// {MONTH} needs {YEAR}; the sequence restarts every month when the format has {MONTH}, every year
+// when it has {YEAR} only, and never otherwise.
+func ValidateInvoiceNumberFormat(format string) error {
+	if !strings.Contains(format, "{NUMBER}") || len(format) > 50 {
+		return ErrInvoiceNumberFormat
+	}
+	// Numbers restart every month, so without the year they would repeat a year later
+	if strings.Contains(format, "{MONTH}") && !strings.Contains(format, "{YEAR}") {
+		return ErrInvoiceNumberFormat
+	}
+	for _, token := range invoiceNumberTokenRegexp.FindAllString(format, -1) {
+		switch token {
+		case "{PREFIX}", "{YEAR}", "{MONTH}", "{AREA}", "{NUMBER}":
+		default:
+			return ErrInvoiceNumberFormat
+		}
+	}
+	return nil
+}
+
+// InvoiceNumberGenerator issues the invoice numbers of a tenant from its InvoiceSequence, rendered
+// with the tenant's InvoiceNumberFormat. Numbers are unique per tenant and have no gaps: the
+// sequence is taken in the transaction that creates the invoice.
+type InvoiceNumberGenerator struct{}
+
+// NewInvoiceNumberGenerator creates new invoice number generator
+func NewInvoiceNumberGenerator() *InvoiceNumberGenerator {
+	return &InvoiceNumberGenerator{}
 }
 
-// GenerateInvoiceNumber generates unique invoice number with format: INV-YYYYMM-XXXX
-// Thread-safe implementation with database counter
-func (g *InvoiceNumberGenerator) GenerateInvoiceNumber(tenantID uuid.UUID, date time.Time) (string, error) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
+// Assign gives an invoice about to be created in tx the next number of its tenant. The sequence
+// stays locked until tx ends, so call it right before the invoice is created.
+func (g *InvoiceNumberGenerator) Assign(tx *gorm.DB, invoice *models.Invoice) error {
+	format := loadInvoiceNumberFormat(invoice.TenantID)
+	issuedAt := time.Now().In(format.location)
+	period := format.period(issuedAt)
+
+	sequence, err := lockSequence(tx, invoice.TenantID, period)
+	if err != nil {
+		return err
+	}
+
+	number, invoiceNumber, err := format.next(tx, invoice.TenantID, sequence.LastNumber, issuedAt, customerAreaCode(tx, invoice.CustomerID))
+	if err != nil {
+		return err
+	}
 
-	// Format: INV-YYYYMM-XXXX
-	yearMonth := date.Format("200601") // YYYYMM
+	if err := updateSequence(tx, sequence, number); err != nil {
+		return err
+	}
 
-	// Get last invoice number for this month
-	var lastInvoice models.Invoice
-	err := config.DB.Where("tenant_id = ? AND invoice_number LIKE ?", tenantID, fmt.Sprintf("INV-%s-%%", yearMonth)).
-		Order("invoice_number DESC").
-		First(&lastInvoice).Error
+	invoice.InvoiceNumber = invoiceNumber
+	return nil
+}
 
-	sequence := 1
-	if err == nil && lastInvoice.InvoiceNumber != "" {
-		// Extract sequence number from last invoice
-		var lastSeq int
-		fmt.Sscanf(lastInvoice.InvoiceNumber, fmt.Sprintf("INV-%s-%%d", yearMonth), &lastSeq)
-		sequence = lastSeq + 1
+// lockSequence locks the sequence of a tenant's numbering period in tx, creating it first when the
+// period has not issued a number yet
+func lockSequence(tx *gorm.DB, tenantID uuid.UUID, period string) (models.InvoiceSequence, error) {
+	// The row is created outside tx, so two transactions starting a period do not deadlock on it
+	if err := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.InvoiceSequence{
+		TenantID: tenantID,
+		Period:   period,
+	}).Error; err != nil {
+		return models.InvoiceSequence{}, fmt.Errorf("failed to create invoice sequence: %w", err)
 	}
 
-	// Generate invoice number
-	invoiceNumber := fmt.Sprintf("INV-%s-%04d", yearMonth, sequence)
+	var sequence models.InvoiceSequence
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("tenant_id = ? AND period = ?", tenantID, period).
+		First(&sequence).Error; err != nil {
+		return models.InvoiceSequence{}, fmt.Errorf("failed to lock invoice sequence: %w", err)
+	}
+	return sequence, nil
+}
 
-	return invoiceNumber, nil
+func updateSequence(tx *gorm.DB, sequence models.InvoiceSequence, number int) error {
+	if err := tx.Model(&models.InvoiceSequence{}).
+		Where("tenant_id = ? AND period = ?", sequence.TenantID, sequence.Period).
+		Update("last_number", number).Error; err != nil {
+		return fmt.Errorf("failed to update invoice sequence: %w", err)
+	}
+	return nil
 }
 
-// GenerateInvoiceNumberBatch generates multiple invoice numbers in batch
-// Useful for bulk generation
-func (g *InvoiceNumberGenerator) GenerateInvoiceNumberBatch(tenantID uuid.UUID, date time.Time, count int) ([]string, error) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
+// Preview numbers invoices that are not going to be created, e.g. in a generation preview, with
+// the numbers they would get now. The sequence is left untouched.
+func (g *InvoiceNumberGenerator) Preview(tenantID uuid.UUID, invoices []*models.Invoice) error {
+	if len(invoices) == 0 {
+		return nil
+	}
+
+	format := loadInvoiceNumberFormat(tenantID)
+	issuedAt := time.Now().In(format.location)
+
+	var sequence models.InvoiceSequence
+	config.DB.Where("tenant_id = ? AND period = ?", tenantID, format.period(issuedAt)).Limit(1).Find(&sequence)
+
+	last := sequence.LastNumber
+	for _, invoice := range invoices {
+		number, invoiceNumber, err := format.next(config.DB, tenantID, last, issuedAt, customerAreaCode(config.DB, invoice.CustomerID))
+		if err != nil {
+			return err
+		}
+		invoice.InvoiceNumber = invoiceNumber
+		last = number
+	}
+	return nil
+}
 
-	yearMonth := date.Format("200601")
+// invoiceNumberFormat is how a tenant renders its invoice numbers
+type invoiceNumberFormat struct {
+	format   string
+	prefix   string
+	location *time.Location
+}
 
-	// Get last invoice number
-	var lastInvoice models.Invoice
-	err := config.DB.Where("tenant_id = ? AND invoice_number LIKE ?", tenantID, fmt.Sprintf("INV-%s-%%", yearMonth)).
-		Order("invoice_number DESC").
-		First(&lastInvoice).Error
+func loadInvoiceNumberFormat(tenantID uuid.UUID) invoiceNumberFormat {
+	settings := LoadBillingSettings(tenantID)
 
-	startSequence := 1
-	if err == nil && lastInvoice.InvoiceNumber != "" {
-		var lastSeq int
-		fmt.Sscanf(lastInvoice.InvoiceNumber, fmt.Sprintf("INV-%s-%%d", yearMonth), &lastSeq)
-		startSequence = lastSeq + 1
+	format := invoiceNumberFormat{
+		format:   settings.InvoiceNumberFormat,
+		prefix:   settings.InvoicePrefix,
+		location: NewBillingCalendar(settings).Location(),
+	}
+	if ValidateInvoiceNumberFormat(format.format) != nil {
+		format.format = DefaultInvoiceNumberFormat
 	}
+	if format.prefix == "" {
+		format.prefix = DefaultInvoicePrefix
+	}
+	return format
+}
 
-	// Generate batch of invoice numbers
-	numbers := make([]string, count)
-	for i := 0; i < count; i++ {
-		numbers[i] = fmt.Sprintf("INV-%s-%04d", yearMonth, startSequence+i)
+// period is the sequence a number issued at the given time counts in
+func (f invoiceNumberFormat) period(at time.Time) string {
+	switch {
+	case strings.Contains(f.format, "{MONTH}"):
+		return at.Format("2006-01")
+	case strings.Contains(f.format, "{YEAR}"):
+		return at.Format("2006")
 	}
+	return "ALL"
+}
 
-	return numbers, nil
+// next finds the first number after last that is not taken yet. Numbers are only taken already
+// when they were issued before the tenant's sequence existed.
+func (f invoiceNumberFormat) next(db *gorm.DB, tenantID uuid.UUID, last int, at time.Time, area string) (int, string, error) {
+	for number := last + 1; ; number++ {
+		invoiceNumber := f.render(at, area, number)
+		if len(invoiceNumber) > 50 {
+			return 0, "", ErrInvoiceNumberTooLong
+		}
+
+		var count int64
+		if err := db.Unscoped().Model(&models.Invoice{}).
+			Where("tenant_id = ? AND invoice_number = ?", tenantID, invoiceNumber).
+			Count(&count).Error; err != nil {
+			return 0, "", err
+		}
+		if count == 0 {
+			return number, invoiceNumber, nil
+		}
+	}
 }
 
-// ValidateInvoiceNumber checks if invoice number is valid format
-func ValidateInvoiceNumber(invoiceNumber string) bool {
-	var year, month, seq int
-	n, err := fmt.Sscanf(invoiceNumber, "INV-%04d%02d-%04d", &year, &month, &seq)
-	if err != nil || n != 3 {
-		return false
+func (f invoiceNumberFormat) render(at time.Time, area string, number int) string {
+	invoiceNumber := strings.NewReplacer(
+		"{PREFIX}", f.prefix,
+		"{YEAR}", at.Format("2006"),
+		"{MONTH}", at.Format("01"),
+		"{AREA}", area,
+		"{NUMBER}", fmt.Sprintf("%04d", number),
+	).Replace(f.format)
+
+	// A customer without service area leaves the separators around {AREA} behind
+	if area == "" && strings.Contains(f.format, "{AREA}") {
+		for _, separator := range []string{"-", "/", ".", "_"} {
+			for strings.Contains(invoiceNumber, separator+separator) {
+				invoiceNumber = strings.ReplaceAll(invoiceNumber, separator+separator, separator)
+			}
+		}
+		invoiceNumber = strings.Trim(invoiceNumber, "-/._")
 	}
-	return year >= 2024 && month >= 1 && month <= 12 && seq >= 1
+	return invoiceNumber
+}
+
+// customerAreaCode is the code of the customer's service area, empty when it has none
+func customerAreaCode(db *gorm.DB, customerID uuid.UUID) string {
+	var code string
+	db.Table("customers").
+		Select("service_areas.code").
+		Joins("JOIN service_areas ON service_areas.id = customers.service_area_id").
+		Where("customers.id = ?", customerID).
+		Limit(1).
+		Scan(&code)
+	return code
 }
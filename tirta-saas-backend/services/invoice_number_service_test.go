@@ -0,0 +1,80 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateInvoiceNumberFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		want   error
+	}{
+		{DefaultInvoiceNumberFormat, nil},
+		{"{NUMBER}", nil},
+		{"{PREFIX}/{AREA}/{YEAR}/{MONTH}/{NUMBER}", nil},
+		{"TAG-{YEAR}-{NUMBER}", nil},
+		{"", ErrInvoiceNumberFormat},
+		{"{PREFIX}-{YEAR}{MONTH}", ErrInvoiceNumberFormat},
+		{"{PREFIX}-{MONTH}-{NUMBER}", ErrInvoiceNumberFormat},
+		{"{PREFIX}/{MONTH}/{YEAR}/{NUMBER}", nil},
+		{"{PREFIX}-{DAY}-{NUMBER}", ErrInvoiceNumberFormat},
+		{"{prefix}-{NUMBER}", ErrInvoiceNumberFormat},
+		{"{}-{NUMBER}", ErrInvoiceNumberFormat},
+		{"{PREFIX}-" + strings.Repeat("X", 33) + "-{NUMBER}", ErrInvoiceNumberFormat},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateInvoiceNumberFormat(tt.format); err != tt.want {
+			t.Errorf("ValidateInvoiceNumberFormat(%q) = %v; want %v", tt.format, err, tt.want)
+		}
+	}
+}
+
+func TestInvoiceNumberFormatRender(t *testing.T) {
+	at := time.Date(2025, 2, 28, 23, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		format string
+		area   string
+		number int
+		want   string
+	}{
+		{DefaultInvoiceNumberFormat, "", 1, "INV-202502-0001"},
+		{DefaultInvoiceNumberFormat, "", 12345, "INV-202502-12345"},
+		{"{PREFIX}/{AREA}/{YEAR}/{NUMBER}", "BDG", 7, "INV/BDG/2025/0007"},
+		{"{PREFIX}/{AREA}/{YEAR}/{NUMBER}", "", 7, "INV/2025/0007"},
+		{"{AREA}-{PREFIX}-{NUMBER}", "", 7, "INV-0007"},
+		{"{PREFIX}-{NUMBER}-{AREA}", "", 7, "INV-0007"},
+		{"{PREFIX}-{AREA}-{NUMBER}", "", 7, "INV-0007"},
+	}
+
+	for _, tt := range tests {
+		format := invoiceNumberFormat{format: tt.format, prefix: DefaultInvoicePrefix, location: time.UTC}
+		if got := format.render(at, tt.area, tt.number); got != tt.want {
+			t.Errorf("render(%q, %q, %d) = %q; want %q", tt.format, tt.area, tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestInvoiceNumberFormatPeriod(t *testing.T) {
+	at := time.Date(2025, 12, 31, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{DefaultInvoiceNumberFormat, "2025-12"},
+		{"{PREFIX}/{MONTH}/{YEAR}/{NUMBER}", "2025-12"},
+		{"{PREFIX}-{YEAR}-{NUMBER}", "2025"},
+		{"{PREFIX}-{AREA}-{NUMBER}", "ALL"},
+	}
+
+	for _, tt := range tests {
+		format := invoiceNumberFormat{format: tt.format, prefix: DefaultInvoicePrefix, location: time.UTC}
+		if got := format.period(at); got != tt.want {
+			t.Errorf("period(%q) = %q; want %q", tt.format, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,555 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/utils"
+	"github.com/google/uuid"
+)
+
+// Default branding when the tenant has not configured colors
+const (
+	defaultPDFPrimaryColor   = "#1565C0"
+	defaultPDFSecondaryColor = "#90CAF9"
+)
+
+const pdfMargin = 40.0
+
+var ErrNoInvoicesToPrint = errors.New("Tidak ada tagihan untuk dicetak")
+
+// InvoicePDFService renders printable invoices and payment receipts with the tenant's branding
+type InvoicePDFService struct{}
+
+// NewInvoicePDFService creates new invoice PDF service
+func NewInvoicePDFService() *InvoicePDFService {
+	return &InvoicePDFService{}
+}
+
+// pdfBranding is the tenant information printed on every page
+type pdfBranding struct {
+	settings       models.TenantSettings
+	bankAccounts   []models.BankAccount
+	logo           *utils.PDFImage
+	primaryColor   string
+	secondaryColor string
+	renderer       *NotificationRenderer
+}
+
+// RenderInvoices renders the invoices one per page, in the given order
+func (s *InvoicePDFService) RenderInvoices(tenantID uuid.UUID, invoices []models.Invoice) ([]byte, error) {
+	if len(invoices) == 0 {
+		return nil, ErrNoInvoicesToPrint
+	}
+
+	branding := s.loadBranding(tenantID)
+
+	// Customers with their route, loaded once for the whole batch
+	customerIDs := make([]uuid.UUID, 0, len(invoices))
+	for _, invoice := range invoices {
+		customerIDs = append(customerIDs, invoice.CustomerID)
+	}
+	var customers []models.Customer
+	config.DB.Preload("ReadingRoute").Where("id IN ? AND tenant_id = ?", customerIDs, tenantID).Find(&customers)
+	customerByID := make(map[uuid.UUID]models.Customer, len(customers))
+	for _, customer := range customers {
+		customerByID[customer.ID] = customer
+	}
+
+	doc := utils.NewPDFDocument()
+	for _, invoice := range invoices {
+		doc.AddPage()
+		s.drawInvoice(doc, branding, invoice, customerByID[invoice.CustomerID])
+	}
+
+	return doc.Bytes(), nil
+}
+
+// RouteInvoices returns the monthly invoices of a usage month in reading route order, so a
+// collector can deliver them while walking the route. Customers without a route come last.
+func (s *InvoicePDFService) RouteInvoices(tenantID uuid.UUID, usageMonth string, routeID *uuid.UUID) ([]models.Invoice, error) {
+	query := config.DB.Model(&models.Invoice{}).
+		Joins("JOIN customers ON customers.id = invoices.customer_id").
+		Joins("LEFT JOIN reading_routes ON reading_routes.id = customers.reading_route_id").
+		Where("invoices.tenant_id = ? AND invoices.usage_month = ? AND invoices.type = ?", tenantID, usageMonth, "monthly")
+
+	if routeID != nil {
+		query = query.Where("customers.reading_route_id = ?", *routeID)
+	}
+
+	var invoices []models.Invoice
+	err := query.
+		Order("reading_routes.code IS NULL, reading_routes.code ASC, customers.reading_sequence ASC, customers.name ASC").
+		Find(&invoices).Error
+	return invoices, err
+}
+
+// RenderReceipt renders the receipt of a payment
+func (s *InvoicePDFService) RenderReceipt(tenantID uuid.UUID, payment models.Payment) ([]byte, error) {
+	branding := s.loadBranding(tenantID)
+
+	var invoice models.Invoice
+	if err := config.DB.Where("id = ? AND tenant_id = ?", payment.InvoiceID, tenantID).First(&invoice).Error; err != nil {
+		return nil, err
+	}
+
+	var customer models.Customer
+	config.DB.Where("id = ? AND tenant_id = ?", invoice.CustomerID, tenantID).First(&customer)
+
+	doc := utils.NewPDFDocument()
+	doc.AddPage()
+	s.drawReceipt(doc, branding, payment, invoice, customer)
+
+	return doc.Bytes(), nil
+}
+
+// RenderCustomerPaymentReceipt renders the receipt of a payment allocated across several invoices,
+// listing how much of it went to each invoice. Allocations must be preloaded with their invoice.
+func (s *InvoicePDFService) RenderCustomerPaymentReceipt(tenantID uuid.UUID, payment models.CustomerPayment) ([]byte, error) {
+	branding := s.loadBranding(tenantID)
+
+	var customer models.Customer
+	if err := config.DB.Where("id = ? AND tenant_id = ?", payment.CustomerID, tenantID).First(&customer).Error; err != nil {
+		return nil, err
+	}
+
+	doc := utils.NewPDFDocument()
+	doc.AddPage()
+	s.drawCustomerPaymentReceipt(doc, branding, payment, customer)
+
+	return doc.Bytes(), nil
+}
+
+func (s *InvoicePDFService) loadBranding(tenantID uuid.UUID) pdfBranding {
+	branding := pdfBranding{
+		primaryColor:   defaultPDFPrimaryColor,
+		secondaryColor: defaultPDFSecondaryColor,
+	}
+
+	config.DB.Where("tenant_id = ?", tenantID).First(&branding.settings)
+	if branding.settings.CompanyName == "" {
+		var tenant models.Tenant
+		if err := config.DB.Where("id = ?", tenantID).First(&tenant).Error; err == nil {
+			branding.settings.CompanyName = tenant.Name
+		}
+	}
+
+	if isHexColor(branding.settings.PrimaryColor) {
+		branding.primaryColor = branding.settings.PrimaryColor
+	}
+	if isHexColor(branding.settings.SecondaryColor) {
+		branding.secondaryColor = branding.settings.SecondaryColor
+	}
+
+	config.DB.Where("tenant_id = ? AND is_active = ?", tenantID, true).
+		Order("is_primary DESC, bank_name ASC").Find(&branding.bankAccounts)
+
+	// Tenants that only filled the bank fields in their settings
+	if len(branding.bankAccounts) == 0 && branding.settings.BankAccountNo != "" {
+		branding.bankAccounts = append(branding.bankAccounts, models.BankAccount{
+			BankName:      branding.settings.BankName,
+			AccountNumber: branding.settings.BankAccountNo,
+			AccountName:   branding.settings.BankAccountName,
+		})
+	}
+
+	// Logos are stored under uploads/; remote URLs are not fetched
+	if logoPath := strings.TrimPrefix(branding.settings.LogoURL, "/"); logoPath != "" && !strings.Contains(logoPath, "://") {
+		logo, err := utils.LoadPDFImage(logoPath)
+		if err != nil {
+			log.Printf("⚠️  Failed to load tenant logo %s: %v", logoPath, err)
+		} else {
+			branding.logo = logo
+		}
+	}
+
+	branding.renderer = NewNotificationRenderer(branding.settings.Language, branding.settings.Currency)
+	return branding
+}
+
+// drawHeader draws the colored band with logo, company details and document title
+func (s *InvoicePDFService) drawHeader(doc *utils.PDFDocument, branding pdfBranding, title, number string) {
+	right := utils.PDFPageWidth - pdfMargin
+
+	doc.SetFillColor(branding.primaryColor)
+	doc.Rect(0, 0, utils.PDFPageWidth, 100, true)
+
+	textX := pdfMargin
+	if branding.logo != nil && branding.logo.Height > 0 {
+		height := 60.0
+		width := height * float64(branding.logo.Width) / float64(branding.logo.Height)
+		if width > 120 {
+			width = 120
+			height = width * float64(branding.logo.Height) / float64(branding.logo.Width)
+		}
+		doc.Image(branding.logo, pdfMargin, 20+(60-height)/2, width, height)
+		textX += width + 12
+	}
+
+	settings := branding.settings
+	doc.SetFillColor("#FFFFFF")
+	doc.Text(textX, 42, 16, true, settings.CompanyName)
+	y := 58.0
+	if settings.Address != "" {
+		doc.Text(textX, y, 9, false, settings.Address)
+		y += 12
+	}
+	var contacts []string
+	for _, contact := range []string{settings.Phone, settings.Email, settings.Website} {
+		if contact != "" {
+			contacts = append(contacts, contact)
+		}
+	}
+	if len(contacts) > 0 {
+		doc.Text(textX, y, 9, false, strings.Join(contacts, "  |  "))
+	}
+
+	doc.TextRight(right, 42, 18, true, title)
+	doc.TextRight(right, 60, 10, false, number)
+}
+
+// drawFooter draws the tenant's footer text at the bottom of the page
+func (s *InvoicePDFService) drawFooter(doc *utils.PDFDocument, branding pdfBranding) {
+	top := utils.PDFPageHeight - 80
+
+	doc.SetStrokeColor(branding.secondaryColor)
+	doc.Line(pdfMargin, top, utils.PDFPageWidth-pdfMargin, top, 1)
+
+	doc.SetFillColor("#555555")
+	footer := branding.settings.InvoiceFooterText
+	if footer == "" {
+		footer = "Terima kasih atas pembayaran Anda tepat waktu."
+	}
+	doc.Paragraph(pdfMargin, top+16, utils.PDFPageWidth-2*pdfMargin, 8, false, footer)
+}
+
+func (s *InvoicePDFService) drawInvoice(doc *utils.PDFDocument, branding pdfBranding, invoice models.Invoice, customer models.Customer) {
+	r := branding.renderer
+	right := utils.PDFPageWidth - pdfMargin
+	middle := utils.PDFPageWidth / 2
+
+	number := invoice.InvoiceNumber
+	if number == "" {
+		number = "-"
+	}
+	s.drawHeader(doc, branding, "TAGIHAN AIR", number)
+
+	// Customer details
+	y := 135.0
+	doc.SetFillColor("#777777")
+	doc.Text(pdfMargin, y, 8, true, "PELANGGAN")
+	doc.SetFillColor("#000000")
+	doc.Text(pdfMargin, y+16, 12, true, customer.Name)
+	doc.Text(pdfMargin, y+31, 9, false, "No. Meter: "+customer.MeterNumber)
+	addressBottom := doc.Paragraph(pdfMargin, y+44, middle-pdfMargin-20, 9, false, customer.Address)
+	if customer.ReadingRoute != nil {
+		doc.Text(pdfMargin, addressBottom, 9, false, fmt.Sprintf("Rute: %s - %s (urutan %d)",
+			customer.ReadingRoute.Code, customer.ReadingRoute.Name, customer.ReadingSequence))
+	}
+
+	// Invoice details
+	details := [][2]string{
+		{"Periode", r.FormatMonth(invoice.UsageMonth)},
+		{"Tanggal terbit", r.FormatDate(invoice.CreatedAt)},
+		{"Jatuh tempo", formatOptionalDate(r, invoice.DueDate)},
+		{"Status", invoiceStatusLabel(invoice)},
+	}
+	for i, detail := range details {
+		rowY := y + 16 + float64(i)*15
+		doc.SetFillColor("#777777")
+		doc.Text(middle+20, rowY, 9, false, detail[0])
+		doc.SetFillColor("#000000")
+		doc.TextRight(right, rowY, 9, true, detail[1])
+	}
+
+	// Charges
+	y = 245
+	doc.SetFillColor(branding.primaryColor)
+	doc.Rect(pdfMargin, y, right-pdfMargin, 22, true)
+	doc.SetFillColor("#FFFFFF")
+	doc.Text(pdfMargin+8, y+15, 10, true, "Keterangan")
+	doc.TextRight(right-8, y+15, 10, true, "Jumlah")
+	y += 40
+
+	row := func(label, amount string, bold bool) {
+		doc.SetFillColor("#000000")
+		doc.Text(pdfMargin+8, y, 10, bold, label)
+		doc.TextRight(right-8, y, 10, bold, amount)
+		y += 18
+	}
+	subRow := func(label, amount string) {
+		doc.SetFillColor("#555555")
+		doc.Text(pdfMargin+24, y, 8, false, label)
+		doc.TextRight(right-8, y, 8, false, amount)
+		y += 14
+	}
+
+	lines, _ := InvoiceLines(config.DB, invoice)
+	for _, line := range lines {
+		// Notes carry their reason, which may not fit next to the amount
+		description := []rune(line.Description)
+		if len(description) > 60 {
+			description = append(description[:57], []rune("...")...)
+		}
+		row(string(description), r.FormatCurrency(line.Amount), false)
+		if line.Unit == "m3" {
+			subRow(fmt.Sprintf("%s m3 x %s", r.FormatNumber(line.Quantity, 2), r.FormatCurrency(line.UnitPrice)), "")
+		}
+		if line.TaxAmount != 0 {
+			subRow(fmt.Sprintf("Pajak %s%%", r.FormatNumber(line.TaxPercent, 2)), r.FormatCurrency(line.TaxAmount))
+		}
+	}
+
+	doc.SetStrokeColor("#BBBBBB")
+	doc.Line(pdfMargin, y-8, right, y-8, 0.5)
+	y += 6
+	row("Total Tagihan", r.FormatCurrency(invoice.TotalAmount), true)
+	if invoice.TotalPaid > 0 {
+		row("Sudah dibayar", r.FormatCurrency(invoice.TotalPaid), false)
+	}
+
+	outstanding := invoice.TotalAmount - invoice.TotalPaid
+	if outstanding < 0 {
+		outstanding = 0
+	}
+	doc.SetFillColor(branding.secondaryColor)
+	doc.Rect(middle, y-4, right-middle, 26, true)
+	doc.SetFillColor("#000000")
+	doc.Text(middle+8, y+13, 11, true, "Sisa Tagihan")
+	doc.TextRight(right-8, y+13, 11, true, r.FormatCurrency(outstanding))
+	y += 40
+
+	if invoice.Status != models.InvoiceStatusVoid && (invoice.IsPaid || invoice.PaymentStatus == models.PaymentStatusPaid) {
+		doc.SetStrokeColor("#2E7D32")
+		doc.Rect(pdfMargin, y, 120, 34, false)
+		doc.SetFillColor("#2E7D32")
+		doc.TextCenter(pdfMargin+60, y+23, 18, true, "LUNAS")
+		y += 54
+	}
+
+	// Payment instructions
+	if len(branding.bankAccounts) > 0 && outstanding > 0 {
+		doc.SetFillColor("#000000")
+		doc.Text(pdfMargin, y, 10, true, "Pembayaran dapat ditransfer ke:")
+		y += 16
+		for _, account := range branding.bankAccounts {
+			doc.Text(pdfMargin, y, 9, false, fmt.Sprintf("%s  %s  a.n. %s", account.BankName, account.AccountNumber, account.AccountName))
+			y += 13
+		}
+		doc.SetFillColor("#555555")
+		doc.Text(pdfMargin, y+4, 8, false, "Cantumkan nomor tagihan "+number+" pada berita transfer.")
+	}
+
+	if invoice.Notes != "" && y < utils.PDFPageHeight-140 {
+		doc.SetFillColor("#555555")
+		doc.Paragraph(pdfMargin, utils.PDFPageHeight-120, right-pdfMargin, 8, false, "Catatan: "+invoice.Notes)
+	}
+
+	s.drawFooter(doc, branding)
+}
+
+func (s *InvoicePDFService) drawReceipt(doc *utils.PDFDocument, branding pdfBranding, payment models.Payment, invoice models.Invoice, customer models.Customer) {
+	r := branding.renderer
+	right := utils.PDFPageWidth - pdfMargin
+
+	receiptNumber := "KW-" + strings.ToUpper(strings.ReplaceAll(payment.ID.String(), "-", "")[:10])
+	s.drawHeader(doc, branding, "KUITANSI", receiptNumber)
+
+	invoiceNumber := invoice.InvoiceNumber
+	if invoiceNumber == "" {
+		invoiceNumber = "-"
+	}
+	purpose := "Tagihan " + invoiceNumber
+	if invoice.Type == "registration" {
+		purpose = "Biaya pendaftaran sambungan baru"
+	} else if invoice.UsageMonth != "" {
+		purpose += " periode " + r.FormatMonth(invoice.UsageMonth)
+	}
+
+	method := "-"
+	if payment.PaymentMethod != nil {
+		method = payment.PaymentMethod.Name
+	}
+
+	rows := [][2]string{
+		{"Tanggal bayar", r.FormatDate(payment.PaidAt) + " " + payment.PaidAt.Format("15:04")},
+		{"Diterima dari", customer.Name},
+		{"No. Meter", customer.MeterNumber},
+		{"Untuk pembayaran", purpose},
+		{"Metode pembayaran", method},
+	}
+	if payment.ReferenceNumber != "" {
+		rows = append(rows, [2]string{"No. Referensi", payment.ReferenceNumber})
+	}
+	if payment.Receiver != nil {
+		rows = append(rows, [2]string{"Petugas", payment.Receiver.Name})
+	}
+
+	y := 140.0
+	for _, row := range rows {
+		doc.SetFillColor("#777777")
+		doc.Text(pdfMargin, y, 10, false, row[0])
+		doc.SetFillColor("#000000")
+		doc.Text(pdfMargin+130, y, 10, false, ": "+row[1])
+		y += 20
+	}
+
+	y += 10
+	doc.SetFillColor(branding.secondaryColor)
+	doc.Rect(pdfMargin, y, right-pdfMargin, 40, true)
+	doc.SetFillColor("#000000")
+	doc.Text(pdfMargin+12, y+25, 12, true, "Jumlah Dibayar")
+	doc.TextRight(right-12, y+26, 16, true, r.FormatCurrency(payment.Amount))
+	y += 62
+
+	outstanding := invoice.TotalAmount - invoice.TotalPaid
+	if outstanding < 0 {
+		outstanding = 0
+	}
+	doc.SetFillColor("#000000")
+	doc.Text(pdfMargin, y, 10, false, "Total tagihan: "+r.FormatCurrency(invoice.TotalAmount))
+	doc.TextRight(right, y, 10, false, "Sisa tagihan: "+r.FormatCurrency(outstanding))
+	y += 50
+
+	doc.TextCenter(right-80, y, 10, false, branding.settings.CompanyName)
+	doc.SetStrokeColor("#000000")
+	doc.Line(right-150, y+60, right-10, y+60, 0.5)
+
+	doc.SetFillColor("#777777")
+	doc.Text(pdfMargin, y+60, 8, false, "Dicetak "+r.FormatDate(time.Now())+". Kuitansi ini sah tanpa tanda tangan basah.")
+
+	s.drawFooter(doc, branding)
+}
+
+func (s *InvoicePDFService) drawCustomerPaymentReceipt(doc *utils.PDFDocument, branding pdfBranding, payment models.CustomerPayment, customer models.Customer) {
+	r := branding.renderer
+	right := utils.PDFPageWidth - pdfMargin
+
+	receiptNumber := "KW-" + strings.ToUpper(strings.ReplaceAll(payment.ID.String(), "-", "")[:10])
+	s.drawHeader(doc, branding, "KUITANSI", receiptNumber)
+
+	method := "-"
+	if payment.PaymentMethod != nil {
+		method = payment.PaymentMethod.Name
+	}
+
+	rows := [][2]string{
+		{"Tanggal bayar", r.FormatDate(payment.PaidAt) + " " + payment.PaidAt.Format("15:04")},
+		{"Diterima dari", customer.Name},
+		{"No. Meter", customer.MeterNumber},
+		{"Metode pembayaran", method},
+	}
+	if payment.ReferenceNumber != "" {
+		rows = append(rows, [2]string{"No. Referensi", payment.ReferenceNumber})
+	}
+	if payment.Receiver != nil {
+		rows = append(rows, [2]string{"Petugas", payment.Receiver.Name})
+	}
+
+	y := 140.0
+	for _, row := range rows {
+		doc.SetFillColor("#777777")
+		doc.Text(pdfMargin, y, 10, false, row[0])
+		doc.SetFillColor("#000000")
+		doc.Text(pdfMargin+130, y, 10, false, ": "+row[1])
+		y += 20
+	}
+
+	// Allocation lines
+	y += 6
+	columns := []float64{pdfMargin + 8, pdfMargin + 150, right - 200, right - 100, right - 8}
+	doc.SetFillColor(branding.primaryColor)
+	doc.Rect(pdfMargin, y, right-pdfMargin, 22, true)
+	doc.SetFillColor("#FFFFFF")
+	doc.Text(columns[0], y+15, 9, true, "No. Tagihan")
+	doc.Text(columns[1], y+15, 9, true, "Periode")
+	doc.TextRight(columns[2], y+15, 9, true, "Denda")
+	doc.TextRight(columns[3], y+15, 9, true, "Pokok")
+	doc.TextRight(columns[4], y+15, 9, true, "Jumlah")
+	y += 38
+
+	doc.SetFillColor("#000000")
+	for _, allocation := range payment.Allocations {
+		number, period := "-", "-"
+		if allocation.Invoice != nil {
+			if allocation.Invoice.InvoiceNumber != "" {
+				number = allocation.Invoice.InvoiceNumber
+			}
+			if allocation.Invoice.Type == "registration" {
+				period = "Pendaftaran"
+			} else if allocation.Invoice.UsageMonth != "" {
+				period = r.FormatMonth(allocation.Invoice.UsageMonth)
+			}
+		}
+		doc.Text(columns[0], y, 9, false, number)
+		doc.Text(columns[1], y, 9, false, period)
+		doc.TextRight(columns[2], y, 9, false, r.FormatCurrency(allocation.PenaltyAmount))
+		doc.TextRight(columns[3], y, 9, false, r.FormatCurrency(allocation.PrincipalAmount))
+		doc.TextRight(columns[4], y, 9, false, r.FormatCurrency(allocation.Amount))
+		y += 16
+	}
+	if payment.CreditAmount > 0 {
+		doc.Text(columns[0], y, 9, false, "Saldo kredit untuk tagihan berikutnya")
+		doc.TextRight(columns[4], y, 9, false, r.FormatCurrency(payment.CreditAmount))
+		y += 16
+	}
+
+	y += 6
+	doc.SetFillColor(branding.secondaryColor)
+	doc.Rect(pdfMargin, y, right-pdfMargin, 40, true)
+	doc.SetFillColor("#000000")
+	doc.Text(pdfMargin+12, y+25, 12, true, "Jumlah Dibayar")
+	doc.TextRight(right-12, y+26, 16, true, r.FormatCurrency(payment.Amount))
+	y += 70
+
+	doc.TextCenter(right-80, y, 10, false, branding.settings.CompanyName)
+	doc.SetStrokeColor("#000000")
+	doc.Line(right-150, y+60, right-10, y+60, 0.5)
+
+	doc.SetFillColor("#777777")
+	doc.Text(pdfMargin, y+60, 8, false, "Dicetak "+r.FormatDate(time.Now())+". Kuitansi ini sah tanpa tanda tangan basah.")
+
+	s.drawFooter(doc, branding)
+}
+
+func formatOptionalDate(r *NotificationRenderer, t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return r.FormatDate(*t)
+}
+
+func invoiceStatusLabel(invoice models.Invoice) string {
+	if invoice.Status == models.InvoiceStatusVoid {
+		return "DIBATALKAN"
+	}
+	if invoice.IsPaid {
+		return "LUNAS"
+	}
+	switch invoice.PaymentStatus {
+	case models.PaymentStatusPartial:
+		return "DIBAYAR SEBAGIAN"
+	case models.PaymentStatusOverdue:
+		return "JATUH TEMPO"
+	case models.PaymentStatusPaid:
+		return "LUNAS"
+	}
+	return "BELUM DIBAYAR"
+}
+
+func isHexColor(color string) bool {
+	if len(color) != 7 || color[0] != '#' {
+		return false
+	}
+	for _, c := range color[1:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
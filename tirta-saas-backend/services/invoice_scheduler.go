@@ -11,27 +11,34 @@ import (
 	"github.com/robfig/cron/v3"
 )
 
-// InvoiceScheduler manages scheduled invoice generation
+// InvoiceScheduler manages scheduled invoice generation. With several instances running, the jobs
+// run only on the leader.
 type InvoiceScheduler struct {
 	cron      *cron.Cron
 	generator *InvoiceGenerationService
+	jobs      *InvoiceGenerationJobService
+	leader    *LeaderElector
 }
 
 // NewInvoiceScheduler creates new invoice scheduler
-func NewInvoiceScheduler() *InvoiceScheduler {
+func NewInvoiceScheduler(leader *LeaderElector) *InvoiceScheduler {
 	return &InvoiceScheduler{
 		cron:      cron.New(),
 		generator: NewInvoiceGenerationService(),
+		jobs:      NewInvoiceGenerationJobService(),
+		leader:    leader,
 	}
 }
 
 // Start starts the scheduler
 func (s *InvoiceScheduler) Start() error {
 	// Schedule monthly invoice generation
-	// Default: Run on 1st of every month at 00:00 (midnight)
-	_, err := s.cron.AddFunc("0 0 1 * *", func() {
-		log.Println("🕐 Starting scheduled invoice generation...")
-		s.runMonthlyGeneration()
+	// Every tenant has its own billing calendar, checked every 15 minutes
+	_, err := s.cron.AddFunc("*/15 * * * *", func() {
+		now := time.Now()
+		s.leader.RunExclusive("invoice-generation", now, func() (string, error) {
+			return s.runMonthlyGeneration(now)
+		})
 	})
 	if err != nil {
 		return fmt.Errorf("failed to schedule monthly generation: %w", err)
@@ -40,18 +47,33 @@ func (s *InvoiceScheduler) Start() error {
 	// Schedule daily overdue update
 	// Run every day at 01:00 to update overdue invoices
 	_, err = s.cron.AddFunc("0 1 * * *", func() {
-		log.Println("🕐 Updating overdue invoice statuses...")
-		s.updateOverdueInvoices()
+		s.leader.RunExclusive("overdue-update", time.Now(), func() (string, error) {
+			log.Println("🕐 Updating overdue invoice statuses...")
+			return s.updateOverdueInvoices()
+		})
 	})
 	if err != nil {
 		return fmt.Errorf("failed to schedule overdue update: %w", err)
 	}
 
+	// Schedule daily payment reminders
+	// Run every day at 08:00 so reminders arrive during the day
+	_, err = s.cron.AddFunc("0 8 * * *", func() {
+		s.leader.RunExclusive("payment-reminders", time.Now(), func() (string, error) {
+			log.Println("🕐 Sending payment reminders...")
+			return s.sendPaymentReminders()
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule payment reminders: %w", err)
+	}
+
 	// Start the cron scheduler
 	s.cron.Start()
 	log.Println("✅ Invoice scheduler started successfully")
-	log.Println("📅 Monthly generation: 1st of month at 00:00")
+	log.Println("📅 Monthly generation: Per tenant billing calendar, checked every 15 minutes")
 	log.Println("📅 Overdue update: Every day at 01:00")
+	log.Println("📅 Payment reminders: Every day at 08:00")
 
 	return nil
 }
@@ -62,64 +84,76 @@ func (s *InvoiceScheduler) Stop() {
 	log.Println("🛑 Invoice scheduler stopped")
 }
 
-// runMonthlyGeneration generates invoices for all active tenants
-func (s *InvoiceScheduler) runMonthlyGeneration() {
-	// Get previous month (the month we're generating invoices for)
-	now := time.Now()
-	lastMonth := now.AddDate(0, -1, 0)
-	usageMonth := lastMonth.Format("2006-01") // YYYY-MM
-
-	log.Printf("📊 Generating invoices for period: %s", usageMonth)
-
+// runMonthlyGeneration queues a generation job for the active tenants whose billing run of this
+// month is due and has not been queued yet. The jobs are processed by the InvoiceGenerationWorker.
+func (s *InvoiceScheduler) runMonthlyGeneration(now time.Time) (string, error) {
 	// Get all active tenants
 	var tenants []models.Tenant
 	if err := config.DB.Where("status = ?", "ACTIVE").Find(&tenants).Error; err != nil {
 		log.Printf("❌ Failed to fetch active tenants: %v", err)
-		return
+		return "", err
 	}
 
-	log.Printf("👥 Found %d active tenants", len(tenants))
-
-	successCount := 0
+	queuedCount := 0
 	failCount := 0
 
-	// Generate invoices for each tenant
+	// Queue a job for each tenant
 	for _, tenant := range tenants {
-		result, err := s.generator.GenerateInvoices(InvoiceGenerationRequest{
+		settings := LoadBillingSettings(tenant.ID)
+		calendar := NewBillingCalendar(settings)
+
+		runAt, due := calendar.CurrentRun(now)
+		if !due {
+			continue
+		}
+		usageMonth := calendar.UsageMonth(runAt)
+
+		// A run is queued once per month; failed jobs are retried manually
+		var queued int64
+		config.DB.Model(&models.InvoiceGenerationJob{}).
+			Where("tenant_id = ? AND usage_month = ? AND trigger_type = ? AND created_at >= ?", tenant.ID, usageMonth, "scheduled", runAt).
+			Count(&queued)
+		if queued > 0 {
+			continue
+		}
+
+		log.Printf("📊 Queueing invoice generation for tenant %s, period: %s", tenant.Name, usageMonth)
+
+		_, err := s.jobs.Enqueue(InvoiceGenerationJobInput{
 			TenantID:    tenant.ID,
 			UsageMonth:  usageMonth,
 			CustomerIDs: []uuid.UUID{}, // Empty = all customers
-			DryRun:      false,
-		})
+			TriggerType: "scheduled",
 
+			EstimateMissing: settings.EstimateMissingReadings,
+		})
 		if err != nil {
-			log.Printf("❌ Failed to generate invoices for tenant %s: %v", tenant.Name, err)
+			log.Printf("❌ Failed to queue invoice generation for tenant %s: %v", tenant.Name, err)
 			failCount++
-			s.logGenerationHistory(tenant.ID, usageMonth, 0, 0, 0, err.Error())
 			continue
 		}
 
-		log.Printf("✅ Tenant %s: Generated %d, Skipped %d, Failed %d",
-			tenant.Name, result.Success, result.Skipped, result.Failed)
-
-		successCount++
-
-		// Log generation history
-		s.logGenerationHistory(tenant.ID, usageMonth, result.Success, result.Skipped, result.Failed, "")
+		queuedCount++
 	}
 
-	log.Printf("🎉 Monthly generation completed: %d tenants succeeded, %d failed", successCount, failCount)
+	summary := fmt.Sprintf("%d tenants queued, %d failed", queuedCount, failCount)
+	if queuedCount+failCount > 0 {
+		log.Printf("🎉 Monthly generation queued: %s", summary)
+	}
+	return summary, nil
 }
 
 // updateOverdueInvoices updates payment status for all overdue invoices
-func (s *InvoiceScheduler) updateOverdueInvoices() {
+func (s *InvoiceScheduler) updateOverdueInvoices() (string, error) {
 	var tenants []models.Tenant
 	if err := config.DB.Where("status = ?", "ACTIVE").Find(&tenants).Error; err != nil {
 		log.Printf("❌ Failed to fetch active tenants: %v", err)
-		return
+		return "", err
 	}
 
 	totalUpdated := 0
+	totalDefaulted := 0
+	plans := NewInstallmentPlanService()
 
 	for _, tenant := range tenants {
 		err := s.generator.UpdateOverdueInvoices(tenant.ID)
@@ -128,47 +162,69 @@ func (s *InvoiceScheduler) updateOverdueInvoices() {
 			continue
 		}
 		totalUpdated++
+
+		// Missed installments are tracked with the overdue invoices
+		defaulted, err := plans.RefreshPlans(tenant.ID)
+		if err != nil {
+			log.Printf("❌ Failed to refresh installment plans for tenant %s: %v", tenant.Name, err)
+			continue
+		}
+		totalDefaulted += defaulted
 	}
 
-	log.Printf("✅ Updated overdue status for %d tenants", totalUpdated)
+	summary := fmt.Sprintf("Updated overdue status for %d tenants, %d installment plans defaulted", totalUpdated, totalDefaulted)
+	log.Printf("✅ %s", summary)
+	return summary, nil
 }
 
-// logGenerationHistory logs invoice generation history
-func (s *InvoiceScheduler) logGenerationHistory(tenantID uuid.UUID, month string, success, skipped, failed int, errorMsg string) {
-	history := models.InvoiceGenerationHistory{
-		TenantID:      tenantID,
-		GeneratedFor:  month,
-		SuccessCount:  success,
-		SkippedCount:  skipped,
-		FailedCount:   failed,
-		GeneratedAt:   time.Now(),
-		Status:        "success",
-		ErrorMessage:  errorMsg,
+// sendPaymentReminders queues payment reminders for all active tenants
+func (s *InvoiceScheduler) sendPaymentReminders() (string, error) {
+	var tenants []models.Tenant
+	if err := config.DB.Where("status = ?", "ACTIVE").Find(&tenants).Error; err != nil {
+		log.Printf("❌ Failed to fetch active tenants: %v", err)
+		return "", err
 	}
 
-	if errorMsg != "" {
-		history.Status = "failed"
-	}
+	notifier := NewBillingNotifier()
+	totalQueued := 0
 
-	if err := config.DB.Create(&history).Error; err != nil {
-		log.Printf("⚠️  Failed to log generation history: %v", err)
+	for _, tenant := range tenants {
+		totalQueued += notifier.SendPaymentReminders(tenant.ID)
 	}
+
+	summary := fmt.Sprintf("Queued %d payment reminders", totalQueued)
+	log.Printf("✅ %s", summary)
+	return summary, nil
 }
 
-// RunManualGeneration manually triggers invoice generation for specific tenant and month
-func (s *InvoiceScheduler) RunManualGeneration(tenantID uuid.UUID, usageMonth string) (*InvoiceGenerationResult, error) {
-	result, err := s.generator.GenerateInvoices(InvoiceGenerationRequest{
+// RunManualGeneration manually queues invoice generation for specific tenant and month
+func (s *InvoiceScheduler) RunManualGeneration(tenantID uuid.UUID, usageMonth string, triggeredBy *uuid.UUID) (*models.InvoiceGenerationJob, error) {
+	return s.jobs.Enqueue(InvoiceGenerationJobInput{
 		TenantID:    tenantID,
 		UsageMonth:  usageMonth,
 		CustomerIDs: []uuid.UUID{},
-		DryRun:      false,
+		TriggerType: "manual",
+		TriggeredBy: triggeredBy,
 	})
+}
 
-	if err != nil {
-		s.logGenerationHistory(tenantID, usageMonth, 0, 0, 0, err.Error())
-		return nil, err
+// LoadBillingSettings returns the settings of a tenant, with the defaults of a new tenant when it
+// has none
+func LoadBillingSettings(tenantID uuid.UUID) models.TenantSettings {
+	var settings models.TenantSettings
+	if err := config.DB.Where("tenant_id = ?", tenantID).First(&settings).Error; err != nil {
+		settings = models.TenantSettings{
+			TenantID:                tenantID,
+			InvoiceDueDays:          14,
+			LatePenaltyPolicy:       models.PenaltyPolicyPercent,
+			LatePenaltyPercent:      2.0,
+			LatePenaltyMaxCap:       100000,
+			GracePeriodDays:         3,
+			TimeZone:                "Asia/Jakarta",
+			BillingDay:              1,
+			BillingUsageMonthOffset: 1,
+			DueDateRule:             models.DueDateRuleDaysAfterIssue,
+		}
 	}
-
-	s.logGenerationHistory(tenantID, usageMonth, result.Success, result.Skipped, result.Failed, "")
-	return result, nil
+	return settings
 }
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	instanceID     string
+	instanceIDOnce sync.Once
+)
+
+// InstanceID identifies this backend process in leases and job run records. Set INSTANCE_ID to
+// name it, e.g. after the pod; by default it is the host name with the process ID.
+func InstanceID() string {
+	instanceIDOnce.Do(func() {
+		instanceID = os.Getenv("INSTANCE_ID")
+		if instanceID == "" {
+			host, _ := os.Hostname()
+			instanceID = fmt.Sprintf("%s-%d-%04x", host, os.Getpid(), rand.Intn(0x10000))
+		}
+	})
+	return instanceID
+}
+
+// AcquireLease takes or renews a named lease for holder until ttl from now. It succeeds when the
+// lease is free, expired or already held by holder. Expiry is checked against the database clock,
+// so the clocks of the instances do not need to agree.
+func AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	// The lease row is created expired the first time it is used
+	expired := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.SchedulerLease{
+		Name:       name,
+		AcquiredAt: expired,
+		RenewedAt:  expired,
+		ExpiresAt:  expired,
+	}).Error; err != nil {
+		return false, err
+	}
+
+	// acquired_at is assigned before holder, so it still compares with the previous holder
+	result := config.DB.Exec(`UPDATE scheduler_leases
+		SET acquired_at = IF(holder = ?, acquired_at, NOW(3)), holder = ?, renewed_at = NOW(3),
+			expires_at = NOW(3) + INTERVAL ? MICROSECOND
+		WHERE name = ? AND (holder = ? OR expires_at < NOW(3))`,
+		holder, holder, ttl.Microseconds(), name, holder)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ReleaseLease gives up a lease held by holder so another instance can take it right away
+func ReleaseLease(name, holder string) error {
+	return config.DB.Exec("UPDATE scheduler_leases SET expires_at = NOW(3) - INTERVAL 1 SECOND WHERE name = ? AND holder = ?",
+		name, holder).Error
+}
+
+// LeaderElector keeps one instance of the backend leader through a database lease. The leader
+// renews the lease at a third of its TTL; when it stops, another instance takes over once the lease
+// has expired. Scheduled jobs are run through RunExclusive so only the leader executes them.
+type LeaderElector struct {
+	lease    string
+	instance string
+	ttl      time.Duration
+
+	mu          sync.RWMutex
+	leaderUntil time.Time // by the local clock, so a leader that cannot renew steps down in time
+	stop        chan struct{}
+}
+
+// NewLeaderElector creates new leader elector for a lease. The TTL is SCHEDULER_LEASE_TTL_SECONDS,
+// 30 seconds by default.
+func NewLeaderElector(lease string) *LeaderElector {
+	ttl := 30 * time.Second
+	if seconds, err := strconv.Atoi(os.Getenv("SCHEDULER_LEASE_TTL_SECONDS")); err == nil && seconds >= 5 {
+		ttl = time.Duration(seconds) * time.Second
+	}
+
+	return &LeaderElector{
+		lease:    lease,
+		instance: InstanceID(),
+		ttl:      ttl,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start tries to become leader right away, so jobs run at startup know the outcome, and then keeps
+// renewing in the background
+func (e *LeaderElector) Start() error {
+	e.renew()
+
+	go func() {
+		ticker := time.NewTicker(e.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.stop:
+				return
+			case <-ticker.C:
+				e.renew()
+			}
+		}
+	}()
+
+	log.Printf("✅ Leader election started for %s as %s (lease %s)", e.lease, e.instance, e.ttl)
+	return nil
+}
+
+// Stop stops renewing and releases the lease when held
+func (e *LeaderElector) Stop() {
+	close(e.stop)
+	if e.IsLeader() {
+		if err := ReleaseLease(e.lease, e.instance); err != nil {
+			log.Printf("⚠️  Failed to release lease %s: %v", e.lease, err)
+		}
+	}
+	e.mu.Lock()
+	e.leaderUntil = time.Time{}
+	e.mu.Unlock()
+	log.Printf("🛑 Leader election stopped for %s", e.lease)
+}
+
+// IsLeader reports whether this instance holds the lease
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return time.Now().Before(e.leaderUntil)
+}
+
+// Instance returns the ID of this instance
+func (e *LeaderElector) Instance() string {
+	return e.instance
+}
+
+func (e *LeaderElector) renew() {
+	attempt := time.Now()
+	acquired, err := AcquireLease(e.lease, e.instance, e.ttl)
+	if err != nil {
+		log.Printf("❌ Failed to renew lease %s: %v", e.lease, err)
+	}
+
+	wasLeader := e.IsLeader()
+	e.mu.Lock()
+	if acquired {
+		e.leaderUntil = attempt.Add(e.ttl)
+	} else if err == nil {
+		e.leaderUntil = time.Time{}
+	}
+	// On a database error the lease is kept until it runs out by the local clock
+	e.mu.Unlock()
+
+	switch {
+	case acquired && !wasLeader:
+		log.Printf("👑 %s is now leader for %s", e.instance, e.lease)
+	case !acquired && err == nil && wasLeader:
+		log.Printf("⚠️  %s lost leadership for %s", e.instance, e.lease)
+	}
+}
+
+// RunExclusive runs a scheduled job when this instance is the leader and records the run with the
+// instance that executed it. slot is the time the job was scheduled for; a job whose run of the
+// same minute is already recorded, e.g. by the previous leader, is not run again. Without an
+// elector the job runs on every instance.
+func (e *LeaderElector) RunExclusive(jobName string, slot time.Time, job func() (string, error)) {
+	if e != nil && !e.IsLeader() {
+		return
+	}
+
+	run := models.SchedulerJobRun{
+		JobName:   jobName,
+		Slot:      slot.Truncate(time.Minute),
+		Instance:  InstanceID(),
+		Status:    models.JobRunRunning,
+		StartedAt: time.Now(),
+	}
+	if err := config.DB.Create(&run).Error; err != nil {
+		log.Printf("⚠️  Skipping %s of %s, run could not be recorded: %v", jobName, run.Slot.Format("2006-01-02 15:04"), err)
+		return
+	}
+
+	summary, err := job()
+
+	finished := time.Now()
+	updates := map[string]interface{}{
+		"status":      models.JobRunSucceeded,
+		"finished_at": finished,
+		"duration_ms": finished.Sub(run.StartedAt).Milliseconds(),
+		"summary":     truncateSummary(summary),
+	}
+	if err != nil {
+		updates["status"] = models.JobRunFailed
+		updates["error_message"] = err.Error()
+	}
+	if err := config.DB.Model(&run).Updates(updates).Error; err != nil {
+		log.Printf("⚠️  Failed to record run of %s: %v", jobName, err)
+	}
+}
+
+func truncateSummary(summary string) string {
+	if len(summary) <= 500 {
+		return summary
+	}
+	return summary[:500]
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Maximum value an 8 digit meter can show
+const maxMeterReading = 99999999
+
+var (
+	ErrReadingNegative         = errors.New("Meter end reading cannot be negative")
+	ErrReadingTooLarge         = errors.New("Meter reading exceeds maximum allowed value")
+	ErrInvalidUsageMonth       = errors.New("Format bulan tidak valid. Gunakan YYYY-MM")
+	ErrReadingCustomerNotFound = errors.New("Pelanggan tidak ditemukan")
+	ErrReadingMeterNotFound    = errors.New("Meter tidak ditemukan untuk pelanggan ini")
+	ErrReadingAlreadyExists    = errors.New("Pembacaan meter untuk bulan ini sudah ada")
+	ErrReadingDecreased        = errors.New("Meter akhir lebih kecil dari meter sebelumnya")
+	ErrReadingSessionNotFound  = errors.New("Sesi pembacaan tidak ditemukan")
+	ErrReadingSessionClosed    = errors.New("Sesi pembacaan sudah ditutup")
+	ErrCustomerNotOnRoute      = errors.New("Pelanggan tidak termasuk dalam rute sesi ini")
+	ErrUsageMonthMismatch      = errors.New("Bulan pemakaian tidak sesuai dengan sesi pembacaan")
+)
+
+// IsReadingNotFound reports whether a reading error means a referenced record does not exist
+func IsReadingNotFound(err error) bool {
+	return errors.Is(err, ErrReadingCustomerNotFound) || errors.Is(err, ErrReadingMeterNotFound) ||
+		errors.Is(err, ErrReadingSessionNotFound)
+}
+
+// RecordReadingInput is a single meter reading to be stored as water usage
+type RecordReadingInput struct {
+	TenantID         uuid.UUID
+	CustomerID       uuid.UUID
+	UsageMonth       string // YYYY-MM, defaults to the session month when recorded in a session
+	MeterEnd         float64
+	MeterID          *uuid.UUID // defaults to the customer's installed meter
+	ReadingSessionID *uuid.UUID
+	RecordedBy       *uuid.UUID
+	PhotoURL         string
+	ReadingMethod    string
+	Notes            string
+
+	// Set when the reading was captured offline by the reader app
+	ClientReadingID string
+	CapturedAt      *time.Time
+	Latitude        *float64
+	Longitude       *float64
+
+	acceptCompletedSession bool
+	estimate               bool // derive the meter end from the customer's average usage
+}
+
+// MeterReadingService turns meter readings into priced WaterUsage records
+type MeterReadingService struct {
+	tariffEngine *TariffEngine
+	sessions     *ReadingSessionService
+	detector     *AnomalyDetector
+}
+
+// NewMeterReadingService creates new meter reading service
+func NewMeterReadingService() *MeterReadingService {
+	return &MeterReadingService{
+		tariffEngine: NewTariffEngine(),
+		sessions:     NewReadingSessionService(),
+		detector:     NewAnomalyDetector(),
+	}
+}
+
+// FindActiveMeter returns the meter currently installed for a customer (active or broken),
+// or nil when none is registered
+func FindActiveMeter(db *gorm.DB, tenantID, customerID uuid.UUID) *models.Meter {
+	var meter models.Meter
+	if err := db.Where("tenant_id = ? AND customer_id = ? AND status IN ?", tenantID, customerID,
+		[]string{models.MeterStatusActive, models.MeterStatusBroken}).
+		Order("install_date DESC").First(&meter).Error; err != nil {
+		return nil
+	}
+	return &meter
+}
+
+// RecordReading validates a reading against the previous one, prices it through the tariff engine
+// and stores it. Readings taken inside a reading session update the session progress.
+func (s *MeterReadingService) RecordReading(input RecordReadingInput) (*models.WaterUsage, error) {
+	if input.MeterEnd < 0 {
+		return nil, ErrReadingNegative
+	}
+	if input.MeterEnd > maxMeterReading {
+		return nil, ErrReadingTooLarge
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("id = ? AND tenant_id = ?", input.CustomerID, input.TenantID).First(&customer).Error; err != nil {
+		return nil, ErrReadingCustomerNotFound
+	}
+
+	if input.ReadingSessionID != nil {
+		session, err := s.sessions.findOpenSession(input.TenantID, *input.ReadingSessionID, input.acceptCompletedSession)
+		if err != nil {
+			return nil, err
+		}
+		if customer.ReadingRouteID == nil || *customer.ReadingRouteID != session.RouteID {
+			return nil, ErrCustomerNotOnRoute
+		}
+		if input.UsageMonth == "" {
+			input.UsageMonth = session.UsageMonth
+		} else if input.UsageMonth != session.UsageMonth {
+			return nil, ErrUsageMonthMismatch
+		}
+	}
+
+	// Hitung bulan sebelumnya
+	month, err := time.Parse("2006-01", input.UsageMonth)
+	if err != nil {
+		return nil, ErrInvalidUsageMonth
+	}
+
+	// Readings entered outside the field app still count towards the route's open session
+	if input.ReadingSessionID == nil {
+		if session := s.sessions.FindOpenSessionForCustomer(customer, input.UsageMonth); session != nil {
+			input.ReadingSessionID = &session.ID
+		}
+	}
+	prevMonthStr := month.AddDate(0, -1, 0).Format("2006-01")
+
+	var existing int64
+	config.DB.Model(&models.WaterUsage{}).
+		Where("customer_id = ? AND usage_month = ? AND tenant_id = ?", input.CustomerID, input.UsageMonth, input.TenantID).
+		Count(&existing)
+	if existing > 0 {
+		return nil, ErrReadingAlreadyExists
+	}
+
+	// Tentukan meter yang dibaca: dari input atau meter terpasang pelanggan
+	var meter *models.Meter
+	if input.MeterID != nil {
+		var selected models.Meter
+		if err := config.DB.Where("id = ? AND customer_id = ? AND tenant_id = ?", *input.MeterID, input.CustomerID, input.TenantID).
+			First(&selected).Error; err != nil {
+			return nil, ErrReadingMeterNotFound
+		}
+		meter = &selected
+	} else {
+		meter = FindActiveMeter(config.DB, input.TenantID, input.CustomerID)
+	}
+
+	// Ambil meter_end bulan sebelumnya
+	var lastUsage models.WaterUsage
+	meterStart := 0.0
+	if err := config.DB.Where("customer_id = ? AND usage_month = ? AND tenant_id = ?", input.CustomerID, prevMonthStr, input.TenantID).
+		First(&lastUsage).Error; err == nil {
+		meterStart = lastUsage.MeterEnd
+	}
+
+	// Meter baru dipasang setelah pembacaan terakhir: mulai dari angka awal meter tersebut
+	if meter != nil && (lastUsage.ID == uuid.Nil || (lastUsage.MeterID != nil && *lastUsage.MeterID != meter.ID)) {
+		meterStart = meter.InitialReading
+	}
+
+	readingMethod := input.ReadingMethod
+	if readingMethod == "" {
+		readingMethod = models.ReadingMethodManual
+	}
+
+	var notes []string
+
+	// Meter rusak: angka yang dicatat hanya perkiraan sampai meter diperbaiki
+	if meter != nil && meter.Status == models.MeterStatusBroken {
+		readingMethod = models.ReadingMethodEstimated
+		notes = append(notes, "Meter rusak, pembacaan diperkirakan")
+	}
+
+	// Meter tidak dapat dibaca: pemakaian diperkirakan dari rata-rata riwayat
+	if input.estimate {
+		stats := s.detector.HistoryStats(input.TenantID, input.CustomerID, input.UsageMonth)
+		if stats.Months == 0 {
+			return nil, ErrNoUsageHistory
+		}
+		estimatedM3 := math.Round(stats.Average*100) / 100
+		readingMethod = models.ReadingMethodEstimated
+		input.MeterEnd = meterStart + estimatedM3
+		notes = append(notes, fmt.Sprintf("Estimasi dari rata-rata %d bulan (%.2f m³)", stats.Months, estimatedM3))
+	}
+
+	// Pembacaan aktual pertama setelah estimasi: hitung dari angka aktual terakhir dan koreksi estimasinya
+	var estimates []models.WaterUsage
+	if readingMethod != models.ReadingMethodEstimated {
+		estimates = s.unsettledEstimates(input.TenantID, input.CustomerID, input.UsageMonth, meter)
+		if len(estimates) > 0 {
+			meterStart = estimates[0].MeterStart
+		}
+	}
+
+	if input.MeterEnd < meterStart {
+		return nil, ErrReadingDecreased
+	}
+
+	if input.Notes != "" {
+		notes = append(notes, input.Notes)
+	}
+
+	usage := models.WaterUsage{
+		CustomerID:       input.CustomerID,
+		UsageMonth:       input.UsageMonth,
+		MeterStart:       meterStart,
+		MeterEnd:         input.MeterEnd,
+		TenantID:         input.TenantID,
+		ReadingSessionID: input.ReadingSessionID,
+		RecordedBy:       input.RecordedBy,
+		PhotoURL:         input.PhotoURL,
+		ReadingMethod:    readingMethod,
+		Notes:            strings.Join(notes, ". "),
+		CapturedAt:       input.CapturedAt,
+		Latitude:         input.Latitude,
+		Longitude:        input.Longitude,
+	}
+	if input.ClientReadingID != "" {
+		usage.ClientReadingID = &input.ClientReadingID
+	}
+	if meter != nil {
+		usage.MeterID = &meter.ID
+	}
+
+	// Hitung tagihan air melalui tariff engine (flat atau progresif)
+	if err := s.priceReading(&usage, customer, estimates); err != nil {
+		return nil, err
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&usage).Error; err != nil {
+			return err
+		}
+		return settleEstimates(tx, usage.ID, estimates)
+	}); err != nil {
+		return nil, err
+	}
+
+	// Unusual readings are kept but wait in the review queue before they can be invoiced
+	if _, err := s.detector.Evaluate(&usage); err != nil {
+		log.Printf("⚠️  Failed to check reading for anomalies: %v", err)
+	}
+
+	s.refreshSession(usage.ReadingSessionID)
+
+	return &usage, nil
+}
+
+// UpdateReading changes the meter end of an existing reading, reprices it and checks it for
+// anomalies again
+func (s *MeterReadingService) UpdateReading(usage *models.WaterUsage, meterEnd float64) error {
+	if err := s.applyMeterEnd(usage, meterEnd); err != nil {
+		return err
+	}
+
+	if _, err := s.detector.Evaluate(usage); err != nil {
+		log.Printf("⚠️  Failed to check reading for anomalies: %v", err)
+	}
+
+	s.refreshSession(usage.ReadingSessionID)
+	return nil
+}
+
+// CorrectReading applies a reviewed meter end to an anomalous reading. The reviewer has already
+// confirmed the value, so it is not checked for anomalies again.
+func (s *MeterReadingService) CorrectReading(usage *models.WaterUsage, meterEnd float64) error {
+	return s.applyMeterEnd(usage, meterEnd)
+}
+
+// applyMeterEnd validates the new meter end and reprices the reading through the tariff engine
+func (s *MeterReadingService) applyMeterEnd(usage *models.WaterUsage, meterEnd float64) error {
+	if meterEnd < 0 {
+		return ErrReadingNegative
+	}
+	if meterEnd > maxMeterReading {
+		return ErrReadingTooLarge
+	}
+	if meterEnd < usage.MeterStart {
+		return ErrReadingDecreased
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("id = ? AND tenant_id = ?", usage.CustomerID, usage.TenantID).First(&customer).Error; err != nil {
+		return ErrReadingCustomerNotFound
+	}
+
+	// Estimates already trued up by this reading are corrected again with the new figure
+	var estimates []models.WaterUsage
+	config.DB.Where("true_up_usage_id = ?", usage.ID).Order("usage_month ASC").Find(&estimates)
+
+	// Hitung ulang tagihan air melalui tariff engine
+	usage.MeterEnd = meterEnd
+	if err := s.priceReading(usage, customer, estimates); err != nil {
+		return err
+	}
+
+	if err := config.DB.Save(usage).Error; err != nil {
+		return err
+	}
+
+	// A corrected estimate changes the true-up of the actual reading that settled it
+	if usage.TrueUpUsageID != nil {
+		if err := s.repriceTrueUp(*usage.TrueUpUsageID); err != nil {
+			log.Printf("⚠️  Failed to update true-up of reading %s: %v", *usage.TrueUpUsageID, err)
+		}
+	}
+
+	return nil
+}
+
+// refreshSession recounts the progress of the session a reading belongs to
+func (s *MeterReadingService) refreshSession(sessionID *uuid.UUID) {
+	if sessionID == nil {
+		return
+	}
+	if err := s.sessions.RefreshProgress(*sessionID); err != nil {
+		log.Printf("⚠️  Failed to refresh reading session progress: %v", err)
+	}
+}
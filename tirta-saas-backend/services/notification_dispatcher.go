@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+)
+
+// Notification delivery defaults
+const (
+	notificationMaxAttempts  = 5
+	notificationBatchSize    = 100
+	notificationSendTimeout  = 30 * time.Second
+	notificationRetryBase    = time.Minute
+	notificationRetryMax     = 6 * time.Hour
+	notificationStaleSending = 10 * time.Minute // SENDING rows older than this were left by a crashed worker
+)
+
+var ErrNoNotificationProvider = errors.New("no provider configured for notification channel")
+
+// NotificationDispatcher delivers queued NotificationLogs through the provider of their channel
+type NotificationDispatcher struct {
+	providers map[models.NotificationChannel]NotificationProvider
+}
+
+// NewNotificationDispatcher creates new notification dispatcher with providers configured from the environment
+func NewNotificationDispatcher() *NotificationDispatcher {
+	return NewNotificationDispatcherWithProviders(NotificationProvidersFromEnv())
+}
+
+// NewNotificationDispatcherWithProviders creates a dispatcher with explicit providers, e.g. the file provider in tests
+func NewNotificationDispatcherWithProviders(providers map[models.NotificationChannel]NotificationProvider) *NotificationDispatcher {
+	return &NotificationDispatcher{providers: providers}
+}
+
+// Enqueue stores a notification as PENDING; the worker delivers it on its next run
+func (d *NotificationDispatcher) Enqueue(notification *models.NotificationLog) error {
+	notification.Status = models.NotificationStatusPending
+	notification.RetryCount = 0
+	notification.NextRetryAt = nil
+
+	// Metadata is a JSON column, an empty string is not valid JSON
+	if notification.Metadata == "" {
+		return config.DB.Omit("Metadata").Create(notification).Error
+	}
+	return config.DB.Create(notification).Error
+}
+
+// ProcessPending delivers due PENDING notifications and returns how many were sent and failed
+func (d *NotificationDispatcher) ProcessPending() (int, int) {
+	d.releaseStale()
+
+	var pending []models.NotificationLog
+	if err := config.DB.Where("status = ? AND (next_retry_at IS NULL OR next_retry_at <= ?)",
+		models.NotificationStatusPending, time.Now()).
+		Order("created_at ASC").Limit(notificationBatchSize).Find(&pending).Error; err != nil {
+		log.Printf("❌ Failed to fetch pending notifications: %v", err)
+		return 0, 0
+	}
+
+	sent, failed := 0, 0
+	for i := range pending {
+		if !d.claim(&pending[i]) {
+			continue
+		}
+		if err := d.deliver(&pending[i]); err != nil {
+			failed++
+			continue
+		}
+		sent++
+	}
+
+	return sent, failed
+}
+
+// Retry puts a FAILED notification back in the queue
+func (d *NotificationDispatcher) Retry(tenantID, id uuid.UUID) (*models.NotificationLog, error) {
+	var notification models.NotificationLog
+	if err := config.DB.Where("id = ? AND tenant_id = ?", id, tenantID).First(&notification).Error; err != nil {
+		return nil, err
+	}
+	if notification.Status != models.NotificationStatusFailed {
+		return nil, fmt.Errorf("only failed notifications can be retried, status is %s", notification.Status)
+	}
+
+	if err := config.DB.Model(&notification).Updates(map[string]interface{}{
+		"status":        models.NotificationStatusPending,
+		"retry_count":   0,
+		"next_retry_at": nil,
+		"failed_at":     nil,
+		"error_message": "",
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	notification.Status = models.NotificationStatusPending
+	notification.RetryCount = 0
+	notification.NextRetryAt = nil
+	notification.FailedAt = nil
+	notification.ErrorMessage = ""
+	return &notification, nil
+}
+
+// claim moves a notification to SENDING so that concurrent workers don't deliver it twice
+func (d *NotificationDispatcher) claim(notification *models.NotificationLog) bool {
+	result := config.DB.Model(&models.NotificationLog{}).
+		Where("id = ? AND status = ?", notification.ID, models.NotificationStatusPending).
+		Update("status", models.NotificationStatusSending)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+	notification.Status = models.NotificationStatusSending
+	return true
+}
+
+// releaseStale returns notifications stuck in SENDING to the queue
+func (d *NotificationDispatcher) releaseStale() {
+	result := config.DB.Model(&models.NotificationLog{}).
+		Where("status = ? AND updated_at < ?", models.NotificationStatusSending, time.Now().Add(-notificationStaleSending)).
+		Update("status", models.NotificationStatusPending)
+	if result.Error == nil && result.RowsAffected > 0 {
+		log.Printf("⚠️  Released %d notifications stuck in sending", result.RowsAffected)
+	}
+}
+
+// deliver sends a claimed notification and records the outcome
+func (d *NotificationDispatcher) deliver(notification *models.NotificationLog) error {
+	provider, ok := d.providers[notification.Channel]
+	if !ok {
+		err := fmt.Errorf("%w: %s", ErrNoNotificationProvider, notification.Channel)
+		d.markFailed(notification, "", err, true)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notificationSendTimeout)
+	defer cancel()
+
+	msgID, err := provider.Send(ctx, NotificationMessage{
+		LogID:         notification.ID,
+		TenantID:      notification.TenantID,
+		Channel:       notification.Channel,
+		Destination:   notification.Destination,
+		RecipientName: notification.RecipientName,
+		Subject:       notification.Subject,
+		Body:          notification.Body,
+		HTMLBody:      notification.HTMLBody,
+	})
+	if err != nil {
+		d.markFailed(notification, provider.Name(), err, errors.Is(err, ErrPermanentDelivery))
+		return err
+	}
+
+	now := time.Now()
+	notification.Status = models.NotificationStatusSent
+	notification.SentAt = &now
+	notification.Provider = provider.Name()
+	notification.ProviderMsgID = msgID
+	notification.NextRetryAt = nil
+	notification.ErrorMessage = ""
+
+	if err := config.DB.Model(notification).Updates(map[string]interface{}{
+		"status":          notification.Status,
+		"sent_at":         notification.SentAt,
+		"provider":        notification.Provider,
+		"provider_msg_id": notification.ProviderMsgID,
+		"next_retry_at":   nil,
+		"error_message":   "",
+	}).Error; err != nil {
+		log.Printf("⚠️  Notification %s was sent but its status could not be saved: %v", notification.ID, err)
+	}
+	return nil
+}
+
+// markFailed schedules a retry with exponential backoff, or fails the notification for good
+// when the error is permanent or the attempts are used up
+func (d *NotificationDispatcher) markFailed(notification *models.NotificationLog, providerName string, sendErr error, permanent bool) {
+	notification.RetryCount++
+	notification.ErrorMessage = sendErr.Error()
+	if providerName != "" {
+		notification.Provider = providerName
+	}
+
+	updates := map[string]interface{}{
+		"retry_count":   notification.RetryCount,
+		"error_message": notification.ErrorMessage,
+		"provider":      notification.Provider,
+	}
+
+	if permanent || notification.RetryCount >= notificationMaxAttempts {
+		now := time.Now()
+		notification.Status = models.NotificationStatusFailed
+		notification.FailedAt = &now
+		notification.NextRetryAt = nil
+		updates["failed_at"] = now
+		updates["next_retry_at"] = nil
+		log.Printf("❌ Notification %s failed after %d attempts: %v", notification.ID, notification.RetryCount, sendErr)
+	} else {
+		nextRetry := time.Now().Add(notificationBackoff(notification.RetryCount))
+		notification.Status = models.NotificationStatusPending
+		notification.NextRetryAt = &nextRetry
+		updates["next_retry_at"] = nextRetry
+		log.Printf("⚠️  Notification %s attempt %d failed, retrying at %s: %v",
+			notification.ID, notification.RetryCount, nextRetry.Format(time.RFC3339), sendErr)
+	}
+	updates["status"] = notification.Status
+
+	if err := config.DB.Model(notification).Updates(updates).Error; err != nil {
+		log.Printf("❌ Failed to update notification %s: %v", notification.ID, err)
+	}
+}
+
+// notificationBackoff returns the wait before the given retry: 1m, 2m, 4m, ... capped at 6h
+func notificationBackoff(retryCount int) time.Duration {
+	wait := notificationRetryBase
+	for i := 1; i < retryCount; i++ {
+		wait *= 2
+		if wait >= notificationRetryMax {
+			return notificationRetryMax
+		}
+	}
+	return wait
+}
@@ -0,0 +1,346 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+)
+
+// ErrPermanentDelivery marks provider errors that will not succeed on retry (invalid number,
+// rejected address). Wrap it with %w so the dispatcher fails the notification immediately.
+var ErrPermanentDelivery = errors.New("permanent delivery failure")
+
+// NotificationMessage is what a provider needs to deliver one notification
+type NotificationMessage struct {
+	LogID         uuid.UUID
+	TenantID      uuid.UUID
+	Channel       models.NotificationChannel
+	Destination   string
+	RecipientName string
+	Subject       string
+	Body          string
+	HTMLBody      string // email only, sent as the HTML alternative of Body
+}
+
+// NotificationProvider delivers notifications over one channel
+type NotificationProvider interface {
+	// Name is stored in NotificationLog.Provider
+	Name() string
+	// Send delivers the message and returns the provider's message ID
+	Send(ctx context.Context, msg NotificationMessage) (string, error)
+}
+
+// NotificationProvidersFromEnv builds a provider for every channel from environment variables.
+// Channels without configuration fall back to the file provider so nothing is lost in development.
+//
+//	NOTIFICATION_PROVIDER=file        send every channel to NOTIFICATION_FILE_PATH
+//	SMTP_HOST, SMTP_PORT, SMTP_USER, SMTP_PASS, SMTP_FROM
+//	SMS_GATEWAY_URL, SMS_GATEWAY_API_KEY, SMS_SENDER_ID
+//	WHATSAPP_API_URL, WHATSAPP_ACCESS_TOKEN
+func NotificationProvidersFromEnv() map[models.NotificationChannel]NotificationProvider {
+	filePath := os.Getenv("NOTIFICATION_FILE_PATH")
+	if filePath == "" {
+		filePath = "storage/notifications.log"
+	}
+	fileProvider := NewFileNotificationProvider(filePath)
+
+	providers := map[models.NotificationChannel]NotificationProvider{
+		models.ChannelEmail:    fileProvider,
+		models.ChannelSMS:      fileProvider,
+		models.ChannelWhatsApp: fileProvider,
+		models.ChannelInApp:    InAppNotificationProvider{},
+	}
+
+	if os.Getenv("NOTIFICATION_PROVIDER") == "file" {
+		return providers
+	}
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		providers[models.ChannelEmail] = &SMTPProvider{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASS"),
+			From:     os.Getenv("SMTP_FROM"),
+		}
+	}
+
+	if url := os.Getenv("SMS_GATEWAY_URL"); url != "" {
+		providers[models.ChannelSMS] = &SMSGatewayProvider{
+			URL:      url,
+			APIKey:   os.Getenv("SMS_GATEWAY_API_KEY"),
+			SenderID: os.Getenv("SMS_SENDER_ID"),
+			client:   &http.Client{Timeout: 15 * time.Second},
+		}
+	}
+
+	if url := os.Getenv("WHATSAPP_API_URL"); url != "" {
+		providers[models.ChannelWhatsApp] = &WhatsAppProvider{
+			URL:         url,
+			AccessToken: os.Getenv("WHATSAPP_ACCESS_TOKEN"),
+			client:      &http.Client{Timeout: 15 * time.Second},
+		}
+	}
+
+	return providers
+}
+
+// SMTPProvider sends email through an SMTP server (STARTTLS is used when the server offers it)
+type SMTPProvider struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (p *SMTPProvider) Name() string { return "smtp" }
+
+func (p *SMTPProvider) Send(ctx context.Context, msg NotificationMessage) (string, error) {
+	if !strings.Contains(msg.Destination, "@") {
+		return "", fmt.Errorf("%w: invalid email address %q", ErrPermanentDelivery, msg.Destination)
+	}
+
+	from := p.From
+	if from == "" {
+		from = p.Username
+	}
+	messageID := fmt.Sprintf("<%s@%s>", msg.LogID, p.Host)
+
+	var body strings.Builder
+	body.WriteString("From: " + from + "\r\n")
+	body.WriteString("To: " + msg.Destination + "\r\n")
+	body.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	body.WriteString("Message-ID: " + messageID + "\r\n")
+	body.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	body.WriteString("MIME-Version: 1.0\r\n")
+	if msg.HTMLBody == "" {
+		body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		body.WriteString("\r\n")
+		body.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	} else {
+		boundary := "alt-" + msg.LogID.String()
+		body.WriteString("Content-Type: multipart/alternative; boundary=\"" + boundary + "\"\r\n")
+		body.WriteString("\r\n")
+		body.WriteString("--" + boundary + "\r\n")
+		body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+		body.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n") + "\r\n")
+		body.WriteString("--" + boundary + "\r\n")
+		body.WriteString("Content-Type: text/html; charset=UTF-8\r\n\r\n")
+		body.WriteString(strings.ReplaceAll(msg.HTMLBody, "\n", "\r\n") + "\r\n")
+		body.WriteString("--" + boundary + "--\r\n")
+	}
+
+	var auth smtp.Auth
+	if p.Username != "" {
+		auth = smtp.PlainAuth("", p.Username, p.Password, p.Host)
+	}
+
+	if err := smtp.SendMail(p.Host+":"+p.Port, auth, from, []string{msg.Destination}, []byte(body.String())); err != nil {
+		// 5xx replies are permanent (unknown mailbox, rejected sender)
+		var reply *textproto.Error
+		if errors.As(err, &reply) && reply.Code >= 500 {
+			return "", fmt.Errorf("%w: %v", ErrPermanentDelivery, err)
+		}
+		return "", err
+	}
+
+	return messageID, nil
+}
+
+// SMSGatewayProvider posts messages to an HTTP SMS gateway. The gateway receives
+// {"to", "message", "sender"} and should answer with {"message_id"} or {"id"}.
+type SMSGatewayProvider struct {
+	URL      string
+	APIKey   string
+	SenderID string
+	client   *http.Client
+}
+
+func (p *SMSGatewayProvider) Name() string { return "sms_gateway" }
+
+func (p *SMSGatewayProvider) Send(ctx context.Context, msg NotificationMessage) (string, error) {
+	payload := map[string]string{
+		"to":      normalizePhoneNumber(msg.Destination),
+		"message": msg.Body,
+		"sender":  p.SenderID,
+	}
+
+	var reply struct {
+		MessageID string `json:"message_id"`
+		ID        string `json:"id"`
+	}
+	if err := postProviderJSON(ctx, p.client, p.URL, p.APIKey, payload, &reply); err != nil {
+		return "", err
+	}
+
+	if reply.MessageID != "" {
+		return reply.MessageID, nil
+	}
+	return reply.ID, nil
+}
+
+// WhatsAppProvider sends text messages through the WhatsApp Business Cloud API messages endpoint
+type WhatsAppProvider struct {
+	URL         string // https://graph.facebook.com/<version>/<phone-number-id>/messages
+	AccessToken string
+	client      *http.Client
+}
+
+func (p *WhatsAppProvider) Name() string { return "whatsapp_cloud" }
+
+func (p *WhatsAppProvider) Send(ctx context.Context, msg NotificationMessage) (string, error) {
+	payload := map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"to":                strings.TrimPrefix(normalizePhoneNumber(msg.Destination), "+"),
+		"type":              "text",
+		"text":              map[string]string{"body": msg.Body},
+	}
+
+	var reply struct {
+		Messages []struct {
+			ID string `json:"id"`
+		} `json:"messages"`
+	}
+	if err := postProviderJSON(ctx, p.client, p.URL, p.AccessToken, payload, &reply); err != nil {
+		return "", err
+	}
+
+	if len(reply.Messages) == 0 {
+		return "", errors.New("whatsapp API returned no message ID")
+	}
+	return reply.Messages[0].ID, nil
+}
+
+// InAppNotificationProvider delivers in-app notifications. The NotificationLog itself is the
+// recipient's inbox, so there is nothing to send.
+type InAppNotificationProvider struct{}
+
+func (InAppNotificationProvider) Name() string { return "in_app" }
+
+func (InAppNotificationProvider) Send(ctx context.Context, msg NotificationMessage) (string, error) {
+	return msg.LogID.String(), nil
+}
+
+// FileNotificationProvider appends every message as a JSON line to a local file. It is used in
+// development and tests instead of real gateways.
+type FileNotificationProvider struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func NewFileNotificationProvider(path string) *FileNotificationProvider {
+	return &FileNotificationProvider{Path: path}
+}
+
+func (p *FileNotificationProvider) Name() string { return "file" }
+
+func (p *FileNotificationProvider) Send(ctx context.Context, msg NotificationMessage) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(p.Path), 0755); err != nil {
+		return "", err
+	}
+
+	file, err := os.OpenFile(p.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	messageID := "file-" + uuid.New().String()
+	line, err := json.Marshal(map[string]interface{}{
+		"message_id":     messageID,
+		"log_id":         msg.LogID,
+		"tenant_id":      msg.TenantID,
+		"channel":        msg.Channel,
+		"destination":    msg.Destination,
+		"recipient_name": msg.RecipientName,
+		"subject":        msg.Subject,
+		"body":           msg.Body,
+		"html_body":      msg.HTMLBody,
+		"sent_at":        time.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return "", err
+	}
+	return messageID, nil
+}
+
+// postProviderJSON posts a JSON payload with a bearer token and decodes the JSON reply.
+// 4xx replies other than 429 are treated as permanent failures.
+func postProviderJSON(ctx context.Context, client *http.Client, url, token string, payload interface{}, reply interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode >= 400 {
+		err := fmt.Errorf("provider returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return fmt.Errorf("%w: %v", ErrPermanentDelivery, err)
+		}
+		return err
+	}
+
+	if reply != nil && len(body) > 0 {
+		if err := json.Unmarshal(body, reply); err != nil {
+			return fmt.Errorf("invalid provider response: %w", err)
+		}
+	}
+	return nil
+}
+
+// normalizePhoneNumber converts local Indonesian numbers (08xx) to international format (+628xx)
+func normalizePhoneNumber(phone string) string {
+	phone = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(phone)
+	switch {
+	case strings.HasPrefix(phone, "+"):
+		return phone
+	case strings.HasPrefix(phone, "62"):
+		return "+" + phone
+	case strings.HasPrefix(phone, "0"):
+		return "+62" + phone[1:]
+	}
+	return phone
+}
@@ -0,0 +1,425 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+)
+
+var ErrUndeclaredTemplateVariable = errors.New("template uses undeclared variables")
+
+// Placeholders written as {{name}} (without the leading dot) are rewritten to {{.name}}
+var bareVariablePattern = regexp.MustCompile(`\{\{(-?\s*)([A-Za-z_][A-Za-z0-9_]*)(\s*-?)\}\}`)
+
+// Keywords that may appear alone inside {{ }} and must not be rewritten to variables
+var templateKeywords = map[string]bool{
+	"else": true, "end": true, "break": true, "continue": true, "nil": true, "true": true, "false": true,
+}
+
+var indonesianMonths = []string{
+	"Januari", "Februari", "Maret", "April", "Mei", "Juni",
+	"Juli", "Agustus", "September", "Oktober", "November", "Desember",
+}
+
+var currencySymbols = map[string]string{
+	"IDR": "Rp",
+	"USD": "$",
+	"SGD": "S$",
+	"MYR": "RM",
+	"EUR": "€",
+}
+
+// RenderedNotification is a template rendered with its variables
+type RenderedNotification struct {
+	Subject  string `json:"subject"`
+	Body     string `json:"body"`
+	HTMLBody string `json:"html_body,omitempty"`
+}
+
+// NotificationRenderer renders notification templates with Go templates. Subject and Body are
+// plain text for SMS/WhatsApp, HTMLBody is HTML-escaped. Formatting helpers follow the language
+// and currency of the tenant:
+//
+//	{{currency .total_amount}}   Rp 125.000
+//	{{number .usage_m3 2}}       12,50
+//	{{date .due_date}}           5 Februari 2026
+//	{{month .usage_month}}       Januari 2026
+//	{{if gt .days_overdue 0}}...{{end}}, {{range .items}}...{{end}}, {{default "-" .notes}}
+type NotificationRenderer struct {
+	Language string
+	Currency string
+}
+
+// NewNotificationRenderer creates a renderer for a language (id, en) and ISO currency code
+func NewNotificationRenderer(language, currency string) *NotificationRenderer {
+	if language == "" {
+		language = "id"
+	}
+	if currency == "" {
+		currency = "IDR"
+	}
+	return &NotificationRenderer{Language: language, Currency: strings.ToUpper(currency)}
+}
+
+// NotificationRendererForTenant creates a renderer with the tenant's language and currency
+func NotificationRendererForTenant(tenantID uuid.UUID) *NotificationRenderer {
+	var settings models.TenantSettings
+	config.DB.Where("tenant_id = ?", tenantID).First(&settings)
+	return NewNotificationRenderer(settings.Language, settings.Currency)
+}
+
+// Render renders the subject, body and HTML body of a template
+func (r *NotificationRenderer) Render(subject, body, htmlBody string, variables map[string]interface{}) (*RenderedNotification, error) {
+	if variables == nil {
+		variables = map[string]interface{}{}
+	}
+
+	rendered := &RenderedNotification{}
+	var err error
+
+	if rendered.Subject, err = r.renderText("subject", subject, variables); err != nil {
+		return nil, err
+	}
+	if rendered.Body, err = r.renderText("body", body, variables); err != nil {
+		return nil, err
+	}
+	if htmlBody != "" {
+		tmpl, err := htmltemplate.New("html_body").Funcs(r.funcs()).Option("missingkey=error").
+			Parse(normalizeTemplateSyntax(htmlBody))
+		if err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, variables); err != nil {
+			return nil, err
+		}
+		rendered.HTMLBody = out.String()
+	}
+
+	return rendered, nil
+}
+
+// RenderTemplate renders a stored notification template
+func (r *NotificationRenderer) RenderTemplate(tmpl models.NotificationTemplate, variables map[string]interface{}) (*RenderedNotification, error) {
+	return r.Render(tmpl.Subject, tmpl.Body, tmpl.HTMLBody, variables)
+}
+
+func (r *NotificationRenderer) renderText(name, text string, variables map[string]interface{}) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+
+	tmpl, err := template.New(name).Funcs(r.funcs()).Option("missingkey=error").Parse(normalizeTemplateSyntax(text))
+	if err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, variables); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// ValidateTemplate parses the template parts and checks that they only reference declared variables
+func (r *NotificationRenderer) ValidateTemplate(subject, body, htmlBody string, declared []string) error {
+	allowed := make(map[string]bool, len(declared))
+	for _, name := range declared {
+		allowed[name] = true
+	}
+
+	undeclared := map[string]bool{}
+	parts := []struct{ name, text string }{{"subject", subject}, {"body", body}, {"html_body", htmlBody}}
+	for _, part := range parts {
+		if part.text == "" {
+			continue
+		}
+
+		tmpl, err := template.New(part.name).Funcs(r.funcs()).Parse(normalizeTemplateSyntax(part.text))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", part.name, err)
+		}
+
+		for _, name := range parsedTemplateVariables(tmpl) {
+			if !allowed[name] {
+				undeclared[name] = true
+			}
+		}
+	}
+
+	if len(undeclared) > 0 {
+		names := make([]string, 0, len(undeclared))
+		for name := range undeclared {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("%w: %s", ErrUndeclaredTemplateVariable, strings.Join(names, ", "))
+	}
+
+	return nil
+}
+
+// TemplateVariables returns the variables referenced by a template text, sorted
+func TemplateVariables(text string) []string {
+	tmpl, err := template.New("text").Funcs(NewNotificationRenderer("", "").funcs()).Parse(normalizeTemplateSyntax(text))
+	if err != nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for _, name := range parsedTemplateVariables(tmpl) {
+		seen[name] = true
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parsedTemplateVariables returns the variables referenced by a template and its {{define}} blocks
+func parsedTemplateVariables(tmpl *template.Template) []string {
+	var names []string
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			names = append(names, templateVariables(t.Tree.Root)...)
+		}
+	}
+	return names
+}
+
+// normalizeTemplateSyntax rewrites the older {{name}} placeholders to {{.name}}
+func normalizeTemplateSyntax(text string) string {
+	return bareVariablePattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := bareVariablePattern.FindStringSubmatch(match)
+		if templateKeywords[parts[2]] {
+			return match
+		}
+		return "{{" + parts[1] + "." + parts[2] + parts[3] + "}}"
+	})
+}
+
+// templateVariables collects the top-level variables a template node references. Fields inside
+// range and with blocks refer to the current element, so only their pipelines are inspected.
+func templateVariables(node parse.Node) []string {
+	var names []string
+
+	var walk func(node parse.Node, root bool)
+	walk = func(node parse.Node, root bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child, root)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, root)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd, root)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg, root)
+			}
+		case *parse.FieldNode:
+			if root {
+				names = append(names, n.Ident[0])
+			}
+		case *parse.ChainNode:
+			walk(n.Node, root)
+		case *parse.VariableNode:
+			// $.name always refers to the template data
+			if n.Ident[0] == "$" && len(n.Ident) > 1 {
+				names = append(names, n.Ident[1])
+			}
+		case *parse.IfNode:
+			walk(n.Pipe, root)
+			walk(n.List, root)
+			walk(n.ElseList, root)
+		case *parse.RangeNode:
+			walk(n.Pipe, root)
+			walk(n.List, false)
+			walk(n.ElseList, root)
+		case *parse.WithNode:
+			walk(n.Pipe, root)
+			walk(n.List, false)
+			walk(n.ElseList, root)
+		case *parse.TemplateNode:
+			walk(n.Pipe, root)
+		}
+	}
+
+	walk(node, true)
+	return names
+}
+
+func (r *NotificationRenderer) funcs() template.FuncMap {
+	return template.FuncMap{
+		"currency": r.FormatCurrency,
+		"number": func(value interface{}, decimals ...int) string {
+			places := 0
+			if len(decimals) > 0 {
+				places = decimals[0]
+			}
+			return r.FormatNumber(toFloat(value), places)
+		},
+		"date": func(value interface{}, layout ...string) string {
+			t, ok := toTime(value)
+			if !ok {
+				if t, isPointer := value.(*time.Time); value == nil || (isPointer && t == nil) {
+					return ""
+				}
+				return fmt.Sprint(value)
+			}
+			if len(layout) > 0 {
+				return t.Format(layout[0])
+			}
+			return r.FormatDate(t)
+		},
+		"month": func(value interface{}) string {
+			return r.FormatMonth(fmt.Sprint(value))
+		},
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"default": func(fallback, value interface{}) interface{} {
+			if value == nil || fmt.Sprint(value) == "" {
+				return fallback
+			}
+			return value
+		},
+	}
+}
+
+// FormatCurrency formats an amount in the renderer's currency, e.g. Rp 125.000 or $ 1,250.50
+func (r *NotificationRenderer) FormatCurrency(value interface{}) string {
+	amount := toFloat(value)
+	places := 2
+	if r.Currency == "IDR" {
+		places = 0
+	}
+
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	symbol, ok := currencySymbols[r.Currency]
+	if !ok {
+		symbol = r.Currency
+	}
+	return sign + symbol + " " + r.FormatNumber(amount, places)
+}
+
+// FormatNumber formats a number with the renderer's thousands and decimal separators
+func (r *NotificationRenderer) FormatNumber(value float64, decimals int) string {
+	thousands, decimal := ".", ","
+	if r.Language == "en" {
+		thousands, decimal = ",", "."
+	}
+
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	text := strconv.FormatFloat(value, 'f', decimals, 64)
+	integer, fraction, _ := strings.Cut(text, ".")
+
+	var grouped strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			grouped.WriteString(thousands)
+		}
+		grouped.WriteRune(digit)
+	}
+
+	if fraction != "" {
+		return sign + grouped.String() + decimal + fraction
+	}
+	return sign + grouped.String()
+}
+
+// FormatDate formats a date in the renderer's language, e.g. 5 Februari 2026 or February 5, 2026
+func (r *NotificationRenderer) FormatDate(t time.Time) string {
+	if r.Language == "en" {
+		return t.Format("January 2, 2006")
+	}
+	return fmt.Sprintf("%d %s %d", t.Day(), r.monthName(t.Month()), t.Year())
+}
+
+// FormatMonth formats a YYYY-MM usage month, e.g. Januari 2026; other values are returned as they are
+func (r *NotificationRenderer) FormatMonth(usageMonth string) string {
+	t, err := time.Parse("2006-01", usageMonth)
+	if err != nil {
+		return usageMonth
+	}
+	return r.monthName(t.Month()) + " " + strconv.Itoa(t.Year())
+}
+
+func (r *NotificationRenderer) monthName(month time.Month) string {
+	if r.Language == "en" {
+		return month.String()
+	}
+	return indonesianMonths[month-1]
+}
+
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f
+	case nil:
+		return 0
+	}
+	f, _ := strconv.ParseFloat(fmt.Sprint(value), 64)
+	return f
+}
+
+func toTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, false
+		}
+		return *v, true
+	case string:
+		for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
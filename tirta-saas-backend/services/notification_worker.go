@@ -0,0 +1,57 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/robfig/cron/v3"
+)
+
+// NotificationWorker drains the notification queue in the background
+type NotificationWorker struct {
+	cron       *cron.Cron
+	dispatcher *NotificationDispatcher
+	running    sync.Mutex
+}
+
+// NewNotificationWorker creates new notification worker
+func NewNotificationWorker() *NotificationWorker {
+	return &NotificationWorker{
+		cron:       cron.New(),
+		dispatcher: NewNotificationDispatcher(),
+	}
+}
+
+// Start starts the worker
+func (w *NotificationWorker) Start() error {
+	_, err := w.cron.AddFunc("@every 30s", w.run)
+	if err != nil {
+		return fmt.Errorf("failed to schedule notification worker: %w", err)
+	}
+
+	w.cron.Start()
+	log.Println("✅ Notification worker started successfully")
+	log.Println("📨 Pending notifications: Every 30 seconds")
+
+	return nil
+}
+
+// Stop stops the worker
+func (w *NotificationWorker) Stop() {
+	w.cron.Stop()
+	log.Println("🛑 Notification worker stopped")
+}
+
+// run processes one batch, skipping the tick while the previous batch is still sending
+func (w *NotificationWorker) run() {
+	if !w.running.TryLock() {
+		return
+	}
+	defer w.running.Unlock()
+
+	sent, failed := w.dispatcher.ProcessPending()
+	if sent > 0 || failed > 0 {
+		log.Printf("📨 Notifications: %d sent, %d failed", sent, failed)
+	}
+}
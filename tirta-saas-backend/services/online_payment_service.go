@@ -0,0 +1,337 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrOnlinePaymentExceedsDue = errors.New("Jumlah pembayaran melebihi sisa tagihan")
+	ErrTransactionNotFound     = errors.New("Transaksi pembayaran tidak ditemukan")
+	ErrUnknownPaymentGateway   = errors.New("Payment gateway tidak dikenal")
+	ErrNotSimulatedTransaction = errors.New("Hanya transaksi simulated yang dapat disimulasikan")
+)
+
+// OnlinePaymentInput asks for an online payment of an invoice
+type OnlinePaymentInput struct {
+	TenantID   uuid.UUID
+	CustomerID uuid.UUID
+	InvoiceID  uuid.UUID
+	Channel    string  // models.GatewayChannel*
+	Amount     float64 // zero pays the remaining amount of the invoice
+}
+
+// OnlinePaymentService opens invoice payments at the payment gateway and posts the Payment once
+// the gateway confirms settlement. Settlement is idempotent on the provider reference: repeated
+// webhooks or status checks never post a payment twice.
+type OnlinePaymentService struct {
+	notifier *BillingNotifier
+}
+
+// NewOnlinePaymentService creates new online payment service
+func NewOnlinePaymentService() *OnlinePaymentService {
+	return &OnlinePaymentService{
+		notifier: NewBillingNotifier(),
+	}
+}
+
+// CreateInvoicePayment opens an online payment for an invoice of the customer. A pending payment
+// for the same invoice, channel and amount that has not expired is returned instead of opening a
+// new one.
+func (s *OnlinePaymentService) CreateInvoicePayment(input OnlinePaymentInput) (*models.PaymentTransaction, error) {
+	var invoice models.Invoice
+	if err := config.DB.Where("id = ? AND tenant_id = ? AND customer_id = ?", input.InvoiceID, input.TenantID, input.CustomerID).
+		First(&invoice).Error; err != nil {
+		return nil, ErrPaymentInvoiceNotFound
+	}
+	if invoice.Status == models.InvoiceStatusVoid {
+		return nil, ErrInvoiceVoided
+	}
+	if invoice.IsPaid {
+		return nil, ErrInvoiceAlreadyPaid
+	}
+
+	remaining := roundMoney(invoice.TotalAmount - invoice.TotalPaid)
+	amount := roundMoney(input.Amount)
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount > remaining {
+		return nil, ErrOnlinePaymentExceedsDue
+	}
+	if err := validatePaymentAmount(amount); err != nil {
+		return nil, err
+	}
+
+	gateway, err := DefaultPaymentGateway()
+	if err != nil {
+		return nil, err
+	}
+
+	var existing models.PaymentTransaction
+	if err := config.DB.Where("tenant_id = ? AND invoice_id = ? AND provider = ? AND channel = ? AND amount = ? AND status = ? AND expires_at > ?",
+		input.TenantID, invoice.ID, gateway.Name(), input.Channel, amount, models.PaymentTransactionPending, time.Now()).
+		Order("created_at DESC").First(&existing).Error; err == nil {
+		return &existing, nil
+	}
+
+	var customer models.Customer
+	config.DB.Where("id = ? AND tenant_id = ?", input.CustomerID, input.TenantID).First(&customer)
+
+	// The reference is a placeholder until the gateway assigns one; the transaction ID is the order ID
+	transaction := models.PaymentTransaction{
+		TenantID:          input.TenantID,
+		CustomerID:        input.CustomerID,
+		InvoiceID:         invoice.ID,
+		Amount:            amount,
+		Provider:          gateway.Name(),
+		ProviderReference: "PENDING-" + uuid.NewString(),
+		Channel:           input.Channel,
+		Status:            models.PaymentTransactionPending,
+	}
+	if err := config.DB.Create(&transaction).Error; err != nil {
+		return nil, err
+	}
+
+	description := "Tagihan air " + invoice.InvoiceNumber
+	if invoice.Type == "registration" {
+		description = "Biaya pendaftaran sambungan baru"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	opened, err := gateway.CreatePayment(ctx, GatewayPaymentRequest{
+		OrderID:       transaction.ID.String(),
+		Amount:        amount,
+		Channel:       input.Channel,
+		Description:   description,
+		CustomerName:  customer.Name,
+		CustomerEmail: customer.Email,
+		CustomerPhone: customer.Phone,
+		ExpiresAt:     time.Now().Add(onlinePaymentExpiry()),
+	})
+	if err != nil {
+		config.DB.Model(&transaction).Updates(map[string]interface{}{
+			"status":         models.PaymentTransactionFailed,
+			"failure_reason": truncate(err.Error(), 255),
+		})
+		return nil, err
+	}
+
+	transaction.ProviderReference = opened.Reference
+	transaction.VirtualAccountNumber = opened.VirtualAccountNumber
+	transaction.BankCode = opened.BankCode
+	transaction.QRISPayload = opened.QRISPayload
+	transaction.PaymentURL = opened.PaymentURL
+	transaction.ExpiresAt = opened.ExpiresAt
+	transaction.LastPayload = opened.Raw
+	if err := config.DB.Save(&transaction).Error; err != nil {
+		return nil, err
+	}
+
+	log.Printf("💳 Opened %s payment %s for invoice %s (%.2f)", transaction.Provider, transaction.ProviderReference, invoice.ID, amount)
+	return &transaction, nil
+}
+
+// HandleWebhook verifies and applies a webhook call of a gateway
+func (s *OnlinePaymentService) HandleWebhook(provider string, header http.Header, body []byte) (*models.PaymentTransaction, error) {
+	gateway, ok := PaymentGatewaysFromEnv()[provider]
+	if !ok {
+		return nil, ErrUnknownPaymentGateway
+	}
+
+	notification, err := gateway.VerifyWebhook(header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.apply(provider, notification)
+}
+
+// SyncTransaction asks the gateway for the status of a pending transaction and applies it, for
+// when a webhook was missed
+func (s *OnlinePaymentService) SyncTransaction(transaction *models.PaymentTransaction) (*models.PaymentTransaction, error) {
+	if transaction.Status != models.PaymentTransactionPending {
+		return transaction, nil
+	}
+
+	gateway, ok := PaymentGatewaysFromEnv()[transaction.Provider]
+	if !ok {
+		return nil, ErrUnknownPaymentGateway
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	notification, err := gateway.QueryStatus(ctx, transaction.ProviderReference)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.apply(transaction.Provider, notification)
+}
+
+// SimulateTransaction makes the simulated gateway send the webhook for a status change of one of
+// its transactions. The webhook goes through signature verification like a real one.
+func (s *OnlinePaymentService) SimulateTransaction(transaction *models.PaymentTransaction, status GatewayStatus) (*models.PaymentTransaction, error) {
+	simulated, ok := PaymentGatewaysFromEnv()[transaction.Provider].(*SimulatedGateway)
+	if !ok {
+		return nil, ErrNotSimulatedTransaction
+	}
+
+	header, body, err := simulated.Simulate(transaction.ProviderReference, transaction.ID.String(), transaction.Amount, status)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.HandleWebhook(transaction.Provider, header, body)
+}
+
+// apply updates the transaction of a gateway notification and posts the payment on settlement
+func (s *OnlinePaymentService) apply(provider string, notification *GatewayNotification) (*models.PaymentTransaction, error) {
+	var transaction models.PaymentTransaction
+	var payment *models.Payment
+	var invoice *models.Invoice
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("provider = ? AND provider_reference = ?", provider, notification.Reference).
+			First(&transaction).Error; err != nil {
+			return ErrTransactionNotFound
+		}
+
+		if notification.OrderID != "" && notification.OrderID != transaction.ID.String() {
+			return fmt.Errorf("%w: order %s does not match reference %s", ErrTransactionNotFound, notification.OrderID, notification.Reference)
+		}
+
+		updates := map[string]interface{}{"last_payload": notification.Raw}
+
+		switch notification.Status {
+		case GatewayStatusSettled:
+			// Already posted by an earlier webhook or status check, or waiting for review
+			if transaction.PaymentID != nil || transaction.Status == models.PaymentTransactionReview {
+				return tx.Model(&transaction).Updates(updates).Error
+			}
+
+			amount := notification.Amount
+			if amount <= 0 {
+				amount = transaction.Amount
+			}
+			if math.Abs(amount-transaction.Amount) >= 0.01 {
+				// Only the requested amount is ever posted automatically
+				log.Printf("⚠️  Gateway settled %.2f for transaction %s opened for %.2f, held for review", amount, transaction.ID, transaction.Amount)
+				updates["status"] = models.PaymentTransactionReview
+				updates["failure_reason"] = truncate(fmt.Sprintf("Gateway melaporkan pembayaran %.2f, diminta %.2f", amount, transaction.Amount), 255)
+				if err := tx.Model(&transaction).Updates(updates).Error; err != nil {
+					return err
+				}
+				return tx.Where("id = ?", transaction.ID).First(&transaction).Error
+			}
+
+			paidAt := time.Now()
+			if notification.PaidAt != nil {
+				paidAt = *notification.PaidAt
+			}
+
+			var err error
+			payment, invoice, err = postPayment(tx, PostPaymentInput{
+				TenantID:        transaction.TenantID,
+				InvoiceID:       transaction.InvoiceID,
+				Amount:          amount,
+				ReferenceNumber: transaction.ProviderReference,
+				Notes:           fmt.Sprintf("Pembayaran online via %s (%s)", transaction.Provider, transaction.Channel),
+			})
+			switch {
+			case errors.Is(err, ErrInvoiceAlreadyPaid), errors.Is(err, ErrInvoiceVoided):
+				// Paid elsewhere or voided in the meantime, the money is kept as credit
+				payment, invoice = nil, nil
+				if err := creditOnlinePayment(tx, transaction, amount); err != nil {
+					return err
+				}
+			case err != nil:
+				return err
+			default:
+				updates["payment_id"] = payment.ID
+			}
+
+			updates["status"] = models.PaymentTransactionPaid
+			updates["paid_at"] = paidAt
+
+		case GatewayStatusExpired, GatewayStatusFailed:
+			if transaction.Status == models.PaymentTransactionPending {
+				updates["status"] = models.PaymentTransactionExpired
+				if notification.Status == GatewayStatusFailed {
+					updates["status"] = models.PaymentTransactionFailed
+				}
+				updates["failure_reason"] = truncate(notification.Reason, 255)
+			}
+		}
+
+		if err := tx.Model(&transaction).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", transaction.ID).First(&transaction).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if payment != nil {
+		log.Printf("✅ Posted online payment %s for invoice %s", transaction.ProviderReference, transaction.InvoiceID)
+		s.notifier.PaymentReceived(*payment, *invoice)
+	}
+
+	return &transaction, nil
+}
+
+// creditOnlinePayment keeps a settled payment of an invoice that was already paid as credit on
+// the customer
+func creditOnlinePayment(tx *gorm.DB, transaction models.PaymentTransaction, amount float64) error {
+	customer, err := lockCustomer(tx, transaction.TenantID, transaction.CustomerID)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Create(&models.CustomerLedgerEntry{
+		TenantID:    transaction.TenantID,
+		CustomerID:  customer.ID,
+		EntryDate:   time.Now(),
+		Type:        models.LedgerEntryPayment,
+		Credit:      roundMoney(amount),
+		InvoiceID:   &transaction.InvoiceID,
+		Description: fmt.Sprintf("Pembayaran online %s untuk tagihan yang sudah lunas, menjadi saldo kredit", transaction.ProviderReference),
+	}).Error; err != nil {
+		return err
+	}
+
+	return adjustCreditBalance(tx, customer, roundMoney(amount))
+}
+
+// onlinePaymentExpiry is how long an opened payment can be paid, PAYMENT_GATEWAY_EXPIRY_HOURS
+func onlinePaymentExpiry() time.Duration {
+	if hours, err := strconv.Atoi(os.Getenv("PAYMENT_GATEWAY_EXPIRY_HOURS")); err == nil && hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+func truncate(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	return text[:max]
+}
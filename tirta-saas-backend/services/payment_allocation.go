@@ -0,0 +1,263 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrNoOutstandingInvoices = errors.New("Pelanggan tidak memiliki tagihan yang belum lunas")
+
+// CustomerPaymentInput is one amount received from a customer to be allocated across the
+// customer's outstanding invoices
+type CustomerPaymentInput struct {
+	TenantID        uuid.UUID
+	CustomerID      uuid.UUID
+	Amount          float64
+	PaymentMethodID *uuid.UUID
+	ReferenceNumber string
+	Notes           string
+	ReceivedBy      *uuid.UUID
+}
+
+// invoiceAllocation is the part of a payment planned for one invoice
+type invoiceAllocation struct {
+	invoice   *models.Invoice
+	penalty   float64
+	principal float64
+}
+
+// PostCustomerPayment allocates one payment across the customer's outstanding invoices, oldest
+// billing month first, in the tenant's allocation order. Every invoice paid gets its own Payment and an
+// allocation line on the customer payment; money left over becomes credit.
+func (s *PaymentPostingService) PostCustomerPayment(input CustomerPaymentInput) (*models.CustomerPayment, error) {
+	if input.Amount <= 0 {
+		return nil, ErrPaymentAmountInvalid
+	}
+
+	order := tenantAllocationOrder(input.TenantID)
+	customerPayment := models.CustomerPayment{
+		TenantID:        input.TenantID,
+		CustomerID:      input.CustomerID,
+		Amount:          input.Amount,
+		AllocationOrder: order,
+		PaymentMethodID: input.PaymentMethodID,
+		ReceivedBy:      input.ReceivedBy,
+		ReferenceNumber: input.ReferenceNumber,
+		Notes:           input.Notes,
+	}
+
+	var payments []models.Payment
+	var invoices []models.Invoice
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		customer, err := lockCustomer(tx, input.TenantID, input.CustomerID)
+		if err != nil {
+			return err
+		}
+
+		open, err := lockOpenInvoices(tx, customer)
+		if err != nil {
+			return err
+		}
+		if err := validateCustomerPaymentAmount(input.Amount, open); err != nil {
+			return err
+		}
+
+		allocations, left := allocatePayment(open, input.Amount, order)
+		if len(allocations) == 0 {
+			return ErrNoOutstandingInvoices
+		}
+
+		customerPayment.AllocatedAmount = roundMoney(input.Amount - left)
+		customerPayment.CreditAmount = left
+		if err := tx.Create(&customerPayment).Error; err != nil {
+			return err
+		}
+
+		for i, allocation := range allocations {
+			payment := models.Payment{
+				TenantID:          input.TenantID,
+				InvoiceID:         allocation.invoice.ID,
+				Amount:            roundMoney(allocation.penalty + allocation.principal),
+				Penalty:           allocation.penalty,
+				PaymentMethodID:   input.PaymentMethodID,
+				ReceivedBy:        input.ReceivedBy,
+				ReferenceNumber:   input.ReferenceNumber,
+				Notes:             input.Notes,
+				Status:            models.PaymentRecordCompleted,
+				CustomerPaymentID: &customerPayment.ID,
+			}
+			if err := tx.Create(&payment).Error; err != nil {
+				return err
+			}
+			if err := recordPaymentEntry(tx, payment, customer.ID); err != nil {
+				return err
+			}
+
+			line := models.PaymentAllocation{
+				TenantID:          input.TenantID,
+				CustomerPaymentID: customerPayment.ID,
+				PaymentID:         payment.ID,
+				InvoiceID:         payment.InvoiceID,
+				PenaltyAmount:     allocation.penalty,
+				PrincipalAmount:   allocation.principal,
+				Amount:            payment.Amount,
+				Sequence:          i + 1,
+			}
+			if err := tx.Create(&line).Error; err != nil {
+				return err
+			}
+			customerPayment.Allocations = append(customerPayment.Allocations, line)
+
+			if err := applyInvoicePayments(tx, allocation.invoice); err != nil {
+				return err
+			}
+			payments = append(payments, payment)
+			invoices = append(invoices, *allocation.invoice)
+		}
+
+		if left > 0 {
+			if err := tx.Create(&models.CustomerLedgerEntry{
+				TenantID:    input.TenantID,
+				CustomerID:  customer.ID,
+				EntryDate:   customerPayment.PaidAt,
+				Type:        models.LedgerEntryPayment,
+				Credit:      left,
+				Description: fmt.Sprintf("Kelebihan pembayaran %.2f menjadi saldo kredit", left),
+			}).Error; err != nil {
+				return err
+			}
+			return adjustCreditBalance(tx, customer, left)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range payments {
+		s.notifier.PaymentReceived(payments[i], invoices[i])
+	}
+
+	return &customerPayment, nil
+}
+
+// lockOpenInvoices locks the unpaid invoices of a locked customer that are not void, oldest
+// billing month first, so a rebilled or late invoice for an older month is paid before newer ones
+func lockOpenInvoices(tx *gorm.DB, customer *models.Customer) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("tenant_id = ? AND customer_id = ? AND is_paid = ? AND status <> ?", customer.TenantID, customer.ID, false, models.InvoiceStatusVoid).
+		Order("usage_month ASC").Order("due_date ASC").Order("created_at ASC").Find(&invoices).Error
+	return invoices, err
+}
+
+// validateCustomerPaymentAmount checks a payment against what the customer owes. It may cover all
+// outstanding invoices at once; what is paid beyond that becomes credit, at most the limit of a
+// single payment. A customer who owes nothing cannot pay in advance through allocation.
+func validateCustomerPaymentAmount(amount float64, invoices []models.Invoice) error {
+	if amount <= 0 {
+		return ErrPaymentAmountInvalid
+	}
+
+	var outstanding float64
+	for _, invoice := range invoices {
+		outstanding += math.Max(0, invoice.TotalAmount-invoice.TotalPaid)
+	}
+	outstanding = roundMoney(outstanding)
+	if outstanding <= 0 {
+		return ErrNoOutstandingInvoices
+	}
+	if amount > outstanding+maxPaymentAmount {
+		return ErrPaymentAmountTooLarge
+	}
+	return nil
+}
+
+// allocatePayment plans how an amount pays the invoices, given oldest first. With PENALTY_FIRST
+// the outstanding penalties of all invoices are paid before any principal; otherwise each invoice
+// is paid in full, principal before penalty, before the next one. It returns the amount left over.
+func allocatePayment(invoices []models.Invoice, amount float64, order string) ([]invoiceAllocation, float64) {
+	planned := make([]invoiceAllocation, len(invoices))
+	left := roundMoney(amount)
+
+	take := func(due float64) float64 {
+		paid := roundMoney(math.Min(left, due))
+		if paid < 0 {
+			paid = 0
+		}
+		left = roundMoney(left - paid)
+		return paid
+	}
+
+	for i := range invoices {
+		planned[i].invoice = &invoices[i]
+	}
+
+	if order == models.AllocationPrincipalFirst {
+		for i := range planned {
+			penaltyDue, principalDue := outstandingSplit(*planned[i].invoice, order)
+			planned[i].principal = take(principalDue)
+			planned[i].penalty = take(penaltyDue)
+		}
+	} else {
+		for i := range planned {
+			penaltyDue, _ := outstandingSplit(*planned[i].invoice, order)
+			planned[i].penalty = take(penaltyDue)
+		}
+		for i := range planned {
+			_, principalDue := outstandingSplit(*planned[i].invoice, order)
+			planned[i].principal = take(principalDue)
+		}
+	}
+
+	allocations := make([]invoiceAllocation, 0, len(planned))
+	for _, allocation := range planned {
+		if allocation.penalty+allocation.principal > 0 {
+			allocations = append(allocations, allocation)
+		}
+	}
+	return allocations, left
+}
+
+// outstandingSplit returns the unpaid penalty and principal of an invoice. What was paid so far is
+// assumed to have gone to the penalty first under PENALTY_FIRST and to the principal first otherwise.
+func outstandingSplit(invoice models.Invoice, order string) (penalty, principal float64) {
+	principalTotal := invoice.TotalAmount - invoice.PenaltyAmount
+
+	var penaltyPaid float64
+	if order == models.AllocationPrincipalFirst {
+		penaltyPaid = math.Max(0, invoice.TotalPaid-principalTotal)
+	} else {
+		penaltyPaid = math.Min(invoice.PenaltyAmount, invoice.TotalPaid)
+	}
+
+	penalty = roundMoney(math.Max(0, invoice.PenaltyAmount-penaltyPaid))
+	principal = roundMoney(math.Max(0, principalTotal-(invoice.TotalPaid-penaltyPaid)))
+	return penalty, principal
+}
+
+// penaltyPortion returns how much of an amount paid on an invoice goes to its penalty
+func penaltyPortion(invoice models.Invoice, amount float64, order string) float64 {
+	penaltyDue, principalDue := outstandingSplit(invoice, order)
+	if order == models.AllocationPrincipalFirst {
+		return roundMoney(math.Min(penaltyDue, math.Max(0, amount-principalDue)))
+	}
+	return roundMoney(math.Min(penaltyDue, amount))
+}
+
+// tenantAllocationOrder returns the tenant's payment allocation order, PENALTY_FIRST by default
+func tenantAllocationOrder(tenantID uuid.UUID) string {
+	var settings models.TenantSettings
+	if err := config.DB.Select("payment_allocation_order").Where("tenant_id = ?", tenantID).
+		First(&settings).Error; err == nil && settings.PaymentAllocationOrder == models.AllocationPrincipalFirst {
+		return models.AllocationPrincipalFirst
+	}
+	return models.AllocationPenaltyFirst
+}
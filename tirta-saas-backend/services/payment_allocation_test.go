@@ -0,0 +1,127 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/adipras/tirta-saas-backend/models"
+)
+
+func TestAllocatePayment(t *testing.T) {
+	type split struct {
+		penalty   float64
+		principal float64
+	}
+
+	// Oldest first: INV-1 owes 100 principal and 10 penalty, INV-2 200 and 20
+	invoices := func(paid float64) []models.Invoice {
+		return []models.Invoice{
+			{InvoiceNumber: "INV-1", TotalAmount: 110, PenaltyAmount: 10, TotalPaid: paid},
+			{InvoiceNumber: "INV-2", TotalAmount: 220, PenaltyAmount: 20},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		paid   float64
+		amount float64
+		order  string
+		want   map[string]split
+		left   float64
+	}{
+		{
+			name:   "penalties of all invoices before principal",
+			amount: 50,
+			order:  models.AllocationPenaltyFirst,
+			want:   map[string]split{"INV-1": {10, 20}, "INV-2": {20, 0}},
+		},
+		{
+			name:   "each invoice in full before the next",
+			amount: 150,
+			order:  models.AllocationPrincipalFirst,
+			want:   map[string]split{"INV-1": {10, 100}, "INV-2": {0, 40}},
+		},
+		{
+			name:   "principal first stops before the penalty",
+			amount: 60,
+			order:  models.AllocationPrincipalFirst,
+			want:   map[string]split{"INV-1": {0, 60}},
+		},
+		{
+			name:   "overpayment is left over",
+			amount: 400,
+			order:  models.AllocationPenaltyFirst,
+			want:   map[string]split{"INV-1": {10, 100}, "INV-2": {20, 200}},
+			left:   70,
+		},
+		{
+			name:   "earlier payment went to the penalty first",
+			paid:   5,
+			amount: 105,
+			order:  models.AllocationPenaltyFirst,
+			want:   map[string]split{"INV-1": {5, 80}, "INV-2": {20, 0}},
+		},
+		{
+			name:   "earlier payment went to the principal first",
+			paid:   5,
+			amount: 105,
+			order:  models.AllocationPrincipalFirst,
+			want:   map[string]split{"INV-1": {10, 95}},
+		},
+		{
+			name:   "cents",
+			amount: 10.25,
+			order:  models.AllocationPenaltyFirst,
+			want:   map[string]split{"INV-1": {10, 0}, "INV-2": {0.25, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		allocations, left := allocatePayment(invoices(tt.paid), tt.amount, tt.order)
+		if left != tt.left {
+			t.Errorf("%s: left = %v; want %v", tt.name, left, tt.left)
+		}
+		if len(allocations) != len(tt.want) {
+			t.Errorf("%s: %d allocations; want %d", tt.name, len(allocations), len(tt.want))
+		}
+		for _, allocation := range allocations {
+			got := split{allocation.penalty, allocation.principal}
+			if want := tt.want[allocation.invoice.InvoiceNumber]; got != want {
+				t.Errorf("%s: %s = %+v; want %+v", tt.name, allocation.invoice.InvoiceNumber, got, want)
+			}
+		}
+	}
+
+	if allocations, left := allocatePayment(nil, 100, models.AllocationPenaltyFirst); len(allocations) != 0 || left != 100 {
+		t.Errorf("no invoices: %d allocations, left %v; want 0, 100", len(allocations), left)
+	}
+}
+
+func TestValidateCustomerPaymentAmount(t *testing.T) {
+	invoices := []models.Invoice{
+		{TotalAmount: 110, TotalPaid: 10},
+		{TotalAmount: 230},
+		{TotalAmount: 50, TotalPaid: 60},
+	}
+
+	tests := []struct {
+		name     string
+		amount   float64
+		invoices []models.Invoice
+		want     error
+	}{
+		{"zero", 0, invoices, ErrPaymentAmountInvalid},
+		{"negative", -1, invoices, ErrPaymentAmountInvalid},
+		{"exact outstanding", 330, invoices, nil},
+		{"more than one invoice at once", 1000000, invoices, nil},
+		{"outstanding plus the payment limit", 330 + maxPaymentAmount, invoices, nil},
+		{"beyond outstanding plus the payment limit", 330 + maxPaymentAmount + 0.01, invoices, ErrPaymentAmountTooLarge},
+		{"no invoices", 100, nil, ErrNoOutstandingInvoices},
+		{"only overpaid invoices", 100, []models.Invoice{{TotalAmount: 50, TotalPaid: 60}}, ErrNoOutstandingInvoices},
+	}
+
+	for _, tt := range tests {
+		if err := validateCustomerPaymentAmount(tt.amount, tt.invoices); err != tt.want {
+			t.Errorf("%s: err = %v; want %v", tt.name, err, tt.want)
+		}
+	}
+}
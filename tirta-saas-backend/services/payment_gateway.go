@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+)
+
+var (
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+	ErrGatewayReferenceUnknown = errors.New("payment reference is unknown to the gateway")
+	ErrGatewayChannelDisabled  = errors.New("payment channel is not supported by the gateway")
+)
+
+// GatewayStatus is the payment status reported by a gateway
+type GatewayStatus string
+
+const (
+	GatewayStatusPending GatewayStatus = "PENDING"
+	GatewayStatusSettled GatewayStatus = "SETTLED"
+	GatewayStatusExpired GatewayStatus = "EXPIRED"
+	GatewayStatusFailed  GatewayStatus = "FAILED"
+)
+
+// GatewayPaymentRequest asks a gateway to open a payment
+type GatewayPaymentRequest struct {
+	OrderID       string // our transaction ID, echoed back in webhooks
+	Amount        float64
+	Channel       string // models.GatewayChannel*
+	Description   string
+	CustomerName  string
+	CustomerEmail string
+	CustomerPhone string
+	ExpiresAt     time.Time
+}
+
+// GatewayPayment is the payment opened at the gateway with the instructions for the customer
+type GatewayPayment struct {
+	Reference            string
+	VirtualAccountNumber string
+	BankCode             string
+	QRISPayload          string
+	PaymentURL           string
+	ExpiresAt            *time.Time
+	Raw                  string
+}
+
+// GatewayNotification is the status of a payment reported by a webhook or a status query
+type GatewayNotification struct {
+	Reference string
+	OrderID   string
+	Status    GatewayStatus
+	Amount    float64
+	PaidAt    *time.Time
+	Reason    string
+	Raw       string
+}
+
+// PaymentGateway opens online payments and reports their settlement
+type PaymentGateway interface {
+	// Name is stored in PaymentTransaction.Provider and used in the webhook URL
+	Name() string
+	// CreatePayment opens a virtual account, QRIS code or checkout page for the request
+	CreatePayment(ctx context.Context, req GatewayPaymentRequest) (*GatewayPayment, error)
+	// VerifyWebhook checks the signature of a webhook call and parses it
+	VerifyWebhook(header http.Header, body []byte) (*GatewayNotification, error)
+	// QueryStatus asks the gateway for the current status of a payment
+	QueryStatus(ctx context.Context, reference string) (*GatewayNotification, error)
+}
+
+var (
+	gatewaysOnce sync.Once
+	gateways     map[string]PaymentGateway
+)
+
+// PaymentGatewaysFromEnv returns the configured gateways by name. A gateway is only registered
+// with its webhook secret; without one anybody could sign its webhooks. The simulated gateway
+// settles payments on request, so it is only available when explicitly enabled for development
+// and testing.
+//
+//	PAYMENT_GATEWAY_PROVIDER=simulated        gateway used for new payments
+//	PAYMENT_GATEWAY_SIMULATED_ENABLED=true    register the simulated gateway (never in production)
+//	PAYMENT_GATEWAY_WEBHOOK_SECRET            key the simulated gateway signs its webhooks with
+func PaymentGatewaysFromEnv() map[string]PaymentGateway {
+	gatewaysOnce.Do(func() {
+		gateways = map[string]PaymentGateway{}
+
+		if SimulatedGatewayEnabled() {
+			secret := os.Getenv("PAYMENT_GATEWAY_WEBHOOK_SECRET")
+			if secret == "" {
+				log.Printf("⚠️  Simulated payment gateway not registered: PAYMENT_GATEWAY_WEBHOOK_SECRET is empty")
+				return
+			}
+			simulated := NewSimulatedGateway(secret)
+			gateways[simulated.Name()] = simulated
+		}
+	})
+	return gateways
+}
+
+// SimulatedGatewayEnabled reports whether the simulated gateway and its simulate endpoint are
+// enabled, PAYMENT_GATEWAY_SIMULATED_ENABLED
+func SimulatedGatewayEnabled() bool {
+	return os.Getenv("PAYMENT_GATEWAY_SIMULATED_ENABLED") == "true"
+}
+
+// DefaultPaymentGateway returns the gateway new online payments are opened with
+func DefaultPaymentGateway() (PaymentGateway, error) {
+	name := os.Getenv("PAYMENT_GATEWAY_PROVIDER")
+	if name == "" {
+		return nil, errors.New("payment gateway is not configured, set PAYMENT_GATEWAY_PROVIDER")
+	}
+
+	gateway, ok := PaymentGatewaysFromEnv()[name]
+	if !ok {
+		return nil, fmt.Errorf("payment gateway %q is not configured", name)
+	}
+	return gateway, nil
+}
+
+// SimulatedGateway behaves like a real gateway without moving money. Payments stay pending until
+// they are settled, expired or failed with Simulate, which also produces the signed webhook the
+// real provider would send.
+type SimulatedGateway struct {
+	secret string
+
+	mu       sync.Mutex
+	payments map[string]*GatewayNotification
+}
+
+// NewSimulatedGateway creates a simulated gateway signing its webhooks with secret
+func NewSimulatedGateway(secret string) *SimulatedGateway {
+	return &SimulatedGateway{
+		secret:   secret,
+		payments: make(map[string]*GatewayNotification),
+	}
+}
+
+// simulatedSignatureHeader carries the hex HMAC-SHA256 of the webhook body
+const simulatedSignatureHeader = "X-Callback-Signature"
+
+// simulatedWebhook is the JSON body of a simulated webhook call
+type simulatedWebhook struct {
+	Reference string     `json:"reference"`
+	OrderID   string     `json:"order_id"`
+	Status    string     `json:"status"`
+	Amount    float64    `json:"amount"`
+	PaidAt    *time.Time `json:"paid_at,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+}
+
+func (g *SimulatedGateway) Name() string { return "simulated" }
+
+func (g *SimulatedGateway) CreatePayment(ctx context.Context, req GatewayPaymentRequest) (*GatewayPayment, error) {
+	reference := "SIM-" + strings.ToUpper(randomHex(8))
+	expiresAt := req.ExpiresAt
+
+	payment := &GatewayPayment{
+		Reference: reference,
+		ExpiresAt: &expiresAt,
+	}
+
+	switch req.Channel {
+	case models.GatewayChannelVirtualAccount:
+		payment.BankCode = "SIMBANK"
+		payment.VirtualAccountNumber = "8808" + randomDigits(12)
+	case models.GatewayChannelQRIS:
+		payment.QRISPayload = simulatedQRISPayload(reference, req.Amount)
+	case models.GatewayChannelEWallet, models.GatewayChannelCard:
+		payment.PaymentURL = "https://simulator.local/pay/" + reference
+	default:
+		return nil, ErrGatewayChannelDisabled
+	}
+
+	g.mu.Lock()
+	g.payments[reference] = &GatewayNotification{
+		Reference: reference,
+		OrderID:   req.OrderID,
+		Status:    GatewayStatusPending,
+		Amount:    req.Amount,
+	}
+	g.mu.Unlock()
+
+	raw, _ := json.Marshal(payment)
+	payment.Raw = string(raw)
+	return payment, nil
+}
+
+func (g *SimulatedGateway) VerifyWebhook(header http.Header, body []byte) (*GatewayNotification, error) {
+	signature, err := hex.DecodeString(header.Get(simulatedSignatureHeader))
+	if err != nil || !hmac.Equal(signature, g.sign(body)) {
+		return nil, ErrInvalidWebhookSignature
+	}
+
+	var webhook simulatedWebhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return nil, fmt.Errorf("invalid webhook body: %w", err)
+	}
+
+	return &GatewayNotification{
+		Reference: webhook.Reference,
+		OrderID:   webhook.OrderID,
+		Status:    GatewayStatus(webhook.Status),
+		Amount:    webhook.Amount,
+		PaidAt:    webhook.PaidAt,
+		Reason:    webhook.Reason,
+		Raw:       string(body),
+	}, nil
+}
+
+func (g *SimulatedGateway) QueryStatus(ctx context.Context, reference string) (*GatewayNotification, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	payment, ok := g.payments[reference]
+	if !ok {
+		return nil, ErrGatewayReferenceUnknown
+	}
+	status := *payment
+	return &status, nil
+}
+
+// Simulate moves a payment to the given status, as if the customer paid or the payment expired,
+// and returns the signed webhook call the gateway would send. Payments opened before a restart are
+// known again from the transaction details.
+func (g *SimulatedGateway) Simulate(reference, orderID string, amount float64, status GatewayStatus) (http.Header, []byte, error) {
+	g.mu.Lock()
+	payment, ok := g.payments[reference]
+	if !ok {
+		payment = &GatewayNotification{Reference: reference, OrderID: orderID, Amount: amount}
+		g.payments[reference] = payment
+	}
+
+	payment.Status = status
+	if status == GatewayStatusSettled {
+		now := time.Now()
+		payment.PaidAt = &now
+	}
+	webhook := simulatedWebhook{
+		Reference: payment.Reference,
+		OrderID:   payment.OrderID,
+		Status:    string(payment.Status),
+		Amount:    payment.Amount,
+		PaidAt:    payment.PaidAt,
+	}
+	g.mu.Unlock()
+
+	body, err := json.Marshal(webhook)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(simulatedSignatureHeader, hex.EncodeToString(g.sign(body)))
+	return header, body, nil
+}
+
+func (g *SimulatedGateway) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(g.secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// simulatedQRISPayload builds a QRIS-like EMV string; it is not scannable by real banking apps
+func simulatedQRISPayload(reference string, amount float64) string {
+	amountText := fmt.Sprintf("%.0f", amount)
+	return fmt.Sprintf("00020101021226%02dID.SIMULATOR.%s530336054%02d%s5802ID5909TIRTASAAS6304SIMQ",
+		len("ID.SIMULATOR.")+len(reference), reference, len(amountText), amountText)
+}
+
+func randomHex(bytes int) string {
+	buf := make([]byte, bytes)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+func randomDigits(n int) string {
+	var digits strings.Builder
+	for i := 0; i < n; i++ {
+		d, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			digits.WriteByte('0')
+			continue
+		}
+		digits.WriteString(d.String())
+	}
+	return digits.String()
+}
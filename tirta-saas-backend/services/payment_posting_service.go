@@ -0,0 +1,379 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Largest amount accepted for a single payment
+const maxPaymentAmount = 999999
+
+var (
+	ErrPaymentInvoiceNotFound = errors.New("Invoice tidak ditemukan")
+	ErrPaymentNotFound        = errors.New("Pembayaran tidak ditemukan")
+	ErrInvoiceAlreadyPaid     = errors.New("Tagihan sudah lunas")
+	ErrPaymentAmountInvalid   = errors.New("Payment amount must be greater than zero")
+	ErrPaymentAmountTooLarge  = errors.New("Payment amount exceeds maximum allowed limit")
+	ErrPaymentCreditUsed      = errors.New("Kelebihan pembayaran ini sudah dipakai dari saldo kredit pelanggan")
+	ErrCreditPaymentReadOnly  = errors.New("Pembayaran dari saldo kredit tidak dapat diubah")
+	ErrAllocatedPaymentLocked = errors.New("Pembayaran hasil alokasi tidak dapat diubah, hapus lalu catat ulang")
+)
+
+// IsPaymentNotFound reports whether a posting error means the payment, its invoice or its
+// customer does not exist
+func IsPaymentNotFound(err error) bool {
+	return errors.Is(err, ErrPaymentNotFound) || errors.Is(err, ErrPaymentInvoiceNotFound) ||
+		errors.Is(err, ErrLedgerCustomerNotFound)
+}
+
+// PostPaymentInput is a payment to be recorded against an invoice
+type PostPaymentInput struct {
+	TenantID   uuid.UUID
+	InvoiceID  uuid.UUID
+	CustomerID *uuid.UUID // when set, the invoice must belong to this customer
+	Amount     float64
+	Notes      string
+
+	PaymentMethodID *uuid.UUID
+	ReferenceNumber string
+	ReceivedBy      *uuid.UUID
+}
+
+// PaymentPostingService records payments and keeps the paid totals and payment status of their
+// invoices and the customer ledger in sync. Every change runs in one transaction that locks the
+// customer and invoice rows, so concurrent payments of a customer are applied one after another.
+type PaymentPostingService struct {
+	notifier *BillingNotifier
+}
+
+// NewPaymentPostingService creates new payment posting service
+func NewPaymentPostingService() *PaymentPostingService {
+	return &PaymentPostingService{
+		notifier: NewBillingNotifier(),
+	}
+}
+
+// PostPayment records a payment and updates its invoice. Money beyond the remaining amount of the
+// invoice becomes credit on the customer. A registration invoice that becomes paid activates the
+// customer.
+func (s *PaymentPostingService) PostPayment(input PostPaymentInput) (*models.Payment, *models.Invoice, error) {
+	if err := validatePaymentAmount(input.Amount); err != nil {
+		return nil, nil, err
+	}
+
+	var payment *models.Payment
+	var invoice *models.Invoice
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		payment, invoice, err = postPayment(tx, input)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.notifier.PaymentReceived(*payment, *invoice)
+
+	return payment, invoice, nil
+}
+
+// postPayment records a payment within the caller's transaction
+func postPayment(tx *gorm.DB, input PostPaymentInput) (*models.Payment, *models.Invoice, error) {
+	var invoice models.Invoice
+	query := tx.Where("id = ? AND tenant_id = ?", input.InvoiceID, input.TenantID)
+	if input.CustomerID != nil {
+		query = query.Where("customer_id = ?", *input.CustomerID)
+	}
+	if err := query.First(&invoice).Error; err != nil {
+		return nil, nil, ErrPaymentInvoiceNotFound
+	}
+
+	customer, locked, err := lockInvoice(tx, invoice)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if locked.Status == models.InvoiceStatusVoid {
+		return nil, nil, ErrInvoiceVoided
+	}
+	if locked.IsPaid {
+		return nil, nil, ErrInvoiceAlreadyPaid
+	}
+
+	applied := math.Min(input.Amount, roundMoney(locked.TotalAmount-locked.TotalPaid))
+	payment := models.Payment{
+		TenantID:        input.TenantID,
+		InvoiceID:       locked.ID,
+		Amount:          applied,
+		Penalty:         penaltyPortion(*locked, applied, tenantAllocationOrder(input.TenantID)),
+		CreditAmount:    roundMoney(input.Amount - applied),
+		PaymentMethodID: input.PaymentMethodID,
+		ReceivedBy:      input.ReceivedBy,
+		ReferenceNumber: input.ReferenceNumber,
+		Notes:           input.Notes,
+		Status:          models.PaymentRecordCompleted,
+	}
+	if err := tx.Create(&payment).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := recordPaymentEntry(tx, payment, customer.ID); err != nil {
+		return nil, nil, err
+	}
+
+	if payment.CreditAmount > 0 {
+		if err := adjustCreditBalance(tx, customer, payment.CreditAmount); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := applyInvoicePayments(tx, locked); err != nil {
+		return nil, nil, err
+	}
+	return &payment, locked, nil
+}
+
+// UpdatePaymentAmount changes the amount of a recorded payment and updates its invoice. The
+// ledger gets a reversal of the old amount and an entry for the new one.
+func (s *PaymentPostingService) UpdatePaymentAmount(tenantID, paymentID uuid.UUID, amount float64) (*models.Payment, error) {
+	if err := validatePaymentAmount(amount); err != nil {
+		return nil, err
+	}
+
+	var payment models.Payment
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		customer, invoice, err := lockPaymentInvoice(tx, tenantID, paymentID, &payment)
+		if err != nil {
+			return err
+		}
+		if payment.FromCredit {
+			return ErrCreditPaymentReadOnly
+		}
+		if payment.CustomerPaymentID != nil {
+			return ErrAllocatedPaymentLocked
+		}
+		if payment.Status != models.PaymentRecordCompleted {
+			return ErrPaymentNotCompleted
+		}
+
+		paidByOthers := invoice.TotalPaid - payment.Amount
+		applied := math.Min(amount, roundMoney(invoice.TotalAmount-paidByOthers))
+		creditAmount := roundMoney(amount - applied)
+
+		others := *invoice
+		others.TotalPaid = paidByOthers
+		penalty := penaltyPortion(others, applied, tenantAllocationOrder(tenantID))
+
+		if err := adjustCreditBalance(tx, customer, creditAmount-payment.CreditAmount); err != nil {
+			return err
+		}
+		if err := recordPaymentReversal(tx, payment, customer.ID, "Koreksi pembayaran"); err != nil {
+			return err
+		}
+
+		payment.Amount = applied
+		payment.Penalty = penalty
+		payment.CreditAmount = creditAmount
+		if err := tx.Model(&payment).Updates(map[string]interface{}{
+			"amount":        payment.Amount,
+			"penalty":       payment.Penalty,
+			"credit_amount": payment.CreditAmount,
+		}).Error; err != nil {
+			return err
+		}
+		if err := recordPaymentEntry(tx, payment, customer.ID); err != nil {
+			return err
+		}
+
+		return applyInvoicePayments(tx, invoice)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+// DeletePayment removes a recorded payment and updates its invoice. A payment made from credit
+// returns the amount to the customer's credit balance. A registration invoice that is no longer
+// paid deactivates the customer again.
+func (s *PaymentPostingService) DeletePayment(tenantID, paymentID uuid.UUID) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var payment models.Payment
+		customer, invoice, err := lockPaymentInvoice(tx, tenantID, paymentID, &payment)
+		if err != nil {
+			return err
+		}
+
+		// Pending and rejected transfers never reached the invoice or the ledger
+		if payment.Status != models.PaymentRecordCompleted {
+			return tx.Delete(&payment).Error
+		}
+
+		if payment.FromCredit {
+			if err := adjustCreditBalance(tx, customer, payment.Amount); err != nil {
+				return err
+			}
+		} else {
+			if err := adjustCreditBalance(tx, customer, -payment.CreditAmount); err != nil {
+				return err
+			}
+			if err := recordPaymentReversal(tx, payment, customer.ID, "Pembayaran dibatalkan"); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Delete(&payment).Error; err != nil {
+			return err
+		}
+
+		// The customer payment it was allocated from no longer covers this invoice
+		if payment.CustomerPaymentID != nil {
+			if err := tx.Where("payment_id = ?", payment.ID).Delete(&models.PaymentAllocation{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.CustomerPayment{}).Where("id = ?", *payment.CustomerPaymentID).
+				Update("allocated_amount", gorm.Expr("allocated_amount - ?", payment.Amount)).Error; err != nil {
+				return err
+			}
+		}
+
+		return applyInvoicePayments(tx, invoice)
+	})
+}
+
+// lockPaymentInvoice loads a payment and locks its customer and invoice for the rest of the
+// transaction. The payment is read again after the lock so it reflects changes committed while
+// waiting for it.
+func lockPaymentInvoice(tx *gorm.DB, tenantID, paymentID uuid.UUID, payment *models.Payment) (*models.Customer, *models.Invoice, error) {
+	if err := tx.Where("id = ? AND tenant_id = ?", paymentID, tenantID).First(payment).Error; err != nil {
+		return nil, nil, ErrPaymentNotFound
+	}
+
+	var invoice models.Invoice
+	if err := tx.Where("id = ? AND tenant_id = ?", payment.InvoiceID, tenantID).First(&invoice).Error; err != nil {
+		return nil, nil, ErrPaymentInvoiceNotFound
+	}
+
+	customer, locked, err := lockInvoice(tx, invoice)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Where("id = ? AND tenant_id = ?", paymentID, tenantID).First(payment).Error; err != nil {
+		return nil, nil, ErrPaymentNotFound
+	}
+
+	return customer, locked, nil
+}
+
+// lockInvoice locks the customer of an invoice and then the invoice itself, and returns both as
+// stored after the locks were taken
+func lockInvoice(tx *gorm.DB, invoice models.Invoice) (*models.Customer, *models.Invoice, error) {
+	customer, err := lockCustomer(tx, invoice.TenantID, invoice.CustomerID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var locked models.Invoice
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND tenant_id = ?", invoice.ID, invoice.TenantID).First(&locked).Error; err != nil {
+		return nil, nil, ErrPaymentInvoiceNotFound
+	}
+
+	return customer, &locked, nil
+}
+
+// adjustCreditBalance changes the credit balance of a locked customer. Credit that was already
+// used for other invoices cannot be taken back.
+func adjustCreditBalance(tx *gorm.DB, customer *models.Customer, delta float64) error {
+	if delta == 0 {
+		return nil
+	}
+
+	balance := roundMoney(customer.CreditBalance + delta)
+	if balance < 0 {
+		return ErrPaymentCreditUsed
+	}
+
+	customer.CreditBalance = balance
+	return tx.Model(customer).Update("credit_balance", balance).Error
+}
+
+// applyInvoicePayments recomputes the paid total of a locked invoice from its completed payments
+// and stores it together with the matching payment status
+func applyInvoicePayments(tx *gorm.DB, invoice *models.Invoice) error {
+	var totalPaid float64
+	if err := tx.Model(&models.Payment{}).
+		Where("invoice_id = ? AND status = ?", invoice.ID, models.PaymentRecordCompleted).
+		Select("COALESCE(SUM(amount), 0)").Scan(&totalPaid).Error; err != nil {
+		return err
+	}
+
+	wasPaid := invoice.IsPaid
+	invoice.TotalPaid = totalPaid
+	invoice.IsPaid = totalPaid >= invoice.TotalAmount
+	invoice.PaymentStatus = invoicePaymentStatus(*invoice, time.Now())
+
+	switch {
+	case invoice.IsPaid && !wasPaid:
+		now := time.Now()
+		invoice.PaidDate = &now
+	case !invoice.IsPaid:
+		invoice.PaidDate = nil
+	}
+
+	if err := tx.Model(&models.Invoice{}).Where("id = ?", invoice.ID).Updates(map[string]interface{}{
+		"total_paid":     invoice.TotalPaid,
+		"is_paid":        invoice.IsPaid,
+		"payment_status": invoice.PaymentStatus,
+		"paid_date":      invoice.PaidDate,
+	}).Error; err != nil {
+		return err
+	}
+
+	// Customer baru aktif setelah tagihan pendaftaran lunas
+	if invoice.Type == "registration" && invoice.IsPaid != wasPaid {
+		if err := tx.Model(&models.Customer{}).
+			Where("id = ? AND tenant_id = ?", invoice.CustomerID, invoice.TenantID).
+			Update("is_active", invoice.IsPaid).Error; err != nil {
+			return err
+		}
+		log.Printf("👤 Customer %s active=%t after registration invoice %s payment change", invoice.CustomerID, invoice.IsPaid, invoice.ID)
+	}
+
+	return nil
+}
+
+// invoicePaymentStatus derives the payment status of an invoice from its paid total. Unpaid
+// invoices past their due date are overdue, matching the overdue scheduler.
+func invoicePaymentStatus(invoice models.Invoice, now time.Time) models.PaymentStatus {
+	switch {
+	case invoice.Status == models.InvoiceStatusVoid:
+		return models.PaymentStatusVoid
+	case invoice.TotalPaid >= invoice.TotalAmount:
+		return models.PaymentStatusPaid
+	case invoice.TotalPaid > 0:
+		return models.PaymentStatusPartial
+	case invoice.DueDate != nil && invoice.DueDate.Before(now):
+		return models.PaymentStatusOverdue
+	}
+	return models.PaymentStatusUnpaid
+}
+
+func validatePaymentAmount(amount float64) error {
+	if amount <= 0 {
+		return ErrPaymentAmountInvalid
+	}
+	if amount > maxPaymentAmount {
+		return ErrPaymentAmountTooLarge
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPaymentNotPending   = errors.New("Pembayaran sudah diverifikasi atau ditolak")
+	ErrPaymentNotCompleted = errors.New("Pembayaran yang belum diverifikasi tidak dapat diubah")
+)
+
+// TransferProofInput is a bank transfer the customer reports with a proof of transfer
+type TransferProofInput struct {
+	TenantID        uuid.UUID
+	CustomerID      uuid.UUID
+	InvoiceID       uuid.UUID
+	Amount          float64
+	PaymentMethodID *uuid.UUID
+	ReferenceNumber string
+	Notes           string
+	ProofImageURL   string
+}
+
+// SubmitTransferProof records a transfer reported by the customer as a pending payment. It does
+// not count towards the invoice until finance approves it.
+func (s *PaymentPostingService) SubmitTransferProof(input TransferProofInput) (*models.Payment, error) {
+	if err := validatePaymentAmount(input.Amount); err != nil {
+		return nil, err
+	}
+
+	var invoice models.Invoice
+	if err := config.DB.Where("id = ? AND tenant_id = ? AND customer_id = ?", input.InvoiceID, input.TenantID, input.CustomerID).
+		First(&invoice).Error; err != nil {
+		return nil, ErrPaymentInvoiceNotFound
+	}
+	if invoice.Status == models.InvoiceStatusVoid {
+		return nil, ErrInvoiceVoided
+	}
+	if invoice.IsPaid {
+		return nil, ErrInvoiceAlreadyPaid
+	}
+
+	payment := models.Payment{
+		TenantID:        input.TenantID,
+		InvoiceID:       invoice.ID,
+		Amount:          roundMoney(input.Amount),
+		PaymentMethodID: input.PaymentMethodID,
+		ReferenceNumber: input.ReferenceNumber,
+		ProofImageURL:   input.ProofImageURL,
+		Notes:           input.Notes,
+		Status:          models.PaymentRecordPending,
+	}
+	if err := config.DB.Create(&payment).Error; err != nil {
+		return nil, err
+	}
+
+	log.Printf("🧾 Transfer proof of %.2f submitted for invoice %s, waiting for verification", payment.Amount, invoice.ID)
+	return &payment, nil
+}
+
+// ApprovePayment completes a pending transfer after finance checked the proof. The reported amount
+// is applied to the invoice like a payment recorded at the counter: money beyond what is still due,
+// or everything when the invoice was paid or voided in the meantime, becomes credit on the customer.
+func (s *PaymentPostingService) ApprovePayment(tenantID, paymentID, verifierID uuid.UUID, notes string) (*models.Payment, *models.Invoice, error) {
+	var payment *models.Payment
+	var invoice *models.Invoice
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		payment, invoice, err = approvePayment(tx, tenantID, paymentID, verifierID, notes)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.notifier.PaymentReceived(*payment, *invoice)
+
+	return payment, invoice, nil
+}
+
+// approvePayment completes a pending transfer within the caller's transaction
+func approvePayment(tx *gorm.DB, tenantID, paymentID, verifierID uuid.UUID, notes string) (*models.Payment, *models.Invoice, error) {
+	var payment models.Payment
+	customer, invoice, err := lockPaymentInvoice(tx, tenantID, paymentID, &payment)
+	if err != nil {
+		return nil, nil, err
+	}
+	if payment.Status != models.PaymentRecordPending {
+		return nil, nil, ErrPaymentNotPending
+	}
+
+	received := payment.Amount
+	applied := math.Max(0, math.Min(received, roundMoney(invoice.TotalAmount-invoice.TotalPaid)))
+	if invoice.Status == models.InvoiceStatusVoid {
+		applied = 0
+	}
+	now := time.Now()
+
+	payment.Amount = roundMoney(applied)
+	payment.Penalty = penaltyPortion(*invoice, payment.Amount, tenantAllocationOrder(tenantID))
+	payment.CreditAmount = roundMoney(received - payment.Amount)
+	payment.Status = models.PaymentRecordCompleted
+	payment.VerifiedBy = &verifierID
+	payment.VerifiedAt = &now
+
+	updates := map[string]interface{}{
+		"amount":        payment.Amount,
+		"penalty":       payment.Penalty,
+		"credit_amount": payment.CreditAmount,
+		"status":        payment.Status,
+		"verified_by":   verifierID,
+		"verified_at":   now,
+		"received_by":   verifierID,
+	}
+	if notes != "" {
+		payment.Notes = notes
+		updates["notes"] = notes
+	}
+	if err := tx.Model(&payment).Updates(updates).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if err := recordPaymentEntry(tx, payment, customer.ID); err != nil {
+		return nil, nil, err
+	}
+	if err := adjustCreditBalance(tx, customer, payment.CreditAmount); err != nil {
+		return nil, nil, err
+	}
+
+	// A void invoice is not marked paid, and a void registration invoice must not activate the
+	// customer
+	if invoice.Status == models.InvoiceStatusVoid {
+		return &payment, invoice, nil
+	}
+	if err := applyInvoicePayments(tx, invoice); err != nil {
+		return nil, nil, err
+	}
+	return &payment, invoice, nil
+}
+
+// RejectPayment rejects a pending transfer and tells the customer why. The invoice is unchanged.
+func (s *PaymentPostingService) RejectPayment(tenantID, paymentID, verifierID uuid.UUID, reason string) (*models.Payment, error) {
+	var payment models.Payment
+	var invoice *models.Invoice
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		_, invoice, err = lockPaymentInvoice(tx, tenantID, paymentID, &payment)
+		if err != nil {
+			return err
+		}
+		if payment.Status != models.PaymentRecordPending {
+			return ErrPaymentNotPending
+		}
+
+		now := time.Now()
+		payment.Status = models.PaymentRecordRejected
+		payment.RejectionReason = reason
+		payment.VerifiedBy = &verifierID
+		payment.VerifiedAt = &now
+
+		return tx.Model(&payment).Updates(map[string]interface{}{
+			"status":           payment.Status,
+			"rejection_reason": reason,
+			"verified_by":      verifierID,
+			"verified_at":      now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifier.PaymentRejected(payment, *invoice)
+
+	return &payment, nil
+}
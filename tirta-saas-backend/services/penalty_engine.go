@@ -0,0 +1,230 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PenaltyEngine computes the late penalties of a customer's overdue invoices. Penalties are
+// charged on the unpaid principal only, so earlier penalties never compound, and each invoice
+// keeps to its cap over all penalties charged for it.
+type PenaltyEngine struct{}
+
+// NewPenaltyEngine creates new penalty engine
+func NewPenaltyEngine() *PenaltyEngine {
+	return &PenaltyEngine{}
+}
+
+// penaltyHistory is what was already charged for an overdue invoice
+type penaltyHistory struct {
+	total     float64
+	lastEnd   *time.Time
+	lineCount int
+}
+
+// Assess returns the penalties not yet charged for the customer's invoices that are overdue
+// beyond the grace period on asOf. The lines are not saved; RecordPenalties bills them on an
+// invoice. Customers whose subscription type has a LateFeePerDay are charged per day whatever the
+// tenant's policy, every overdue day after the last charged period; PERCENT and FIXED are charged
+// once per invoice. The subscription type's MaxLateFee and the tenant's cap limit the penalty
+// under every policy. Invoices in an active installment plan are skipped.
+func (e *PenaltyEngine) Assess(tenantID, customerID uuid.UUID, settings models.TenantSettings, subType models.SubscriptionType, asOf time.Time) ([]models.InvoicePenalty, error) {
+	today := truncateToDay(asOf)
+	firstDayAfterGrace := func(due time.Time) time.Time {
+		return truncateToDay(due).AddDate(0, 0, settings.GracePeriodDays+1)
+	}
+
+	var invoices []models.Invoice
+	if err := config.DB.Where("tenant_id = ? AND customer_id = ? AND is_paid = ? AND status <> ? AND due_date IS NOT NULL AND due_date < ?",
+		tenantID, customerID, false, models.InvoiceStatusVoid, today.AddDate(0, 0, -settings.GracePeriodDays)).
+		Order("due_date ASC").Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	if len(invoices) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, len(invoices))
+	for i, invoice := range invoices {
+		ids[i] = invoice.ID
+	}
+	var charged []models.InvoicePenalty
+	if err := config.DB.Where("invoice_id IN ?", ids).Order("period_end ASC").Find(&charged).Error; err != nil {
+		return nil, err
+	}
+	history := make(map[uuid.UUID]*penaltyHistory)
+	for _, line := range charged {
+		h, ok := history[line.InvoiceID]
+		if !ok {
+			h = &penaltyHistory{}
+			history[line.InvoiceID] = h
+		}
+		end := line.PeriodEnd
+		h.total += line.Amount
+		h.lastEnd = &end
+		h.lineCount++
+	}
+
+	// Penalties are suspended while an invoice is in an active installment plan and resume the
+	// day after the plan ended without being paid off
+	var planIDs []uuid.UUID
+	for _, invoice := range invoices {
+		if invoice.InstallmentPlanID != nil {
+			planIDs = append(planIDs, *invoice.InstallmentPlanID)
+		}
+	}
+	plans := make(map[uuid.UUID]models.InstallmentPlan)
+	if len(planIDs) > 0 {
+		var found []models.InstallmentPlan
+		if err := config.DB.Where("id IN ?", planIDs).Find(&found).Error; err != nil {
+			return nil, err
+		}
+		for _, plan := range found {
+			plans[plan.ID] = plan
+		}
+	}
+
+	policy := penaltyPolicy(settings, subType)
+	order := tenantAllocationOrder(tenantID)
+
+	var penalties []models.InvoicePenalty
+	for _, invoice := range invoices {
+		start := firstDayAfterGrace(*invoice.DueDate)
+		if invoice.InstallmentPlanID != nil {
+			if plan, ok := plans[*invoice.InstallmentPlanID]; ok {
+				if plan.Status == models.InstallmentPlanActive || plan.EndedAt == nil {
+					continue
+				}
+				if resume := truncateToDay(*plan.EndedAt).AddDate(0, 0, 1); resume.After(start) {
+					start = resume
+				}
+			}
+		}
+		if start.After(today) {
+			continue
+		}
+
+		// Penalties billed on this invoice are not penalized again
+		_, principal := outstandingSplit(invoice, order)
+		if principal <= 0 {
+			continue
+		}
+
+		h := history[invoice.ID]
+		if h == nil {
+			h = &penaltyHistory{}
+		}
+
+		line, ok := invoicePenalty(models.InvoicePenalty{
+			TenantID:    tenantID,
+			CustomerID:  customerID,
+			InvoiceID:   invoice.ID,
+			Policy:      policy,
+			PeriodStart: start,
+			PeriodEnd:   today,
+			Base:        principal,
+		}, *h, settings, subType)
+		if !ok {
+			continue
+		}
+
+		penalties = append(penalties, line)
+	}
+
+	return penalties, nil
+}
+
+// penaltyPolicy is the policy a customer is penalized with: PER_DAY when the subscription type has
+// a per-day late fee, otherwise the tenant's policy, PERCENT by default
+func penaltyPolicy(settings models.TenantSettings, subType models.SubscriptionType) string {
+	if subType.LateFeePerDay > 0 {
+		return models.PenaltyPolicyPerDay
+	}
+	if settings.LatePenaltyPolicy == "" {
+		return models.PenaltyPolicyPercent
+	}
+	return settings.LatePenaltyPolicy
+}
+
+// penaltyCap is the most an invoice may be penalized in total: the lower of the tenant's cap and
+// the subscription type's MaxLateFee, zero when neither is set
+func penaltyCap(settings models.TenantSettings, subType models.SubscriptionType) float64 {
+	maxCap := settings.LatePenaltyMaxCap
+	if subType.MaxLateFee > 0 && (maxCap <= 0 || subType.MaxLateFee < maxCap) {
+		maxCap = subType.MaxLateFee
+	}
+	return maxCap
+}
+
+// invoicePenalty prices the penalty of an overdue invoice for the period of line, from its first
+// day after the grace period to today, given what was charged for the invoice before. It reports
+// false when nothing is to be charged.
+func invoicePenalty(line models.InvoicePenalty, h penaltyHistory, settings models.TenantSettings, subType models.SubscriptionType) (models.InvoicePenalty, bool) {
+	start := line.PeriodStart
+	switch line.Policy {
+	case models.PenaltyPolicyPerDay:
+		if h.lastEnd != nil {
+			if next := truncateToDay(*h.lastEnd).AddDate(0, 0, 1); next.After(line.PeriodStart) {
+				line.PeriodStart = next
+			}
+		}
+		if line.PeriodStart.After(line.PeriodEnd) {
+			return line, false
+		}
+		line.Days = daysBetween(line.PeriodStart, line.PeriodEnd) + 1
+		line.Rate = subType.LateFeePerDay
+		line.Amount = float64(line.Days) * subType.LateFeePerDay
+	case models.PenaltyPolicyFixed:
+		if h.lineCount > 0 {
+			return line, false
+		}
+		line.Days = daysBetween(start, line.PeriodEnd) + 1
+		line.Rate = settings.LatePenaltyFixedAmount
+		line.Amount = settings.LatePenaltyFixedAmount
+	default:
+		if h.lineCount > 0 {
+			return line, false
+		}
+		line.Days = daysBetween(start, line.PeriodEnd) + 1
+		line.Rate = settings.LatePenaltyPercent
+		line.Amount = line.Base * settings.LatePenaltyPercent / 100.0
+	}
+
+	if maxCap := penaltyCap(settings, subType); maxCap > 0 && h.total+line.Amount > maxCap {
+		line.Amount = math.Max(0, maxCap-h.total)
+		line.Capped = true
+	}
+	line.Amount = roundMoney(line.Amount)
+	return line, line.Amount > 0
+}
+
+// PenaltyTotal returns the sum of penalty lines
+func PenaltyTotal(penalties []models.InvoicePenalty) float64 {
+	var total float64
+	for _, penalty := range penalties {
+		total += penalty.Amount
+	}
+	return roundMoney(total)
+}
+
+// RecordPenalties stores assessed penalties as billed on an invoice. The unique period of each
+// overdue invoice makes a second charge of the same days fail instead of billing them twice.
+func RecordPenalties(tx *gorm.DB, invoice models.Invoice, penalties []models.InvoicePenalty) error {
+	if len(penalties) == 0 {
+		return nil
+	}
+
+	for i := range penalties {
+		penalties[i].ChargedInvoiceID = &invoice.ID
+	}
+	return tx.Create(&penalties).Error
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+)
+
+func TestPenaltyPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		perDay float64
+		want   string
+	}{
+		{"tenant default", "", 0, models.PenaltyPolicyPercent},
+		{"tenant fixed", models.PenaltyPolicyFixed, 0, models.PenaltyPolicyFixed},
+		{"per-day fee of the subscription type", models.PenaltyPolicyPercent, 1000, models.PenaltyPolicyPerDay},
+		{"per-day tenant without per-day fee", models.PenaltyPolicyPerDay, 0, models.PenaltyPolicyPerDay},
+	}
+
+	for _, tt := range tests {
+		got := penaltyPolicy(models.TenantSettings{LatePenaltyPolicy: tt.policy}, models.SubscriptionType{LateFeePerDay: tt.perDay})
+		if got != tt.want {
+			t.Errorf("%s: penaltyPolicy = %s; want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPenaltyCap(t *testing.T) {
+	tests := []struct {
+		tenantCap  float64
+		maxLateFee float64
+		want       float64
+	}{
+		{0, 0, 0},
+		{50000, 0, 50000},
+		{0, 20000, 20000},
+		{50000, 20000, 20000},
+		{10000, 20000, 10000},
+	}
+
+	for _, tt := range tests {
+		got := penaltyCap(models.TenantSettings{LatePenaltyMaxCap: tt.tenantCap}, models.SubscriptionType{MaxLateFee: tt.maxLateFee})
+		if got != tt.want {
+			t.Errorf("penaltyCap(%v, %v) = %v; want %v", tt.tenantCap, tt.maxLateFee, got, tt.want)
+		}
+	}
+}
+
+func TestInvoicePenalty(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2025, 3, d, 0, 0, 0, 0, time.UTC)
+	}
+	lastEnd := func(d int) *time.Time {
+		end := day(d)
+		return &end
+	}
+
+	percent := models.TenantSettings{LatePenaltyPolicy: models.PenaltyPolicyPercent, LatePenaltyPercent: 2}
+	fixed := models.TenantSettings{LatePenaltyPolicy: models.PenaltyPolicyFixed, LatePenaltyFixedAmount: 5000}
+	perDay := models.SubscriptionType{LateFeePerDay: 1000}
+
+	tests := []struct {
+		name     string
+		settings models.TenantSettings
+		subType  models.SubscriptionType
+		history  penaltyHistory
+		ok       bool
+		start    time.Time
+		days     int
+		amount   float64
+		capped   bool
+	}{
+		{
+			name:     "percent of the principal",
+			settings: percent,
+			ok:       true, start: day(1), days: 10, amount: 2000,
+		},
+		{
+			name:     "percent charged once",
+			settings: percent,
+			history:  penaltyHistory{total: 2000, lastEnd: lastEnd(5), lineCount: 1},
+		},
+		{
+			name:     "percent capped by the subscription type",
+			settings: percent,
+			subType:  models.SubscriptionType{MaxLateFee: 1500},
+			ok:       true, start: day(1), days: 10, amount: 1500, capped: true,
+		},
+		{
+			name:     "fixed capped by the tenant",
+			settings: models.TenantSettings{LatePenaltyPolicy: models.PenaltyPolicyFixed, LatePenaltyFixedAmount: 5000, LatePenaltyMaxCap: 4000},
+			ok:       true, start: day(1), days: 10, amount: 4000, capped: true,
+		},
+		{
+			name:     "fixed charged once",
+			settings: fixed,
+			history:  penaltyHistory{total: 5000, lastEnd: lastEnd(5), lineCount: 1},
+		},
+		{
+			name:    "per day",
+			subType: perDay,
+			ok:      true, start: day(1), days: 10, amount: 10000,
+		},
+		{
+			name:    "per day continues after the last charged period",
+			subType: perDay,
+			history: penaltyHistory{total: 5000, lastEnd: lastEnd(5), lineCount: 1},
+			ok:      true, start: day(6), days: 5, amount: 5000,
+		},
+		{
+			name:    "per day already charged up to today",
+			subType: perDay,
+			history: penaltyHistory{total: 10000, lastEnd: lastEnd(10), lineCount: 2},
+		},
+		{
+			name:    "per day up to the lower cap",
+			subType: models.SubscriptionType{LateFeePerDay: 1000, MaxLateFee: 7000},
+			history: penaltyHistory{total: 5000, lastEnd: lastEnd(5), lineCount: 1},
+			ok:      true, start: day(6), days: 5, amount: 2000, capped: true,
+		},
+		{
+			name:     "per day cap reached",
+			settings: models.TenantSettings{LatePenaltyMaxCap: 5000},
+			subType:  perDay,
+			history:  penaltyHistory{total: 5000, lastEnd: lastEnd(5), lineCount: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		line, ok := invoicePenalty(models.InvoicePenalty{
+			Policy:      penaltyPolicy(tt.settings, tt.subType),
+			PeriodStart: day(1),
+			PeriodEnd:   day(10),
+			Base:        100000,
+		}, tt.history, tt.settings, tt.subType)
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v; want %v (line %+v)", tt.name, ok, tt.ok, line)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !line.PeriodStart.Equal(tt.start) || line.Days != tt.days || line.Amount != tt.amount || line.Capped != tt.capped {
+			t.Errorf("%s: start %v, %d days, amount %v, capped %v; want %v, %d, %v, %v", tt.name,
+				line.PeriodStart, line.Days, line.Amount, line.Capped, tt.start, tt.days, tt.amount, tt.capped)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrNoUsageHistory = errors.New("Riwayat pemakaian belum ada, pemakaian tidak dapat diestimasi")
+
+// EstimateReadingInput describes a meter that could not be read
+type EstimateReadingInput struct {
+	TenantID         uuid.UUID
+	CustomerID       uuid.UUID
+	UsageMonth       string // YYYY-MM, defaults to the session month when estimated in a session
+	ReadingSessionID *uuid.UUID
+	RecordedBy       *uuid.UUID
+	Reason           string // e.g. gate locked, meter covered
+
+	ClientReadingID string
+	CapturedAt      *time.Time
+
+	acceptCompletedSession bool
+}
+
+// EstimateReading records an estimated usage for a meter that could not be read. The usage is the
+// average of the customer's recent actual readings; the next actual reading trues it up.
+func (s *MeterReadingService) EstimateReading(input EstimateReadingInput) (*models.WaterUsage, error) {
+	return s.RecordReading(RecordReadingInput{
+		TenantID:         input.TenantID,
+		CustomerID:       input.CustomerID,
+		UsageMonth:       input.UsageMonth,
+		ReadingSessionID: input.ReadingSessionID,
+		RecordedBy:       input.RecordedBy,
+		ReadingMethod:    models.ReadingMethodEstimated,
+		Notes:            input.Reason,
+		ClientReadingID:  input.ClientReadingID,
+		CapturedAt:       input.CapturedAt,
+
+		acceptCompletedSession: input.acceptCompletedSession,
+		estimate:               true,
+	})
+}
+
+// EstimateMissingReadings estimates the usage of every active customer without a reading for the
+// month. Customers that could not be estimated, e.g. without any usage history, are returned with
+// the reason.
+func (s *MeterReadingService) EstimateMissingReadings(tenantID uuid.UUID, usageMonth string, customerIDs []uuid.UUID) (int, map[uuid.UUID]error, error) {
+	query := config.DB.Where("tenant_id = ? AND is_active = ?", tenantID, true).
+		Where("id NOT IN (?)", config.DB.Model(&models.WaterUsage{}).Select("customer_id").
+			Where("tenant_id = ? AND usage_month = ?", tenantID, usageMonth))
+	if len(customerIDs) > 0 {
+		query = query.Where("id IN ?", customerIDs)
+	}
+
+	var customers []models.Customer
+	if err := query.Find(&customers).Error; err != nil {
+		return 0, nil, fmt.Errorf("failed to fetch customers without reading: %w", err)
+	}
+
+	estimated := 0
+	failures := make(map[uuid.UUID]error)
+	for _, customer := range customers {
+		if _, err := s.EstimateReading(EstimateReadingInput{
+			TenantID:   tenantID,
+			CustomerID: customer.ID,
+			UsageMonth: usageMonth,
+			Reason:     "Meter tidak terbaca sampai penerbitan tagihan",
+		}); err != nil {
+			failures[customer.ID] = err
+			continue
+		}
+		estimated++
+	}
+
+	if estimated > 0 {
+		log.Printf("✅ Estimated %d missing readings for tenant %s (%s)", estimated, tenantID, usageMonth)
+	}
+
+	return estimated, failures, nil
+}
+
+// unsettledEstimates returns the estimates recorded on the same meter since the customer's last
+// actual reading, oldest first
+func (s *MeterReadingService) unsettledEstimates(tenantID, customerID uuid.UUID, usageMonth string, meter *models.Meter) []models.WaterUsage {
+	query := config.DB.Where("tenant_id = ? AND customer_id = ? AND usage_month < ? AND reading_method = ? AND true_up_usage_id IS NULL",
+		tenantID, customerID, usageMonth, models.ReadingMethodEstimated)
+
+	var lastActual models.WaterUsage
+	if err := config.DB.Where("tenant_id = ? AND customer_id = ? AND usage_month < ? AND reading_method <> ?",
+		tenantID, customerID, usageMonth, models.ReadingMethodEstimated).
+		Order("usage_month DESC").First(&lastActual).Error; err == nil {
+		query = query.Where("usage_month > ?", lastActual.UsageMonth)
+	}
+
+	// Estimates of a replaced meter can't be compared with the new meter's figures
+	if meter != nil {
+		query = query.Where("meter_id = ?", meter.ID)
+	} else {
+		query = query.Where("meter_id IS NULL")
+	}
+
+	var estimates []models.WaterUsage
+	query.Order("usage_month ASC").Find(&estimates)
+	return estimates
+}
+
+// settleEstimates links estimates to the actual reading that trued them up
+func settleEstimates(tx *gorm.DB, usageID uuid.UUID, estimates []models.WaterUsage) error {
+	if len(estimates) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(estimates))
+	for i, estimate := range estimates {
+		ids[i] = estimate.ID
+	}
+	return tx.Model(&models.WaterUsage{}).Where("id IN ?", ids).Update("true_up_usage_id", usageID).Error
+}
+
+// priceReading sets the usage and charges of a reading from its meter figures. When the reading
+// trues up earlier estimates, the measured consumption is spread evenly over the estimated months
+// and this month; the difference between each month's share and what was estimated is repriced
+// and stored as TrueUpM3/TrueUpAmount so the next invoice can settle it.
+func (s *MeterReadingService) priceReading(usage *models.WaterUsage, customer models.Customer, estimates []models.WaterUsage) error {
+	consumed := usage.MeterEnd - usage.MeterStart
+	usage.UsageM3 = consumed
+	usage.TrueUpM3 = 0
+	usage.TrueUpAmount = 0
+
+	if len(estimates) > 0 {
+		share := math.Round(consumed/float64(len(estimates)+1)*100) / 100
+
+		corrected, err := s.tariffEngine.CalculateForCustomer(usage.TenantID, customer, share)
+		if err != nil {
+			return err
+		}
+
+		for _, estimate := range estimates {
+			usage.TrueUpM3 += share - estimate.UsageM3
+			usage.TrueUpAmount += corrected.TotalAmount - estimate.AmountCalculated
+		}
+		usage.TrueUpM3 = math.Round(usage.TrueUpM3*100) / 100
+		usage.TrueUpAmount = math.Round(usage.TrueUpAmount*100) / 100
+		usage.UsageM3 = math.Round((consumed-share*float64(len(estimates)))*100) / 100
+	}
+
+	tariff, err := s.tariffEngine.CalculateForCustomer(usage.TenantID, customer, usage.UsageM3)
+	if err != nil {
+		return err
+	}
+
+	usage.AmountCalculated = tariff.TotalAmount
+	usage.TariffMethod = tariff.Method
+	usage.TariffCategoryID = tariff.CategoryID
+	usage.TariffBreakdown = tariff.BreakdownJSON()
+	return nil
+}
+
+// repriceTrueUp recalculates the true-up of an actual reading after one of its estimates changed
+func (s *MeterReadingService) repriceTrueUp(usageID uuid.UUID) error {
+	var usage models.WaterUsage
+	if err := config.DB.Where("id = ?", usageID).First(&usage).Error; err != nil {
+		return err
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("id = ? AND tenant_id = ?", usage.CustomerID, usage.TenantID).First(&customer).Error; err != nil {
+		return ErrReadingCustomerNotFound
+	}
+
+	var estimates []models.WaterUsage
+	config.DB.Where("true_up_usage_id = ?", usage.ID).Order("usage_month ASC").Find(&estimates)
+
+	if err := s.priceReading(&usage, customer, estimates); err != nil {
+		return err
+	}
+	return config.DB.Save(&usage).Error
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// ReadingScheduler opens meter reading sessions for routes on their schedule day. With several
+// instances running, sessions are opened only by the leader.
+type ReadingScheduler struct {
+	cron     *cron.Cron
+	sessions *ReadingSessionService
+	leader   *LeaderElector
+}
+
+// NewReadingScheduler creates new reading scheduler
+func NewReadingScheduler(leader *LeaderElector) *ReadingScheduler {
+	return &ReadingScheduler{
+		cron:     cron.New(),
+		sessions: NewReadingSessionService(),
+		leader:   leader,
+	}
+}
+
+// Start starts the scheduler
+func (s *ReadingScheduler) Start() error {
+	// Run every day at 05:00 so readers find today's worklist before going out
+	_, err := s.cron.AddFunc("0 5 * * *", func() {
+		s.leader.RunExclusive("reading-sessions", s.dailySlot(time.Now()), func() (string, error) {
+			log.Println("🕐 Opening scheduled reading sessions...")
+			return s.runDailySessions()
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule reading sessions: %w", err)
+	}
+
+	s.cron.Start()
+	log.Println("✅ Reading scheduler started successfully")
+	log.Println("📅 Reading sessions: Every day at 05:00")
+
+	// Catch up on today's routes when the server starts after 05:00. The run is recorded for
+	// today's 05:00, so it is skipped when it already ran today.
+	now := time.Now().In(s.cron.Location())
+	if slot := s.dailySlot(now); !now.Before(slot) {
+		go s.leader.RunExclusive("reading-sessions", slot, s.runDailySessions)
+	}
+
+	return nil
+}
+
+// dailySlot returns 05:00 of the day of t in the scheduler's time zone, the slot of that day's run
+func (s *ReadingScheduler) dailySlot(t time.Time) time.Time {
+	local := t.In(s.cron.Location())
+	return time.Date(local.Year(), local.Month(), local.Day(), 5, 0, 0, 0, local.Location())
+}
+
+// Stop stops the scheduler
+func (s *ReadingScheduler) Stop() {
+	s.cron.Stop()
+	log.Println("🛑 Reading scheduler stopped")
+}
+
+// runDailySessions opens sessions for all routes scheduled today
+func (s *ReadingScheduler) runDailySessions() (string, error) {
+	opened, err := s.sessions.OpenScheduledSessions(time.Now())
+	if err != nil {
+		log.Printf("❌ Failed to open reading sessions: %v", err)
+		return "", err
+	}
+
+	log.Printf("✅ Opened %d reading sessions", opened)
+	return fmt.Sprintf("Opened %d reading sessions", opened), nil
+}
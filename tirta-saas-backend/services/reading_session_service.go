@@ -0,0 +1,262 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrRouteHasNoReader is returned when a session is opened for a route without an assigned reader
+	ErrRouteHasNoReader = errors.New("reading route has no assigned meter reader")
+	// ErrRouteInactive is returned when a session is opened for an inactive route
+	ErrRouteInactive = errors.New("reading route is not active")
+)
+
+// WorklistItem is one stop on a meter reader's route
+type WorklistItem struct {
+	Sequence        int        `json:"sequence"`
+	CustomerID      uuid.UUID  `json:"customer_id"`
+	CustomerName    string     `json:"customer_name"`
+	Address         string     `json:"address"`
+	Phone           string     `json:"phone"`
+	MeterID         *uuid.UUID `json:"meter_id,omitempty"`
+	MeterNumber     string     `json:"meter_number"`
+	MeterStatus     string     `json:"meter_status,omitempty"`
+	PreviousReading float64    `json:"previous_reading"`
+	IsRead          bool       `json:"is_read"`
+	WaterUsageID    *uuid.UUID `json:"water_usage_id,omitempty"`
+	MeterEnd        *float64   `json:"meter_end,omitempty"`
+}
+
+// ReadingSessionService opens reading sessions for routes and tracks their progress
+type ReadingSessionService struct{}
+
+// NewReadingSessionService creates new reading session service
+func NewReadingSessionService() *ReadingSessionService {
+	return &ReadingSessionService{}
+}
+
+// IsScheduledOn reports whether a route schedule day falls on date. Schedule days past the
+// end of a short month are read on the last day of that month.
+func IsScheduledOn(scheduleDay int, date time.Time) bool {
+	lastDay := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, date.Location()).Day()
+	if scheduleDay > lastDay {
+		scheduleDay = lastDay
+	}
+	return date.Day() == scheduleDay
+}
+
+// OpenSession opens the reading session of a route for the month of date. It returns the
+// existing session instead when one was already opened for that month.
+func (s *ReadingSessionService) OpenSession(route models.ReadingRoute, date time.Time) (*models.ReadingSession, bool, error) {
+	if !route.IsActive {
+		return nil, false, ErrRouteInactive
+	}
+	if route.AssignedTo == nil {
+		return nil, false, ErrRouteHasNoReader
+	}
+
+	usageMonth := date.Format("2006-01")
+
+	var existing models.ReadingSession
+	if err := config.DB.Where("route_id = ? AND usage_month = ? AND status <> ?", route.ID, usageMonth, models.ReadingSessionCancelled).
+		First(&existing).Error; err == nil {
+		return &existing, false, nil
+	}
+
+	var totalCustomers int64
+	config.DB.Model(&models.Customer{}).
+		Where("tenant_id = ? AND reading_route_id = ? AND is_active = ?", route.TenantID, route.ID, true).
+		Count(&totalCustomers)
+
+	session := models.ReadingSession{
+		TenantID:       route.TenantID,
+		RouteID:        route.ID,
+		ReaderID:       *route.AssignedTo,
+		ScheduledDate:  time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location()),
+		UsageMonth:     usageMonth,
+		Status:         models.ReadingSessionScheduled,
+		TotalCustomers: int(totalCustomers),
+	}
+
+	if err := config.DB.Create(&session).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to create reading session: %w", err)
+	}
+
+	// Readings recorded before the session was opened still count towards it
+	if err := config.DB.Model(&models.WaterUsage{}).
+		Where("tenant_id = ? AND usage_month = ? AND reading_session_id IS NULL AND customer_id IN (?)", route.TenantID, usageMonth,
+			config.DB.Model(&models.Customer{}).Select("id").Where("reading_route_id = ?", route.ID)).
+		Update("reading_session_id", session.ID).Error; err != nil {
+		log.Printf("⚠️  Failed to attach existing readings to session: %v", err)
+	}
+	if err := s.RefreshProgress(session.ID); err != nil {
+		log.Printf("⚠️  Failed to refresh reading session progress: %v", err)
+	}
+
+	return &session, true, nil
+}
+
+// OpenScheduledSessions opens sessions for every active route of active tenants scheduled on date
+func (s *ReadingSessionService) OpenScheduledSessions(date time.Time) (int, error) {
+	var routes []models.ReadingRoute
+	if err := config.DB.Joins("JOIN tenants ON tenants.id = reading_routes.tenant_id").
+		Where("tenants.status = ? AND reading_routes.is_active = ?", "ACTIVE", true).
+		Find(&routes).Error; err != nil {
+		return 0, fmt.Errorf("failed to fetch reading routes: %w", err)
+	}
+
+	opened := 0
+	for _, route := range routes {
+		if !IsScheduledOn(route.ScheduleDay, date) {
+			continue
+		}
+
+		_, created, err := s.OpenSession(route, date)
+		if err != nil {
+			log.Printf("❌ Failed to open reading session for route %s (%s): %v", route.Code, route.Name, err)
+			continue
+		}
+		if created {
+			opened++
+		}
+	}
+
+	return opened, nil
+}
+
+// RefreshProgress recounts the readings of a session and moves it through
+// scheduled -> in_progress -> completed as readings come in
+func (s *ReadingSessionService) RefreshProgress(sessionID uuid.UUID) error {
+	var session models.ReadingSession
+	if err := config.DB.Where("id = ?", sessionID).First(&session).Error; err != nil {
+		return ErrReadingSessionNotFound
+	}
+
+	var completed, anomalies int64
+	config.DB.Model(&models.WaterUsage{}).Where("reading_session_id = ?", session.ID).Count(&completed)
+	config.DB.Model(&models.WaterUsage{}).Where("reading_session_id = ? AND is_anomaly = ?", session.ID, true).Count(&anomalies)
+
+	updates := map[string]interface{}{
+		"completed_count": int(completed),
+		"anomaly_count":   int(anomalies),
+	}
+
+	now := time.Now()
+	if session.Status == models.ReadingSessionScheduled && completed > 0 {
+		updates["status"] = models.ReadingSessionInProgress
+		updates["start_time"] = now
+	}
+	if (session.Status == models.ReadingSessionScheduled || session.Status == models.ReadingSessionInProgress) &&
+		session.TotalCustomers > 0 && int(completed) >= session.TotalCustomers {
+		updates["status"] = models.ReadingSessionCompleted
+		updates["end_time"] = now
+		if session.StartTime == nil {
+			updates["start_time"] = now
+		}
+	}
+
+	return config.DB.Model(&models.ReadingSession{}).Where("id = ?", session.ID).Updates(updates).Error
+}
+
+// Worklist returns the customers of the session route in visit order with their reading status
+func (s *ReadingSessionService) Worklist(session models.ReadingSession) ([]WorklistItem, error) {
+	var customers []models.Customer
+	if err := config.DB.Where("tenant_id = ? AND reading_route_id = ? AND is_active = ?", session.TenantID, session.RouteID, true).
+		Order("reading_sequence ASC").Order("name ASC").Find(&customers).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch route customers: %w", err)
+	}
+
+	customerIDs := make([]uuid.UUID, len(customers))
+	for i, customer := range customers {
+		customerIDs[i] = customer.ID
+	}
+
+	month, err := time.Parse("2006-01", session.UsageMonth)
+	if err != nil {
+		return nil, ErrInvalidUsageMonth
+	}
+	prevMonth := month.AddDate(0, -1, 0).Format("2006-01")
+
+	readings := map[uuid.UUID]models.WaterUsage{}
+	previous := map[uuid.UUID]models.WaterUsage{}
+	if len(customerIDs) > 0 {
+		var usages []models.WaterUsage
+		config.DB.Where("tenant_id = ? AND customer_id IN ? AND usage_month IN ?", session.TenantID, customerIDs,
+			[]string{session.UsageMonth, prevMonth}).Find(&usages)
+		for _, usage := range usages {
+			if usage.UsageMonth == session.UsageMonth {
+				readings[usage.CustomerID] = usage
+			} else {
+				previous[usage.CustomerID] = usage
+			}
+		}
+	}
+
+	items := make([]WorklistItem, len(customers))
+	for i, customer := range customers {
+		item := WorklistItem{
+			Sequence:     i + 1,
+			CustomerID:   customer.ID,
+			CustomerName: customer.Name,
+			Address:      customer.Address,
+			Phone:        customer.Phone,
+			MeterNumber:  customer.MeterNumber,
+		}
+
+		if meter := FindActiveMeter(config.DB, session.TenantID, customer.ID); meter != nil {
+			item.MeterID = &meter.ID
+			item.MeterNumber = meter.MeterNumber
+			item.MeterStatus = meter.Status
+			item.PreviousReading = meter.InitialReading
+		}
+		if prev, ok := previous[customer.ID]; ok {
+			item.PreviousReading = prev.MeterEnd
+		}
+		if usage, ok := readings[customer.ID]; ok {
+			usageID := usage.ID
+			meterEnd := usage.MeterEnd
+			item.IsRead = true
+			item.WaterUsageID = &usageID
+			item.MeterEnd = &meterEnd
+		}
+
+		items[i] = item
+	}
+
+	return items, nil
+}
+
+// findOpenSession loads a session that still accepts readings. Completed sessions only accept
+// readings when allowCompleted is set, which is used for offline syncs arriving late.
+func (s *ReadingSessionService) findOpenSession(tenantID, sessionID uuid.UUID, allowCompleted bool) (*models.ReadingSession, error) {
+	var session models.ReadingSession
+	if err := config.DB.Where("id = ? AND tenant_id = ?", sessionID, tenantID).First(&session).Error; err != nil {
+		return nil, ErrReadingSessionNotFound
+	}
+	if session.Status == models.ReadingSessionCancelled || (session.Status == models.ReadingSessionCompleted && !allowCompleted) {
+		return nil, ErrReadingSessionClosed
+	}
+	return &session, nil
+}
+
+// FindOpenSessionForCustomer returns the open session of the customer's route for a usage month, if any
+func (s *ReadingSessionService) FindOpenSessionForCustomer(customer models.Customer, usageMonth string) *models.ReadingSession {
+	if customer.ReadingRouteID == nil {
+		return nil
+	}
+
+	var session models.ReadingSession
+	if err := config.DB.Where("tenant_id = ? AND route_id = ? AND usage_month = ? AND status IN ?", customer.TenantID,
+		*customer.ReadingRouteID, usageMonth, []string{models.ReadingSessionScheduled, models.ReadingSessionInProgress}).
+		First(&session).Error; err != nil {
+		return nil
+	}
+	return &session
+}
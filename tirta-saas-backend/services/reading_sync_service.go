@@ -0,0 +1,192 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+)
+
+// Allowed clock drift between the reader's device and the server
+const maxCaptureClockSkew = 10 * time.Minute
+
+// Sync result statuses
+const (
+	SyncStatusCreated   = "created"   // reading stored
+	SyncStatusDuplicate = "duplicate" // same client ID was synced before, nothing changed
+	SyncStatusConflict  = "conflict"  // another reading already exists for the customer and month
+	SyncStatusRejected  = "rejected"  // reading is invalid and will be rejected again on retry
+	SyncStatusFailed    = "failed"    // server side problem, the client should retry later
+)
+
+var (
+	ErrCapturedInFuture      = errors.New("Waktu pengambilan pembacaan berada di masa depan")
+	ErrClientIDUsedElsewhere = errors.New("Client ID sudah dipakai untuk pelanggan lain")
+)
+
+// readingValidationErrors are errors caused by the reading itself rather than the server
+var readingValidationErrors = []error{
+	ErrReadingNegative, ErrReadingTooLarge, ErrInvalidUsageMonth, ErrReadingDecreased,
+	ErrReadingSessionClosed, ErrCustomerNotOnRoute, ErrUsageMonthMismatch,
+	ErrCapturedInFuture, ErrClientIDUsedElsewhere, ErrNoUsageHistory,
+}
+
+// IsReadingValidationError reports whether a reading was refused because its data is invalid
+func IsReadingValidationError(err error) bool {
+	for _, target := range readingValidationErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// SyncReadingItem is a reading captured offline by the reader app
+type SyncReadingItem struct {
+	ClientID      string // generated on the device, identifies the reading across retries
+	CustomerID    uuid.UUID
+	MeterID       *uuid.UUID
+	MeterEnd      float64
+	CapturedAt    time.Time
+	PhotoURL      string
+	Latitude      *float64
+	Longitude     *float64
+	ReadingMethod string
+	Notes         string
+	CannotRead    bool // the meter was not accessible, Notes holds the reason
+}
+
+// SyncReadingResult is the outcome of one synced reading
+type SyncReadingResult struct {
+	ClientID string
+	Status   string
+	Usage    *models.WaterUsage // stored reading for created and duplicate items
+	Existing *models.WaterUsage // reading that caused a conflict
+	Err      error
+}
+
+// SyncSessionReadings applies a batch of offline readings to a session. Items are applied in
+// capture order and every item gets its own result, so one bad reading does not fail the batch.
+// Sending the same batch again is safe: readings already stored are reported as duplicates.
+func (s *MeterReadingService) SyncSessionReadings(session *models.ReadingSession, recordedBy uuid.UUID, items []SyncReadingItem) []SyncReadingResult {
+	results := make([]SyncReadingResult, len(items))
+
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return items[order[a]].CapturedAt.Before(items[order[b]].CapturedAt)
+	})
+
+	for _, i := range order {
+		results[i] = s.syncReading(session, recordedBy, items[i])
+	}
+
+	return results
+}
+
+func (s *MeterReadingService) syncReading(session *models.ReadingSession, recordedBy uuid.UUID, item SyncReadingItem) SyncReadingResult {
+	result := SyncReadingResult{ClientID: item.ClientID}
+
+	// Retried upload: return what was stored the first time
+	if previous, ok := previousSync(session.TenantID, item); ok {
+		return previous
+	}
+
+	if item.CapturedAt.After(time.Now().Add(maxCaptureClockSkew)) {
+		result.Status = SyncStatusRejected
+		result.Err = ErrCapturedInFuture
+		return result
+	}
+
+	capturedAt := item.CapturedAt
+	var usage *models.WaterUsage
+	var err error
+	if item.CannotRead {
+		usage, err = s.EstimateReading(EstimateReadingInput{
+			TenantID:         session.TenantID,
+			CustomerID:       item.CustomerID,
+			ReadingSessionID: &session.ID,
+			RecordedBy:       &recordedBy,
+			Reason:           item.Notes,
+			ClientReadingID:  item.ClientID,
+			CapturedAt:       &capturedAt,
+
+			acceptCompletedSession: true,
+		})
+	} else {
+		usage, err = s.RecordReading(RecordReadingInput{
+			TenantID:         session.TenantID,
+			CustomerID:       item.CustomerID,
+			MeterEnd:         item.MeterEnd,
+			MeterID:          item.MeterID,
+			ReadingSessionID: &session.ID,
+			RecordedBy:       &recordedBy,
+			PhotoURL:         item.PhotoURL,
+			ReadingMethod:    item.ReadingMethod,
+			Notes:            item.Notes,
+			ClientReadingID:  item.ClientID,
+			CapturedAt:       &capturedAt,
+			Latitude:         item.Latitude,
+			Longitude:        item.Longitude,
+
+			// Readers may only get signal after the session was closed
+			acceptCompletedSession: true,
+		})
+	}
+
+	// A retry racing the original upload loses on the reading of the month or on the unique
+	// client ID; either way the reading was stored by the other request
+	if err != nil {
+		if previous, ok := previousSync(session.TenantID, item); ok {
+			return previous
+		}
+	}
+
+	switch {
+	case err == nil:
+		result.Status = SyncStatusCreated
+		result.Usage = usage
+	case errors.Is(err, ErrReadingAlreadyExists):
+		result.Status = SyncStatusConflict
+		result.Err = err
+		var existing models.WaterUsage
+		if err := config.DB.Where("tenant_id = ? AND customer_id = ? AND usage_month = ?",
+			session.TenantID, item.CustomerID, session.UsageMonth).First(&existing).Error; err == nil {
+			result.Existing = &existing
+		}
+	case IsReadingNotFound(err), IsReadingValidationError(err):
+		result.Status = SyncStatusRejected
+		result.Err = err
+	default:
+		log.Printf("❌ Failed to sync reading %s for customer %s: %v", item.ClientID, item.CustomerID, err)
+		result.Status = SyncStatusFailed
+		result.Err = err
+	}
+
+	return result
+}
+
+// previousSync returns the result of a reading already stored with the item's client ID
+func previousSync(tenantID uuid.UUID, item SyncReadingItem) (SyncReadingResult, bool) {
+	var previous models.WaterUsage
+	if err := config.DB.Where("tenant_id = ? AND client_reading_id = ?", tenantID, item.ClientID).
+		First(&previous).Error; err != nil {
+		return SyncReadingResult{}, false
+	}
+
+	result := SyncReadingResult{ClientID: item.ClientID}
+	if previous.CustomerID != item.CustomerID {
+		result.Status = SyncStatusRejected
+		result.Err = ErrClientIDUsedElsewhere
+		return result, true
+	}
+	result.Status = SyncStatusDuplicate
+	result.Usage = &previous
+	return result, true
+}
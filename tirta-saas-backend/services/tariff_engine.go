@@ -0,0 +1,197 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+)
+
+// Tariff calculation methods
+const (
+	TariffMethodFlat        = "flat"
+	TariffMethodProgressive = "progressive"
+)
+
+var (
+	// ErrNoActiveWaterRate is returned when a customer's subscription has no active water rate
+	ErrNoActiveWaterRate = errors.New("no active water rate found for subscription")
+	// ErrNoProgressiveRates is returned when a tariff category has no active tiers
+	ErrNoProgressiveRates = errors.New("no active progressive rates found for this category")
+)
+
+// TariffTier is a single block of a tiered water charge
+type TariffTier struct {
+	TierRange    string   `json:"tier_range"`
+	MinVolume    float64  `json:"min_volume"`
+	MaxVolume    *float64 `json:"max_volume,omitempty"`
+	Volume       float64  `json:"volume"`
+	PricePerUnit float64  `json:"price_per_unit"`
+	Amount       float64  `json:"amount"`
+}
+
+// TariffCalculation is the result of pricing a usage volume
+type TariffCalculation struct {
+	Method      string       `json:"method"`
+	WaterRateID *uuid.UUID   `json:"water_rate_id,omitempty"`
+	CategoryID  *uuid.UUID   `json:"category_id,omitempty"`
+	UsageM3     float64      `json:"usage_m3"`
+	TotalAmount float64      `json:"total_amount"`
+	Breakdown   []TariffTier `json:"breakdown"`
+}
+
+// PricePerM3 returns the effective average price per m3
+func (t *TariffCalculation) PricePerM3() float64 {
+	if t.UsageM3 <= 0 {
+		return 0
+	}
+	return t.TotalAmount / t.UsageM3
+}
+
+// BreakdownJSON serializes the tier breakdown for persisting on usage and invoices
+func (t *TariffCalculation) BreakdownJSON() string {
+	data, err := json.Marshal(t.Breakdown)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// TariffEngine prices water usage using flat WaterRates or tiered ProgressiveRates.
+// It is shared by the bill simulator, meter reading and invoice generation so that
+// all of them produce the same amounts.
+type TariffEngine struct{}
+
+// NewTariffEngine creates new tariff engine
+func NewTariffEngine() *TariffEngine {
+	return &TariffEngine{}
+}
+
+// CalculateForCategory prices usage against the active progressive rates of a tariff category
+func (e *TariffEngine) CalculateForCategory(tenantID, categoryID uuid.UUID, usageM3 float64) (*TariffCalculation, error) {
+	var rates []models.ProgressiveRate
+	if err := config.DB.Where("tenant_id = ? AND category_id = ? AND is_active = ?", tenantID, categoryID, true).
+		Order("min_volume ASC").Find(&rates).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch progressive rates: %w", err)
+	}
+
+	if len(rates) == 0 {
+		return nil, ErrNoProgressiveRates
+	}
+
+	total, breakdown := CalculateProgressiveCharge(rates, usageM3)
+
+	return &TariffCalculation{
+		Method:      TariffMethodProgressive,
+		CategoryID:  &categoryID,
+		UsageM3:     usageM3,
+		TotalAmount: total,
+		Breakdown:   breakdown,
+	}, nil
+}
+
+// CalculateForCustomer prices usage for a customer. When the customer's active water rate is
+// linked to a tariff category with active tiers the usage is billed block-by-block, otherwise
+// the flat rate amount per m3 is used.
+func (e *TariffEngine) CalculateForCustomer(tenantID uuid.UUID, customer models.Customer, usageM3 float64) (*TariffCalculation, error) {
+	var rate models.WaterRate
+	if err := config.DB.
+		Where("subscription_id = ? AND tenant_id = ? AND active = ?", customer.SubscriptionID, tenantID, true).
+		Order("effective_date DESC").
+		First(&rate).Error; err != nil {
+		return nil, ErrNoActiveWaterRate
+	}
+
+	if rate.CategoryID != nil {
+		var category models.TariffCategory
+		err := config.DB.Where("id = ? AND tenant_id = ? AND is_active = ?", *rate.CategoryID, tenantID, true).
+			First(&category).Error
+		if err == nil {
+			calc, err := e.CalculateForCategory(tenantID, category.ID, usageM3)
+			if err == nil {
+				calc.WaterRateID = &rate.ID
+				return calc, nil
+			}
+			if !errors.Is(err, ErrNoProgressiveRates) {
+				return nil, err
+			}
+		}
+	}
+
+	amount := usageM3 * rate.Amount
+	return &TariffCalculation{
+		Method:      TariffMethodFlat,
+		WaterRateID: &rate.ID,
+		CategoryID:  rate.CategoryID,
+		UsageM3:     usageM3,
+		TotalAmount: amount,
+		Breakdown: []TariffTier{{
+			TierRange:    "flat",
+			Volume:       usageM3,
+			PricePerUnit: rate.Amount,
+			Amount:       amount,
+		}},
+	}, nil
+}
+
+// CalculateProgressiveCharge splits usage over tiers ordered by min volume.
+// Each tier absorbs at most (MaxVolume - MinVolume) m3; a nil MaxVolume is unlimited.
+// Usage above a bounded last tier is billed at the last tier's price.
+func CalculateProgressiveCharge(rates []models.ProgressiveRate, usageM3 float64) (float64, []TariffTier) {
+	remaining := usageM3
+	total := 0.0
+	breakdown := []TariffTier{}
+
+	for _, rate := range rates {
+		if remaining <= 0 {
+			break
+		}
+
+		volumeInTier := remaining
+		if rate.MaxVolume != nil {
+			width := *rate.MaxVolume - rate.MinVolume
+			if width < 0 {
+				continue
+			}
+			if volumeInTier > width {
+				volumeInTier = width
+			}
+		}
+
+		amount := volumeInTier * rate.PricePerUnit
+		total += amount
+
+		tierRange := fmt.Sprintf("%.0f - ", rate.MinVolume)
+		if rate.MaxVolume != nil {
+			tierRange += fmt.Sprintf("%.0f m³", *rate.MaxVolume)
+		} else {
+			tierRange += "unlimited m³"
+		}
+
+		breakdown = append(breakdown, TariffTier{
+			TierRange:    tierRange,
+			MinVolume:    rate.MinVolume,
+			MaxVolume:    rate.MaxVolume,
+			Volume:       volumeInTier,
+			PricePerUnit: rate.PricePerUnit,
+			Amount:       amount,
+		})
+
+		remaining -= volumeInTier
+	}
+
+	if remaining > 0 && len(breakdown) > 0 {
+		last := &breakdown[len(breakdown)-1]
+		overflow := remaining * last.PricePerUnit
+		total += overflow
+		last.Volume += remaining
+		last.Amount += overflow
+		last.MaxVolume = nil
+		last.TierRange = fmt.Sprintf("%.0f - unlimited m³", last.MinVolume)
+	}
+
+	return total, breakdown
+}
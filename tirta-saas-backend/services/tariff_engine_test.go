@@ -0,0 +1,71 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/adipras/tirta-saas-backend/models"
+)
+
+func volume(v float64) *float64 {
+	return &v
+}
+
+func TestCalculateProgressiveCharge(t *testing.T) {
+	rates := []models.ProgressiveRate{
+		{MinVolume: 0, MaxVolume: volume(10), PricePerUnit: 1000},
+		{MinVolume: 10, MaxVolume: volume(20), PricePerUnit: 1500},
+		{MinVolume: 20, MaxVolume: nil, PricePerUnit: 2000},
+	}
+	bounded := rates[:2]
+
+	tests := []struct {
+		name    string
+		rates   []models.ProgressiveRate
+		usage   float64
+		total   float64
+		volumes []float64
+	}{
+		{"no usage", rates, 0, 0, nil},
+		{"within first tier", rates, 7, 7000, []float64{7}},
+		{"first tier boundary", rates, 10, 10000, []float64{10}},
+		{"just over first tier", rates, 10.5, 10750, []float64{10, 0.5}},
+		{"second tier boundary", rates, 20, 25000, []float64{10, 10}},
+		{"unlimited last tier", rates, 35, 55000, []float64{10, 10, 15}},
+		{"usage beyond the last bounded tier at its price", bounded, 25, 32500, []float64{10, 15}},
+		{"inverted tier is skipped", []models.ProgressiveRate{
+			{MinVolume: 10, MaxVolume: volume(5), PricePerUnit: 9999},
+			{MinVolume: 0, MaxVolume: nil, PricePerUnit: 1000},
+		}, 12, 12000, []float64{12}},
+	}
+
+	for _, tt := range tests {
+		total, breakdown := CalculateProgressiveCharge(tt.rates, tt.usage)
+		if total != tt.total {
+			t.Errorf("%s: total = %v; want %v", tt.name, total, tt.total)
+		}
+		if len(breakdown) != len(tt.volumes) {
+			t.Errorf("%s: %d tiers = %+v; want %d", tt.name, len(breakdown), breakdown, len(tt.volumes))
+			continue
+		}
+		for i, want := range tt.volumes {
+			if breakdown[i].Volume != want {
+				t.Errorf("%s: tier %d volume = %v; want %v", tt.name, i, breakdown[i].Volume, want)
+			}
+			if amount := breakdown[i].Volume * breakdown[i].PricePerUnit; breakdown[i].Amount != amount {
+				t.Errorf("%s: tier %d amount = %v; want %v", tt.name, i, breakdown[i].Amount, amount)
+			}
+		}
+	}
+
+	_, breakdown := CalculateProgressiveCharge(rates, 35)
+	for i, want := range []string{"0 - 10 m³", "10 - 20 m³", "20 - unlimited m³"} {
+		if breakdown[i].TierRange != want {
+			t.Errorf("tier %d range = %q; want %q", i, breakdown[i].TierRange, want)
+		}
+	}
+
+	_, breakdown = CalculateProgressiveCharge(bounded, 25)
+	if last := breakdown[len(breakdown)-1]; last.TierRange != "10 - unlimited m³" || last.MaxVolume != nil {
+		t.Errorf("overflowing last tier = %q, max %v; want %q, unlimited", last.TierRange, last.MaxVolume, "10 - unlimited m³")
+	}
+}
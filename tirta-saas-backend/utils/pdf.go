@@ -0,0 +1,345 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	_ "image/gif" // register decoders for logos
+	"image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A4 page size in points
+const (
+	PDFPageWidth  = 595.28
+	PDFPageHeight = 841.89
+)
+
+// PDFDocument is a minimal PDF writer for printable documents (invoices, receipts). It supports
+// text in the standard Helvetica fonts, lines, rectangles and images. Coordinates are in points
+// measured from the top-left corner of the page.
+type PDFDocument struct {
+	pages    []*bytes.Buffer
+	page     *bytes.Buffer
+	images   []*PDFImage
+	imageIDs map[*PDFImage]int
+}
+
+// PDFImage is an image that can be drawn on the pages of a document
+type PDFImage struct {
+	Width  int
+	Height int
+	data   []byte
+	filter string // DCTDecode for JPEG, FlateDecode for decoded images
+}
+
+// NewPDFDocument creates an empty document
+func NewPDFDocument() *PDFDocument {
+	return &PDFDocument{imageIDs: map[*PDFImage]int{}}
+}
+
+// AddPage starts a new A4 page
+func (d *PDFDocument) AddPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+}
+
+// PageCount returns the number of pages
+func (d *PDFDocument) PageCount() int {
+	return len(d.pages)
+}
+
+// SetFillColor sets the color of text and filled rectangles from a #RRGGBB string
+func (d *PDFDocument) SetFillColor(hex string) {
+	r, g, b := parseHexColor(hex)
+	fmt.Fprintf(d.page, "%s %s %s rg\n", pdfNumber(r), pdfNumber(g), pdfNumber(b))
+}
+
+// SetStrokeColor sets the color of lines and rectangle borders from a #RRGGBB string
+func (d *PDFDocument) SetStrokeColor(hex string) {
+	r, g, b := parseHexColor(hex)
+	fmt.Fprintf(d.page, "%s %s %s RG\n", pdfNumber(r), pdfNumber(g), pdfNumber(b))
+}
+
+// Text draws text with its baseline at y
+func (d *PDFDocument) Text(x, y, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.page, "BT /%s %s Tf %s %s Td (%s) Tj ET\n",
+		font, pdfNumber(size), pdfNumber(x), pdfNumber(PDFPageHeight-y), escapePDFText(text))
+}
+
+// TextRight draws text that ends at x
+func (d *PDFDocument) TextRight(x, y, size float64, bold bool, text string) {
+	d.Text(x-TextWidth(text, size, bold), y, size, bold, text)
+}
+
+// TextCenter draws text centered on x
+func (d *PDFDocument) TextCenter(x, y, size float64, bold bool, text string) {
+	d.Text(x-TextWidth(text, size, bold)/2, y, size, bold, text)
+}
+
+// Paragraph draws text wrapped to width and returns the y below the last line
+func (d *PDFDocument) Paragraph(x, y, width, size float64, bold bool, text string) float64 {
+	lineHeight := size * 1.3
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := strings.TrimSpace(line + " " + word)
+			if line != "" && TextWidth(candidate, size, bold) > width {
+				d.Text(x, y, size, bold, line)
+				y += lineHeight
+				candidate = word
+			}
+			line = candidate
+		}
+		d.Text(x, y, size, bold, line)
+		y += lineHeight
+	}
+	return y
+}
+
+// Line draws a line
+func (d *PDFDocument) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(d.page, "%s w %s %s m %s %s l S\n", pdfNumber(width),
+		pdfNumber(x1), pdfNumber(PDFPageHeight-y1), pdfNumber(x2), pdfNumber(PDFPageHeight-y2))
+}
+
+// Rect draws a rectangle whose top-left corner is at x, y, filled with the fill color or outlined
+func (d *PDFDocument) Rect(x, y, w, h float64, fill bool) {
+	op := "S"
+	if fill {
+		op = "f"
+	}
+	fmt.Fprintf(d.page, "%s %s %s %s re %s\n",
+		pdfNumber(x), pdfNumber(PDFPageHeight-y-h), pdfNumber(w), pdfNumber(h), op)
+}
+
+// Image draws an image whose top-left corner is at x, y
+func (d *PDFDocument) Image(img *PDFImage, x, y, w, h float64) {
+	id, ok := d.imageIDs[img]
+	if !ok {
+		d.images = append(d.images, img)
+		id = len(d.images)
+		d.imageIDs[img] = id
+	}
+	fmt.Fprintf(d.page, "q %s 0 0 %s %s %s cm /Im%d Do Q\n",
+		pdfNumber(w), pdfNumber(h), pdfNumber(x), pdfNumber(PDFPageHeight-y-h), id)
+}
+
+// LoadPDFImage reads a JPEG, PNG or GIF file for use in a document
+func LoadPDFImage(path string) (*PDFImage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// JPEG files are embedded as they are, unless they use CMYK
+	if format == "jpeg" {
+		if img, err := jpeg.Decode(bytes.NewReader(data)); err == nil {
+			if _, isCMYK := img.(*image.CMYK); !isCMYK {
+				return &PDFImage{Width: config.Width, Height: config.Height, data: data, filter: "DCTDecode"}, nil
+			}
+		}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// Other formats are stored as RGB, transparent pixels become white
+	bounds := img.Bounds()
+	var raw bytes.Buffer
+	writer := zlib.NewWriter(&raw)
+	row := make([]byte, 0, bounds.Dx()*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row = row[:0]
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			white := 0xffff - a
+			row = append(row, byte((r+white)>>8), byte((g+white)>>8), byte((b+white)>>8))
+		}
+		writer.Write(row)
+	}
+	writer.Close()
+
+	return &PDFImage{Width: bounds.Dx(), Height: bounds.Dy(), data: raw.Bytes(), filter: "FlateDecode"}, nil
+}
+
+// Bytes serializes the document
+func (d *PDFDocument) Bytes() []byte {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string, stream []byte) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\n", len(offsets), body)
+		if stream != nil {
+			out.WriteString("stream\n")
+			out.Write(stream)
+			out.WriteString("\nendstream\n")
+		}
+		out.WriteString("endobj\n")
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects: 1 catalog, 2 page tree, 3-4 fonts, images, then a page and its content per page
+	firstImage := 5
+	firstPage := firstImage + len(d.images)
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+i*2)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>", nil)
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)), nil)
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>", nil)
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>", nil)
+
+	var xObjects []string
+	for i, img := range d.images {
+		object(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /%s /Length %d >>",
+			img.Width, img.Height, img.filter, len(img.data)), img.data)
+		xObjects = append(xObjects, fmt.Sprintf("/Im%d %d 0 R", i+1, firstImage+i))
+	}
+
+	resources := "<< /Font << /F1 3 0 R /F2 4 0 R >>"
+	if len(xObjects) > 0 {
+		resources += " /XObject << " + strings.Join(xObjects, " ") + " >>"
+	}
+	resources += " >>"
+
+	for i, page := range d.pages {
+		var content bytes.Buffer
+		writer := zlib.NewWriter(&content)
+		writer.Write(page.Bytes())
+		writer.Close()
+
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources %s /Contents %d 0 R >>",
+			pdfNumber(PDFPageWidth), pdfNumber(PDFPageHeight), resources, firstPage+i*2+1), nil)
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>", content.Len()), content.Bytes())
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+// TextWidth returns the width of text in points
+func TextWidth(text string, size float64, bold bool) float64 {
+	widths := helveticaWidths
+	if bold {
+		widths = helveticaBoldWidths
+	}
+
+	total := 0
+	for _, b := range toWinAnsi(text) {
+		if b >= 32 && b <= 126 {
+			total += widths[b-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// escapePDFText encodes text as a WinAnsi PDF string literal
+func escapePDFText(text string) string {
+	var out strings.Builder
+	for _, b := range toWinAnsi(text) {
+		switch b {
+		case '(', ')', '\\':
+			out.WriteByte('\\')
+			out.WriteByte(b)
+		default:
+			if b < 32 || b > 126 {
+				fmt.Fprintf(&out, "\\%03o", b)
+			} else {
+				out.WriteByte(b)
+			}
+		}
+	}
+	return out.String()
+}
+
+// toWinAnsi converts text to the WinAnsi encoding of the standard fonts, unknown characters become ?
+func toWinAnsi(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r == '€':
+			out = append(out, 128)
+		case r == '–' || r == '—':
+			out = append(out, '-')
+		case r == '‘' || r == '’':
+			out = append(out, '\'')
+		case r == '“' || r == '”':
+			out = append(out, '"')
+		case r == '\t':
+			out = append(out, ' ')
+		case r < 128 || (r >= 160 && r <= 255):
+			out = append(out, byte(r))
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+func parseHexColor(hex string) (float64, float64, float64) {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) != 6 {
+		return 0, 0, 0
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0
+	}
+	return float64(value>>16&0xff) / 255, float64(value>>8&0xff) / 255, float64(value&0xff) / 255
+}
+
+func pdfNumber(value float64) string {
+	return strconv.FormatFloat(math.Round(value*1000)/1000, 'f', -1, 64)
+}
+
+// Character widths of ASCII 32-126 in 1/1000 em
+var helveticaWidths = []int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = []int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}